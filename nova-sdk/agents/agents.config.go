@@ -1,5 +1,7 @@
 package agents
 
+import "net/http"
+
 // Config represents the core configuration parameters for creating an agent
 type Config struct {
 	// Name is the identifier for the agent
@@ -18,4 +20,8 @@ type Config struct {
 	APIKey string
 
 	KeepConversationHistory bool
+
+	// HTTPClient is the HTTP client used to reach the engine (optional).
+	// Use a cassette.Recorder client to record or replay the traffic in tests.
+	HTTPClient *http.Client
 }
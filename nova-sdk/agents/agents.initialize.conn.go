@@ -3,10 +3,12 @@ package agents
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/snipwise/nova/nova-sdk/cassette"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
 )
@@ -28,6 +30,33 @@ func normalizeModelName(modelName string) string {
 	return normalized
 }
 
+// ResolveHTTPClient returns the HTTP client an agent must use: Config.HTTPClient when set,
+// otherwise the cassette recorder configured with NOVA_CASSETTE, otherwise nil (default client)
+func ResolveHTTPClient(agentConfig Config) (*http.Client, error) {
+	if agentConfig.HTTPClient != nil {
+		return agentConfig.HTTPClient, nil
+	}
+	return cassette.HTTPClientFromEnv()
+}
+
+// NewOpenAIClient creates the OpenAI client of an agent configuration: every engine request
+// must use it so that Config.HTTPClient and the NOVA_CASSETTE recorder apply
+func NewOpenAIClient(agentConfig Config) (openai.Client, error) {
+	httpClient, err := ResolveHTTPClient(agentConfig)
+	if err != nil {
+		return openai.Client{}, err
+	}
+
+	requestOptions := []option.RequestOption{
+		option.WithBaseURL(agentConfig.EngineURL),
+		option.WithAPIKey(agentConfig.APIKey),
+	}
+	if httpClient != nil {
+		requestOptions = append(requestOptions, option.WithHTTPClient(httpClient))
+	}
+	return openai.NewClient(requestOptions...), nil
+}
+
 func InitializeConnection(ctx context.Context, agentConfig Config, modelConfig models.Config) (client openai.Client, log logger.Logger, err error) {
 	// export NOVA_LOG_LEVEL=debug  # Shows all logs
 	// export NOVA_LOG_LEVEL=info   # Shows info, warn, error
@@ -38,10 +67,11 @@ func InitializeConnection(ctx context.Context, agentConfig Config, modelConfig m
 	// Create logger from environment variable
 	log = logger.GetLoggerFromEnv()
 
-	client = openai.NewClient(
-		option.WithBaseURL(agentConfig.EngineURL),
-		option.WithAPIKey(agentConfig.APIKey),
-	)
+	client, err = NewOpenAIClient(agentConfig)
+	if err != nil {
+		log.Error("Error setting up the HTTP client: %v", err)
+		return openai.Client{}, nil, err
+	}
 
 	// Check if the model is available on the specified engine URL
 	// Uses normalizeModelName to handle variations like:
@@ -0,0 +1,192 @@
+package agenttool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/agents/crew"
+	"github.com/snipwise/nova/nova-sdk/agents/rag"
+	"github.com/snipwise/nova/nova-sdk/agents/remote"
+	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+)
+
+// DefaultMaxPassages is the default number of passages returned by a rag agent tool
+const DefaultMaxPassages = 5
+
+// Chat turns a chat agent into a tool (named ask_<agent name> by default) returning its answer
+func Chat(agent *chat.Agent, options ...Option) *tools.RegisteredTool {
+	d := newDelegate(agent.GetName(), agent.Kind(), "ask",
+		describe("Delegate a task to", agent.GetName(), agent.GetConfig().SystemInstructions), options)
+	d.reset = agent.ResetMessages
+	d.history = agent.GetMessages
+	d.usage = agent.GetTotalUsage
+	d.contexts = []contextual{agent}
+	d.use(slotOf(agent))
+
+	return tools.Register(d.name, d.description,
+		func(ctx context.Context, input TaskInput) (string, error) {
+			return d.run(ctx, input.message(), func(userMessages []messages.Message) (string, error) {
+				result, err := agent.GenerateCompletion(userMessages)
+				if err != nil {
+					return "", err
+				}
+				return result.Response, nil
+			})
+		})
+}
+
+// Remote turns a remote agent into a tool (named ask_<agent name> by default) returning its answer.
+// The usage of the remote server is not reported.
+func Remote(agent *remote.Agent, options ...Option) *tools.RegisteredTool {
+	d := newDelegate(agent.GetName(), agent.Kind(), "ask", describe("Delegate a task to", agent.GetName(), ""), options)
+	d.reset = agent.ResetMessages
+	d.contexts = []contextual{agent}
+	d.use(slotOf(agent))
+
+	return tools.Register(d.name, d.description,
+		func(ctx context.Context, input TaskInput) (string, error) {
+			return d.run(ctx, input.message(), func(userMessages []messages.Message) (string, error) {
+				result, err := agent.GenerateCompletion(userMessages)
+				if err != nil {
+					return "", err
+				}
+				return result.Response, nil
+			})
+		})
+}
+
+// Crew turns a crew agent into a tool (named ask_<crew name> by default) returning the answer
+// of its selected agent. The usage is the usage of the chat agents of the crew.
+func Crew(agent *crew.CrewAgent, options ...Option) *tools.RegisteredTool {
+	d := newDelegate(agent.GetName(), agent.Kind(), "ask", describe("Delegate a task to", agent.GetName(), ""), options)
+	d.reset = agent.ResetMessages
+	d.usage = func() budget.Usage {
+		var usage budget.Usage
+		for _, chatAgent := range agent.GetChatAgents() {
+			usage = usage.Add(chatAgent.GetTotalUsage())
+		}
+		return usage
+	}
+	d.contexts = []contextual{agent}
+	d.use(slotOf(agent))
+	for _, chatAgent := range agent.GetChatAgents() {
+		d.contexts = append(d.contexts, chatAgent)
+		d.use(slotOf(chatAgent))
+	}
+
+	return tools.Register(d.name, d.description,
+		func(ctx context.Context, input TaskInput) (string, error) {
+			return d.run(ctx, input.message(), func(userMessages []messages.Message) (string, error) {
+				result, err := agent.GenerateCompletion(userMessages)
+				if err != nil {
+					return "", err
+				}
+				return result.Response, nil
+			})
+		})
+}
+
+// Structured turns a structured agent into a tool (named ask_<agent name> by default) returning
+// its structured output as JSON. The fields of the output are listed in the generated description.
+func Structured[Output any](agent *structured.Agent[Output], options ...Option) *tools.RegisteredTool {
+	description := describe("Delegate a task to", agent.GetName(), agent.GetConfig().SystemInstructions)
+	if fields := jsonFields(reflect.TypeOf((*Output)(nil)).Elem()); len(fields) > 0 {
+		description += ". Returns JSON with the fields: " + strings.Join(fields, ", ")
+	}
+	d := newDelegate(agent.GetName(), agent.Kind(), "ask", description, options)
+	d.reset = agent.ResetMessages
+	d.history = agent.GetMessages
+	d.usage = agent.GetTotalUsage
+	d.contexts = []contextual{agent}
+	d.use(slotOf(agent))
+
+	return tools.Register(d.name, d.description,
+		func(ctx context.Context, input TaskInput) (string, error) {
+			return d.run(ctx, input.message(), func(userMessages []messages.Message) (string, error) {
+				output, _, err := agent.GenerateStructuredData(userMessages)
+				if err != nil {
+					return "", err
+				}
+				if output == nil {
+					return "", fmt.Errorf("the %s agent returned no data", agent.GetName())
+				}
+				encoded, err := json.Marshal(output)
+				return string(encoded), err
+			})
+		})
+}
+
+// SearchInput holds the arguments of the rag agent tools
+type SearchInput struct {
+	Query      string `json:"query" description:"Text to search for"`
+	MaxResults int    `json:"max_results,omitempty" description:"Maximum number of passages to return"`
+}
+
+// Passage is a search result of a rag agent tool
+type Passage struct {
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+// Rag turns a rag agent into a tool (named search_<agent name> by default) returning the
+// passages most similar to the query, above similarityLimit
+func Rag(agent *rag.Agent, similarityLimit float64, options ...Option) *tools.RegisteredTool {
+	d := newDelegate(agent.GetName(), agent.Kind(), "search", "Search the knowledge base of the "+agent.GetName()+" agent", options)
+	d.contexts = []contextual{agent}
+	d.use(slotOf(agent))
+
+	return tools.Register(d.name, d.description,
+		func(ctx context.Context, input SearchInput) ([]Passage, error) {
+			maxResults := input.MaxResults
+			if maxResults <= 0 {
+				maxResults = DefaultMaxPassages
+			}
+			var passages []Passage
+			_, err := d.run(ctx, input.Query, func([]messages.Message) (string, error) {
+				records, err := agent.SearchTopN(input.Query, similarityLimit, maxResults)
+				if err != nil {
+					return "", err
+				}
+				passages = make([]Passage, 0, len(records))
+				for _, record := range records {
+					passages = append(passages, Passage{Content: record.Prompt, Score: record.Similarity})
+				}
+				encoded, err := json.Marshal(passages)
+				return string(encoded), err
+			})
+			return passages, err
+		})
+}
+
+// jsonFields returns the JSON names of the exported fields of a struct type
+func jsonFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []string
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -0,0 +1,295 @@
+// Package agenttool turns agents into tools of a tools agent (agents-as-tools): a chat,
+// structured, rag, remote or crew agent becomes a registered tool with a generated name,
+// description and input schema, and its handler.
+//
+//	sqlExpert := agenttool.Chat(sqlAgent, agenttool.WithDescription("Ask the SQL expert to write a query"))
+//	agent, err := tools.NewAgent(ctx, agentConfig, modelConfig, tools.WithRegisteredTools(sqlExpert))
+//
+// Each call of the tool runs in a fresh sub-conversation (FreshConversation, the default) or
+// goes on with the previous ones (PersistentConversation). The usage of the sub-agent is added
+// to the budgets of the calling tools agent, and the trace of the call to its nested calls
+// (tools.Agent.GetNestedCalls). The requests of the sub-agent use the context of the tool call,
+// so the loop timeout of the calling tools agent cancels them. An agent handles one call at a
+// time, even when it is wrapped by several tools (e.g. agenttool.Chat and mcpserver.WithChatAgent).
+package agenttool
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"weak"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+// Conversation is the sub-conversation of the calls of an agent tool
+type Conversation int
+
+const (
+	// FreshConversation: the messages of the sub-agent are reset before each call
+	FreshConversation Conversation = iota
+	// PersistentConversation: the calls go on with the same sub-conversation.
+	// The sub-agent must keep its history (agents.Config.KeepConversationHistory).
+	PersistentConversation
+)
+
+// maxInstructionsLength limits the excerpt of the system instructions in the generated description
+const maxInstructionsLength = 200
+
+type settings struct {
+	name         string
+	description  string
+	conversation Conversation
+}
+
+// Option configures an agent tool
+type Option func(*settings)
+
+// WithName sets the name of the tool (default: generated from the name of the agent, e.g. ask_sql_expert)
+func WithName(name string) Option {
+	return func(s *settings) {
+		s.name = name
+	}
+}
+
+// WithDescription sets the description of the tool (default: generated from the name and the
+// system instructions of the agent)
+func WithDescription(description string) Option {
+	return func(s *settings) {
+		s.description = description
+	}
+}
+
+// WithConversation sets the sub-conversation of the calls (default: FreshConversation)
+func WithConversation(conversation Conversation) Option {
+	return func(s *settings) {
+		s.conversation = conversation
+	}
+}
+
+// TaskInput holds the arguments of the tools delegating to a chat, structured, remote or crew agent
+type TaskInput struct {
+	Task    string `json:"task" description:"Task or question for the agent, with all the details it needs"`
+	Context string `json:"context,omitempty" description:"Additional context or data the agent works on"`
+}
+
+// message is the user message sent to the sub-agent
+func (input TaskInput) message() string {
+	if input.Context == "" {
+		return input.Task
+	}
+	return input.Task + "\n\nContext:\n" + input.Context
+}
+
+// contextual is an agent whose requests use the context set by SetContext
+type contextual interface {
+	GetContext() context.Context
+	SetContext(ctx context.Context)
+}
+
+// agentSlot lets an agent handle one call at a time (a buffered channel of one slot, so that
+// the waiting calls give up when their context is done)
+type agentSlot struct {
+	// id orders the acquisitions of the slots of several agents
+	id   uint64
+	slot chan struct{}
+}
+
+// slots are shared by all the tools of an agent (e.g. agenttool.Chat and mcpserver.WithChatAgent
+// over the same agent); an entry is removed when its agent is collected
+var slots = struct {
+	sync.Mutex
+	byAgent map[any]*agentSlot
+	next    uint64
+}{byAgent: make(map[any]*agentSlot)}
+
+// slotOf returns the slot of an agent
+func slotOf[Agent any](agent *Agent) *agentSlot {
+	key := weak.Make(agent)
+	slots.Lock()
+	defer slots.Unlock()
+	if slot, found := slots.byAgent[key]; found {
+		return slot
+	}
+	slots.next++
+	slot := &agentSlot{id: slots.next, slot: make(chan struct{}, 1)}
+	slots.byAgent[key] = slot
+	runtime.AddCleanup(agent, func(key weak.Pointer[Agent]) {
+		slots.Lock()
+		defer slots.Unlock()
+		delete(slots.byAgent, key)
+	}, key)
+	return slot
+}
+
+// delegate runs the calls of an agent tool
+type delegate struct {
+	settings
+	agentName string
+	kind      agents.Kind
+	// reset clears the sub-conversation (nil: the agent has no conversation)
+	reset func()
+	// history returns the messages of the sub-conversation (nil: not kept locally)
+	history func() []messages.Message
+	// usage returns the total usage of the sub-agent (nil: not reported)
+	usage func() budget.Usage
+	// contexts are the agents whose requests use the context of the call
+	contexts []contextual
+	// slots of the agents used by a call (ordered by id)
+	slots []*agentSlot
+}
+
+func newDelegate(agentName string, kind agents.Kind, prefix string, description string, options []Option) *delegate {
+	d := &delegate{agentName: agentName, kind: kind}
+	for _, option := range options {
+		option(&d.settings)
+	}
+	if d.name == "" {
+		d.name = prefix + "_" + toolName(agentName)
+	}
+	if d.description == "" {
+		d.description = description
+	}
+	return d
+}
+
+// use adds the slots of the agents used by the calls
+func (d *delegate) use(agentSlots ...*agentSlot) {
+	for _, agentSlot := range agentSlots {
+		if !slices.Contains(d.slots, agentSlot) {
+			d.slots = append(d.slots, agentSlot)
+		}
+	}
+	slices.SortFunc(d.slots, func(a, b *agentSlot) int { return cmp.Compare(a.id, b.id) })
+}
+
+// acquire waits for the slots of the agents (in the order of their ids, so that the delegates
+// sharing agents don't deadlock) and returns the function releasing them
+func (d *delegate) acquire(ctx context.Context) (release func(), err error) {
+	acquired := 0
+	release = func() {
+		for _, agentSlot := range d.slots[:acquired] {
+			<-agentSlot.slot
+		}
+	}
+	for _, agentSlot := range d.slots {
+		select {
+		case agentSlot.slot <- struct{}{}:
+			acquired++
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// run executes a call and records its trace in the calling tools agent.
+// The requests of the sub-agent use ctx: its cancellation stops the call.
+// An agent handles one call at a time, even when it is wrapped by several tools.
+func (d *delegate) run(ctx context.Context, input string, call func(userMessages []messages.Message) (string, error)) (string, error) {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	for _, agent := range d.contexts {
+		previous := agent.GetContext()
+		agent.SetContext(ctx)
+		defer agent.SetContext(previous)
+	}
+
+	if d.conversation == FreshConversation && d.reset != nil {
+		d.reset()
+	}
+	var before budget.Usage
+	if d.usage != nil {
+		before = d.usage()
+	}
+	historyLength := 0
+	if d.history != nil {
+		historyLength = len(d.history())
+	}
+	start := time.Now()
+
+	userMessages := []messages.Message{{Role: roles.User, Content: input}}
+	output, err := call(userMessages)
+
+	trace := tools.NestedCall{
+		Tool:     d.name,
+		Agent:    d.agentName,
+		Kind:     d.kind,
+		Input:    input,
+		Output:   output,
+		Messages: d.exchanged(historyLength, userMessages, output, err),
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	if d.usage != nil {
+		trace.Usage = d.usage().Sub(before)
+	}
+	tools.RecordNestedCall(ctx, trace)
+
+	return output, err
+}
+
+// exchanged returns the messages of a call: the messages added to the sub-conversation, or
+// the messages sent (framed by the directives of the sub-agent) and the answer when the
+// sub-conversation is not kept. An agent without conversation (a search) exchanges no messages.
+func (d *delegate) exchanged(historyLength int, userMessages []messages.Message, output string, err error) []messages.Message {
+	if d.reset == nil {
+		return nil
+	}
+	if d.history != nil {
+		if history := d.history(); len(history) > historyLength {
+			return slices.Clone(history[historyLength:])
+		}
+	}
+	exchanged := slices.Clone(userMessages)
+	if err == nil {
+		exchanged = append(exchanged, messages.Message{Role: roles.Assistant, Content: output})
+	}
+	return exchanged
+}
+
+// describe generates the description of a tool from the system instructions of the agent
+func describe(action string, agentName string, instructions string) string {
+	description := action + " the " + agentName + " agent"
+	instructions = strings.Join(strings.Fields(instructions), " ")
+	if instructions == "" {
+		return description
+	}
+	if len(instructions) > maxInstructionsLength {
+		cut := maxInstructionsLength
+		// Don't cut a UTF-8 sequence
+		for cut > 0 && instructions[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		instructions = instructions[:cut] + "…"
+	}
+	return description + ". Its instructions: " + instructions
+}
+
+var invalidToolNameCharacters = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// toolName turns the name of an agent into a tool name (e.g. "SQL Expert" into "sql_expert")
+func toolName(agentName string) string {
+	name := invalidToolNameCharacters.ReplaceAllString(strings.ToLower(agentName), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "agent"
+	}
+	return name
+}
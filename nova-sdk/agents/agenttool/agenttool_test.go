@@ -0,0 +1,218 @@
+package agenttool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func newSQLExpert(t *testing.T, engineURL string) *chat.Agent {
+	t.Helper()
+	agent, err := chat.NewAgent(context.Background(),
+		agents.Config{Name: "SQL Expert", EngineURL: engineURL, SystemInstructions: "You write SQL queries.", KeepConversationHistory: true},
+		models.Config{Name: "test-model"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return agent
+}
+
+func TestChat_NestsTheSubAgentCalls(t *testing.T) {
+	subEngine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("SELECT count(*) FROM users")))
+	defer subEngine.Close()
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("There are 42 users")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("ask_sql_expert", `{"task":"count the users"}`),
+		novatest.Call("ask_sql_expert", `{"task":"count the users again"}`),
+	))
+
+	sqlExpert := Chat(newSQLExpert(t, subEngine.URL))
+	if !strings.Contains(sqlExpert.Tool.Description, "You write SQL queries.") {
+		t.Errorf("the description should include the instructions, got %q", sqlExpert.Tool.Description)
+	}
+
+	agent, err := tools.NewAgent(context.Background(),
+		agents.Config{Name: "main", EngineURL: engine.URL, SystemInstructions: "You delegate"},
+		models.Config{Name: "test-model"},
+		tools.WithRegisteredTools(sqlExpert),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "How many users?"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second := engine.ChatRequests()[1].Messages
+	if result := second[len(second)-1]; result.Role != "tool" || result.Content != "SELECT count(*) FROM users" {
+		t.Errorf("the answer of the sub-agent should be the tool result, got %+v", result)
+	}
+
+	// Fresh sub-conversations: the second call doesn't see the first one
+	if sent := subEngine.ChatRequests()[1].Messages; len(sent) != 2 || sent[1].Content != "count the users again" {
+		t.Errorf("each call should start a new sub-conversation, got %+v", sent)
+	}
+
+	nested := agent.GetNestedCalls()
+	if len(nested) != 2 || nested[0].Agent != "SQL Expert" || nested[0].Tool != "ask_sql_expert" ||
+		nested[0].Usage.Requests != 1 || len(nested[0].Messages) != 2 {
+		t.Fatalf("unexpected nested calls %+v", nested)
+	}
+	if exchanged := nested[1].Messages; exchanged[0].Content != "count the users again" || exchanged[1].Content != "SELECT count(*) FROM users" {
+		t.Errorf("the messages of the sub-conversation should be recorded, got %+v", exchanged)
+	}
+	if usage := agent.GetCallUsage(); usage.Requests != 4 {
+		t.Errorf("the usage of the sub-agent should be added to the parent, got %+v", usage)
+	}
+}
+
+func TestChat_PersistentConversation(t *testing.T) {
+	subEngine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("noted")))
+	defer subEngine.Close()
+
+	tool := Chat(newSQLExpert(t, subEngine.URL), WithConversation(PersistentConversation), WithName("sql"))
+	for _, task := range []string{`{"task":"first"}`, `{"task":"second","context":"users table"}`} {
+		if _, err := tool.Handler(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	sent := subEngine.ChatRequests()[1].Messages
+	if len(sent) != 4 || sent[1].Content != "first" || !strings.HasSuffix(sent[3].Content, "Context:\nusers table") {
+		t.Errorf("the second call should go on with the sub-conversation, got %+v", sent)
+	}
+	if tool.Tool.GetName() != "sql" {
+		t.Errorf("unexpected name %q", tool.Tool.GetName())
+	}
+}
+
+func TestChat_ContextCancelsTheSubAgent(t *testing.T) {
+	// The first chat completion of the engine answers only when the request is canceled
+	backend := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("SELECT 1")))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	proxy := httputil.NewSingleHostReverseProxy(backendURL)
+	var chatRequests atomic.Int32
+	release := make(chan struct{})
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chat/completions") && chatRequests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer engine.Close()
+	defer close(release)
+
+	tool := Chat(newSQLExpert(t, engine.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := tool.Handler(ctx, `{"task":"count the users"}`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the call should be canceled at the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the call should stop at the deadline, took %v", elapsed)
+	}
+
+	// The sub-agent is released and its context restored
+	if output, err := tool.Handler(context.Background(), `{"task":"count the users"}`); err != nil || output != "SELECT 1" {
+		t.Errorf("unexpected output %q (%v)", output, err)
+	}
+}
+
+func TestChat_ToolsOfTheSameAgentShareItsSlot(t *testing.T) {
+	// The engine records the highest number of concurrent chat completions
+	backend := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("SELECT 1")))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	proxy := httputil.NewSingleHostReverseProxy(backendURL)
+	var running, maxRunning atomic.Int32
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			current := running.Add(1)
+			defer running.Add(-1)
+			if current > maxRunning.Load() {
+				maxRunning.Store(current)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer engine.Close()
+
+	agent := newSQLExpert(t, engine.URL)
+	first, second := Chat(agent), Chat(agent, WithName("sql"))
+	done := make(chan error, 2)
+	for _, tool := range []*tools.RegisteredTool{first, second} {
+		go func() {
+			_, err := tool.Handler(context.Background(), `{"task":"count the users"}`)
+			done <- err
+		}()
+	}
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if maxRunning.Load() != 1 {
+		t.Errorf("the agent should handle one call at a time, got %d concurrent requests", maxRunning.Load())
+	}
+}
+
+type ticket struct {
+	Title    string `json:"title"`
+	Priority string `json:"priority,omitempty"`
+	internal bool
+}
+
+func TestStructured(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text(`{"title":"Login fails","priority":"high"}`)))
+	defer engine.Close()
+
+	agent, err := structured.NewAgent[ticket](context.Background(),
+		agents.Config{Name: "triage", EngineURL: engine.URL, SystemInstructions: "You create tickets."},
+		models.Config{Name: "test-model"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tool := Structured(agent)
+	if tool.Tool.GetName() != "ask_triage" || !strings.HasSuffix(tool.Tool.Description, "Returns JSON with the fields: title, priority") {
+		t.Errorf("unexpected tool %q: %q", tool.Tool.GetName(), tool.Tool.Description)
+	}
+	output, err := tool.Handler(context.Background(), `{"task":"users can't log in"}`)
+	if err != nil || output != `{"title":"Login fails","priority":"high"}` {
+		t.Errorf("unexpected output %q (%v)", output, err)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	for name, expected := range map[string]string{"SQL Expert": "sql_expert", "summarizer-v2": "summarizer_v2", "???": "agent"} {
+		if got := toolName(name); got != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, got)
+		}
+	}
+}
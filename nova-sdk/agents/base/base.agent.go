@@ -2,12 +2,13 @@ package base
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 
 	"github.com/openai/openai-go/v3"
 
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/cache"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
@@ -36,6 +37,23 @@ type Agent struct {
 	// totalTokensUsed      int
 	lastRequestJSON  string
 	lastResponseJSON string
+
+	// Automatic continuation of truncated completions (finish_reason == "length")
+	continuation      ContinuationConfig
+	lastContinuations int
+
+	// Response cache for deterministic requests
+	cache           cache.Config
+	bypassCacheOnce bool
+	lastFromCache   bool
+
+	// Token and cost budgets
+	budget       budget.Config
+	callUsage    budget.Tracker
+	sessionUsage budget.Tracker
+	totalUsage   budget.Tracker
+	// usageEstimation estimates the usage the engine doesn't report even without budget
+	usageEstimation bool
 }
 
 // AgentOption is a functional option for configuring an Agent
@@ -122,7 +140,9 @@ func (agent *Agent) StopStream() {
 }
 
 // ResetMessages clears the agent's message history except for the initial system message
+// and starts a new budget session
 func (agent *Agent) ResetMessages() {
+	agent.sessionUsage.Reset()
 	if len(agent.ChatCompletionParams.Messages) > 0 {
 		firstMsg := agent.ChatCompletionParams.Messages[0]
 		if firstMsg.OfSystem != nil {
@@ -192,6 +212,11 @@ func (agent *Agent) SetSystemInstructions(instructions string) {
 // GenerateCompletion executes a chat completion with the provided messages
 // and returns the response, finish reason, and any error
 func (agent *Agent) GenerateCompletion(messages []openai.ChatCompletionMessageParamUnion) (response string, finishReason string, err error) {
+	agent.BeginBudgetCall()
+	if stop, err := agent.CheckBudget(); stop {
+		return "", budget.FinishReasonExceeded, err
+	}
+
 	// Prepare messages for the API call
 	// If KeepConversationHistory is true, add to history permanently
 	// Otherwise, create a temporary message list for this call only
@@ -210,37 +235,63 @@ func (agent *Agent) GenerateCompletion(messages []openai.ChatCompletionMessagePa
 	paramsForCall := agent.ChatCompletionParams
 	paramsForCall.Messages = messagesToSend
 
-	agent.SaveLastRequest()
+	agent.lastContinuations = 0
 
-	completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+	for pass := 0; ; pass++ {
+		agent.SaveLastRequest()
 
-	agent.SaveLastResponse(completion)
+		completion, err := agent.NewChatCompletion(paramsForCall)
 
-	if err != nil {
-		return "", "", err
-	}
+		agent.SaveLastResponse(completion)
 
-	if len(completion.Choices) > 0 {
-		response = completion.Choices[0].Message.Content
+		if err != nil {
+			return "", "", err
+		}
+
+		if len(completion.Choices) == 0 {
+			return "", "", errors.New(errNoChoices)
+		}
+
+		response = mergeContinuation(response, completion.Choices[0].Message.Content, agent.continuation.MaxOverlap)
 		finishReason = completion.Choices[0].FinishReason
 
-		// Only add assistant response to history if KeepConversationHistory is true and response is not empty
-		if agent.Config.KeepConversationHistory && response != "" {
-			agent.ChatCompletionParams.Messages = append(
-				agent.ChatCompletionParams.Messages,
-				openai.AssistantMessage(response),
-			)
+		if !agent.shouldContinue(finishReason, pass) {
+			break
+		}
+		if stop, err := agent.CheckBudget(); stop {
+			finishReason = budget.FinishReasonExceeded
+			if err != nil {
+				return response, finishReason, err
+			}
+			break
 		}
 
-		return response, finishReason, nil
+		// The answer was truncated: ask the model to resume where it stopped
+		agent.lastContinuations++
+		agent.Log.Info("✂️ Response truncated (finish_reason: length), requesting continuation %d/%d",
+			agent.lastContinuations, agent.continuation.MaxContinuations)
+		paramsForCall.Messages = agent.continuationMessages(messagesToSend, response)
 	}
 
-	return "", "", errors.New(errNoChoices)
+	// Only add assistant response to history if KeepConversationHistory is true and response is not empty
+	if agent.Config.KeepConversationHistory && response != "" {
+		agent.ChatCompletionParams.Messages = append(
+			agent.ChatCompletionParams.Messages,
+			openai.AssistantMessage(response),
+		)
+	}
+
+	return response, finishReason, nil
 }
 
 // GenerateCompletionWithReasoning executes a chat completion with the provided messages
 // and returns both the response and reasoning content
 func (agent *Agent) GenerateCompletionWithReasoning(messages []openai.ChatCompletionMessageParamUnion) (response string, reasoning string, finishReason string, err error) {
+	agent.BeginBudgetCall()
+	if stop, err := agent.CheckBudget(); stop {
+		return "", "", budget.FinishReasonExceeded, err
+	}
+
 	// Prepare messages for the API call
 	// If KeepConversationHistory is true, add to history permanently
 	// Otherwise, create a temporary message list for this call only
@@ -261,7 +312,7 @@ func (agent *Agent) GenerateCompletionWithReasoning(messages []openai.ChatComple
 
 	agent.SaveLastRequest()
 
-	completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+	completion, err := agent.NewChatCompletion(paramsForCall)
 
 	agent.SaveLastResponse(completion)
 
@@ -274,19 +325,12 @@ func (agent *Agent) GenerateCompletionWithReasoning(messages []openai.ChatComple
 	}
 
 	finishReason = completion.Choices[0].FinishReason
-	jsonResponse := completion.Choices[0].Message.RawJSON()
-
-	// Extract the content of the reasoning_content field from the jsonResponse
-	var reasoningContent struct {
-		ReasoningContent string `json:"reasoning_content"`
-	}
 
-	err = json.Unmarshal([]byte(jsonResponse), &reasoningContent)
+	// Extract the content of the reasoning_content field from the message
+	reasoning, err = reasoningContent(completion.Choices[0].Message)
 	if err != nil {
 		return "", "", finishReason, err
 	}
-
-	reasoning = reasoningContent.ReasoningContent
 	response = completion.Choices[0].Message.Content
 
 	// Only add assistant response to history if KeepConversationHistory is true and response is not empty
@@ -308,26 +352,123 @@ func (agent *Agent) GenerateStreamCompletion(
 ) (response string, finishReason string, err error) {
 
 	agent.StreamCanceled = false
+	agent.lastContinuations = 0
+
+	agent.BeginBudgetCall()
+	if stop, err := agent.CheckBudget(); stop {
+		if err == nil {
+			err = callBack("", budget.FinishReasonExceeded)
+		}
+		return "", budget.FinishReasonExceeded, err
+	}
 
 	paramsForCall := agent.ChatCompletionParams
 	paramsForCall.Messages = agent.prepareMessagesToSend(messages)
-	agent.SaveLastRequest()
+	messagesToSend := paramsForCall.Messages
 
+	for pass := 0; ; pass++ {
+		agent.SaveLastRequest()
+
+		passCallBack := callBack
+		var trimmer *seamTrimmer
+		if pass > 0 {
+			// Remove the text the model repeats when resuming before it reaches the caller
+			trimmer = newSeamTrimmer(response, agent.continuation.MaxOverlap, callBack)
+			passCallBack = trimmer.write
+		}
+
+		var passResponse string
+		passResponse, finishReason, err = agent.streamCompletionPass(paramsForCall, pass, passCallBack)
+		response = mergeContinuation(response, passResponse, agent.continuation.MaxOverlap)
+		if err != nil {
+			return response, finishReason, err
+		}
+		if trimmer != nil {
+			// Emit the text still buffered when the pass ended without a finish reason
+			if err := trimmer.flush(""); err != nil {
+				return response, finishReason, err
+			}
+		}
+
+		if !agent.shouldContinue(finishReason, pass) {
+			break
+		}
+		if stop, err := agent.CheckBudget(); stop {
+			// The truncated pass did not report its finish reason: close the stream for the caller
+			finishReason = budget.FinishReasonExceeded
+			if err != nil {
+				return response, finishReason, err
+			}
+			if err := callBack("", finishReason); err != nil {
+				return response, finishReason, err
+			}
+			break
+		}
+
+		// The answer was truncated: ask the model to resume where it stopped
+		agent.lastContinuations++
+		agent.Log.Info("✂️ Streamed response truncated (finish_reason: length), requesting continuation %d/%d",
+			agent.lastContinuations, agent.continuation.MaxContinuations)
+		paramsForCall.Messages = agent.continuationMessages(messagesToSend, response)
+	}
+
+	agent.appendAssistantToHistory(response)
+	return response, finishReason, nil
+}
+
+// streamCompletionPass runs a single streaming request and forwards the chunks to callBack.
+// When the pass is truncated and will be continued, the intermediate "length" finish reason
+// is hidden from callBack so the caller sees one continuous stream.
+func (agent *Agent) streamCompletionPass(
+	paramsForCall openai.ChatCompletionNewParams,
+	pass int,
+	callBack func(partialResponse string, finishReason string) error,
+) (response string, finishReason string, err error) {
+
+	emit := func(content string, reason string) error {
+		if agent.shouldContinue(reason, pass) {
+			reason = ""
+		}
+		return callBack(content, reason)
+	}
+
+	key := agent.cacheKey(agent.Ctx, paramsForCall)
+	if cached := agent.cachedCompletion(key); cached != nil {
+		// Replay the cached answer as simulated chunks
+		agent.SaveLastResponse(cached)
+		response = cached.Choices[0].Message.Content
+		finishReason = cached.Choices[0].FinishReason
+		if err := agent.replayChunks(response, emit); err != nil {
+			return response, finishReason, err
+		}
+		if !agent.shouldContinue(finishReason, pass) {
+			return response, finishReason, callBack("", finishReason)
+		}
+		return response, finishReason, nil
+	}
+
+	paramsForCall = agent.WithStreamUsage(paramsForCall)
 	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(agent.Ctx, paramsForCall)
 
 	var callBackError error
+	var usage openai.CompletionUsage
 
 	for stream.Next() {
 		if agent.StreamCanceled {
 			callBackError = canceledError
 			break
 		}
-		if callBackError = agent.processStreamChunk(stream.Current(), &finishReason, &response, callBack); callBackError != nil {
+		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
+		if callBackError = agent.processStreamChunk(chunk, &finishReason, &response, emit); callBackError != nil {
 			break
 		}
 	}
+	agent.RecordStreamUsage(paramsForCall, usage, response)
 
-	if finishReason != "" {
+	if finishReason != "" && !agent.shouldContinue(finishReason, pass) {
 		callBackError = callBack("", finishReason)
 		if callBackError != nil {
 			return response, finishReason, callBackError
@@ -341,7 +482,7 @@ func (agent *Agent) GenerateStreamCompletion(
 		return response, finishReason, err
 	}
 
-	agent.appendAssistantToHistory(response)
+	agent.storeStreamedCompletion(key, paramsForCall.Model, response, "", finishReason)
 	return response, finishReason, nil
 }
 
@@ -355,15 +496,35 @@ func (agent *Agent) GenerateStreamCompletionWithReasoning(
 
 	agent.StreamCanceled = false
 
+	agent.BeginBudgetCall()
+	if stop, err := agent.CheckBudget(); stop {
+		if err == nil {
+			err = responseCallback("", budget.FinishReasonExceeded)
+		}
+		return "", "", budget.FinishReasonExceeded, err
+	}
+
 	paramsForCall := agent.ChatCompletionParams
 	paramsForCall.Messages = agent.prepareMessagesToSend(messages)
 	agent.SaveLastRequest()
 
+	key := agent.cacheKey(agent.Ctx, paramsForCall)
+	if cached := agent.cachedCompletion(key); cached != nil {
+		response, reasoning, finishReason, err = agent.replayCompletionWithReasoning(cached, reasoningCallback, responseCallback)
+		if err != nil {
+			return response, reasoning, finishReason, err
+		}
+		agent.appendAssistantToHistory(response)
+		return response, reasoning, finishReason, nil
+	}
+
+	paramsForCall = agent.WithStreamUsage(paramsForCall)
 	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(agent.Ctx, paramsForCall)
 
 	var callBackError error
 	var hasReceivedReasoning bool
 	var reasoningEnded bool
+	var usage openai.CompletionUsage
 
 	for stream.Next() {
 		if agent.StreamCanceled {
@@ -372,6 +533,9 @@ func (agent *Agent) GenerateStreamCompletionWithReasoning(
 		}
 
 		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
 
 		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
 			agent.SaveLastChunkResponse(&chunk)
@@ -385,6 +549,7 @@ func (agent *Agent) GenerateStreamCompletionWithReasoning(
 			break
 		}
 	}
+	agent.RecordStreamUsage(paramsForCall, usage, reasoning+response)
 
 	if finishReason != "" {
 		callBackError = responseCallback("", finishReason)
@@ -400,6 +565,7 @@ func (agent *Agent) GenerateStreamCompletionWithReasoning(
 		return response, reasoning, finishReason, err
 	}
 
+	agent.storeStreamedCompletion(key, paramsForCall.Model, response, reasoning, finishReason)
 	agent.appendAssistantToHistory(response)
 	return response, reasoning, finishReason, nil
 }
@@ -418,6 +584,10 @@ func (agent *Agent) SaveLastRequest() error {
 
 // SaveLastResponse stores the last response JSON for telemetry or debugging
 func (agent *Agent) SaveLastResponse(completion *openai.ChatCompletion) error {
+	// No response when the request failed (e.g. canceled)
+	if completion == nil {
+		return nil
+	}
 	//Store last request and response JSON for telemetry or debugging
 	agent.lastResponseJSON = completion.RawJSON()
 	agent.Log.Debug("📝 Response Received:\n%s", agent.lastResponseJSON)
@@ -0,0 +1,198 @@
+package base
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+)
+
+// WithBudget sets the token and cost budgets of the agent
+func WithBudget(config budget.Config) AgentOption {
+	return func(agent *Agent) {
+		agent.SetBudgetConfig(config)
+	}
+}
+
+// SetBudgetConfig updates the budgets of the agent (the usage already recorded is kept)
+func (agent *Agent) SetBudgetConfig(config budget.Config) {
+	agent.budget = config
+	agent.callUsage.SetLimits(config.PerCall)
+	agent.sessionUsage.SetLimits(config.PerSession)
+	agent.totalUsage.SetLimits(config.PerAgent)
+}
+
+// GetBudgetConfig returns the budgets of the agent
+func (agent *Agent) GetBudgetConfig() budget.Config {
+	return agent.budget
+}
+
+// GetCallUsage returns the usage of the current (or last) call
+func (agent *Agent) GetCallUsage() budget.Usage {
+	return agent.callUsage.Usage()
+}
+
+// GetSessionUsage returns the usage of the current conversation
+func (agent *Agent) GetSessionUsage() budget.Usage {
+	return agent.sessionUsage.Usage()
+}
+
+// GetTotalUsage returns the usage of the agent since its creation
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.totalUsage.Usage()
+}
+
+// GetRemainingBudget returns the tightest remaining budget among the session and agent scopes
+func (agent *Agent) GetRemainingBudget() budget.Remaining {
+	return agent.sessionUsage.Remaining().Min(agent.totalUsage.Remaining())
+}
+
+// ResetBudgetSession starts a new budget session (ResetMessages does it too)
+func (agent *Agent) ResetBudgetSession() {
+	agent.sessionUsage.Reset()
+}
+
+// BeginBudgetCall starts a new per-call budget window.
+// The Generate* methods and the tool calls loops call it on entry.
+func (agent *Agent) BeginBudgetCall() {
+	agent.callUsage.Reset()
+}
+
+// CheckBudget must be called before each request of a call.
+// It returns stop == true when the call must end: with an *budget.ExceededError for
+// budget.ActionError, without error for budget.ActionStop (the caller returns its partial
+// result with budget.FinishReasonExceeded). With budget.ActionCompress an exhausted session
+// budget compresses the conversation history, starts a new session and lets the call go on.
+func (agent *Agent) CheckBudget() (stop bool, err error) {
+	exceeded := agent.exceededBudget()
+	if exceeded == nil {
+		return false, nil
+	}
+
+	switch agent.budget.OnExceeded {
+	case budget.ActionStop:
+		agent.Log.Warn("💸 %v: stopping", exceeded)
+		return true, nil
+
+	case budget.ActionCompress:
+		if exceeded.Scope != budget.ScopeSession || agent.budget.Compressor == nil {
+			agent.Log.Warn("💸 %v: stopping", exceeded)
+			return true, nil
+		}
+		agent.Log.Info("💸 %v: compressing the conversation", exceeded)
+		compressed, err := agent.budget.Compressor(agent.ChatCompletionParams.Messages)
+		if err != nil {
+			agent.Log.Error("Error compressing the conversation: %v", err)
+			return true, err
+		}
+		agent.ChatCompletionParams.Messages = compressed
+		agent.sessionUsage.Reset()
+		return agent.CheckBudget()
+
+	default:
+		agent.Log.Error("💸 %v", exceeded)
+		return true, exceeded
+	}
+}
+
+// exceededBudget returns the first exhausted budget (call, session, then agent), or nil
+func (agent *Agent) exceededBudget() *budget.ExceededError {
+	checks := []struct {
+		tracker *budget.Tracker
+		scope   budget.Scope
+	}{
+		{&agent.callUsage, budget.ScopeCall},
+		{&agent.sessionUsage, budget.ScopeSession},
+		{&agent.totalUsage, budget.ScopeAgent},
+	}
+	for _, check := range checks {
+		if err := check.tracker.Check(check.scope); err != nil {
+			return err.(*budget.ExceededError)
+		}
+	}
+	return nil
+}
+
+// SetUsageEstimation estimates the usage of the requests when the engine reports none, even
+// without budget (e.g. for the client budgets of a server). A configured budget always enables it.
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.usageEstimation = enabled
+}
+
+// recordUsage adds the usage of a live request to the call, session and agent trackers,
+// and to the request tracker of ctx (budget.WithRequestTracker).
+// When the engine reports no usage, it is estimated from the number of characters
+// (only when a budget or the usage estimation is enabled).
+func (agent *Agent) recordUsage(
+	ctx context.Context,
+	params openai.ChatCompletionNewParams,
+	usage openai.CompletionUsage,
+	completionChars int,
+) {
+	promptTokens := int(usage.PromptTokens)
+	completionTokens := int(usage.CompletionTokens)
+	if usage.TotalTokens == 0 && (agent.usageEstimation || agent.budget.Enabled()) {
+		promptChars := 0
+		for _, message := range messages.ConvertFromOpenAIMessages(params.Messages) {
+			promptChars += len(message.Content)
+		}
+		promptTokens = budget.EstimateTokens(promptChars)
+		completionTokens = budget.EstimateTokens(completionChars)
+	}
+
+	recorded := budget.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             budget.Cost(agent.budget.Prices, params.Model, promptTokens, completionTokens),
+		Requests:         1,
+	}
+	agent.callUsage.Add(recorded)
+	agent.sessionUsage.Add(recorded)
+	agent.totalUsage.Add(recorded)
+	if tracker := budget.RequestTracker(ctx); tracker != nil {
+		tracker.Add(recorded)
+	}
+}
+
+// AddUsage records usage consumed outside the requests of the agent (e.g. by a sub-agent
+// called from one of its tools) in the call, session and agent trackers.
+// The request tracker is left out: the sub-agent records its requests in it.
+func (agent *Agent) AddUsage(usage budget.Usage) {
+	agent.callUsage.Add(usage)
+	agent.sessionUsage.Add(usage)
+	agent.totalUsage.Add(usage)
+}
+
+// RecordCompletionUsage records the usage of a completion obtained without NewChatCompletion
+func (agent *Agent) RecordCompletionUsage(params openai.ChatCompletionNewParams, completion *openai.ChatCompletion) {
+	agent.recordCompletionUsage(agent.Ctx, params, completion)
+}
+
+// recordCompletionUsage records the usage of a completion requested with ctx
+func (agent *Agent) recordCompletionUsage(ctx context.Context, params openai.ChatCompletionNewParams, completion *openai.ChatCompletion) {
+	completionChars := 0
+	for _, choice := range completion.Choices {
+		completionChars += len(choice.Message.Content)
+		for _, toolCall := range choice.Message.ToolCalls {
+			completionChars += len(toolCall.Function.Name) + len(toolCall.Function.Arguments)
+		}
+	}
+	agent.recordUsage(ctx, params, completion.Usage, completionChars)
+}
+
+// RecordStreamUsage records the usage of a streamed request: usage is the usage chunk
+// (zero when the engine sent none) and response the streamed text
+func (agent *Agent) RecordStreamUsage(params openai.ChatCompletionNewParams, usage openai.CompletionUsage, response string) {
+	agent.recordUsage(agent.Ctx, params, usage, len(response))
+}
+
+// WithStreamUsage asks the engine to report the usage of a streamed request
+// when a budget or the usage estimation is enabled
+func (agent *Agent) WithStreamUsage(params openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+	if agent.usageEstimation || agent.budget.Enabled() {
+		params.StreamOptions.IncludeUsage = openai.Bool(true)
+	}
+	return params
+}
@@ -0,0 +1,216 @@
+package base
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+// newBudgetAgent returns an agent talking to a novatest engine answering every request
+// with a truncated "part " costing 10 tokens
+func newBudgetAgent(t *testing.T, maxContinuations int, config budget.Config) (*Agent, *novatest.Server) {
+	t.Helper()
+	engine := novatest.NewServer()
+	t.Cleanup(engine.Close)
+	engine.Reply(novatest.Response{
+		Content:      "part ",
+		FinishReason: "length",
+		Usage:        &novatest.Usage{PromptTokens: 6, CompletionTokens: 4},
+	})
+	agent := newClientAgent(engine.URL, maxContinuations)
+	agent.SetBudgetConfig(config)
+	return agent, engine
+}
+
+func TestBudget_PerCallStopReturnsPartialResult(t *testing.T) {
+	agent, engine := newBudgetAgent(t, 10, budget.Config{
+		PerCall:    budget.Limits{MaxTokens: 25},
+		OnExceeded: budget.ActionStop,
+	})
+
+	response, finishReason, err := agent.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 10 tokens per request: the third request reaches 30 >= 25, no fourth request
+	if len(engine.ChatRequests()) != 3 {
+		t.Errorf("want 3 requests, got %d", len(engine.ChatRequests()))
+	}
+	if response != "part part part " || finishReason != budget.FinishReasonExceeded {
+		t.Errorf("unexpected result %q / %q", response, finishReason)
+	}
+	if usage := agent.GetCallUsage(); usage.TotalTokens() != 30 || usage.Requests != 3 {
+		t.Errorf("unexpected call usage %+v", usage)
+	}
+
+	// The per-call window restarts with the next call
+	if _, _, err := agent.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("again")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if agent.GetTotalUsage().TotalTokens() != 60 {
+		t.Errorf("want 60 total tokens, got %d", agent.GetTotalUsage().TotalTokens())
+	}
+}
+
+func TestBudget_SessionErrorAndReset(t *testing.T) {
+	agent, _ := newBudgetAgent(t, 0, budget.Config{PerSession: budget.Limits{MaxTokens: 15}})
+	message := []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")}
+
+	for range 2 {
+		if _, _, err := agent.GenerateCompletion(message); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_, finishReason, err := agent.GenerateCompletion(message)
+	var exceeded *budget.ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Scope != budget.ScopeSession || !errors.Is(err, budget.ErrExceeded) {
+		t.Fatalf("want a session ExceededError, got %v", err)
+	}
+	if finishReason != budget.FinishReasonExceeded {
+		t.Errorf("unexpected finish reason %q", finishReason)
+	}
+
+	agent.ResetMessages()
+	if _, _, err := agent.GenerateCompletion(message); err != nil {
+		t.Errorf("a new session should be allowed: %v", err)
+	}
+}
+
+func TestBudget_CompressStartsNewSession(t *testing.T) {
+	compressions := 0
+	agent, engine := newBudgetAgent(t, 0, budget.Config{
+		PerSession: budget.Limits{MaxTokens: 10},
+		OnExceeded: budget.ActionCompress,
+		Compressor: func(history []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+			compressions++
+			return []openai.ChatCompletionMessageParamUnion{openai.SystemMessage("summary")}, nil
+		},
+	})
+	agent.Config.KeepConversationHistory = true
+	message := []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")}
+
+	for range 3 {
+		if _, _, err := agent.GenerateCompletion(message); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if compressions != 2 {
+		t.Errorf("want 2 compressions, got %d", compressions)
+	}
+	last, _ := engine.LastChatRequest()
+	if last.Messages[0].Content != "summary" || len(last.Messages) != 2 {
+		t.Errorf("the history should have been compressed, got %+v", last.Messages)
+	}
+}
+
+func TestBudget_CostAndStreamUsage(t *testing.T) {
+	agent, engine := newBudgetAgent(t, 0, budget.Config{
+		Prices: budget.PriceTable{"test": {Prompt: 1_000, Completion: 2_000}},
+	})
+
+	var streamed string
+	if _, _, err := agent.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+		func(chunk string, _ string) error { streamed += chunk; return nil },
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	last, _ := engine.LastChatRequest()
+	if last.StreamOptions == nil || !last.StreamOptions.IncludeUsage {
+		t.Error("streams should request usage when a budget is configured")
+	}
+	usage := agent.GetTotalUsage()
+	// "test-model" matches the "test" prefix: (6*1000 + 4*2000) / 1M
+	if usage.TotalTokens() != 10 || usage.Cost != 0.014 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if streamed != "part " {
+		t.Errorf("unexpected stream %q", streamed)
+	}
+}
+
+func TestBudget_StreamStopClosesTheStream(t *testing.T) {
+	agent, _ := newBudgetAgent(t, 5, budget.Config{
+		PerCall:    budget.Limits{MaxTokens: 10},
+		OnExceeded: budget.ActionStop,
+	})
+
+	var finishReasons []string
+	response, _, err := agent.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+		func(_ string, finishReason string) error {
+			if finishReason != "" {
+				finishReasons = append(finishReasons, finishReason)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "part " || len(finishReasons) != 1 || finishReasons[0] != budget.FinishReasonExceeded {
+		t.Errorf("unexpected stream end %q / %v", response, finishReasons)
+	}
+}
+
+func TestBudget_UsageEstimationOnlyWhenNeeded(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("hello")))
+	t.Cleanup(engine.Close)
+	agent := newClientAgent(engine.URL, 0)
+	stream := func() {
+		if _, _, err := agent.GenerateStreamCompletion(
+			[]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+			func(string, string) error { return nil },
+		); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// No budget: the usage the engine doesn't report is not estimated
+	stream()
+	if usage := agent.GetTotalUsage(); usage.TotalTokens() != 0 || usage.Requests != 1 {
+		t.Errorf("want one request without tokens, got %+v", usage)
+	}
+
+	agent.SetUsageEstimation(true)
+	stream()
+	last, _ := engine.LastChatRequest()
+	if last.StreamOptions == nil || !last.StreamOptions.IncludeUsage {
+		t.Error("streams should request usage when the usage estimation is enabled")
+	}
+	if agent.GetTotalUsage().TotalTokens() == 0 {
+		t.Error("the usage should be recorded once the usage estimation is enabled")
+	}
+}
+
+func TestBudget_RequestTrackerOfTheContext(t *testing.T) {
+	agent, _ := newBudgetAgent(t, 0, budget.Config{})
+	requestUsage := &budget.Tracker{}
+	restore := budget.TrackRequest(requestUsage, agent)
+
+	if _, _, err := agent.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The usage of a sub-agent is recorded in the request tracker by the sub-agent itself
+	agent.AddUsage(budget.Usage{PromptTokens: 100, Requests: 1})
+	restore()
+	if usage := requestUsage.Usage(); usage.TotalTokens() != 10 || usage.Requests != 1 {
+		t.Errorf("want the usage of the request (10 tokens), got %+v", usage)
+	}
+
+	// Once the context is restored, the requests are no longer charged to the request
+	if _, _, err := agent.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("again")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage := requestUsage.Usage(); usage.Requests != 1 {
+		t.Errorf("the request tracker should be left out after restore, got %+v", usage)
+	}
+	if budget.RequestTracker(agent.GetContext()) != nil {
+		t.Error("the context of the agent should be restored")
+	}
+}
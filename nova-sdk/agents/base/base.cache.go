@@ -0,0 +1,205 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/cache"
+)
+
+// WithCache enables the response cache of the agent
+func WithCache(config cache.Config) AgentOption {
+	return func(agent *Agent) {
+		agent.SetCacheConfig(config)
+	}
+}
+
+// SetCacheConfig updates the response cache settings (a nil Store disables the cache)
+func (agent *Agent) SetCacheConfig(config cache.Config) {
+	agent.cache = config
+}
+
+// GetCacheConfig returns the response cache settings
+func (agent *Agent) GetCacheConfig() cache.Config {
+	return agent.cache
+}
+
+// BypassCacheOnce makes the next request of the agent skip the cache (no lookup, no write).
+// Use cache.WithBypass on the agent context to bypass the cache for every request made with it.
+func (agent *Agent) BypassCacheOnce() {
+	agent.bypassCacheOnce = true
+}
+
+// IsLastResponseFromCache reports whether the last request was answered from the cache
+func (agent *Agent) IsLastResponseFromCache() bool {
+	return agent.lastFromCache
+}
+
+// cacheKey returns the cache key of the request, or "" when the cache does not apply:
+// no store, bypass requested (BypassCacheOnce or cache.WithBypass on ctx), or non-deterministic request without Force
+func (agent *Agent) cacheKey(ctx context.Context, params openai.ChatCompletionNewParams) string {
+	agent.lastFromCache = false
+
+	if !agent.cache.Enabled() {
+		return ""
+	}
+	if agent.bypassCacheOnce || cache.IsBypassed(ctx) {
+		agent.bypassCacheOnce = false
+		return ""
+	}
+	if !agent.cache.Force && !cache.IsDeterministic(params) {
+		return ""
+	}
+
+	key, err := cache.Key(cache.KindChat, params)
+	if err != nil {
+		agent.Log.Error("Error building cache key: %v", err)
+		return ""
+	}
+	return key
+}
+
+// cachedCompletion returns the completion stored under key, or nil on a miss
+func (agent *Agent) cachedCompletion(key string) *openai.ChatCompletion {
+	if key == "" {
+		return nil
+	}
+
+	data, found, err := agent.cache.Store.Get(key)
+	if err != nil {
+		agent.Log.Error("Error reading cache: %v", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(data, &completion); err != nil || len(completion.Choices) == 0 {
+		agent.Log.Warn("Ignoring invalid cache entry %s", key)
+		return nil
+	}
+
+	agent.lastFromCache = true
+	agent.Log.Debug("💾 Cache hit: %s", key)
+	return &completion
+}
+
+// storeCompletion saves the raw JSON of a completion under key
+func (agent *Agent) storeCompletion(key string, rawJSON string) {
+	if key == "" || rawJSON == "" {
+		return
+	}
+	if err := agent.cache.Store.Set(key, []byte(rawJSON), agent.cache.TTL); err != nil {
+		agent.Log.Error("Error writing cache: %v", err)
+	}
+}
+
+// storeStreamedCompletion saves a streamed answer as a regular completion,
+// so streaming and non-streaming calls share the same cache entries
+func (agent *Agent) storeStreamedCompletion(key string, model string, response string, reasoning string, finishReason string) {
+	if key == "" || finishReason == "" {
+		return
+	}
+
+	message := map[string]any{"role": "assistant", "content": response}
+	if reasoning != "" {
+		message["reasoning_content"] = reasoning
+	}
+	data, err := json.Marshal(map[string]any{
+		"object": "chat.completion",
+		"model":  model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason,
+		}},
+	})
+	if err != nil {
+		agent.Log.Error("Error encoding streamed completion for cache: %v", err)
+		return
+	}
+	agent.storeCompletion(key, string(data))
+}
+
+// NewChatCompletion sends a chat completion request through the response cache:
+// cached answers are returned without calling the model, fresh ones are stored.
+// The usage of live requests is recorded against the budgets of the agent.
+func (agent *Agent) NewChatCompletion(params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return agent.NewChatCompletionWithContext(agent.Ctx, params)
+}
+
+// NewChatCompletionWithContext is NewChatCompletion with the context of the request
+// (e.g. bounded by a deadline) instead of the context of the agent
+func (agent *Agent) NewChatCompletionWithContext(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	key := agent.cacheKey(ctx, params)
+	if completion := agent.cachedCompletion(key); completion != nil {
+		return completion, nil
+	}
+
+	completion, err := agent.OpenaiClient.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return completion, err
+	}
+
+	agent.recordCompletionUsage(ctx, params, completion)
+	agent.storeCompletion(key, completion.RawJSON())
+	return completion, nil
+}
+
+// replayChunks sends a cached text to callBack as simulated stream chunks
+func (agent *Agent) replayChunks(content string, callBack func(string, string) error) error {
+	for _, chunk := range cache.SplitChunks(content, agent.cache.GetReplayChunkSize()) {
+		if agent.StreamCanceled {
+			return canceledError
+		}
+		if err := callBack(chunk, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reasoningContent extracts the reasoning_content field of a completion message
+func reasoningContent(message openai.ChatCompletionMessage) (string, error) {
+	var content struct {
+		ReasoningContent string `json:"reasoning_content"`
+	}
+	if err := json.Unmarshal([]byte(message.RawJSON()), &content); err != nil {
+		return "", err
+	}
+	return content.ReasoningContent, nil
+}
+
+// replayCompletionWithReasoning sends a cached answer to the reasoning and response callbacks
+// in the same order as a live reasoning stream
+func (agent *Agent) replayCompletionWithReasoning(
+	cached *openai.ChatCompletion,
+	reasoningCallback func(partialReasoning string, finishReason string) error,
+	responseCallback func(partialResponse string, finishReason string) error,
+) (response string, reasoning string, finishReason string, err error) {
+
+	agent.SaveLastResponse(cached)
+	response = cached.Choices[0].Message.Content
+	finishReason = cached.Choices[0].FinishReason
+
+	reasoning, err = reasoningContent(cached.Choices[0].Message)
+	if err != nil {
+		return response, "", finishReason, err
+	}
+
+	if err = agent.replayChunks(reasoning, reasoningCallback); err != nil {
+		return response, reasoning, finishReason, err
+	}
+	if reasoning != "" && response != "" {
+		if err = reasoningCallback("", "end_of_reasoning"); err != nil {
+			return response, reasoning, finishReason, err
+		}
+	}
+	if err = agent.replayChunks(response, responseCallback); err != nil {
+		return response, reasoning, finishReason, err
+	}
+	return response, reasoning, finishReason, responseCallback("", finishReason)
+}
@@ -0,0 +1,156 @@
+package base
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/cache"
+)
+
+func newCachedAgent(serverURL string, temperature float64) *Agent {
+	a := newClientAgent(serverURL, 0)
+	a.ChatCompletionParams.Temperature = openai.Float(temperature)
+	a.SetCacheConfig(cache.Config{Store: cache.NewMemoryStore(10), ReplayChunkSize: 5})
+	return a
+}
+
+// ── GenerateCompletion ────────────────────────────────────────────────────────
+
+func TestCache_DeterministicCompletionIsServedFromCache(t *testing.T) {
+	var requests []string
+	server := newScriptedServer(t, [][2]string{{"cached answer", "stop"}}, &requests)
+	defer server.Close()
+
+	a := newCachedAgent(server.URL, 0)
+	for range 3 {
+		response, finishReason, err := a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response != "cached answer" || finishReason != "stop" {
+			t.Errorf("unexpected result %q / %q", response, finishReason)
+		}
+	}
+	if len(requests) != 1 {
+		t.Errorf("want 1 request to the model, got %d", len(requests))
+	}
+	if !a.IsLastResponseFromCache() {
+		t.Error("last response should come from the cache")
+	}
+}
+
+func TestCache_NonDeterministicCompletionIsNotCached(t *testing.T) {
+	var requests []string
+	server := newScriptedServer(t, [][2]string{{"answer", "stop"}}, &requests)
+	defer server.Close()
+
+	a := newCachedAgent(server.URL, 0.8)
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if len(requests) != 2 {
+		t.Errorf("want 2 requests to the model, got %d", len(requests))
+	}
+
+	config := a.GetCacheConfig()
+	config.Force = true
+	a.SetCacheConfig(config)
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if len(requests) != 3 {
+		t.Errorf("Force should cache non-deterministic requests, got %d requests", len(requests))
+	}
+}
+
+func TestCache_Bypass(t *testing.T) {
+	var requests []string
+	server := newScriptedServer(t, [][2]string{{"answer", "stop"}}, &requests)
+	defer server.Close()
+
+	a := newCachedAgent(server.URL, 0)
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+
+	a.BypassCacheOnce()
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if len(requests) != 2 || a.IsLastResponseFromCache() {
+		t.Errorf("BypassCacheOnce should reach the model, got %d requests", len(requests))
+	}
+
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if len(requests) != 2 {
+		t.Errorf("bypass must only apply once, got %d requests", len(requests))
+	}
+
+	a.Ctx = cache.WithBypass(context.Background())
+	_, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if len(requests) != 3 {
+		t.Errorf("context bypass should reach the model, got %d requests", len(requests))
+	}
+
+	// A bypass on the context of a single request
+	a.Ctx = context.Background()
+	params := a.ChatCompletionParams
+	params.Messages = []openai.ChatCompletionMessageParamUnion{userMsg("hi")}
+	if _, err := a.NewChatCompletionWithContext(cache.WithBypass(context.Background()), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 4 || a.IsLastResponseFromCache() {
+		t.Errorf("the bypass of the request context should reach the model, got %d requests", len(requests))
+	}
+}
+
+// ── GenerateStreamCompletion ──────────────────────────────────────────────────
+
+func TestCache_StreamReplaysCachedResponseAsChunks(t *testing.T) {
+	var requests []string
+	server := newScriptedServer(t, [][2]string{{"one two three four", "stop"}}, &requests)
+	defer server.Close()
+
+	a := newCachedAgent(server.URL, 0)
+	// Populate the cache with a streaming call
+	_, _, err := a.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{userMsg("hi")},
+		func(string, string) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var chunks []string
+	var reasons []string
+	response, finishReason, err := a.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{userMsg("hi")},
+		func(chunk string, reason string) error {
+			if chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("want 1 request to the model, got %d", len(requests))
+	}
+	if response != "one two three four" || strings.Join(chunks, "") != response {
+		t.Errorf("unexpected replay: response %q, chunks %q", response, chunks)
+	}
+	if len(chunks) < 2 {
+		t.Errorf("replay should be split in several chunks, got %q", chunks)
+	}
+	if finishReason != "stop" || len(reasons) != 1 || reasons[0] != "stop" {
+		t.Errorf("want a single terminal stop, got %q / %v", finishReason, reasons)
+	}
+
+	// The entry written by the stream also serves non-streaming calls
+	response, _, _ = a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("hi")})
+	if response != "one two three four" || len(requests) != 1 {
+		t.Errorf("streamed entry should serve GenerateCompletion, got %q after %d requests", response, len(requests))
+	}
+}
@@ -0,0 +1,144 @@
+package base
+
+import (
+	"github.com/openai/openai-go/v3"
+)
+
+const (
+	// finishReasonLength is the finish reason returned when the model hits MaxTokens
+	finishReasonLength = "length"
+
+	// DefaultContinuationPrompt is the user message sent to ask the model to resume a truncated answer
+	DefaultContinuationPrompt = "Continue exactly where you stopped. Do not repeat what you already wrote and do not add any introduction."
+
+	// DefaultContinuationMaxOverlap is the default number of characters inspected at each seam
+	// to detect text the model repeated when resuming
+	DefaultContinuationMaxOverlap = 200
+
+	// minContinuationOverlap is the shortest repeated text considered as a real overlap.
+	// Shorter matches (a space, a bracket...) are most likely legitimate content.
+	minContinuationOverlap = 8
+)
+
+// ContinuationConfig controls the automatic continuation of truncated completions
+// (finish_reason == "length"). Auto-continue is disabled when MaxContinuations is 0.
+type ContinuationConfig struct {
+	// MaxContinuations caps the number of follow-up "continue" requests per completion
+	MaxContinuations int
+	// Prompt is the user message used to ask the model to resume (default: DefaultContinuationPrompt)
+	Prompt string
+	// MaxOverlap is the number of characters checked for duplicated text at the seams
+	// (default: DefaultContinuationMaxOverlap)
+	MaxOverlap int
+}
+
+// WithAutoContinue enables automatic continuation of truncated completions
+func WithAutoContinue(config ContinuationConfig) AgentOption {
+	return func(agent *Agent) {
+		agent.SetContinuationConfig(config)
+	}
+}
+
+// SetContinuationConfig enables (or disables with MaxContinuations == 0) automatic continuation
+func (agent *Agent) SetContinuationConfig(config ContinuationConfig) {
+	if config.Prompt == "" {
+		config.Prompt = DefaultContinuationPrompt
+	}
+	if config.MaxOverlap <= 0 {
+		config.MaxOverlap = DefaultContinuationMaxOverlap
+	}
+	agent.continuation = config
+}
+
+// GetContinuationConfig returns the automatic continuation settings
+func (agent *Agent) GetContinuationConfig() ContinuationConfig {
+	return agent.continuation
+}
+
+// GetLastContinuationsCount returns the number of follow-up requests issued by the last completion
+func (agent *Agent) GetLastContinuationsCount() int {
+	return agent.lastContinuations
+}
+
+// shouldContinue reports whether a follow-up request must be issued after the given pass
+// (pass 0 is the initial request)
+func (agent *Agent) shouldContinue(finishReason string, pass int) bool {
+	return finishReason == finishReasonLength && pass < agent.continuation.MaxContinuations
+}
+
+// continuationMessages builds the message list for a follow-up request:
+// the original messages, the partial answer so far, and the continuation prompt.
+func (agent *Agent) continuationMessages(
+	messages []openai.ChatCompletionMessageParamUnion,
+	partialResponse string,
+) []openai.ChatCompletionMessageParamUnion {
+	// Copy to never alias the caller's slice (history may share its backing array)
+	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages)+2)
+	result = append(result, messages...)
+	return append(result,
+		openai.AssistantMessage(partialResponse),
+		openai.UserMessage(agent.continuation.Prompt),
+	)
+}
+
+// continuationOverlap returns the length of the longest suffix of previous that is also
+// a prefix of next, bounded by maxOverlap. Overlaps shorter than minContinuationOverlap are ignored.
+func continuationOverlap(previous, next string, maxOverlap int) int {
+	limit := min(len(previous), len(next), maxOverlap)
+	for size := limit; size >= minContinuationOverlap; size-- {
+		if previous[len(previous)-size:] == next[:size] {
+			return size
+		}
+	}
+	return 0
+}
+
+// mergeContinuation stitches a continuation onto the previous text, dropping the duplicated seam
+func mergeContinuation(previous, next string, maxOverlap int) string {
+	return previous + next[continuationOverlap(previous, next, maxOverlap):]
+}
+
+// seamTrimmer wraps a stream callback for a continuation pass. It buffers the first
+// maxOverlap characters of the pass, removes the text already emitted by the previous
+// passes, then forwards everything else untouched.
+type seamTrimmer struct {
+	previous   string
+	maxOverlap int
+	buffer     string
+	flushed    bool
+	callBack   func(string, string) error
+}
+
+// newSeamTrimmer creates a seamTrimmer for a pass resuming after previous
+func newSeamTrimmer(previous string, maxOverlap int, callBack func(string, string) error) *seamTrimmer {
+	return &seamTrimmer{previous: previous, maxOverlap: maxOverlap, callBack: callBack}
+}
+
+// write receives a chunk of the continuation pass
+func (trimmer *seamTrimmer) write(content string, finishReason string) error {
+	if trimmer.flushed {
+		if content == "" && finishReason == "" {
+			return nil
+		}
+		return trimmer.callBack(content, finishReason)
+	}
+	trimmer.buffer += content
+	if len(trimmer.buffer) < trimmer.maxOverlap && finishReason == "" {
+		return nil
+	}
+	return trimmer.flush(finishReason)
+}
+
+// flush emits the buffered text without the overlapping seam
+func (trimmer *seamTrimmer) flush(finishReason string) error {
+	if trimmer.flushed {
+		return nil
+	}
+	trimmer.flushed = true
+	pending := trimmer.buffer[continuationOverlap(trimmer.previous, trimmer.buffer, trimmer.maxOverlap):]
+	trimmer.buffer = ""
+	if pending == "" && finishReason == "" {
+		return nil
+	}
+	return trimmer.callBack(pending, finishReason)
+}
@@ -0,0 +1,264 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ── helpers ───────────────────────────────────────────────────────────────────
+
+// newScriptedServer returns a server answering POST /chat/completions with the given
+// (content, finish_reason) pairs, one per request, in streaming or non-streaming format.
+func newScriptedServer(t *testing.T, passes [][2]string, requests *[]string) *httptest.Server {
+	t.Helper()
+	call := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream   bool              `json:"stream"`
+			Messages []json.RawMessage `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if requests != nil {
+			last, _ := json.Marshal(req.Messages[len(req.Messages)-1])
+			*requests = append(*requests, string(last))
+		}
+		pass := passes[min(call, len(passes)-1)]
+		call++
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "chatcmpl-test", "object": "chat.completion", "model": "test-model",
+				"choices": []map[string]any{{
+					"index":         0,
+					"message":       map[string]any{"role": "assistant", "content": pass[0]},
+					"finish_reason": pass[1],
+				}},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range strings.SplitAfter(pass[0], " ") {
+			data, _ := json.Marshal(map[string]any{
+				"id": "chatcmpl-test", "object": "chat.completion.chunk", "model": "test-model",
+				"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": word}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		data, _ := json.Marshal(map[string]any{
+			"id": "chatcmpl-test", "object": "chat.completion.chunk", "model": "test-model",
+			"choices": []map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": pass[1]}},
+		})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+}
+
+func newClientAgent(serverURL string, maxContinuations int) *Agent {
+	a := newTestAgent(false)
+	a.Ctx = context.Background()
+	a.OpenaiClient = openai.NewClient(option.WithBaseURL(serverURL), option.WithAPIKey("test"))
+	a.ChatCompletionParams.Model = "test-model"
+	a.SetContinuationConfig(ContinuationConfig{MaxContinuations: maxContinuations})
+	return a
+}
+
+// ── continuationOverlap / mergeContinuation ───────────────────────────────────
+
+func TestContinuationOverlap_DetectsRepeatedSeam(t *testing.T) {
+	got := continuationOverlap("func main() {\n\tfmt.Println(", "\tfmt.Println(\"hello\")", 100)
+	if got != len("\tfmt.Println(") {
+		t.Errorf("want overlap %d, got %d", len("\tfmt.Println("), got)
+	}
+}
+
+func TestContinuationOverlap_IgnoresShortMatches(t *testing.T) {
+	if got := continuationOverlap("a := b ", " c", 100); got != 0 {
+		t.Errorf("short overlaps must be ignored, got %d", got)
+	}
+}
+
+func TestContinuationOverlap_BoundedByMaxOverlap(t *testing.T) {
+	if got := continuationOverlap("0123456789abcdef", "0123456789abcdef!", 10); got != 0 {
+		t.Errorf("overlap longer than maxOverlap must not be detected, got %d", got)
+	}
+}
+
+func TestMergeContinuation_NoOverlap_Concatenates(t *testing.T) {
+	if got := mergeContinuation("Hello ", "world", 100); got != "Hello world" {
+		t.Errorf("want %q, got %q", "Hello world", got)
+	}
+}
+
+func TestMergeContinuation_DropsDuplicatedSeam(t *testing.T) {
+	got := mergeContinuation("The quick brown fox", "brown fox jumps", 100)
+	if got != "The quick brown fox jumps" {
+		t.Errorf("want %q, got %q", "The quick brown fox jumps", got)
+	}
+}
+
+// ── seamTrimmer ───────────────────────────────────────────────────────────────
+
+func TestSeamTrimmer_TrimsOverlapAcrossChunks(t *testing.T) {
+	var out strings.Builder
+	trimmer := newSeamTrimmer("one two three four", 100, func(c, _ string) error {
+		out.WriteString(c)
+		return nil
+	})
+	for _, chunk := range []string{"three ", "four", " five ", "six"} {
+		if err := trimmer.write(chunk, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := trimmer.flush(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != " five six" {
+		t.Errorf("want %q, got %q", " five six", out.String())
+	}
+}
+
+func TestSeamTrimmer_FinishReasonFlushesBuffer(t *testing.T) {
+	var reasons []string
+	trimmer := newSeamTrimmer("abc", 100, func(_ string, reason string) error {
+		reasons = append(reasons, reason)
+		return nil
+	})
+	_ = trimmer.write("def", "")
+	_ = trimmer.write("", "stop")
+	if len(reasons) != 1 || reasons[0] != "stop" {
+		t.Errorf("want a single flush carrying the finish reason, got %v", reasons)
+	}
+}
+
+// ── shouldContinue ────────────────────────────────────────────────────────────
+
+func TestShouldContinue(t *testing.T) {
+	a := newTestAgent(false)
+	if a.shouldContinue("length", 0) {
+		t.Error("auto-continue must be disabled by default")
+	}
+	a.SetContinuationConfig(ContinuationConfig{MaxContinuations: 2})
+	if !a.shouldContinue("length", 1) {
+		t.Error("want continuation while under the cap")
+	}
+	if a.shouldContinue("length", 2) {
+		t.Error("want no continuation once the cap is reached")
+	}
+	if a.shouldContinue("stop", 0) {
+		t.Error("only the length finish reason triggers a continuation")
+	}
+}
+
+// ── GenerateCompletion / GenerateStreamCompletion ─────────────────────────────
+
+func TestGenerateCompletion_AutoContinue_StitchesPieces(t *testing.T) {
+	var requests []string
+	server := newScriptedServer(t, [][2]string{
+		{"package main\n\nfunc main() {\n", "length"},
+		{"func main() {\n\tprintln(1)\n}", "stop"},
+	}, &requests)
+	defer server.Close()
+
+	a := newClientAgent(server.URL, 3)
+	response, finishReason, err := a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("write code")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "package main\n\nfunc main() {\n\tprintln(1)\n}" {
+		t.Errorf("unexpected stitched response: %q", response)
+	}
+	if finishReason != "stop" {
+		t.Errorf("want finish reason stop, got %q", finishReason)
+	}
+	if a.GetLastContinuationsCount() != 1 || len(requests) != 2 {
+		t.Errorf("want 1 continuation, got %d (%d requests)", a.GetLastContinuationsCount(), len(requests))
+	}
+	if !strings.Contains(requests[1], "Continue exactly where you stopped") {
+		t.Errorf("continuation request should end with the continuation prompt, got %s", requests[1])
+	}
+}
+
+func TestGenerateCompletion_AutoContinue_RespectsCap(t *testing.T) {
+	server := newScriptedServer(t, [][2]string{{"more text ", "length"}}, nil)
+	defer server.Close()
+
+	a := newClientAgent(server.URL, 2)
+	_, finishReason, err := a.GenerateCompletion([]openai.ChatCompletionMessageParamUnion{userMsg("go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if finishReason != "length" || a.GetLastContinuationsCount() != 2 {
+		t.Errorf("want length after 2 continuations, got %q after %d", finishReason, a.GetLastContinuationsCount())
+	}
+}
+
+func TestGenerateStreamCompletion_AutoContinue_OneContinuousStream(t *testing.T) {
+	server := newScriptedServer(t, [][2]string{
+		{"alpha beta gamma delta", "length"},
+		{"gamma delta epsilon zeta", "stop"},
+	}, nil)
+	defer server.Close()
+
+	a := newClientAgent(server.URL, 1)
+	var streamed strings.Builder
+	var reasons []string
+	response, finishReason, err := a.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{userMsg("go")},
+		func(chunk string, reason string) error {
+			streamed.WriteString(chunk)
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "alpha beta gamma delta epsilon zeta"
+	if response != want || streamed.String() != want {
+		t.Errorf("want %q, got response %q and stream %q", want, response, streamed.String())
+	}
+	if finishReason != "stop" {
+		t.Errorf("want finish reason stop, got %q", finishReason)
+	}
+	for _, reason := range reasons {
+		if reason == "length" {
+			t.Error("intermediate length finish reason must not reach the callback")
+		}
+	}
+}
+
+func TestGenerateStreamCompletion_AutoContinue_FlushesPassWithoutFinishReason(t *testing.T) {
+	server := newScriptedServer(t, [][2]string{
+		{"alpha beta gamma delta", "length"},
+		{"gamma delta epsilon", ""},
+	}, nil)
+	defer server.Close()
+
+	a := newClientAgent(server.URL, 1)
+	var streamed strings.Builder
+	response, _, err := a.GenerateStreamCompletion(
+		[]openai.ChatCompletionMessageParamUnion{userMsg("go")},
+		func(chunk string, _ string) error {
+			streamed.WriteString(chunk)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "alpha beta gamma delta epsilon"
+	if response != want || streamed.String() != want {
+		t.Errorf("want %q, got response %q and stream %q", want, response, streamed.String())
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"errors"
 
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/base"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/cache"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
@@ -47,6 +50,41 @@ func AfterCompletion(fn func(*Agent)) ChatAgentOption {
 	}
 }
 
+// ContinuationConfig controls the automatic continuation of truncated completions
+type ContinuationConfig = base.ContinuationConfig
+
+// WithAutoContinue enables automatic continuation when a completion is truncated
+// (finish_reason == "length"). The agent issues up to maxContinuations follow-up requests
+// and stitches the pieces into a single response (or a single continuous stream).
+func WithAutoContinue(maxContinuations int) ChatAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetContinuationConfig(ContinuationConfig{MaxContinuations: maxContinuations})
+	}
+}
+
+// WithContinuationConfig enables automatic continuation with a custom prompt and seam overlap window
+func WithContinuationConfig(config ContinuationConfig) ChatAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetContinuationConfig(config)
+	}
+}
+
+// WithCache enables the response cache. Only deterministic requests (temperature 0 or seed set)
+// are cached unless config.Force is true; cached answers are replayed as chunks to streaming calls.
+func WithCache(config cache.Config) ChatAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetCacheConfig(config)
+	}
+}
+
+// WithBudget sets per-call, per-session and per-agent token and cost budgets.
+// With budget.ActionStop the completions stop with the finish reason budget.FinishReasonExceeded.
+func WithBudget(config budget.Config) ChatAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetBudgetConfig(config)
+	}
+}
+
 // Agent represents a simplified chat agent that hides OpenAI SDK details
 type Agent struct {
 	config        agents.Config
@@ -401,6 +439,81 @@ func (agent *Agent) GenerateStreamCompletionWithReasoning(
 	return result, nil
 }
 
+// SetContinuationConfig updates the automatic continuation settings (MaxContinuations == 0 disables it)
+func (agent *Agent) SetContinuationConfig(config ContinuationConfig) {
+	agent.internalAgent.SetContinuationConfig(config)
+}
+
+// GetContinuationConfig returns the automatic continuation settings
+func (agent *Agent) GetContinuationConfig() ContinuationConfig {
+	return agent.internalAgent.GetContinuationConfig()
+}
+
+// GetLastContinuationsCount returns the number of "continue" requests issued by the last completion
+func (agent *Agent) GetLastContinuationsCount() int {
+	return agent.internalAgent.GetLastContinuationsCount()
+}
+
+// SetCacheConfig updates the response cache settings (a nil Store disables the cache)
+func (agent *Agent) SetCacheConfig(config cache.Config) {
+	agent.internalAgent.SetCacheConfig(config)
+}
+
+// GetCacheConfig returns the response cache settings
+func (agent *Agent) GetCacheConfig() cache.Config {
+	return agent.internalAgent.GetCacheConfig()
+}
+
+// BypassCacheOnce makes the next completion skip the response cache
+func (agent *Agent) BypassCacheOnce() {
+	agent.internalAgent.BypassCacheOnce()
+}
+
+// IsLastResponseFromCache reports whether the last completion was answered from the cache
+func (agent *Agent) IsLastResponseFromCache() bool {
+	return agent.internalAgent.IsLastResponseFromCache()
+}
+
+// SetBudgetConfig updates the token and cost budgets (the usage already recorded is kept)
+func (agent *Agent) SetBudgetConfig(config budget.Config) {
+	agent.internalAgent.SetBudgetConfig(config)
+}
+
+// GetBudgetConfig returns the token and cost budgets
+func (agent *Agent) GetBudgetConfig() budget.Config {
+	return agent.internalAgent.GetBudgetConfig()
+}
+
+// GetCallUsage returns the tokens and cost of the last call
+func (agent *Agent) GetCallUsage() budget.Usage {
+	return agent.internalAgent.GetCallUsage()
+}
+
+// GetSessionUsage returns the tokens and cost of the current conversation
+func (agent *Agent) GetSessionUsage() budget.Usage {
+	return agent.internalAgent.GetSessionUsage()
+}
+
+// GetTotalUsage returns the tokens and cost since the agent was created
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.internalAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.internalAgent.SetUsageEstimation(enabled)
+}
+
+// GetRemainingBudget returns the tightest remaining session or agent budget
+func (agent *Agent) GetRemainingBudget() budget.Remaining {
+	return agent.internalAgent.GetRemainingBudget()
+}
+
+// ResetBudgetSession starts a new budget session (ResetMessages does it too)
+func (agent *Agent) ResetBudgetSession() {
+	agent.internalAgent.ResetBudgetSession()
+}
+
 // ExportMessagesToJSON exports the conversation history to JSON
 func (agent *Agent) ExportMessagesToJSON() (string, error) {
 	messagesList := agent.GetMessages()
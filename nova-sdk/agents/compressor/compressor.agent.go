@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/openai/openai-go/v3"
+
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
@@ -178,6 +181,40 @@ func (agent *Agent) CompressContextStream(
 	return result, nil
 }
 
+// GetTotalUsage returns the tokens used by the agent since its creation
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.internalAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.internalAgent.SetUsageEstimation(enabled)
+}
+
+// BudgetCompressor returns a budget.Compressor (for budget.ActionCompress) that keeps the
+// leading system message and replaces the rest of the conversation with its summary
+func (agent *Agent) BudgetCompressor() budget.Compressor {
+	return func(history []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+		var compressed []openai.ChatCompletionMessageParamUnion
+		if len(history) > 0 && history[0].OfSystem != nil {
+			compressed = append(compressed, history[0])
+			history = history[1:]
+		}
+		if len(history) == 0 {
+			return compressed, nil
+		}
+
+		result, err := agent.CompressContext(messages.ConvertFromOpenAIMessages(history))
+		if err != nil {
+			return nil, err
+		}
+		if result.CompressedText != "" {
+			compressed = append(compressed, openai.SystemMessage(result.CompressedText))
+		}
+		return compressed, nil
+	}
+}
+
 // === Config Getters and Setters ===
 
 // GetConfig returns the agent configuration
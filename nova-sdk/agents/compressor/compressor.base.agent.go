@@ -81,6 +81,7 @@ func (agent *BaseAgent) CompressContext(messagesList []openai.ChatCompletionMess
 	if err != nil {
 		return "", "", err
 	}
+	agent.RecordCompletionUsage(agent.ChatCompletionParams, completion)
 
 	if len(completion.Choices) > 0 {
 		response = completion.Choices[0].Message.Content
@@ -109,13 +110,18 @@ func (agent *BaseAgent) CompressContextStream(
 		openai.UserMessage("CONVERSATION:\n"+buildConversationText(messagesList)),
 	)
 
-	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(agent.Ctx, agent.ChatCompletionParams)
+	params := agent.WithStreamUsage(agent.ChatCompletionParams)
+	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(agent.Ctx, params)
 
 	var callBackError error
+	var usage openai.CompletionUsage
 	finalFinishReason := ""
 
 	for stream.Next() {
 		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
 
 		// Capture finishReason if present (even if there's no content)
 		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
@@ -133,6 +139,7 @@ func (agent *BaseAgent) CompressContextStream(
 		}
 
 	}
+	agent.RecordStreamUsage(params, usage, response)
 
 	// Call callback one last time with the final finishReason and empty content
 	if finalFinishReason != "" {
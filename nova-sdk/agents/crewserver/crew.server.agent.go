@@ -14,6 +14,7 @@ import (
 	"github.com/snipwise/nova/nova-sdk/agents/serverbase"
 	"github.com/snipwise/nova/nova-sdk/agents/tasks"
 	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 )
@@ -42,6 +43,7 @@ type CrewServerAgent struct {
 	portConfig             string
 	executeFnConfig        func(string, string) (string, error)
 	toolsAgentConfig       *tools.Agent
+	auditConfig            audit.Config
 	tasksAgentConfig       *tasks.Agent
 	ragAgentConfig         *rag.Agent
 	compressorAgentConfig  *compressor.Agent
@@ -141,6 +143,14 @@ func WithToolsAgent(toolsAgent *tools.Agent) CrewServerAgentOption {
 	}
 }
 
+// WithAudit emits an audit event for every server-side tool call (tools agent executions)
+func WithAudit(config audit.Config) CrewServerAgentOption {
+	return func(agent *CrewServerAgent) error {
+		agent.auditConfig = config
+		return nil
+	}
+}
+
 // WithTasksAgent sets the tasks agent for task planning and orchestration.
 // When configured, the agent will first analyze user requests to identify a plan of tasks,
 // then execute each task using either the tools agent (for "tool" tasks) or the chat agent
@@ -269,6 +279,7 @@ func AfterCompletion(fn func(*CrewServerAgent)) CrewServerAgentOption {
 //   - WithMatchAgentIdToTopicFn(fn) - Sets the function to match agent ID to topic for routing
 //   - WithExecuteFn(fn) - Sets the custom function executor for tool execution
 //   - WithToolsAgent(toolsAgent) - Attaches a tools agent for function calling capabilities
+//   - WithAudit(config) - Emits an audit event for every server-side tool call
 //   - WithTasksAgent(tasksAgent) - Attaches a tasks agent for task planning and orchestration
 //   - WithCompressorAgent(compressorAgent) - Attaches a compressor agent for context compression
 //   - WithCompressorAgentAndContextSize(compressorAgent, contextSizeLimit) - Attaches a compressor agent and sets the context size limit
@@ -321,6 +332,9 @@ func (agent *CrewServerAgent) applyConfigFields() {
 	if agent.toolsAgentConfig != nil {
 		agent.ToolsAgent = agent.toolsAgentConfig
 	}
+	if agent.auditConfig.Enabled() {
+		agent.SetAudit(agent.auditConfig, audit.SourceCrewServer)
+	}
 	if agent.ragAgentConfig != nil {
 		agent.RagAgent = agent.ragAgentConfig
 		if agent.similarityLimitConfig != 0 {
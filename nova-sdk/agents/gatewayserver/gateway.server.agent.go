@@ -13,6 +13,8 @@ import (
 	"github.com/snipwise/nova/nova-sdk/agents/rag"
 	"github.com/snipwise/nova/nova-sdk/agents/tasks"
 	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
@@ -68,10 +70,23 @@ type GatewayServerAgent struct {
 	tlsCertPath string
 	tlsKeyPath  string
 
+	// Budgets per client key
+	budgetLedger *budget.Ledger
+	clientKeyFn  func(*http.Request) string
+
+	// Audit of the server-side tool calls
+	auditConfig audit.Config
+
+	// Prompt-based tool calling of the tools agents ("": native function calling)
+	promptToolFormat tools.PromptToolFormat
+
 	// Stream control
 	stopStreamChan chan bool
 	streamMutex    sync.Mutex
 
+	// requestMutex serializes the work of the chat completions on the agents: they keep one conversation
+	requestMutex sync.Mutex
+
 	// Lifecycle hooks
 	beforeCompletion func(*GatewayServerAgent)
 	afterCompletion  func(*GatewayServerAgent)
@@ -130,6 +145,24 @@ func WithToolsAgent(toolsAgent *tools.Agent) GatewayServerAgentOption {
 	}
 }
 
+// WithAudit emits an audit event for every server-side tool call (tools agent executions).
+// Client-side tools are executed by the client and are not audited.
+func WithAudit(config audit.Config) GatewayServerAgentOption {
+	return func(agent *GatewayServerAgent) error {
+		agent.auditConfig = config.WithSource(audit.SourceGateway)
+		return nil
+	}
+}
+
+// WithPromptToolCalling enables prompt-based tool calling (see tools.PromptToolCalling) on the
+// tools agent and the client-side tools agent, for the models without native function calling
+func WithPromptToolCalling(format tools.PromptToolFormat) GatewayServerAgentOption {
+	return func(agent *GatewayServerAgent) error {
+		agent.promptToolFormat = format
+		return nil
+	}
+}
+
 // WithTasksAgent sets the tasks agent for task planning and orchestration.
 // When configured, the agent will first analyze user requests to identify a plan of tasks,
 // then execute each task using either the tools agent (for "tool" tasks) or the chat agent
@@ -280,6 +313,30 @@ func WithMatchAgentIdToTopicFn(fn func(string, string) string) GatewayServerAgen
 	}
 }
 
+// WithClientBudget enforces token and cost budgets per client key.
+// Exhausted clients get a 429 "insufficient_quota" error; the other ones receive their
+// remaining budget in the "budget" field of the response (or of the last chunk before [DONE]).
+func WithClientBudget(ledger *budget.Ledger) GatewayServerAgentOption {
+	return func(agent *GatewayServerAgent) error {
+		if ledger == nil {
+			return fmt.Errorf("budget ledger cannot be nil")
+		}
+		agent.budgetLedger = ledger
+		return nil
+	}
+}
+
+// WithClientKeyFn sets how the client key of a request is found (default: budget.ClientKeyFromRequest).
+// The function should map the requests to known clients (e.g. the user of a validated session):
+// the default trusts any bearer token or API key, so that a caller gets a new budget by
+// sending a new token.
+func WithClientKeyFn(fn func(*http.Request) string) GatewayServerAgentOption {
+	return func(agent *GatewayServerAgent) error {
+		agent.clientKeyFn = fn
+		return nil
+	}
+}
+
 // BeforeCompletion sets a hook called before each completion request.
 func BeforeCompletion(fn func(*GatewayServerAgent)) GatewayServerAgentOption {
 	return func(agent *GatewayServerAgent) error {
@@ -305,6 +362,7 @@ func AfterCompletion(fn func(*GatewayServerAgent)) GatewayServerAgentOption {
 //   - WithSingleAgent(chatAgent) - Creates a single-agent crew
 //   - WithPort(port) - Sets the HTTP server port (default: 8080)
 //   - WithToolsAgent(toolsAgent) - Attaches a tools agent for server-side execution
+//   - WithAudit(config) - Emits an audit event for every server-side tool call
 //   - WithTasksAgent(tasksAgent) - Attaches a tasks agent for task planning and orchestration
 //   - WithClientSideToolsAgent(toolsAgent) - Attaches a tools agent for client-side execution
 //   - WithAgentExecutionOrder(order) - Sets the agent execution order
@@ -318,6 +376,8 @@ func AfterCompletion(fn func(*GatewayServerAgent)) GatewayServerAgentOption {
 //   - WithCompressorAgentAndContextSize(compressorAgent, limit) - Compressor with size limit
 //   - WithOrchestratorAgent(orchestratorAgent) - Attaches an orchestrator
 //   - WithMatchAgentIdToTopicFn(fn) - Sets topic-to-agent routing
+//   - WithClientBudget(ledger) - Enforces token and cost budgets per client key
+//   - WithClientKeyFn(fn) - Sets how the client key of a request is found
 //   - BeforeCompletion(fn) - Hook before completion
 //   - AfterCompletion(fn) - Hook after completion
 func NewAgent(ctx context.Context, options ...GatewayServerAgentOption) (*GatewayServerAgent, error) {
@@ -351,6 +411,21 @@ func NewAgent(ctx context.Context, options ...GatewayServerAgentOption) (*Gatewa
 
 	// Note: No default executeFn - if not configured, toolsAgent will use its own configured callbacks
 
+	if agent.toolsAgent != nil && agent.auditConfig.Enabled() {
+		agent.toolsAgent.SetAudit(agent.auditConfig)
+	}
+	if agent.promptToolFormat != "" {
+		for _, toolsAgent := range []*tools.Agent{agent.toolsAgent, agent.clientSideToolsAgent} {
+			if toolsAgent != nil {
+				toolsAgent.SetPromptToolCalling(agent.promptToolFormat)
+			}
+		}
+	}
+	if agent.budgetLedger != nil {
+		// The client budgets need the usage of the engines that do not report it
+		budget.EnableUsageEstimation(agent.usageReporters()...)
+	}
+
 	agent.log.Info("🌐 GatewayServerAgent initialized (agent: %s)", agent.selectedAgentId)
 
 	return agent, nil
@@ -4,159 +4,36 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
-	"time"
 
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/chat"
 	"github.com/snipwise/nova/nova-sdk/agents/gatewayserver"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/cassette"
 	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
 )
 
 // --- Fake LLM Server ---
 
-// newFakeLLMServer creates an httptest.Server that mimics an OpenAI-compatible LLM backend.
-// It responds to POST /chat/completions with a simple response and GET /models with a model list.
-func newFakeLLMServer(responseContent string) *httptest.Server {
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("POST /chat/completions", func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Stream bool              `json:"stream"`
-			Tools  []json.RawMessage `json:"tools"`
-		}
-		_ = json.NewDecoder(r.Body).Decode(&req)
-
-		if len(req.Tools) > 0 {
-			// Simulate a tool_calls response
-			handleFakeToolCallResponse(w, r, req.Stream)
-			return
-		}
-
-		if req.Stream {
-			handleFakeStreamResponse(w, responseContent)
-		} else {
-			handleFakeNonStreamResponse(w, responseContent)
-		}
-	})
-
-	mux.HandleFunc("GET /models", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{
-			"object": "list",
-			"data": []map[string]any{
-				{"id": "test-model", "object": "model", "created": time.Now().Unix(), "owned_by": "test"},
-			},
-		})
-	})
-
-	return httptest.NewServer(mux)
-}
-
-func handleFakeNonStreamResponse(w http.ResponseWriter, content string) {
-	w.Header().Set("Content-Type", "application/json")
-	resp := map[string]any{
-		"id":      "chatcmpl-fake",
-		"object":  "chat.completion",
-		"created": time.Now().Unix(),
-		"model":   "test-model",
-		"choices": []map[string]any{
-			{
-				"index":         0,
-				"message":       map[string]any{"role": "assistant", "content": content},
-				"finish_reason": "stop",
-			},
-		},
-		"usage": map[string]any{
-			"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15,
-		},
-	}
-	_ = json.NewEncoder(w).Encode(resp)
-}
-
-func handleFakeStreamResponse(w http.ResponseWriter, content string) {
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "no flusher", 500)
-		return
-	}
-
-	// Chunk 1: role
-	writeSSEChunk(w, flusher, map[string]any{
-		"id": "chatcmpl-fake", "object": "chat.completion.chunk", "model": "test-model",
-		"choices": []map[string]any{
-			{"index": 0, "delta": map[string]any{"role": "assistant"}, "finish_reason": nil},
-		},
-	})
-
-	// Chunk 2: content (word by word)
-	words := strings.Fields(content)
-	for _, word := range words {
-		writeSSEChunk(w, flusher, map[string]any{
-			"id": "chatcmpl-fake", "object": "chat.completion.chunk", "model": "test-model",
-			"choices": []map[string]any{
-				{"index": 0, "delta": map[string]any{"content": word + " "}, "finish_reason": nil},
-			},
-		})
-	}
-
-	// Chunk 3: finish
-	writeSSEChunk(w, flusher, map[string]any{
-		"id": "chatcmpl-fake", "object": "chat.completion.chunk", "model": "test-model",
-		"choices": []map[string]any{
-			{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"},
-		},
-	})
-
-	fmt.Fprintf(w, "data: [DONE]\n\n")
-	flusher.Flush()
-}
-
-func handleFakeToolCallResponse(w http.ResponseWriter, r *http.Request, stream bool) {
-	w.Header().Set("Content-Type", "application/json")
-	resp := map[string]any{
-		"id":      "chatcmpl-fake-tool",
-		"object":  "chat.completion",
-		"created": time.Now().Unix(),
-		"model":   "test-model",
-		"choices": []map[string]any{
-			{
-				"index": 0,
-				"message": map[string]any{
-					"role":    "assistant",
-					"content": nil,
-					"tool_calls": []map[string]any{
-						{
-							"id":   "call_test123",
-							"type": "function",
-							"function": map[string]any{
-								"name":      "get_weather",
-								"arguments": `{"city":"Paris"}`,
-							},
-						},
-					},
-				},
-				"finish_reason": "tool_calls",
-			},
-		},
-		"usage": map[string]any{
-			"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15,
-		},
-	}
-	_ = json.NewEncoder(w).Encode(resp)
-}
-
-func writeSSEChunk(w http.ResponseWriter, flusher http.Flusher, data map[string]any) {
-	jsonData, _ := json.Marshal(data)
-	fmt.Fprintf(w, "data: %s\n\n", string(jsonData))
-	flusher.Flush()
+// newFakeLLMServer starts a novatest engine answering with responseContent.
+// Requests declaring tools get a get_weather tool call instead.
+func newFakeLLMServer(responseContent string) *novatest.Server {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text(responseContent)))
+	engine.When(novatest.HasTools()).Reply(novatest.ToolCalls(novatest.ToolCall{
+		ID:        "call_test123",
+		Name:      "get_weather",
+		Arguments: `{"city":"Paris"}`,
+	}))
+	return engine
 }
 
 // (helpers moved inline to each test)
@@ -655,3 +532,247 @@ func TestIntegration_HealthEndpoint(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestIntegration_ClientBudget(t *testing.T) {
+	fakeLLM := newFakeLLMServer("Hello from the gateway!")
+	defer fakeLLM.Close()
+
+	ctx := context.Background()
+	chatAgent, err := chat.NewAgent(ctx, agents.Config{
+		Name: "test", EngineURL: fakeLLM.URL, SystemInstructions: "test",
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create chat agent: %v", err)
+	}
+
+	gateway, err := gatewayserver.NewAgent(ctx,
+		gatewayserver.WithSingleAgent(chatAgent),
+		gatewayserver.WithPort(0),
+		gatewayserver.WithClientBudget(budget.NewLedger(budget.Limits{MaxTokens: 1})),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	testMux := http.NewServeMux()
+	testMux.HandleFunc("POST /v1/chat/completions", gateway.HandleChatCompletionsForTest)
+	ts := httptest.NewServer(testMux)
+	defer ts.Close()
+
+	post := func(apiKey string) *http.Response {
+		reqBody := `{"model":"test","messages":[{"role":"user","content":"Hello!"}]}`
+		req, _ := http.NewRequest("POST", ts.URL+"/v1/chat/completions", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("alice")
+	var result gatewayserver.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if result.Budget == nil || result.Budget.Tokens != 0 {
+		t.Errorf("expected an exhausted budget in the response, got %+v", result.Budget)
+	}
+
+	resp = post("alice")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the budget is exhausted, got %d", resp.StatusCode)
+	}
+
+	resp = post("bob")
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected 200 for another client key, got %d", resp.StatusCode)
+	}
+}
+
+func TestIntegration_ClientBudget_ConcurrentClients(t *testing.T) {
+	fakeLLM := newFakeLLMServer("Hello from the gateway!")
+	defer fakeLLM.Close()
+
+	ctx := context.Background()
+	chatAgent, err := chat.NewAgent(ctx, agents.Config{
+		Name: "test", EngineURL: fakeLLM.URL, SystemInstructions: "test",
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create chat agent: %v", err)
+	}
+
+	ledger := budget.NewLedger(budget.Limits{MaxTokens: 1_000_000})
+	gateway, err := gatewayserver.NewAgent(ctx,
+		gatewayserver.WithSingleAgent(chatAgent),
+		gatewayserver.WithPort(0),
+		gatewayserver.WithClientBudget(ledger),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	testMux := http.NewServeMux()
+	testMux.HandleFunc("POST /v1/chat/completions", gateway.HandleChatCompletionsForTest)
+	ts := httptest.NewServer(testMux)
+	defer ts.Close()
+
+	requests := map[string]int{"alice": 3, "bob": 2}
+	var wg sync.WaitGroup
+	for apiKey, count := range requests {
+		for range count {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reqBody := `{"model":"test","messages":[{"role":"user","content":"Hello!"}]}`
+				req, _ := http.NewRequest("POST", ts.URL+"/v1/chat/completions", strings.NewReader(reqBody))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+apiKey)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Errorf("Request failed: %v", err)
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode != 200 {
+					t.Errorf("Expected 200, got %d", resp.StatusCode)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	alice, bob := ledger.Usage("alice"), ledger.Usage("bob")
+	if alice.Requests != 3 || bob.Requests != 2 {
+		t.Errorf("each client should be charged its own requests, got alice=%d bob=%d", alice.Requests, bob.Requests)
+	}
+	if total := chatAgent.GetTotalUsage(); alice.TotalTokens()+bob.TotalTokens() != total.TotalTokens() {
+		t.Errorf("the charged tokens (%d + %d) should add up to the agent usage (%d)",
+			alice.TotalTokens(), bob.TotalTokens(), total.TotalTokens())
+	}
+}
+
+func TestIntegration_ClientSideToolsWithPromptToolCalling(t *testing.T) {
+	// The model ignores the tools parameter and writes the call in its answer
+	fakeLLM := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text(
+		`{"tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}]}`,
+	)))
+	defer fakeLLM.Close()
+
+	ctx := context.Background()
+	chatAgent, err := chat.NewAgent(ctx, agents.Config{
+		Name: "test", EngineURL: fakeLLM.URL, SystemInstructions: "test",
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create chat agent: %v", err)
+	}
+	toolsAgent, err := tools.NewAgent(ctx, agents.Config{
+		Name: "client-tools", EngineURL: fakeLLM.URL, SystemInstructions: "You call tools",
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create tools agent: %v", err)
+	}
+
+	gateway, err := gatewayserver.NewAgent(ctx,
+		gatewayserver.WithSingleAgent(chatAgent),
+		gatewayserver.WithClientSideToolsAgent(toolsAgent),
+		gatewayserver.WithPromptToolCalling(tools.PromptToolFormatJSON),
+		gatewayserver.WithPort(0),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	testMux := http.NewServeMux()
+	testMux.HandleFunc("POST /v1/chat/completions", gateway.HandleChatCompletionsForTest)
+	ts := httptest.NewServer(testMux)
+	defer ts.Close()
+
+	reqBody := `{"model":"test","messages":[{"role":"user","content":"Weather in Paris?"}],
+		"tools":[{"type":"function","function":{"name":"get_weather","description":"Get the weather",
+		"parameters":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}}]}`
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result gatewayserver.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(result.Choices) != 1 || result.Choices[0].FinishReason == nil || *result.Choices[0].FinishReason != "tool_calls" {
+		t.Fatalf("expected tool calls, got %+v", result)
+	}
+	toolCalls := result.Choices[0].Message.ToolCalls
+	if len(toolCalls) != 1 || toolCalls[0].Function.Name != "get_weather" || toolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool calls %+v", toolCalls)
+	}
+	if request := fakeLLM.ChatRequests()[0]; len(request.Tools) != 0 || !strings.Contains(request.Messages[0].Content, "get_weather") {
+		t.Errorf("the tools should be described in the prompt, got %+v", request)
+	}
+}
+
+func TestIntegration_ClientSideToolsUseTheAgentHTTPClient(t *testing.T) {
+	fakeLLM := newFakeLLMServer("no tool")
+	defer fakeLLM.Close()
+
+	recorder, err := cassette.New(filepath.Join(t.TempDir(), "session.json"), cassette.ModeRecord)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	ctx := context.Background()
+	chatAgent, err := chat.NewAgent(ctx, agents.Config{
+		Name: "test", EngineURL: fakeLLM.URL, SystemInstructions: "test",
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create chat agent: %v", err)
+	}
+	toolsAgent, err := tools.NewAgent(ctx, agents.Config{
+		Name: "client-tools", EngineURL: fakeLLM.URL, SystemInstructions: "You call tools",
+		HTTPClient: recorder.Client(),
+	}, models.Config{Name: "test-model", Temperature: models.Float64(0.0)})
+	if err != nil {
+		t.Fatalf("Failed to create tools agent: %v", err)
+	}
+
+	gateway, err := gatewayserver.NewAgent(ctx,
+		gatewayserver.WithSingleAgent(chatAgent),
+		gatewayserver.WithClientSideToolsAgent(toolsAgent),
+		gatewayserver.WithPort(0),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	testMux := http.NewServeMux()
+	testMux.HandleFunc("POST /v1/chat/completions", gateway.HandleChatCompletionsForTest)
+	ts := httptest.NewServer(testMux)
+	defer ts.Close()
+
+	reqBody := `{"model":"test","messages":[{"role":"user","content":"Weather in Paris?"}],
+		"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]}`
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	detected := false
+	for _, interaction := range recorder.Interactions() {
+		if strings.HasSuffix(interaction.Request.URL, "/chat/completions") {
+			detected = true
+		}
+	}
+	if !detected {
+		t.Errorf("the tool detection should use the HTTP client of the tools agent, got %+v", recorder.Interactions())
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
+	"github.com/snipwise/nova/nova-sdk/agents"
 )
 
 // handleClientSideToolDetection processes requests with client-side tool execution.
@@ -43,11 +43,11 @@ func (agent *GatewayServerAgent) handleClientSideToolDetection(
 	agent.log.Info("🔀 Processing request with client-side tool detection")
 
 	// Create OpenAI client using the client-side tools agent's config
-	agentConfig := agent.clientSideToolsAgent.GetConfig()
-	client := openai.NewClient(
-		option.WithBaseURL(agentConfig.EngineURL),
-		option.WithAPIKey(agentConfig.APIKey),
-	)
+	client, err := agents.NewOpenAIClient(agent.clientSideToolsAgent.GetConfig())
+	if err != nil {
+		agent.log.Error("Client-side tool detection failed: %v", err)
+		return false
+	}
 
 	// Build OpenAI-compatible messages and tools from the request
 	openaiMessages := agent.convertToOpenAIMessages(req.Messages)
@@ -71,14 +71,25 @@ func (agent *GatewayServerAgent) handleClientSideToolDetection(
 		params.MaxTokens = openai.Opt(*req.MaxTokens)
 	}
 
+	// Models without native function calling get the tools in the prompt
+	promptToolCalling := agent.clientSideToolsAgent.GetPromptToolCalling()
+	requestParams := params
+	if promptToolCalling != nil {
+		requestParams = promptToolCalling.PrepareRequest(params)
+	}
+
 	// Make a detection call (always non-streaming first for detection)
 	agent.log.Info("🔍 Detecting tool calls...")
-	completion, err := client.Chat.Completions.New(agent.ctx, params)
+	completion, err := client.Chat.Completions.New(agent.ctx, requestParams)
 	if err != nil {
 		agent.log.Error("Client-side tool detection failed: %v", err)
 		// Don't fail, just let next handler try
 		return false
 	}
+	agent.addDirectUsage(r, params.Model, completion.Usage)
+	if promptToolCalling != nil {
+		promptToolCalling.ParseCompletion(completion, params.Tools)
+	}
 
 	if len(completion.Choices) == 0 {
 		agent.log.Warn("⚠️  Client-side tool detection returned no choices")
@@ -99,7 +110,7 @@ func (agent *GatewayServerAgent) handleClientSideToolDetection(
 		if req.Stream {
 			agent.sendClientSideToolCallsStreaming(w, r, req, toolCalls, completion)
 		} else {
-			agent.sendClientSideToolCallsNonStreaming(w, req, toolCalls, completion)
+			agent.sendClientSideToolCallsNonStreaming(w, r, req, toolCalls, completion)
 		}
 
 		return true // We handled the request
@@ -113,6 +124,7 @@ func (agent *GatewayServerAgent) handleClientSideToolDetection(
 // sendClientSideToolCallsNonStreaming sends tool calls in non-streaming format
 func (agent *GatewayServerAgent) sendClientSideToolCallsNonStreaming(
 	w http.ResponseWriter,
+	r *http.Request,
 	req ChatCompletionRequest,
 	toolCalls []ToolCall,
 	completion *openai.ChatCompletion,
@@ -148,6 +160,7 @@ func (agent *GatewayServerAgent) sendClientSideToolCallsNonStreaming(
 			CompletionTokens: int(completion.Usage.CompletionTokens),
 			TotalTokens:      int(completion.Usage.TotalTokens),
 		},
+		Budget: agent.remainingClientBudget(r),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -203,5 +216,5 @@ func (agent *GatewayServerAgent) sendClientSideToolCallsStreaming(
 	}
 
 	// Send [DONE] marker
-	agent.writeStreamDone(w, flusher)
+	agent.writeStreamDone(w, r, flusher)
 }
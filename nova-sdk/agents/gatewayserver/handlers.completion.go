@@ -43,6 +43,16 @@ func (agent *GatewayServerAgent) handleChatCompletions(w http.ResponseWriter, r
 		return
 	}
 
+	r, allowed := agent.beginClientBudget(w, r)
+	if !allowed {
+		return
+	}
+	defer agent.endClientBudget(r)
+
+	agent.requestMutex.Lock()
+	defer agent.requestMutex.Unlock()
+	defer agent.trackClientBudget(r)()
+
 	// Call before completion hook
 	if agent.beforeCompletion != nil {
 		agent.beforeCompletion(agent)
@@ -150,7 +160,8 @@ func (agent *GatewayServerAgent) handleNonStreamingCompletion(w http.ResponseWri
 				FinishReason: &finishReason,
 			},
 		},
-		Usage: agent.estimateUsage(req.Messages, fullResponse),
+		Usage:  agent.estimateUsage(req.Messages, fullResponse),
+		Budget: agent.remainingClientBudget(r),
 	}
 
 	w.Header().Set(handlerContentType, handlerMIMEJSON)
@@ -220,7 +231,7 @@ func (agent *GatewayServerAgent) handleStreamingCompletion(w http.ResponseWriter
 	}
 
 	// Send [DONE] marker
-	agent.writeStreamDone(w, flusher)
+	agent.writeStreamDone(w, r, flusher)
 }
 
 // handleServerSideToolExecution processes requests with server-side tool execution.
@@ -455,8 +466,10 @@ func (agent *GatewayServerAgent) writeStreamToolCalls(
 	flusher.Flush()
 }
 
-// writeStreamDone writes the [DONE] marker to end the SSE stream.
-func (agent *GatewayServerAgent) writeStreamDone(w http.ResponseWriter, flusher http.Flusher) {
+// writeStreamDone writes the remaining client budget (when enabled) and the [DONE] marker
+// to end the SSE stream.
+func (agent *GatewayServerAgent) writeStreamDone(w http.ResponseWriter, r *http.Request, flusher http.Flusher) {
+	agent.writeStreamBudget(w, r, flusher)
 	if _, err := fmt.Fprintf(w, "data: [DONE]\n\n"); err != nil {
 		agent.log.Error("Failed to write [DONE] marker: %v", err)
 		return
@@ -96,6 +96,7 @@ func (agent *GatewayServerAgent) sendCompletionOrToolResponse(
 				FinishReason: &finishReason,
 			},
 		},
+		Budget: agent.remainingClientBudget(r),
 	}
 	w.Header().Set(handlerContentType, handlerMIMEJSON)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -0,0 +1,152 @@
+package gatewayserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
+)
+
+// clientBudgetRequest tracks the usage of the request being served for its client key
+type clientBudgetRequest struct {
+	key string
+	// usage is the request tracker of the agents, and of the requests made without an agent
+	// (client-side tool detection)
+	usage *budget.Tracker
+}
+
+// clientKey returns the budget key of the client that sent the request.
+func (agent *GatewayServerAgent) clientKey(r *http.Request) string {
+	if agent.clientKeyFn != nil {
+		return agent.clientKeyFn(r)
+	}
+	return budget.ClientKeyFromRequest(r)
+}
+
+// usageReporters returns the agents of the gateway that report their usage.
+func (agent *GatewayServerAgent) usageReporters() []budget.Reporter {
+	var reporters []budget.Reporter
+	for _, chatAgent := range agent.chatAgents {
+		reporters = append(reporters, chatAgent)
+	}
+	if agent.toolsAgent != nil {
+		reporters = append(reporters, agent.toolsAgent)
+	}
+	if agent.clientSideToolsAgent != nil {
+		reporters = append(reporters, agent.clientSideToolsAgent)
+	}
+	if agent.compressorAgent != nil {
+		reporters = append(reporters, agent.compressorAgent)
+	}
+	if agent.tasksAgent != nil {
+		reporters = append(reporters, agent.tasksAgent)
+	}
+	if reporter, ok := agent.orchestratorAgent.(budget.Reporter); ok {
+		reporters = append(reporters, reporter)
+	}
+	return reporters
+}
+
+// clientBudgetKey is the context key of the clientBudgetRequest of a request.
+type clientBudgetKey struct{}
+
+// clientBudget returns the budget tracking of the request, or nil when client budgets are disabled.
+func clientBudget(r *http.Request) *clientBudgetRequest {
+	tracking, _ := r.Context().Value(clientBudgetKey{}).(*clientBudgetRequest)
+	return tracking
+}
+
+// beginClientBudget starts tracking the request for its client key and returns the request
+// carrying the tracking in its context.
+// It answers 429 "insufficient_quota" and returns false when the budget is exhausted.
+func (agent *GatewayServerAgent) beginClientBudget(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if agent.budgetLedger == nil {
+		return r, true
+	}
+
+	key := agent.clientKey(r)
+	if err := agent.budgetLedger.Allow(key); err != nil {
+		agent.log.Warn("💸 Request rejected: %v", err)
+		agent.writeAPIError(w, http.StatusTooManyRequests, "insufficient_quota", err.Error())
+		return r, false
+	}
+	tracking := &clientBudgetRequest{key: key, usage: &budget.Tracker{}}
+	return r.WithContext(context.WithValue(r.Context(), clientBudgetKey{}, tracking)), true
+}
+
+// trackClientBudget adds the request tracker to the contexts of the agents and returns the
+// function restoring them. The caller must hold requestMutex until then.
+func (agent *GatewayServerAgent) trackClientBudget(r *http.Request) (restore func()) {
+	tracking := clientBudget(r)
+	if tracking == nil {
+		return func() {}
+	}
+	return budget.TrackRequest(tracking.usage, agent.usageReporters()...)
+}
+
+// endClientBudget charges the usage of the request to its client key.
+func (agent *GatewayServerAgent) endClientBudget(r *http.Request) {
+	tracking := clientBudget(r)
+	if agent.budgetLedger == nil || tracking == nil {
+		return
+	}
+	agent.budgetLedger.Charge(tracking.key, tracking.usage.Usage())
+}
+
+// addDirectUsage records the usage of a request made without an agent.
+func (agent *GatewayServerAgent) addDirectUsage(r *http.Request, model string, usage openai.CompletionUsage) {
+	tracking := clientBudget(r)
+	if tracking == nil {
+		return
+	}
+	promptTokens := int(usage.PromptTokens)
+	completionTokens := int(usage.CompletionTokens)
+	tracking.usage.Add(budget.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             budget.Cost(nil, model, promptTokens, completionTokens),
+		Requests:         1,
+	})
+}
+
+// remainingClientBudget returns the budget left to the client once the request is charged,
+// or nil when client budgets are disabled.
+func (agent *GatewayServerAgent) remainingClientBudget(r *http.Request) *budget.Remaining {
+	tracking := clientBudget(r)
+	if agent.budgetLedger == nil || tracking == nil {
+		return nil
+	}
+	tracker := agent.budgetLedger.Tracker(tracking.key)
+	remaining := tracker.Limits().Remaining(tracker.Usage().Add(tracking.usage.Usage()))
+	return &remaining
+}
+
+// writeStreamBudget writes a chunk without choices carrying the remaining client budget.
+func (agent *GatewayServerAgent) writeStreamBudget(w http.ResponseWriter, r *http.Request, flusher http.Flusher) {
+	remaining := agent.remainingClientBudget(r)
+	if remaining == nil {
+		return
+	}
+	jsonData, err := json.Marshal(ChatCompletionChunk{
+		ID:      generateCompletionID(),
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   agent.currentChatAgent.GetModelID(),
+		Choices: []ChatCompletionChunkChoice{},
+		Budget:  remaining,
+	})
+	if err != nil {
+		agent.log.Error("Failed to marshal budget chunk: %v", err)
+		return
+	}
+	if _, err := fmt.Fprintf(w, handlerSSEData, string(jsonData)); err != nil {
+		agent.log.Error("Failed to write budget chunk: %v", err)
+		return
+	}
+	flusher.Flush()
+}
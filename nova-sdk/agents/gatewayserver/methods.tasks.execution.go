@@ -52,7 +52,7 @@ func (agent *GatewayServerAgent) executePlanOpenAI(
 	if req.Stream {
 		agent.executePlanStreaming(w, r, req, plan, lastUserMessage)
 	} else {
-		agent.executePlanNonStreaming(w, req, plan, lastUserMessage)
+		agent.executePlanNonStreaming(w, r, req, plan, lastUserMessage)
 	}
 
 	return true
@@ -143,12 +143,13 @@ func (agent *GatewayServerAgent) executePlanStreaming(
 
 	fr := "stop"
 	agent.writeStreamChunk(w, flusher, completionID, modelName, &ChatCompletionDelta{}, &fr)
-	agent.writeStreamDone(w, flusher)
+	agent.writeStreamDone(w, r, flusher)
 }
 
 // executePlanNonStreaming executes a plan and returns a single JSON response.
 func (agent *GatewayServerAgent) executePlanNonStreaming(
 	w http.ResponseWriter,
+	r *http.Request,
 	req ChatCompletionRequest,
 	plan *agents.Plan,
 	originalQuestion string,
@@ -212,7 +213,8 @@ func (agent *GatewayServerAgent) executePlanNonStreaming(
 				FinishReason: &finishReason,
 			},
 		},
-		Usage: agent.estimateUsage(req.Messages, content),
+		Usage:  agent.estimateUsage(req.Messages, content),
+		Budget: agent.remainingClientBudget(r),
 	}
 
 	// Preserve conversation history: add the original question and a summary
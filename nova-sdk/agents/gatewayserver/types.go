@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
 )
 
 // OpenAI-compatible Chat Completions API types.
@@ -140,6 +142,8 @@ type ChatCompletionResponse struct {
 	Model   string                 `json:"model"`
 	Choices []ChatCompletionChoice `json:"choices"`
 	Usage   *Usage                 `json:"usage,omitempty"`
+	// Budget is the remaining budget of the client key (set when client budgets are enabled)
+	Budget *budget.Remaining `json:"budget,omitempty"`
 }
 
 // ChatCompletionChoice represents one choice in a non-streaming response.
@@ -166,6 +170,8 @@ type ChatCompletionChunk struct {
 	Model   string                      `json:"model"`
 	Choices []ChatCompletionChunkChoice `json:"choices"`
 	Usage   *Usage                      `json:"usage,omitempty"`
+	// Budget is the remaining budget of the client key, sent in the last chunk before [DONE]
+	Budget *budget.Remaining `json:"budget,omitempty"`
 }
 
 // ChatCompletionChunkChoice represents one choice in a streaming chunk.
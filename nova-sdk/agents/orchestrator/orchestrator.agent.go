@@ -3,10 +3,12 @@ package orchestrator
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
@@ -37,6 +39,15 @@ func WithRoutingConfig(config AgentRoutingConfig) OrchestratorAgentOption {
 	}
 }
 
+// WithBudget sets per-call, per-session and per-agent token and cost budgets.
+// With budget.ActionStop the intent identifications stop without result and with the finish
+// reason budget.FinishReasonExceeded.
+func WithBudget(config budget.Config) OrchestratorAgentOption {
+	return func(a *Agent) {
+		a.internalStructAgent.SetBudgetConfig(config)
+	}
+}
+
 // AgentRoutingConfig defines the routing configuration for the orchestrator
 // It maps topics to specific agents and provides a default fallback
 type AgentRoutingConfig struct {
@@ -123,7 +134,8 @@ func (agent *Agent) ResetMessages() {
 	agent.internalStructAgent.ResetMessages()
 }
 
-// IdentifyIntent sends messages and returns the identified intent
+// IdentifyIntent sends messages and returns the identified intent.
+// It returns an error when no intent is identified (e.g. the budget stopped the call).
 func (agent *Agent) IdentifyIntent(userMessages []messages.Message) (intent *agents.Intent, finishReason string, err error) {
 	if len(userMessages) == 0 {
 		return nil, "", errors.New("no messages provided")
@@ -139,6 +151,9 @@ func (agent *Agent) IdentifyIntent(userMessages []messages.Message) (intent *age
 	if err != nil {
 		return nil, finishReason, err
 	}
+	if intent == nil {
+		return nil, finishReason, fmt.Errorf("no intent identified (finish reason: %s)", finishReason)
+	}
 
 	// Call after completion hook if set
 	if agent.afterCompletion != nil {
@@ -217,6 +232,31 @@ func (agent *Agent) GetLastResponseJSON() (string, error) {
 	return agent.internalStructAgent.GetLastResponseJSON()
 }
 
+// SetBudgetConfig updates the token and cost budgets (the usage already recorded is kept)
+func (agent *Agent) SetBudgetConfig(config budget.Config) {
+	agent.internalStructAgent.SetBudgetConfig(config)
+}
+
+// GetBudgetConfig returns the token and cost budgets
+func (agent *Agent) GetBudgetConfig() budget.Config {
+	return agent.internalStructAgent.GetBudgetConfig()
+}
+
+// GetRemainingBudget returns the tightest remaining session or agent budget
+func (agent *Agent) GetRemainingBudget() budget.Remaining {
+	return agent.internalStructAgent.GetRemainingBudget()
+}
+
+// GetTotalUsage returns the tokens and cost since the agent was created
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.internalStructAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.internalStructAgent.SetUsageEstimation(enabled)
+}
+
 // GetContext returns the agent's context
 func (agent *Agent) GetContext() context.Context {
 	return agent.internalStructAgent.GetContext()
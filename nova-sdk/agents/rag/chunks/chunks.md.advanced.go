@@ -99,6 +99,27 @@ func buildHierarchy(stack []MarkdownChunk, currentHeader string) string {
 	return strings.Join(hierarchy, " > ")
 }
 
+// RecordMetadata returns the metadata of the vector record of the chunk
+// (e.g. for rag.Agent.SaveEmbeddingWithMetadata): header, level, parent_header and hierarchy,
+// keywords when there are some, and the entries of Metadata
+func (chunk MarkdownChunk) RecordMetadata() map[string]any {
+	metadata := map[string]any{
+		"header":    chunk.Header,
+		"level":     chunk.Level,
+		"hierarchy": chunk.Hierarchy,
+	}
+	if chunk.ParentHeader != "" {
+		metadata["parent_header"] = chunk.ParentHeader
+	}
+	if len(chunk.KeyWords) > 0 {
+		metadata["keywords"] = chunk.KeyWords
+	}
+	for key, value := range chunk.Metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
 // ChunkWithMarkdownHierarchy processes markdown content into formatted chunks with hierarchical context
 func ChunkWithMarkdownHierarchy(content string) []string {
 	// Parse the markdown content and return the chunks with hierarchy
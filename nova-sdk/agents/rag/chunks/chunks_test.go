@@ -134,3 +134,25 @@ func TestCollectHeaderContent_LastLine(t *testing.T) {
 		t.Errorf("starting at last line: expected empty, got %q", content)
 	}
 }
+
+// ── RecordMetadata ────────────────────────────────────────────────────────────
+
+func TestRecordMetadata_HeadersAndExtraMetadata(t *testing.T) {
+	chunks := ParseMarkdownHierarchy("# Guide\nintro\n## Install\nrun it")
+	if len(chunks) != 2 {
+		t.Fatalf("want 2 chunks, got %d", len(chunks))
+	}
+	chunk := chunks[1]
+	chunk.Metadata = map[string]interface{}{"source": "guide.md"}
+
+	metadata := chunk.RecordMetadata()
+	if metadata["header"] != "Install" || metadata["parent_header"] != "Guide" || metadata["level"] != 2 {
+		t.Errorf("unexpected headers: %v", metadata)
+	}
+	if metadata["hierarchy"] != "Guide > Install" || metadata["source"] != "guide.md" {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+	if _, found := chunks[0].RecordMetadata()["parent_header"]; found {
+		t.Error("a top-level chunk should have no parent_header")
+	}
+}
@@ -6,13 +6,15 @@ import (
 
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/rag/stores"
+	"github.com/snipwise/nova/nova-sdk/cache"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
 )
 
 const errEmptyContent = "content cannot be empty"
 
-// VectorRecord represents a vector record with prompt and embedding
+// VectorRecord represents a vector record with prompt, embedding and metadata
 type VectorRecord struct {
 	ID        string
 	Prompt    string
@@ -148,6 +150,25 @@ func (agent *Agent) SaveEmbedding(content string) error {
 	return agent.internalAgent.GenerateThenSaveEmbeddingVector(content)
 }
 
+// SaveEmbeddingWithMetadata generates and saves an embedding for the given content with its metadata
+// (e.g. source, chunk_index, the headers of chunks.ParseMarkdownHierarchy, tags, timestamps).
+// The metadata is returned with the search results and can be used to filter the searches:
+//
+//	err := ragAgent.SaveEmbeddingWithMetadata(chunk, map[string]any{
+//	    "source":      "guide.md",
+//	    "chunk_index": 3,
+//	})
+//	results, err := ragAgent.SearchTopNWithFilter(question, 0.6, 3, stores.MetadataFilter{
+//	    stores.Equal("source", "guide.md"),
+//	})
+func (agent *Agent) SaveEmbeddingWithMetadata(content string, metadata map[string]any) error {
+	if content == "" {
+		return errors.New(errEmptyContent)
+	}
+
+	return agent.internalAgent.GenerateThenSaveEmbeddingVectorWithMetadata(content, metadata)
+}
+
 // SaveEmbeddingIntoMemoryVectorStore is an alias for SaveEmbedding.
 func (agent *Agent) SaveEmbeddingIntoMemoryVectorStore(content string) error {
 	return agent.SaveEmbedding(content)
@@ -195,19 +216,7 @@ func (agent *Agent) SearchSimilar(content string, limit float64) ([]VectorRecord
 		return nil, err
 	}
 
-	// Convert internal VectorRecord to public VectorRecord
-	publicResults := make([]VectorRecord, len(results))
-	for i, result := range results {
-		publicResults[i] = VectorRecord{
-			ID:        result.Id,
-			Prompt:    result.Prompt,
-			Embedding: result.Embedding,
-			//Metadata:   result,
-			Similarity: result.CosineSimilarity,
-		}
-	}
-
-	return publicResults, nil
+	return toPublicRecords(results), nil
 }
 
 // SearchTopN searches for top N similar records based on content
@@ -227,19 +236,59 @@ func (agent *Agent) SearchTopN(content string, limit float64, n int) ([]VectorRe
 		return nil, err
 	}
 
-	// Convert internal VectorRecord to public VectorRecord
+	return toPublicRecords(results), nil
+}
+
+// SearchSimilarWithFilter searches for similar records based on content among the records
+// whose metadata match the filter (e.g. stores.Equal("source", "guide.md"))
+// limit is the minimum cosine similarity threshold (1.0 = exact match, 0.0 = no similarity)
+func (agent *Agent) SearchSimilarWithFilter(content string, limit float64, filter stores.MetadataFilter) ([]VectorRecord, error) {
+	if content == "" {
+		return nil, errors.New(errEmptyContent)
+	}
+
+	results, err := agent.internalAgent.SearchSimilaritiesWithFilter(content, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPublicRecords(results), nil
+}
+
+// SearchTopNWithFilter searches for top N similar records based on content among the records
+// whose metadata match the filter
+// limit is the minimum cosine similarity threshold (1.0 = exact match, 0.0 = no similarity)
+// n is the maximum number of results to return
+func (agent *Agent) SearchTopNWithFilter(content string, limit float64, n int, filter stores.MetadataFilter) ([]VectorRecord, error) {
+	if content == "" {
+		return nil, errors.New(errEmptyContent)
+	}
+
+	if n <= 0 {
+		return nil, errors.New("n must be greater than 0")
+	}
+
+	results, err := agent.internalAgent.SearchTopNSimilaritiesWithFilter(content, limit, n, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPublicRecords(results), nil
+}
+
+// toPublicRecords converts internal VectorRecords to public VectorRecords
+func toPublicRecords(results []stores.VectorRecord) []VectorRecord {
 	publicResults := make([]VectorRecord, len(results))
 	for i, result := range results {
 		publicResults[i] = VectorRecord{
-			ID:        result.Id,
-			Prompt:    result.Prompt,
-			Embedding: result.Embedding,
-			//Metadata:   result.Metadata,
+			ID:         result.Id,
+			Prompt:     result.Prompt,
+			Embedding:  result.Embedding,
+			Metadata:   result.Metadata,
 			Similarity: result.CosineSimilarity,
 		}
 	}
-
-	return publicResults, nil
+	return publicResults
 }
 
 // === Config Getters and Setters ===
@@ -282,6 +331,26 @@ func (agent *Agent) GetLastResponseJSON() (string, error) {
 	return agent.GetLastResponseJSON()
 }
 
+// SetCacheConfig updates the embedding cache settings (a nil Store disables the cache)
+func (agent *Agent) SetCacheConfig(config cache.Config) {
+	agent.internalAgent.SetCacheConfig(config)
+}
+
+// GetCacheConfig returns the embedding cache settings
+func (agent *Agent) GetCacheConfig() cache.Config {
+	return agent.internalAgent.GetCacheConfig()
+}
+
+// BypassCacheOnce makes the next embedding request skip the cache
+func (agent *Agent) BypassCacheOnce() {
+	agent.internalAgent.BypassCacheOnce()
+}
+
+// IsLastResponseFromCache reports whether the last embedding was served from the cache
+func (agent *Agent) IsLastResponseFromCache() bool {
+	return agent.internalAgent.IsLastResponseFromCache()
+}
+
 // GetContext returns the agent's context
 func (agent *Agent) GetContext() context.Context {
 	return agent.internalAgent.GetContext()
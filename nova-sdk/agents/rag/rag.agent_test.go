@@ -0,0 +1,74 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/rag/stores"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func newTestAgent(t *testing.T, options ...any) *Agent {
+	t.Helper()
+	engine := novatest.NewServer()
+	t.Cleanup(engine.Close)
+	agent, err := NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultEmbeddingModel},
+		options...,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return agent
+}
+
+func TestAgent_SearchWithMetadataFilter(t *testing.T) {
+	agent := newTestAgent(t)
+	documents := []struct {
+		content  string
+		metadata map[string]any
+	}{
+		{"Squirrels run in the forest", map[string]any{"source": "animals.md", "chunk_index": 0}},
+		{"Squirrels eat nuts in the forest", map[string]any{"source": "food.md", "chunk_index": 0}},
+		{"Birds fly in the sky", map[string]any{"source": "animals.md", "chunk_index": 1}},
+	}
+	for _, document := range documents {
+		if err := agent.SaveEmbeddingWithMetadata(document.content, document.metadata); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	results, err := agent.SearchTopNWithFilter("squirrels in the forest", 0.1, 5, stores.MetadataFilter{stores.Equal("source", "food.md")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Prompt != "Squirrels eat nuts in the forest" || results[0].Metadata["source"] != "food.md" {
+		t.Errorf("only the food.md record should be found, got %+v", results)
+	}
+
+	results, err = agent.SearchSimilarWithFilter("squirrels in the forest", 0.1, stores.MetadataFilter{stores.Range("chunk_index", 0, 0)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Metadata == nil {
+		t.Errorf("want the two first chunks with their metadata, got %+v", results)
+	}
+
+	results, _ = agent.SearchTopN("squirrels in the forest", 0.5, 5)
+	if len(results) != 2 || results[0].Metadata["chunk_index"] != 0 {
+		t.Errorf("the unfiltered search should return the metadata, got %+v", results)
+	}
+}
+
+func TestBaseAgent_SearchWithFilterNeedsAFilterableStore(t *testing.T) {
+	agent := newTestBaseAgent(&stubStore{})
+	if _, err := agent.SearchSimilaritiesWithFilter("squirrels", 0.5, nil); err == nil {
+		t.Error("a store without filter support should return an error")
+	}
+	if _, err := agent.SearchTopNSimilaritiesWithFilter("squirrels", 0.5, 3, nil); err == nil {
+		t.Error("a store without filter support should return an error")
+	}
+}
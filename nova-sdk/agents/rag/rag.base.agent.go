@@ -2,9 +2,12 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/cache"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
@@ -27,6 +30,11 @@ type BaseAgent struct {
 
 	lastRequestJSON  string
 	lastResponseJSON string
+
+	// Embedding cache (embeddings are deterministic, so every request is cacheable)
+	cache           cache.Config
+	bypassCacheOnce bool
+	lastFromCache   bool
 }
 
 // DocumentLoadMode defines how documents should be loaded when a store already contains data
@@ -102,6 +110,11 @@ func (agent *BaseAgent) GenerateEmbeddingVector(content string) (embeddingVector
 
 	agent.SaveLastEmbeddingRequest()
 
+	key := agent.cacheKey()
+	if vector, found := agent.cachedEmbedding(key); found {
+		return vector, nil
+	}
+
 	// Use the client to create embeddings
 	embeddingResponse, err := agent.openaiClient.Embeddings.New(agent.ctx, agent.EmbeddingParams)
 	if err != nil {
@@ -110,9 +123,105 @@ func (agent *BaseAgent) GenerateEmbeddingVector(content string) (embeddingVector
 
 	agent.SaveLastEmbeddingResponse(embeddingResponse)
 
+	if len(embeddingResponse.Data) == 0 {
+		return nil, errors.New("no embedding returned")
+	}
+
+	agent.storeEmbedding(key, embeddingResponse.Data[0].Embedding)
+
 	return embeddingResponse.Data[0].Embedding, nil
 }
 
+// === Embedding Cache ===
+
+// WithCache enables the embedding cache: identical (model, input) requests are served from the cache
+func WithCache(config cache.Config) AgentOption {
+	return func(agent *BaseAgent) {
+		agent.cache = config
+	}
+}
+
+// SetCacheConfig updates the embedding cache settings (a nil Store disables the cache)
+func (agent *BaseAgent) SetCacheConfig(config cache.Config) {
+	agent.cache = config
+}
+
+// GetCacheConfig returns the embedding cache settings
+func (agent *BaseAgent) GetCacheConfig() cache.Config {
+	return agent.cache
+}
+
+// BypassCacheOnce makes the next embedding request skip the cache
+func (agent *BaseAgent) BypassCacheOnce() {
+	agent.bypassCacheOnce = true
+}
+
+// IsLastResponseFromCache reports whether the last embedding was served from the cache
+func (agent *BaseAgent) IsLastResponseFromCache() bool {
+	return agent.lastFromCache
+}
+
+// cacheKey returns the cache key of the current embedding request, or "" when the cache does not apply
+func (agent *BaseAgent) cacheKey() string {
+	agent.lastFromCache = false
+
+	if !agent.cache.Enabled() {
+		return ""
+	}
+	if agent.bypassCacheOnce || cache.IsBypassed(agent.ctx) {
+		agent.bypassCacheOnce = false
+		return ""
+	}
+
+	key, err := cache.Key(cache.KindEmbedding, agent.EmbeddingParams)
+	if err != nil {
+		agent.log.Error("Error building cache key: %v", err)
+		return ""
+	}
+	return key
+}
+
+// cachedEmbedding returns the vector stored under key
+func (agent *BaseAgent) cachedEmbedding(key string) ([]float64, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	data, found, err := agent.cache.Store.Get(key)
+	if err != nil {
+		agent.log.Error("Error reading cache: %v", err)
+		return nil, false
+	}
+	if !found {
+		return nil, false
+	}
+
+	var vector []float64
+	if err := json.Unmarshal(data, &vector); err != nil || len(vector) == 0 {
+		agent.log.Warn("Ignoring invalid cache entry %s", key)
+		return nil, false
+	}
+
+	agent.lastFromCache = true
+	agent.log.Debug("💾 Cache hit: %s", key)
+	return vector, true
+}
+
+// storeEmbedding saves a vector under key
+func (agent *BaseAgent) storeEmbedding(key string, vector []float64) {
+	if key == "" {
+		return
+	}
+	data, err := json.Marshal(vector)
+	if err != nil {
+		agent.log.Error("Error encoding embedding for cache: %v", err)
+		return
+	}
+	if err := agent.cache.Store.Set(key, data, agent.cache.TTL); err != nil {
+		agent.log.Error("Error writing cache: %v", err)
+	}
+}
+
 // Embedding vector dimension: the size of the produced vector (e.g., 384, 768, 1024, 3072 dimensions).
 // GetEmbeddingDimension returns the dimension of the embedding vectors generated by the agent's model
 func (agent *BaseAgent) GetEmbeddingDimension() int {
@@ -125,6 +234,12 @@ func (agent *BaseAgent) GetEmbeddingDimension() int {
 
 // GenerateThenSaveEmbeddingVector creates a vector embedding for the given text content
 func (agent *BaseAgent) GenerateThenSaveEmbeddingVector(content string) (err error) {
+	return agent.GenerateThenSaveEmbeddingVectorWithMetadata(content, nil)
+}
+
+// GenerateThenSaveEmbeddingVectorWithMetadata creates a vector embedding for the given text content
+// and saves it with its metadata (source, chunk index, headers, tags...)
+func (agent *BaseAgent) GenerateThenSaveEmbeddingVectorWithMetadata(content string, metadata map[string]any) (err error) {
 	embeddingVector, err := agent.GenerateEmbeddingVector(content)
 	if err != nil {
 		return err
@@ -133,6 +248,7 @@ func (agent *BaseAgent) GenerateThenSaveEmbeddingVector(content string) (err err
 	_, errSave := agent.store.Save(stores.VectorRecord{
 		Prompt:    content,
 		Embedding: embeddingVector,
+		Metadata:  metadata,
 	})
 
 	if errSave != nil {
@@ -191,6 +307,58 @@ func (agent *BaseAgent) SearchTopNSimilarities(content string, limit float64, n
 	return results, nil
 }
 
+// SearchSimilaritiesWithFilter works like SearchSimilarities but only compares the records
+// whose metadata match the filter. It returns an error when the store doesn't support
+// the filtered searches (see stores.FilterableStore).
+func (agent *BaseAgent) SearchSimilaritiesWithFilter(content string, limit float64, filter stores.MetadataFilter) (results []stores.VectorRecord, err error) {
+	filterable, err := agent.filterableStore()
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingVector, err := agent.GenerateEmbeddingVector(content)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorRecord := stores.VectorRecord{
+		Prompt:    content,
+		Embedding: embeddingVector,
+	}
+
+	return filterable.SearchSimilaritiesWithFilter(vectorRecord, limit, filter)
+}
+
+// SearchTopNSimilaritiesWithFilter works like SearchTopNSimilarities but only compares the records
+// whose metadata match the filter
+func (agent *BaseAgent) SearchTopNSimilaritiesWithFilter(content string, limit float64, n int, filter stores.MetadataFilter) (results []stores.VectorRecord, err error) {
+	filterable, err := agent.filterableStore()
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingVector, err := agent.GenerateEmbeddingVector(content)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorRecord := stores.VectorRecord{
+		Prompt:    content,
+		Embedding: embeddingVector,
+	}
+
+	return filterable.SearchTopNSimilaritiesWithFilter(vectorRecord, limit, n, filter)
+}
+
+// filterableStore returns the store of the agent when it supports the filtered searches
+func (agent *BaseAgent) filterableStore() (stores.FilterableStore, error) {
+	filterable, ok := agent.store.(stores.FilterableStore)
+	if !ok {
+		return nil, errors.New("this store does not support metadata filters")
+	}
+	return filterable, nil
+}
+
 // === Store Management ===
 
 // PersistStore saves the in-memory vector store to a file (JSON format)
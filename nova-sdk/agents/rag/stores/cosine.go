@@ -31,7 +31,7 @@ func dotProduct(v1 []float64, v2 []float64) float64 {
 	return sum
 }
 
-// cosineSimilarity calculates the cosine similarity between two vectors (0 to 1 scale)
+// CosineSimilarity calculates the cosine similarity between two vectors (0 to 1 scale)
 // Returns values between 0 and 1:
 //   - 1.0: vectors are identical or perfectly aligned (maximum similarity, close distance)
 //   - 0.0: vectors are orthogonal/perpendicular (no similarity)
@@ -39,7 +39,11 @@ func dotProduct(v1 []float64, v2 []float64) float64 {
 //
 // Note: Cosine similarity measures the angle between vectors, not their magnitude.
 // Two vectors can have different lengths but still be considered similar if they point in the same direction.
-func cosineSimilarity(v1, v2 []float64) float64 {
+// Vectors of different dimensions (embeddings of different models) have no similarity.
+func CosineSimilarity(v1, v2 []float64) float64 {
+	if len(v1) != len(v2) {
+		return 0.0
+	}
 	// Calculate the cosine distance between two vectors
 	product := dotProduct(v1, v2)
 
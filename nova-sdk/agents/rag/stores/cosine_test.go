@@ -0,0 +1,24 @@
+package stores
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		v1, v2   []float64
+		expected float64
+	}{
+		{[]float64{1, 0}, []float64{2, 0}, 1},
+		{[]float64{1, 0}, []float64{0, 1}, 0},
+		{[]float64{1, 1}, []float64{1, 0}, 1 / math.Sqrt2},
+		{[]float64{0, 0}, []float64{1, 0}, 0},
+		{[]float64{1, 0}, []float64{1, 0, 0}, 0},
+	}
+	for _, test := range tests {
+		if similarity := CosineSimilarity(test.v1, test.v2); math.Abs(similarity-test.expected) > 1e-9 {
+			t.Errorf("%v %v: expected %v, got %v", test.v1, test.v2, test.expected, similarity)
+		}
+	}
+}
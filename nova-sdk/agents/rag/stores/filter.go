@@ -0,0 +1,185 @@
+package stores
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// FilterableStore defines the interface for stores that can restrict a similarity search
+// to the records matching a metadata filter (implemented by MemoryVectorStore and RedisVectorStore)
+type FilterableStore interface {
+	SearchSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, filter MetadataFilter) ([]VectorRecord, error)
+	SearchTopNSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, max int, filter MetadataFilter) ([]VectorRecord, error)
+}
+
+// FilterOperator is the comparison applied by a MetadataCondition
+type FilterOperator string
+
+const (
+	// FilterEqual matches the records whose metadata value equals the value of the condition
+	FilterEqual FilterOperator = "eq"
+	// FilterIn matches the records whose metadata value is one of the values of the condition
+	FilterIn FilterOperator = "in"
+	// FilterRange matches the records whose numeric metadata value is between Min and Max (inclusive)
+	FilterRange FilterOperator = "range"
+)
+
+// MetadataCondition is a condition on the value of one metadata key.
+// A list value (e.g. tags) matches FilterEqual and FilterIn when one of its elements matches.
+// The values are compared with their default format: a time.Time value doesn't match anymore
+// after a round trip through JSON (a persisted memory store, Redis): it is decoded as an RFC 3339 string, and FilterRange only
+// applies to numbers, so store the timestamps as Unix numbers (e.g. time.Now().Unix()).
+type MetadataCondition struct {
+	Key      string
+	Operator FilterOperator
+	// Values of FilterEqual (one value) and FilterIn
+	Values []any
+	// Min and Max of FilterRange (use math.Inf for an open bound)
+	Min float64
+	Max float64
+}
+
+// MetadataFilter selects the records matching all its conditions
+//
+// Example:
+//
+//	filter := stores.MetadataFilter{
+//	    stores.Equal("source", "guide.md"),
+//	    stores.In("tags", "go", "rag"),
+//	    stores.Range("chunk_index", 0, 10),
+//	}
+type MetadataFilter []MetadataCondition
+
+// Equal creates a condition matching the records whose metadata value for key equals value
+func Equal(key string, value any) MetadataCondition {
+	return MetadataCondition{Key: key, Operator: FilterEqual, Values: []any{value}}
+}
+
+// In creates a condition matching the records whose metadata value for key is one of values
+func In(key string, values ...any) MetadataCondition {
+	return MetadataCondition{Key: key, Operator: FilterIn, Values: values}
+}
+
+// Range creates a condition matching the records whose numeric metadata value for key
+// is between min and max (inclusive), e.g. Unix timestamps:
+//
+//	stores.Range("created_at", float64(since.Unix()), math.Inf(1))
+func Range(key string, min, max float64) MetadataCondition {
+	return MetadataCondition{Key: key, Operator: FilterRange, Min: min, Max: max}
+}
+
+// Validate returns an error when a condition has no key, no value or an unknown operator
+func (filter MetadataFilter) Validate() error {
+	for _, condition := range filter {
+		if condition.Key == "" {
+			return errors.New("metadata filter: a condition has no key")
+		}
+		switch condition.Operator {
+		case FilterEqual, FilterIn:
+			if len(condition.Values) == 0 {
+				return fmt.Errorf("metadata filter: the %s condition on %q has no value", condition.Operator, condition.Key)
+			}
+		case FilterRange:
+			if math.IsNaN(condition.Min) || math.IsNaN(condition.Max) {
+				return fmt.Errorf("metadata filter: invalid range on %q", condition.Key)
+			}
+		default:
+			return fmt.Errorf("metadata filter: unknown operator %q on %q", condition.Operator, condition.Key)
+		}
+	}
+	return nil
+}
+
+// Match reports whether metadata matches all the conditions of the filter
+// (an empty filter matches every record)
+func (filter MetadataFilter) Match(metadata map[string]any) bool {
+	for _, condition := range filter {
+		if !condition.Match(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether metadata matches the condition (false when the key is missing)
+func (condition MetadataCondition) Match(metadata map[string]any) bool {
+	value, found := metadata[condition.Key]
+	if !found {
+		return false
+	}
+	if condition.Operator == FilterRange {
+		number, ok := metadataNumber(value)
+		return ok && number >= condition.Min && number <= condition.Max
+	}
+	for _, element := range metadataValues(value) {
+		for _, expected := range condition.Values {
+			if element == metadataString(expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// metadataValues returns the elements of a list value, or the value itself, as strings
+func metadataValues(value any) []string {
+	switch value := value.(type) {
+	case []string:
+		return value
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, element := range value {
+			values = append(values, metadataString(element))
+		}
+		return values
+	default:
+		return []string{metadataString(value)}
+	}
+}
+
+// metadataString formats a metadata value for the comparisons
+// (the numbers have the same format whatever their type: int 3 and float64 3.0 are equal)
+func metadataString(value any) string {
+	if number, ok := metadataNumber(value); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// metadataNumber converts a numeric metadata value to float64
+// (the numbers of the persisted metadata are decoded as float64)
+func metadataNumber(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int8:
+		return float64(value), true
+	case int16:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint:
+		return float64(value), true
+	case uint8:
+		return float64(value), true
+	case uint16:
+		return float64(value), true
+	case uint32:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	}
+	return 0, false
+}
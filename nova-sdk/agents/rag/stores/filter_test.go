@@ -0,0 +1,168 @@
+package stores
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func newFilterTestStore() *MemoryVectorStore {
+	store := &MemoryVectorStore{Records: make(map[string]VectorRecord)}
+	store.Save(VectorRecord{Id: "guide-0", Prompt: "install", Embedding: []float64{1, 0},
+		Metadata: map[string]any{"source": "guide.md", "chunk_index": 0, "tags": []string{"go", "setup"}}})
+	store.Save(VectorRecord{Id: "guide-1", Prompt: "configure", Embedding: []float64{0.9, 0.1},
+		Metadata: map[string]any{"source": "guide.md", "chunk_index": 1, "tags": []string{"config"}}})
+	store.Save(VectorRecord{Id: "faq-0", Prompt: "questions", Embedding: []float64{1, 0.05},
+		Metadata: map[string]any{"source": "faq.md", "chunk_index": 0}})
+	store.Save(VectorRecord{Id: "plain", Prompt: "no metadata", Embedding: []float64{1, 0}})
+	return store
+}
+
+func recordIDs(records []VectorRecord) map[string]bool {
+	ids := make(map[string]bool, len(records))
+	for _, record := range records {
+		ids[record.Id] = true
+	}
+	return ids
+}
+
+// ── MetadataCondition.Match ───────────────────────────────────────────────────
+
+func TestMetadataCondition_Equal(t *testing.T) {
+	metadata := map[string]any{"source": "guide.md", "chunk_index": 3}
+	if !Equal("source", "guide.md").Match(metadata) {
+		t.Error("equal values should match")
+	}
+	if Equal("source", "faq.md").Match(metadata) {
+		t.Error("different values should not match")
+	}
+	if !Equal("chunk_index", 3.0).Match(metadata) {
+		t.Error("int 3 and float64 3.0 should match")
+	}
+	if Equal("missing", "x").Match(metadata) {
+		t.Error("a missing key should not match")
+	}
+}
+
+func TestMetadataCondition_InWithListValues(t *testing.T) {
+	metadata := map[string]any{"tags": []any{"go", "rag"}}
+	if !In("tags", "python", "rag").Match(metadata) {
+		t.Error("a list value should match when one of its elements is in the values")
+	}
+	if In("tags", "python").Match(metadata) {
+		t.Error("no common element: no match")
+	}
+}
+
+func TestMetadataCondition_Range(t *testing.T) {
+	metadata := map[string]any{"chunk_index": 5, "created_at": float64(1700000000), "source": "guide.md"}
+	if !Range("chunk_index", 5, 10).Match(metadata) {
+		t.Error("the bounds should be inclusive")
+	}
+	if Range("chunk_index", 6, 10).Match(metadata) {
+		t.Error("a value below the range should not match")
+	}
+	if !Range("created_at", 1600000000, math.Inf(1)).Match(metadata) {
+		t.Error("an open range should match")
+	}
+	if Range("source", math.Inf(-1), math.Inf(1)).Match(metadata) {
+		t.Error("a non numeric value should not match a range")
+	}
+}
+
+func TestMetadataFilter_Validate(t *testing.T) {
+	if err := (MetadataFilter{Equal("source", "a"), Range("chunk_index", 0, 1)}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	invalid := []MetadataFilter{
+		{Equal("", "a")},
+		{In("tags")},
+		{Range("chunk_index", math.NaN(), 1)},
+		{{Key: "source", Operator: "like", Values: []any{"a"}}},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("filter %+v should be invalid", filter)
+		}
+	}
+}
+
+// ── MemoryVectorStore ─────────────────────────────────────────────────────────
+
+func TestMemoryVectorStore_SearchSimilaritiesWithFilter(t *testing.T) {
+	store := newFilterTestStore()
+	question := VectorRecord{Embedding: []float64{1, 0}}
+
+	records, err := store.SearchSimilaritiesWithFilter(question, 0.5, MetadataFilter{Equal("source", "guide.md")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := recordIDs(records)
+	if len(records) != 2 || !ids["guide-0"] || !ids["guide-1"] {
+		t.Errorf("want the guide records, got %v", ids)
+	}
+	if records[0].Metadata["chunk_index"] != 0 {
+		t.Errorf("the results should be sorted and keep their metadata, got %+v", records[0])
+	}
+
+	records, _ = store.SearchSimilaritiesWithFilter(question, 0.5, MetadataFilter{In("tags", "setup", "config"), Range("chunk_index", 1, 1)})
+	if ids := recordIDs(records); len(records) != 1 || !ids["guide-1"] {
+		t.Errorf("all the conditions should match, got %v", ids)
+	}
+
+	records, _ = store.SearchSimilaritiesWithFilter(question, 0.5, nil)
+	if len(records) != 4 {
+		t.Errorf("an empty filter should keep every record, got %d", len(records))
+	}
+
+	if _, err := store.SearchSimilaritiesWithFilter(question, 0.5, MetadataFilter{In("tags")}); err == nil {
+		t.Error("an invalid filter should return an error")
+	}
+}
+
+func TestMemoryVectorStore_SearchTopNSimilaritiesWithFilter(t *testing.T) {
+	store := newFilterTestStore()
+	records, err := store.SearchTopNSimilaritiesWithFilter(VectorRecord{Embedding: []float64{1, 0}}, 0, 1, MetadataFilter{Equal("chunk_index", 0)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Id != "guide-0" {
+		t.Errorf("want the most similar first chunk, got %+v", records)
+	}
+}
+
+func TestMemoryVectorStore_PersistKeepsMetadata(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "store.json")
+	if err := newFilterTestStore().Persist(storeFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded := &MemoryVectorStore{Records: make(map[string]VectorRecord)}
+	if err := loaded.Load(storeFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metadata := loaded.Records["guide-1"].Metadata
+	if metadata["source"] != "guide.md" || metadata["chunk_index"] != 1.0 {
+		t.Errorf("the metadata should be persisted, got %v", metadata)
+	}
+	if loaded.Records["plain"].Metadata != nil {
+		t.Errorf("a record without metadata should stay without metadata, got %v", loaded.Records["plain"].Metadata)
+	}
+
+	records, _ := loaded.SearchSimilaritiesWithFilter(VectorRecord{Embedding: []float64{1, 0}}, 0, MetadataFilter{In("tags", "config"), Range("chunk_index", 1, 1)})
+	if len(records) != 1 || records[0].Id != "guide-1" {
+		t.Errorf("the loaded metadata should be filterable, got %+v", records)
+	}
+}
+
+func TestMemoryVectorStore_SaveCopiesMetadata(t *testing.T) {
+	store := &MemoryVectorStore{Records: make(map[string]VectorRecord)}
+	metadata := map[string]any{"source": "guide.md"}
+	store.Save(VectorRecord{Id: "guide-0", Embedding: []float64{1, 0}, Metadata: metadata})
+	metadata["source"] = "faq.md"
+	store.Save(VectorRecord{Id: "faq-0", Embedding: []float64{1, 0}, Metadata: metadata})
+
+	if source := store.Records["guide-0"].Metadata["source"]; source != "guide.md" {
+		t.Errorf("reusing the metadata map should not change the saved record, got %v", source)
+	}
+}
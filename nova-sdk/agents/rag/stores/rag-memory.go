@@ -2,6 +2,7 @@ package stores
 
 import (
 	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,9 +12,13 @@ import (
 
 // VectorRecord represents a stored vector with metadata and similarity score
 type VectorRecord struct {
-	Id               string    `json:"id"`
-	Prompt           string    `json:"prompt"`
-	Embedding        []float64 `json:"embedding"`
+	Id        string    `json:"id"`
+	Prompt    string    `json:"prompt"`
+	Embedding []float64 `json:"embedding"`
+	// Metadata describes the origin of the record (source, chunk index, headers, tags, timestamps...)
+	// and can be used to filter the searches (see MetadataFilter).
+	// Store the timestamps as Unix numbers: a time.Time value is a string after persistence.
+	Metadata         map[string]any `json:"metadata,omitempty"`
 	CosineSimilarity float64
 }
 
@@ -54,10 +59,12 @@ func (mvs *MemoryVectorStore) GetAll() ([]VectorRecord, error) {
 // If the record does not have an ID, it generates a new UUID for it.
 // It returns the saved vector record and an error if any occurred during the save operation.
 // If the record already exists, it will be overwritten.
+// The metadata map is copied: the caller can reuse it for the next records.
 func (mvs *MemoryVectorStore) Save(vectorRecord VectorRecord) (VectorRecord, error) {
 	if vectorRecord.Id == "" {
 		vectorRecord.Id = uuid.New().String()
 	}
+	vectorRecord.Metadata = maps.Clone(vectorRecord.Metadata)
 	mvs.Records[vectorRecord.Id] = vectorRecord
 	return vectorRecord, nil
 }
@@ -72,11 +79,31 @@ func (mvs *MemoryVectorStore) Save(vectorRecord VectorRecord) (VectorRecord, err
 //   - []llm.VectorRecord: a slice of vector records that have a cosine distance similarity greater than or equal to the limit.
 //   - error: an error if any occurred during the search.
 func (mvs *MemoryVectorStore) SearchSimilarities(embeddingFromQuestion VectorRecord, limit float64) ([]VectorRecord, error) {
+	return mvs.SearchSimilaritiesWithFilter(embeddingFromQuestion, limit, nil)
+}
+
+// SearchTopNSimilarities searches for the top N similar vector records based on the given embedding from a question.
+// It returns a slice of vector records and an error if any.
+// The limit parameter specifies the minimum similarity score for a record to be considered similar.
+// The max parameter specifies the maximum number of vector records to return.
+func (mvs *MemoryVectorStore) SearchTopNSimilarities(embeddingFromQuestion VectorRecord, limit float64, max int) ([]VectorRecord, error) {
+	return mvs.SearchTopNSimilaritiesWithFilter(embeddingFromQuestion, limit, max, nil)
+}
+
+// SearchSimilaritiesWithFilter works like SearchSimilarities but only compares the records
+// whose metadata match the filter (an empty filter compares every record)
+func (mvs *MemoryVectorStore) SearchSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, filter MetadataFilter) ([]VectorRecord, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
 
 	var records []VectorRecord
 
 	for _, v := range mvs.Records {
-		distance := cosineSimilarity(embeddingFromQuestion.Embedding, v.Embedding)
+		if !filter.Match(v.Metadata) {
+			continue
+		}
+		distance := CosineSimilarity(embeddingFromQuestion.Embedding, v.Embedding)
 		if distance >= limit {
 			v.CosineSimilarity = distance
 			records = append(records, v)
@@ -91,12 +118,10 @@ func (mvs *MemoryVectorStore) SearchSimilarities(embeddingFromQuestion VectorRec
 	return records, nil
 }
 
-// SearchTopNSimilarities searches for the top N similar vector records based on the given embedding from a question.
-// It returns a slice of vector records and an error if any.
-// The limit parameter specifies the minimum similarity score for a record to be considered similar.
-// The max parameter specifies the maximum number of vector records to return.
-func (mvs *MemoryVectorStore) SearchTopNSimilarities(embeddingFromQuestion VectorRecord, limit float64, max int) ([]VectorRecord, error) {
-	records, err := mvs.SearchSimilarities(embeddingFromQuestion, limit)
+// SearchTopNSimilaritiesWithFilter works like SearchTopNSimilarities but only compares the records
+// whose metadata match the filter
+func (mvs *MemoryVectorStore) SearchTopNSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, max int, filter MetadataFilter) ([]VectorRecord, error) {
+	records, err := mvs.SearchSimilaritiesWithFilter(embeddingFromQuestion, limit, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -3,10 +3,13 @@ package stores
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -18,6 +21,11 @@ type RedisConfig struct {
 	Password  string // Redis password (empty string for no password)
 	DB        int    // Redis database number (default: 0)
 	IndexName string // Name of the Redis search index (default: "nova_rag_index")
+
+	// Metadata keys indexed for the filtered searches.
+	// They are declared when the index is created: use a new IndexName after changing them.
+	TagFields     []string // Exact values without commas (source, tags...): equality and in filters
+	NumericFields []string // Numbers (chunk index, Unix timestamps...): range, equality and in filters
 }
 
 // RedisVectorStore implements VectorStore using Redis as the backend
@@ -101,6 +109,13 @@ func (rvs *RedisVectorStore) ensureIndexExists() error {
 		"DIM", strconv.Itoa(rvs.dimension),
 		"DISTANCE_METRIC", "COSINE",
 	}
+	// Each indexed metadata key is stored in its own hash field (the metadata field holds the JSON map)
+	for _, key := range rvs.config.TagFields {
+		args = append(args, metadataFieldName(key), "TAG", "CASESENSITIVE")
+	}
+	for _, key := range rvs.config.NumericFields {
+		args = append(args, metadataFieldName(key), "NUMERIC")
+	}
 
 	_, err = rvs.client.Do(rvs.ctx, args...).Result()
 	if err != nil {
@@ -112,6 +127,8 @@ func (rvs *RedisVectorStore) ensureIndexExists() error {
 
 // Save saves a vector record to Redis
 // If the record doesn't have an ID, a new UUID will be generated
+// The metadata keys of TagFields and NumericFields are also stored in indexed fields
+// (the values of NumericFields must be numbers)
 func (rvs *RedisVectorStore) Save(vectorRecord VectorRecord) (VectorRecord, error) {
 	// Generate ID if not provided
 	if vectorRecord.Id == "" {
@@ -121,14 +138,24 @@ func (rvs *RedisVectorStore) Save(vectorRecord VectorRecord) (VectorRecord, erro
 	// Convert embedding to bytes
 	embeddingBytes := floatsToBytes(vectorRecord.Embedding)
 
+	fields, err := rvs.metadataFields(vectorRecord.Metadata)
+	if err != nil {
+		return VectorRecord{}, fmt.Errorf("failed to save vector record: %w", err)
+	}
+
 	// Store in Redis as a hash
+	// (the previous hash is deleted so that an overwritten record keeps no stale metadata field)
 	key := fmt.Sprintf("doc:%s", vectorRecord.Id)
-	pipe := rvs.client.Pipeline()
+	pipe := rvs.client.TxPipeline()
+	pipe.Del(rvs.ctx, key)
 	pipe.HSet(rvs.ctx, key, "id", vectorRecord.Id)
 	pipe.HSet(rvs.ctx, key, "prompt", vectorRecord.Prompt)
 	pipe.HSet(rvs.ctx, key, "embedding", embeddingBytes)
+	for field, value := range fields {
+		pipe.HSet(rvs.ctx, key, field, value)
+	}
 
-	_, err := pipe.Exec(rvs.ctx)
+	_, err = pipe.Exec(rvs.ctx)
 	if err != nil {
 		return VectorRecord{}, fmt.Errorf("failed to save vector record: %w", err)
 	}
@@ -162,6 +189,9 @@ func (rvs *RedisVectorStore) GetAll() ([]VectorRecord, error) {
 		if embeddingBytes, ok := data["embedding"]; ok {
 			record.Embedding = bytesToFloats([]byte(embeddingBytes))
 		}
+		if metadata, ok := data["metadata"]; ok {
+			record.Metadata = parseMetadataField(metadata)
+		}
 
 		records = append(records, record)
 	}
@@ -176,6 +206,31 @@ func (rvs *RedisVectorStore) GetAll() ([]VectorRecord, error) {
 //
 // Returns records sorted by similarity (highest first)
 func (rvs *RedisVectorStore) SearchSimilarities(embeddingFromQuestion VectorRecord, limit float64) ([]VectorRecord, error) {
+	return rvs.SearchSimilaritiesWithFilter(embeddingFromQuestion, limit, nil)
+}
+
+// SearchTopNSimilarities searches for the top N most similar records
+// Parameters:
+//   - embeddingFromQuestion: the vector record to search for
+//   - limit: minimum cosine similarity threshold
+//   - max: maximum number of results to return
+func (rvs *RedisVectorStore) SearchTopNSimilarities(embeddingFromQuestion VectorRecord, limit float64, max int) ([]VectorRecord, error) {
+	return rvs.SearchTopNSimilaritiesWithFilter(embeddingFromQuestion, limit, max, nil)
+}
+
+// SearchSimilaritiesWithFilter works like SearchSimilarities but only compares the records
+// whose metadata match the filter. The filter is applied by Redis with the TAG and NUMERIC
+// fields of the index: its keys must be declared in RedisConfig.TagFields or NumericFields
+// (range conditions need a numeric field).
+func (rvs *RedisVectorStore) SearchSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, filter MetadataFilter) ([]VectorRecord, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	filterQuery, err := rvs.filterQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert limit (cosine similarity) to distance for Redis
 	// Redis returns distance, we need to convert back to similarity
 	// For COSINE metric in Redis: distance = 1 - cosine_similarity
@@ -188,11 +243,11 @@ func (rvs *RedisVectorStore) SearchSimilarities(embeddingFromQuestion VectorReco
 	args := []interface{}{
 		"FT.SEARCH",
 		rvs.config.IndexName,
-		"*=>[KNN 100 @embedding $query_vec AS score]",
+		filterQuery + "=>[KNN 100 @embedding $query_vec AS score]",
 		"PARAMS", "2", "query_vec", queryVector,
 		"SORTBY", "score",
 		"DIALECT", "2",
-		"RETURN", "3", "id", "prompt", "score",
+		"RETURN", "4", "id", "prompt", "metadata", "score",
 	}
 
 	result, err := rvs.client.Do(rvs.ctx, args...).Result()
@@ -208,13 +263,10 @@ func (rvs *RedisVectorStore) SearchSimilarities(embeddingFromQuestion VectorReco
 	return records, nil
 }
 
-// SearchTopNSimilarities searches for the top N most similar records
-// Parameters:
-//   - embeddingFromQuestion: the vector record to search for
-//   - limit: minimum cosine similarity threshold
-//   - max: maximum number of results to return
-func (rvs *RedisVectorStore) SearchTopNSimilarities(embeddingFromQuestion VectorRecord, limit float64, max int) ([]VectorRecord, error) {
-	records, err := rvs.SearchSimilarities(embeddingFromQuestion, limit)
+// SearchTopNSimilaritiesWithFilter works like SearchTopNSimilarities but only compares the records
+// whose metadata match the filter (see SearchSimilaritiesWithFilter)
+func (rvs *RedisVectorStore) SearchTopNSimilaritiesWithFilter(embeddingFromQuestion VectorRecord, limit float64, max int, filter MetadataFilter) ([]VectorRecord, error) {
+	records, err := rvs.SearchSimilaritiesWithFilter(embeddingFromQuestion, limit, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -255,6 +307,10 @@ func parseDocumentFields(id string, fields []interface{}) VectorRecord {
 			if prompt, ok := fields[j+1].(string); ok {
 				record.Prompt = prompt
 			}
+		case "metadata":
+			if metadata, ok := fields[j+1].(string); ok {
+				record.Metadata = parseMetadataField(metadata)
+			}
 		case "score":
 			if similarity, ok := parseScoreField(fields[j+1]); ok {
 				record.CosineSimilarity = similarity
@@ -300,6 +356,121 @@ func (rvs *RedisVectorStore) parseSearchResults(result interface{}, similarityLi
 	return records, nil
 }
 
+// metadataFieldName returns the hash field of an indexed metadata key
+func metadataFieldName(key string) string {
+	return "meta_" + key
+}
+
+// parseMetadataField decodes the JSON metadata of a record (nil when it is invalid)
+func parseMetadataField(raw string) map[string]any {
+	var metadata map[string]any
+	if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+		return nil
+	}
+	return metadata
+}
+
+// metadataFields returns the hash fields storing the metadata of a record: the JSON map,
+// and a field for each metadata key of TagFields (a list value is joined with commas) and NumericFields
+func (rvs *RedisVectorStore) metadataFields(metadata map[string]any) (map[string]string, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	fields := map[string]string{"metadata": string(encoded)}
+	for _, key := range rvs.config.TagFields {
+		if value, found := metadata[key]; found {
+			fields[metadataFieldName(key)] = strings.Join(metadataValues(value), ",")
+		}
+	}
+	for _, key := range rvs.config.NumericFields {
+		value, found := metadata[key]
+		if !found {
+			continue
+		}
+		number, ok := metadataNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("metadata %q is a numeric field, got %v", key, value)
+		}
+		fields[metadataFieldName(key)] = formatNumber(number)
+	}
+	return fields, nil
+}
+
+// filterQuery converts a metadata filter to the filter of an FT.SEARCH query
+// ("*" when the filter is empty):
+//
+//	(@meta_source:{guide\.md} @meta_chunk_index:[0 10])
+func (rvs *RedisVectorStore) filterQuery(filter MetadataFilter) (string, error) {
+	if len(filter) == 0 {
+		return "*", nil
+	}
+	clauses := make([]string, 0, len(filter))
+	for _, condition := range filter {
+		field := "@" + escapeQueryTerm(metadataFieldName(condition.Key))
+		switch {
+		case slices.Contains(rvs.config.TagFields, condition.Key):
+			if condition.Operator == FilterRange {
+				return "", fmt.Errorf("metadata filter: a range on %q needs a numeric field (RedisConfig.NumericFields)", condition.Key)
+			}
+			values := make([]string, 0, len(condition.Values))
+			for _, value := range condition.Values {
+				values = append(values, escapeQueryTerm(metadataString(value)))
+			}
+			clauses = append(clauses, fmt.Sprintf("%s:{%s}", field, strings.Join(values, " | ")))
+
+		case slices.Contains(rvs.config.NumericFields, condition.Key):
+			if condition.Operator == FilterRange {
+				clauses = append(clauses, fmt.Sprintf("%s:[%s %s]", field, formatNumber(condition.Min), formatNumber(condition.Max)))
+				continue
+			}
+			ranges := make([]string, 0, len(condition.Values))
+			for _, value := range condition.Values {
+				number, ok := metadataNumber(value)
+				if !ok {
+					return "", fmt.Errorf("metadata filter: %q is a numeric field, got %v", condition.Key, value)
+				}
+				ranges = append(ranges, fmt.Sprintf("%s:[%s %s]", field, formatNumber(number), formatNumber(number)))
+			}
+			if len(ranges) == 1 {
+				clauses = append(clauses, ranges[0])
+			} else {
+				clauses = append(clauses, "("+strings.Join(ranges, " | ")+")")
+			}
+
+		default:
+			return "", fmt.Errorf("metadata filter: %q is not indexed (RedisConfig.TagFields or NumericFields)", condition.Key)
+		}
+	}
+	return "(" + strings.Join(clauses, " ") + ")", nil
+}
+
+// formatNumber formats a number for a NUMERIC field or range (-inf and +inf for the open bounds)
+func formatNumber(number float64) string {
+	switch {
+	case math.IsInf(number, -1):
+		return "-inf"
+	case math.IsInf(number, 1):
+		return "+inf"
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// escapeQueryTerm escapes the punctuation and spaces of a field name or a tag value of a query
+func escapeQueryTerm(term string) string {
+	var builder strings.Builder
+	for _, r := range term {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
 // floatsToBytes converts a float64 slice to bytes for Redis storage (FLOAT32 encoding)
 func floatsToBytes(floats []float64) []byte {
 	bytes := make([]byte, len(floats)*4)
@@ -1,6 +1,7 @@
 package stores
 
 import (
+	"math"
 	"testing"
 )
 
@@ -128,3 +129,82 @@ func TestParseDocumentFields_EmptyFields(t *testing.T) {
 		t.Errorf("empty fields: expected zero-value record, got %+v", record)
 	}
 }
+
+func TestParseDocumentFields_Metadata(t *testing.T) {
+	fields := []interface{}{"prompt", "hi", "metadata", `{"source":"guide.md","chunk_index":2}`}
+	record := parseDocumentFields("id5", fields)
+	if record.Metadata["source"] != "guide.md" || record.Metadata["chunk_index"] != 2.0 {
+		t.Errorf("Metadata: unexpected %v", record.Metadata)
+	}
+}
+
+// ── metadata filters ───────────────────────────────────────────────────────────
+
+func newFilterRedisStore() *RedisVectorStore {
+	return &RedisVectorStore{config: RedisConfig{
+		TagFields:     []string{"source", "tags"},
+		NumericFields: []string{"chunk_index", "created_at"},
+	}}
+}
+
+func TestFilterQuery_Empty(t *testing.T) {
+	query, err := newFilterRedisStore().filterQuery(nil)
+	if err != nil || query != "*" {
+		t.Errorf("want '*', got %q (%v)", query, err)
+	}
+}
+
+func TestFilterQuery_TagsAndNumbers(t *testing.T) {
+	filter := MetadataFilter{
+		Equal("source", "my guide.md"),
+		In("tags", "go", "rag"),
+		Range("created_at", 1700000000, math.Inf(1)),
+		In("chunk_index", 1, 2),
+	}
+	query, err := newFilterRedisStore().filterQuery(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `(@meta_source:{my\ guide\.md} @meta_tags:{go | rag} @meta_created_at:[1700000000 +inf] (@meta_chunk_index:[1 1] | @meta_chunk_index:[2 2]))`
+	if query != want {
+		t.Errorf("want %s, got %s", want, query)
+	}
+}
+
+func TestFilterQuery_Errors(t *testing.T) {
+	invalid := []MetadataFilter{
+		{Equal("author", "bob")},              // not indexed
+		{Range("source", 0, 1)},               // range on a tag field
+		{Equal("chunk_index", "first chunk")}, // not a number
+	}
+	for _, filter := range invalid {
+		if _, err := newFilterRedisStore().filterQuery(filter); err == nil {
+			t.Errorf("filter %+v should return an error", filter)
+		}
+	}
+}
+
+func TestMetadataFields(t *testing.T) {
+	fields, err := newFilterRedisStore().metadataFields(map[string]any{
+		"source": "guide.md", "tags": []string{"go", "rag"}, "chunk_index": 3, "author": "bob",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields["meta_source"] != "guide.md" || fields["meta_tags"] != "go,rag" || fields["meta_chunk_index"] != "3" {
+		t.Errorf("unexpected indexed fields: %v", fields)
+	}
+	if _, found := fields["meta_author"]; found {
+		t.Error("a key that is not indexed should only be stored in the metadata JSON")
+	}
+	if parseMetadataField(fields["metadata"])["author"] != "bob" {
+		t.Errorf("unexpected metadata JSON: %s", fields["metadata"])
+	}
+
+	if _, err := newFilterRedisStore().metadataFields(map[string]any{"chunk_index": "three"}); err == nil {
+		t.Error("a non numeric value of a numeric field should return an error")
+	}
+	if fields, _ := newFilterRedisStore().metadataFields(nil); fields != nil {
+		t.Errorf("no metadata: want no field, got %v", fields)
+	}
+}
@@ -80,11 +80,19 @@ func NewAgent(
 		return nil, errors.New("baseURL cannot be empty")
 	}
 
+	httpClient, err := agents.ResolveHTTPClient(agentConfig)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
 	agent := &Agent{
 		ctx:     ctx,
 		config:  agentConfig,
 		baseURL: strings.TrimSuffix(baseURL, "/"),
-		client:  &http.Client{},
+		client:  httpClient,
 		log:     log,
 	}
 
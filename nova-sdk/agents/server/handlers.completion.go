@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 )
@@ -13,6 +14,18 @@ import (
 // ----------------------------------------
 
 func (agent *ServerAgent) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	clientKey := agent.ClientKey(r)
+	if !agent.AllowClientBudget(w, clientKey) {
+		return
+	}
+
+	agent.conversationMutex.Lock()
+	defer agent.conversationMutex.Unlock()
+
+	// The agents add the usage of the request to requestUsage
+	requestUsage := &budget.Tracker{}
+	defer budget.TrackRequest(requestUsage, agent.usageReporters()...)()
+
 	if agent.beforeCompletion != nil {
 		agent.beforeCompletion(agent)
 	}
@@ -30,6 +43,11 @@ func (agent *ServerAgent) handleCompletion(w http.ResponseWriter, r *http.Reques
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if agent.BudgetLedger != nil {
+		defer func() {
+			agent.WriteSSEBudget(w, flusher, agent.ChargeClientBudget(clientKey, requestUsage.Usage()))
+		}()
+	}
 
 	if planExecuted, planErr := agent.executePlanHTTP(question, w, flusher); planErr != nil {
 		agent.WriteSSEError(w, flusher, planErr)
@@ -112,3 +130,12 @@ func (agent *ServerAgent) generateStreamingCompletion(
 	agent.AddRAGContext(agent.chatAgent, question)
 	agent.StreamCompletionResponse(agent.chatAgent, question, w, flusher)
 }
+
+// usageReporters returns all the agents of the server that report their usage
+func (agent *ServerAgent) usageReporters() []budget.Reporter {
+	reporters := agent.UsageReporters()
+	if agent.tasksAgentConfig != nil {
+		reporters = append(reporters, agent.tasksAgentConfig)
+	}
+	return reporters
+}
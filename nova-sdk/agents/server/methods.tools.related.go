@@ -9,6 +9,7 @@ import (
 // SetToolsAgent sets the tools agent
 func (agent *ServerAgent) SetToolsAgent(toolsAgent *tools.Agent) {
 	agent.ToolsAgent = toolsAgent
+	agent.AuditToolsAgent(toolsAgent)
 }
 
 // GetToolsAgent returns the tools agent
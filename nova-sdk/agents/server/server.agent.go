@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/chat"
@@ -13,6 +14,8 @@ import (
 	"github.com/snipwise/nova/nova-sdk/agents/serverbase"
 	"github.com/snipwise/nova/nova-sdk/agents/tasks"
 	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
@@ -23,6 +26,9 @@ type ServerAgent struct {
 	*serverbase.BaseServerAgent
 	chatAgent *chat.Agent
 
+	// conversationMutex serializes the completions: the agents keep one conversation
+	conversationMutex sync.Mutex
+
 	// HTTP server multiplexer for custom routes
 	Mux *http.ServeMux
 
@@ -31,12 +37,15 @@ type ServerAgent struct {
 	executeFnConfig            func(string, string) (string, error)
 	confirmationPromptFnConfig func(string, string) tools.ConfirmationResponse
 	toolsAgentConfig           *tools.Agent
+	auditConfig                audit.Config
 	tasksAgentConfig           *tasks.Agent
 	ragAgentConfig             *rag.Agent
 	compressorAgentConfig      *compressor.Agent
 	similarityLimitConfig      float64
 	maxSimilaritiesConfig      int
 	contextSizeLimitConfig     int
+	budgetLedgerConfig         *budget.Ledger
+	clientKeyFnConfig          func(*http.Request) string
 
 	// TLS/HTTPS configuration
 	tlsCertData []byte
@@ -122,6 +131,14 @@ func WithToolsAgent(toolsAgent *tools.Agent) ServerAgentOption {
 	}
 }
 
+// WithAudit emits an audit event for every server-side tool call (tools agent executions)
+func WithAudit(config audit.Config) ServerAgentOption {
+	return func(agent *ServerAgent) error {
+		agent.auditConfig = config
+		return nil
+	}
+}
+
 // WithTasksAgent sets the tasks agent for task planning and orchestration.
 // When configured, the agent will first analyze user requests to identify a plan of tasks,
 // then execute each task using either the tools agent (for "tool" tasks) or the chat agent
@@ -168,6 +185,30 @@ func WithRagAgentAndSimilarityConfig(ragAgent *rag.Agent, similarityLimit float6
 	}
 }
 
+// WithClientBudget enforces token and cost budgets per client key on POST /completion.
+// Exhausted clients get 429 Too Many Requests; the other ones receive their remaining budget
+// in a final SSE event: {"budget":{"remaining_tokens":...,"remaining_cost":...}}.
+func WithClientBudget(ledger *budget.Ledger) ServerAgentOption {
+	return func(agent *ServerAgent) error {
+		if ledger == nil {
+			return fmt.Errorf("budget ledger cannot be nil")
+		}
+		agent.budgetLedgerConfig = ledger
+		return nil
+	}
+}
+
+// WithClientKeyFn sets how the client key of a request is found (default: budget.ClientKeyFromRequest).
+// The function should map the requests to known clients (e.g. the user of a validated session):
+// the default trusts any bearer token or API key, so that a caller gets a new budget by
+// sending a new token.
+func WithClientKeyFn(fn func(*http.Request) string) ServerAgentOption {
+	return func(agent *ServerAgent) error {
+		agent.clientKeyFnConfig = fn
+		return nil
+	}
+}
+
 // BeforeCompletion sets a hook that is called before each completion (HTTP and CLI)
 func BeforeCompletion(fn func(*ServerAgent)) ServerAgentOption {
 	return func(agent *ServerAgent) error {
@@ -193,11 +234,14 @@ func AfterCompletion(fn func(*ServerAgent)) ServerAgentOption {
 //   - WithTLSCert(certData, keyData) - Enables HTTPS with PEM-encoded certificate and key data
 //   - WithTLSCertFromFile(certPath, keyPath) - Enables HTTPS with certificate and key files
 //   - WithToolsAgent(toolsAgent) - Attaches a tools agent for function calling capabilities
+//   - WithAudit(config) - Emits an audit event for every server-side tool call
 //   - WithTasksAgent(tasksAgent) - Attaches a tasks agent for task planning and orchestration
 //   - WithCompressorAgent(compressorAgent) - Attaches a compressor agent for context compression
 //   - WithCompressorAgentAndContextSize(compressorAgent, contextSizeLimit) - Attaches a compressor agent and sets the context size limit
 //   - WithRagAgent(ragAgent) - Attaches a RAG agent for document retrieval
 //   - WithRagAgentAndSimilarityConfig(ragAgent, similarityLimit, maxSimilarities) - Attaches a RAG agent and configures similarity settings
+//   - WithClientBudget(ledger) - Enforces token and cost budgets per client key
+//   - WithClientKeyFn(fn) - Sets how the client key of a request is found
 //   - BeforeCompletion(fn) - Sets a hook called before each completion (HTTP and CLI)
 //   - AfterCompletion(fn) - Sets a hook called after each completion (HTTP and CLI)
 //
@@ -246,6 +290,9 @@ func (agent *ServerAgent) applyConfigFields() {
 	if agent.toolsAgentConfig != nil {
 		agent.ToolsAgent = agent.toolsAgentConfig
 	}
+	if agent.auditConfig.Enabled() {
+		agent.SetAudit(agent.auditConfig, audit.SourceServer)
+	}
 	if agent.ragAgentConfig != nil {
 		agent.RagAgent = agent.ragAgentConfig
 		if agent.similarityLimitConfig != 0 {
@@ -261,6 +308,12 @@ func (agent *ServerAgent) applyConfigFields() {
 			agent.ContextSizeLimit = agent.contextSizeLimitConfig
 		}
 	}
+	agent.BudgetLedger = agent.budgetLedgerConfig
+	if agent.BudgetLedger != nil {
+		// The client budgets need the usage of every request
+		budget.EnableUsageEstimation(agent.usageReporters()...)
+	}
+	agent.ClientKeyFn = agent.clientKeyFnConfig
 	if agent.ExecuteFn == nil {
 		agent.ExecuteFn = agent.executeFunction
 	}
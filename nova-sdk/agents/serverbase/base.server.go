@@ -10,6 +10,8 @@ import (
 	"github.com/snipwise/nova/nova-sdk/agents/compressor"
 	"github.com/snipwise/nova/nova-sdk/agents/rag"
 	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
 )
 
@@ -64,6 +66,15 @@ type BaseServerAgent struct {
 
 	// Custom confirmation prompt function (for CLI mode)
 	ConfirmationPromptFn func(string, string) tools.ConfirmationResponse
+
+	// Token and cost budgets per client key (nil: no client budgets)
+	BudgetLedger *budget.Ledger
+	// ClientKeyFn extracts the client key of a request (default: budget.ClientKeyFromRequest).
+	// It should map the requests to known clients: the default trusts any token.
+	ClientKeyFn func(*http.Request) string
+
+	// Audit of the server-side tool calls (applied to the tools agent)
+	Audit audit.Config
 }
 
 // NewBaseServerAgent creates a new base server agent
@@ -209,10 +220,17 @@ func (agent *BaseServerAgent) handleOperationSSE(
 		return
 	}
 	flusher.Flush()
-	op.Response <- response
+
+	decision := tools.ConfirmationDecision{Response: response}
+	if response == tools.Confirmed {
+		decision = req.Decision()
+	}
+	op.Response <- decision
 }
 
-// HandleOperationValidate handles the operation validation endpoint
+// HandleOperationValidate handles the operation validation endpoint.
+// The optional "scope" ("session" or "always") and "arguments" (edited arguments)
+// fields of the request are passed to the tools agent.
 func (agent *BaseServerAgent) HandleOperationValidate(w http.ResponseWriter, r *http.Request) {
 	agent.handleOperationSSE(w, r,
 		"❌ Operation %s not found",
@@ -241,7 +259,7 @@ func (agent *BaseServerAgent) HandleOperationReset(w http.ResponseWriter, r *htt
 	agent.OperationsMutex.Lock()
 	count := len(agent.PendingOperations)
 	for id, op := range agent.PendingOperations {
-		op.Response <- tools.Quit
+		op.Response <- tools.ConfirmationDecision{Response: tools.Quit}
 		delete(agent.PendingOperations, id)
 	}
 	agent.OperationsMutex.Unlock()
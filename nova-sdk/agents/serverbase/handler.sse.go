@@ -1,6 +1,7 @@
 package serverbase
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -84,11 +85,16 @@ func (agent *BaseServerAgent) SendToolCallNotification(
 	notification ToolCallNotification,
 ) {
 	notifData := map[string]interface{}{
-		"kind":         "tool_call",
+		"kind":         NotificationKindToolCall,
 		"status":       "pending",
 		"operation_id": notification.OperationID,
 		"message":      notification.Message,
 	}
+	// The other operations come with their request (e.g. the schema of an elicitation)
+	if notification.Kind != "" && notification.Kind != NotificationKindToolCall {
+		notifData["kind"] = notification.Kind
+		notifData["arguments"] = notification.Arguments
+	}
 	jsonData, _ := json.Marshal(notifData)
 	if _, err := fmt.Fprintf(w, sseDataFmt, string(jsonData)); err != nil {
 		agent.Log.Error("Failed to write notification: %v", err)
@@ -156,7 +162,7 @@ func (agent *BaseServerAgent) HandleToolCallsWithNotifications(
 			toolCallsResult, err = agent.ToolsAgent.DetectToolCallsLoopWithConfirmation(
 				historyMessages,
 				agent.ExecuteFn,
-				agent.WebConfirmationPrompt,
+				agent.WebConfirmationDecision,
 			)
 		}
 	}
@@ -307,41 +313,58 @@ func (agent *BaseServerAgent) StreamCompletionResponse(
 }
 
 // WebConfirmationPrompt sends a confirmation prompt via web interface and waits for user response.
+// Edited arguments are ignored: use WebConfirmationDecision to support them.
 func (agent *BaseServerAgent) WebConfirmationPrompt(functionName string, arguments string) tools.ConfirmationResponse {
-	operationID := fmt.Sprintf("op_%p", &arguments)
+	return agent.WebConfirmationDecision(functionName, arguments).Response
+}
 
-	agent.Log.Info("🟡 Tool call detected: %s with args: %s (ID: %s)", functionName, arguments, operationID)
+// WebConfirmationDecision sends a confirmation prompt via web interface and waits for user decision
+// (validation for once, for the session or always, with or without edited arguments).
+func (agent *BaseServerAgent) WebConfirmationDecision(functionName string, arguments string) tools.ConfirmationDecision {
+	agent.Log.Info("🟡 Tool call detected: %s with args: %s", functionName, arguments)
+	decision, _ := agent.awaitOperation(context.Background(), ToolCallNotification{
+		FunctionName: functionName,
+		Arguments:    arguments,
+		Message:      fmt.Sprintf("Tool call detected: %s", functionName),
+	})
+	return decision
+}
+
+// awaitOperation registers a pending operation, notifies the web client and waits for its
+// validation or cancellation (or the end of ctx)
+func (agent *BaseServerAgent) awaitOperation(ctx context.Context, notification ToolCallNotification) (tools.ConfirmationDecision, error) {
+	operationID := fmt.Sprintf("op_%p", &notification)
+	notification.OperationID = operationID
 
-	responseChan := make(chan tools.ConfirmationResponse)
+	responseChan := make(chan tools.ConfirmationDecision, 1)
 
 	agent.OperationsMutex.Lock()
 	agent.PendingOperations[operationID] = &PendingOperation{
 		ID:           operationID,
-		FunctionName: functionName,
-		Arguments:    arguments,
+		FunctionName: notification.FunctionName,
+		Arguments:    notification.Arguments,
 		Response:     responseChan,
 	}
 	agent.OperationsMutex.Unlock()
 
-	message := fmt.Sprintf("Tool call detected: %s", functionName)
 	agent.NotificationChanMutex.Lock()
 	if agent.CurrentNotificationChan != nil {
-		agent.CurrentNotificationChan <- ToolCallNotification{
-			OperationID:  operationID,
-			FunctionName: functionName,
-			Arguments:    arguments,
-			Message:      message,
-		}
+		agent.CurrentNotificationChan <- notification
 	}
 	agent.NotificationChanMutex.Unlock()
 
 	agent.Log.Info("⏳ Waiting for validation of operation %s", operationID)
 
-	response := <-responseChan
-
-	agent.Log.Info("✅ Operation %s resolved with response: %v", operationID, response)
-
-	return response
+	select {
+	case decision := <-responseChan:
+		agent.Log.Info("✅ Operation %s resolved with response: %v", operationID, decision.Response)
+		return decision, nil
+	case <-ctx.Done():
+		agent.OperationsMutex.Lock()
+		delete(agent.PendingOperations, operationID)
+		agent.OperationsMutex.Unlock()
+		return tools.ConfirmationDecision{Response: tools.Quit}, ctx.Err()
+	}
 }
 
 // WriteSSEChunk writes a chunk of content via SSE.
@@ -0,0 +1,20 @@
+package serverbase
+
+import (
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+)
+
+// SetAudit makes the tools agent emit an audit event for every server-side tool call,
+// with source as the default source of the events
+func (agent *BaseServerAgent) SetAudit(config audit.Config, source string) {
+	agent.Audit = config.WithSource(source)
+	agent.AuditToolsAgent(agent.ToolsAgent)
+}
+
+// AuditToolsAgent applies the audit settings of the server to a tools agent
+func (agent *BaseServerAgent) AuditToolsAgent(toolsAgent *tools.Agent) {
+	if toolsAgent != nil && agent.Audit.Enabled() {
+		toolsAgent.SetAudit(agent.Audit)
+	}
+}
@@ -0,0 +1,75 @@
+package serverbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
+)
+
+// ClientKey returns the budget key of the client that sent the request
+func (agent *BaseServerAgent) ClientKey(r *http.Request) string {
+	if agent.ClientKeyFn != nil {
+		return agent.ClientKeyFn(r)
+	}
+	return budget.ClientKeyFromRequest(r)
+}
+
+// TotalUsage returns the tokens and cost used by the chat, tools and compressor agents
+func (agent *BaseServerAgent) TotalUsage() budget.Usage {
+	return budget.Sum(agent.UsageReporters()...)
+}
+
+// UsageReporters returns the chat, tools and compressor agents that report their usage
+func (agent *BaseServerAgent) UsageReporters() []budget.Reporter {
+	var reporters []budget.Reporter
+	if reporter, ok := agent.ChatAgent.(budget.Reporter); ok {
+		reporters = append(reporters, reporter)
+	}
+	if agent.ToolsAgent != nil {
+		reporters = append(reporters, agent.ToolsAgent)
+	}
+	if agent.CompressorAgent != nil {
+		reporters = append(reporters, agent.CompressorAgent)
+	}
+	return reporters
+}
+
+// AllowClientBudget answers 429 Too Many Requests and returns false
+// when the budget of the client key is exhausted
+func (agent *BaseServerAgent) AllowClientBudget(w http.ResponseWriter, key string) bool {
+	if agent.BudgetLedger == nil {
+		return true
+	}
+	if err := agent.BudgetLedger.Allow(key); err != nil {
+		agent.Log.Warn("💸 Request rejected: %v", err)
+		w.Header().Set(headerContentType, contentTypeJSON)
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error":  err.Error(),
+			"budget": agent.BudgetLedger.Remaining(key),
+		})
+		return false
+	}
+	return true
+}
+
+// ChargeClientBudget charges the usage of a request (see budget.TrackRequest) to the client
+// key and returns its remaining budget
+func (agent *BaseServerAgent) ChargeClientBudget(key string, usage budget.Usage) budget.Remaining {
+	if agent.BudgetLedger == nil {
+		return budget.Remaining{Tokens: budget.Unlimited, Cost: budget.Unlimited}
+	}
+	return agent.BudgetLedger.Charge(key, usage)
+}
+
+// WriteSSEBudget writes the remaining budget of the client via SSE
+func (agent *BaseServerAgent) WriteSSEBudget(w http.ResponseWriter, flusher http.Flusher, remaining budget.Remaining) {
+	data := map[string]any{"budget": remaining}
+	jsonData, _ := json.Marshal(data)
+	if _, err := fmt.Fprintf(w, sseDataFmt, string(jsonData)); err != nil {
+		agent.Log.Error("Failed to write budget: %v", err)
+	}
+	flusher.Flush()
+}
@@ -0,0 +1,69 @@
+package serverbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/mcptools"
+)
+
+// WebMCPSamplingConfirmation asks the web client to approve a completion requested by an
+// MCP server, as a pending operation (kind NotificationKindMCPSampling, the request in its
+// arguments): the validation approves it, the cancellation refuses it.
+// It can be used as a mcptools.SamplingConfirmation.
+func (agent *BaseServerAgent) WebMCPSamplingConfirmation(ctx context.Context, request mcptools.SamplingRequest) (bool, error) {
+	arguments, err := json.Marshal(request)
+	if err != nil {
+		return false, err
+	}
+	agent.Log.Info("🧠 MCP sampling request: %s", string(arguments))
+	decision, err := agent.awaitOperation(ctx, ToolCallNotification{
+		FunctionName: "mcp_sampling",
+		Arguments:    string(arguments),
+		Message:      "An MCP server requests a completion",
+		Kind:         NotificationKindMCPSampling,
+	})
+	if err != nil {
+		return false, err
+	}
+	return decision.Response != tools.Denied && decision.Response != tools.Quit, nil
+}
+
+// WebMCPElicitation asks the web client for the input requested by an MCP server, as a
+// pending operation (kind NotificationKindMCPElicitation, the message and the schema in its
+// arguments). The content is sent in the "arguments" field of the validation (a JSON
+// object following the schema); the cancellation declines the request and the reset of
+// the operations cancels it.
+// It can be used as a mcptools.ElicitationCallback.
+func (agent *BaseServerAgent) WebMCPElicitation(ctx context.Context, request mcptools.ElicitationRequest) (mcptools.ElicitationResponse, error) {
+	arguments, err := json.Marshal(map[string]any{"message": request.Message, "schema": request.Schema})
+	if err != nil {
+		return mcptools.ElicitationResponse{}, err
+	}
+	agent.Log.Info("🙋 MCP elicitation request: %s", request.Message)
+	decision, err := agent.awaitOperation(ctx, ToolCallNotification{
+		FunctionName: "mcp_elicitation",
+		Arguments:    string(arguments),
+		Message:      request.Message,
+		Kind:         NotificationKindMCPElicitation,
+	})
+	if err != nil {
+		return mcptools.ElicitationResponse{}, err
+	}
+
+	switch decision.Response {
+	case tools.Denied:
+		return mcptools.ElicitationResponse{Action: mcptools.ElicitationDecline}, nil
+	case tools.Quit:
+		return mcptools.ElicitationResponse{Action: mcptools.ElicitationCancel}, nil
+	}
+	content := map[string]any{}
+	if decision.Arguments != "" {
+		if err := json.Unmarshal([]byte(decision.Arguments), &content); err != nil {
+			return mcptools.ElicitationResponse{}, fmt.Errorf("invalid elicitation content: %w", err)
+		}
+	}
+	return mcptools.ElicitationResponse{Action: mcptools.ElicitationAccept, Content: content}, nil
+}
@@ -5,12 +5,22 @@ import (
 	"github.com/snipwise/nova/nova-sdk/messages"
 )
 
+// Kinds of the pending operations notified to the web clients
+const (
+	NotificationKindToolCall       = "tool_call"
+	NotificationKindMCPSampling    = "mcp_sampling"
+	NotificationKindMCPElicitation = "mcp_elicitation"
+)
+
 // ToolCallNotification represents a notification about a pending tool call
+// (or another pending operation, see Kind)
 type ToolCallNotification struct {
 	OperationID  string
 	FunctionName string
 	Arguments    string
 	Message      string
+	// Kind of the operation (default: NotificationKindToolCall)
+	Kind string
 }
 
 // PendingOperation represents a tool call operation awaiting user confirmation
@@ -18,7 +28,7 @@ type PendingOperation struct {
 	ID           string
 	FunctionName string
 	Arguments    string
-	Response     chan tools.ConfirmationResponse
+	Response     chan tools.ConfirmationDecision
 }
 
 // CompletionRequest represents an HTTP request for chat completion
@@ -31,6 +41,24 @@ type CompletionRequest struct {
 // OperationRequest represents an HTTP request for operation management
 type OperationRequest struct {
 	OperationID string `json:"operation_id"`
+	// Scope of a validation: "session" or "always" approves the tool for the next calls
+	Scope string `json:"scope,omitempty"`
+	// Arguments replace the arguments of the validated call (edited by the user)
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Decision returns the confirmation decision of a validation request
+func (req OperationRequest) Decision() tools.ConfirmationDecision {
+	switch {
+	case req.Arguments != "":
+		return tools.ConfirmationDecision{Response: tools.EditedAndConfirmed, Arguments: req.Arguments}
+	case req.Scope == "session":
+		return tools.ConfirmationDecision{Response: tools.ConfirmedForSession}
+	case req.Scope == "always":
+		return tools.ConfirmationDecision{Response: tools.ConfirmedAlways}
+	default:
+		return tools.ConfirmationDecision{Response: tools.Confirmed}
+	}
 }
 
 // MemoryResponse represents the response containing conversation history
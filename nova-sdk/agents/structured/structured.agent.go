@@ -9,6 +9,8 @@ import (
 
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/cache"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
@@ -38,6 +40,23 @@ func AfterCompletion[Output any](fn func(*Agent[Output])) StructuredAgentOption[
 	}
 }
 
+// WithCache enables the response cache. Only deterministic requests (temperature 0 or seed set)
+// are cached unless config.Force is true.
+func WithCache[Output any](config cache.Config) StructuredAgentOption[Output] {
+	return func(a *Agent[Output]) {
+		a.internalAgent.SetCacheConfig(config)
+	}
+}
+
+// WithBudget sets per-call, per-session and per-agent token and cost budgets.
+// With budget.ActionStop the generations stop without data and with the finish reason
+// budget.FinishReasonExceeded.
+func WithBudget[Output any](config budget.Config) StructuredAgentOption[Output] {
+	return func(a *Agent[Output]) {
+		a.internalAgent.SetBudgetConfig(config)
+	}
+}
+
 // Agent represents a simplified structured data agent that hides OpenAI SDK details
 type Agent[Output any] struct {
 	config        agents.Config
@@ -164,7 +183,8 @@ func (agent *Agent[Output]) ResetMessages() {
 	agent.internalAgent.ResetMessages()
 }
 
-// Generate sends messages and returns structured data
+// Generate sends messages and returns structured data.
+// When the budget stops the generation, it returns no data with budget.FinishReasonExceeded.
 func (agent *Agent[Output]) GenerateStructuredData(userMessages []messages.Message) (response *Output, finishReason string, err error) {
 	if len(userMessages) == 0 {
 		return nil, "", errors.New("no messages provided")
@@ -234,6 +254,66 @@ func (agent *Agent[Output]) GetLastResponseJSON() (string, error) {
 	return agent.internalAgent.GetLastResponseJSON()
 }
 
+// SetCacheConfig updates the response cache settings (a nil Store disables the cache)
+func (agent *Agent[Output]) SetCacheConfig(config cache.Config) {
+	agent.internalAgent.SetCacheConfig(config)
+}
+
+// GetCacheConfig returns the response cache settings
+func (agent *Agent[Output]) GetCacheConfig() cache.Config {
+	return agent.internalAgent.GetCacheConfig()
+}
+
+// BypassCacheOnce makes the next generation skip the response cache
+func (agent *Agent[Output]) BypassCacheOnce() {
+	agent.internalAgent.BypassCacheOnce()
+}
+
+// IsLastResponseFromCache reports whether the last generation was answered from the cache
+func (agent *Agent[Output]) IsLastResponseFromCache() bool {
+	return agent.internalAgent.IsLastResponseFromCache()
+}
+
+// SetBudgetConfig updates the token and cost budgets (the usage already recorded is kept)
+func (agent *Agent[Output]) SetBudgetConfig(config budget.Config) {
+	agent.internalAgent.SetBudgetConfig(config)
+}
+
+// GetBudgetConfig returns the token and cost budgets
+func (agent *Agent[Output]) GetBudgetConfig() budget.Config {
+	return agent.internalAgent.GetBudgetConfig()
+}
+
+// GetCallUsage returns the tokens and cost of the last generation
+func (agent *Agent[Output]) GetCallUsage() budget.Usage {
+	return agent.internalAgent.GetCallUsage()
+}
+
+// GetSessionUsage returns the tokens and cost of the current conversation
+func (agent *Agent[Output]) GetSessionUsage() budget.Usage {
+	return agent.internalAgent.GetSessionUsage()
+}
+
+// GetTotalUsage returns the tokens and cost since the agent was created
+func (agent *Agent[Output]) GetTotalUsage() budget.Usage {
+	return agent.internalAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent[Output]) SetUsageEstimation(enabled bool) {
+	agent.internalAgent.SetUsageEstimation(enabled)
+}
+
+// GetRemainingBudget returns the tightest remaining session or agent budget
+func (agent *Agent[Output]) GetRemainingBudget() budget.Remaining {
+	return agent.internalAgent.GetRemainingBudget()
+}
+
+// ResetBudgetSession starts a new budget session (ResetMessages does it too)
+func (agent *Agent[Output]) ResetBudgetSession() {
+	agent.internalAgent.ResetBudgetSession()
+}
+
 // GetContext returns the agent's context
 func (agent *Agent[Output]) GetContext() context.Context {
 	return agent.internalAgent.GetContext()
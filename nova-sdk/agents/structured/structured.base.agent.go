@@ -10,6 +10,7 @@ import (
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/base"
+	"github.com/snipwise/nova/nova-sdk/budget"
 )
 
 // BaseAgent wraps the shared base.Agent for structured output functionality
@@ -77,7 +78,14 @@ func (agent *BaseAgent[Output]) Kind() (kind agents.Kind) {
 	return agents.Structured
 }
 
+// GenerateStructuredData sends messages and decodes the answer into Output.
+// When the budget stops the call, it returns no data with budget.FinishReasonExceeded.
 func (agent *BaseAgent[Output]) GenerateStructuredData(messages []openai.ChatCompletionMessageParamUnion) (response *Output, finishReason string, err error) {
+	agent.BeginBudgetCall()
+	if stop, err := agent.CheckBudget(); stop {
+		return nil, budget.FinishReasonExceeded, err
+	}
+
 	// Prepare messages for the API call
 	// If KeepConversationHistory is true, add to history permanently
 	// Otherwise, create a temporary message list for this call only
@@ -98,7 +106,7 @@ func (agent *BaseAgent[Output]) GenerateStructuredData(messages []openai.ChatCom
 
 	agent.SaveLastRequest()
 
-	completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+	completion, err := agent.NewChatCompletion(paramsForCall)
 
 	if err != nil {
 		return nil, "", err
@@ -0,0 +1,57 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+type city struct {
+	Name string `json:"name"`
+}
+
+func TestBudget_StopsTheGenerations(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Response{
+		Content: `{"name":"Paris"}`,
+		Usage:   &novatest.Usage{PromptTokens: 6, CompletionTokens: 4},
+	}))
+	defer engine.Close()
+
+	agent, err := NewAgent[city](context.Background(),
+		agents.Config{Name: "cities", EngineURL: engine.URL, SystemInstructions: "You find cities"},
+		models.Config{Name: "test-model", Temperature: models.Float64(0.0)},
+		WithBudget[city](budget.Config{PerAgent: budget.Limits{MaxTokens: 15}, OnExceeded: budget.ActionStop}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	question := []messages.Message{{Role: roles.User, Content: "The capital of France?"}}
+
+	// 10 tokens: the budget is not reached before the second generation
+	for range 2 {
+		if output, _, err := agent.GenerateStructuredData(question); err != nil || output == nil || output.Name != "Paris" {
+			t.Fatalf("unexpected result %+v (error %v)", output, err)
+		}
+	}
+
+	output, finishReason, err := agent.GenerateStructuredData(question)
+	if err != nil || output != nil || finishReason != budget.FinishReasonExceeded {
+		t.Errorf("want no data with the finish reason %q, got %+v / %q / %v", budget.FinishReasonExceeded, output, finishReason, err)
+	}
+	if len(engine.ChatRequests()) != 2 {
+		t.Errorf("want 2 requests, got %d", len(engine.ChatRequests()))
+	}
+
+	agent.SetBudgetConfig(budget.Config{PerAgent: budget.Limits{MaxTokens: 15}})
+	var exceeded *budget.ExceededError
+	if _, _, err := agent.GenerateStructuredData(question); !errors.As(err, &exceeded) {
+		t.Errorf("want an *budget.ExceededError, got %v", err)
+	}
+}
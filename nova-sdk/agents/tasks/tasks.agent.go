@@ -6,6 +6,7 @@ import (
 
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
@@ -28,6 +29,15 @@ func AfterCompletion(fn func(*Agent)) TasksAgentOption {
 	}
 }
 
+// WithBudget sets per-call, per-session and per-agent token and cost budgets.
+// With budget.ActionStop the plan identifications stop without result and with the finish
+// reason budget.FinishReasonExceeded.
+func WithBudget(config budget.Config) TasksAgentOption {
+	return func(a *Agent) {
+		a.internalStructAgent.SetBudgetConfig(config)
+	}
+}
+
 // Agent represents an tasks agent that identifies tasks (plan) from user input
 // It's a specialized structured agent that uses agents.Plan as its output type
 type Agent struct {
@@ -103,6 +113,8 @@ func (agent *Agent) ResetMessages() {
 	agent.internalStructAgent.ResetMessages()
 }
 
+// IdentifyPlan sends messages and returns the identified plan.
+// When the budget stops the identification, the plan is nil (budget.FinishReasonExceeded).
 func (agent *Agent) IdentifyPlan(userMessages []messages.Message) (plan *agents.Plan, finishReason string, err error) {
 	if len(userMessages) == 0 {
 		return nil, "", errors.New("no messages provided")
@@ -184,6 +196,31 @@ func (agent *Agent) GetLastResponseJSON() (string, error) {
 	return agent.internalStructAgent.GetLastResponseJSON()
 }
 
+// SetBudgetConfig updates the token and cost budgets (the usage already recorded is kept)
+func (agent *Agent) SetBudgetConfig(config budget.Config) {
+	agent.internalStructAgent.SetBudgetConfig(config)
+}
+
+// GetBudgetConfig returns the token and cost budgets
+func (agent *Agent) GetBudgetConfig() budget.Config {
+	return agent.internalStructAgent.GetBudgetConfig()
+}
+
+// GetRemainingBudget returns the tightest remaining session or agent budget
+func (agent *Agent) GetRemainingBudget() budget.Remaining {
+	return agent.internalStructAgent.GetRemainingBudget()
+}
+
+// GetTotalUsage returns the tokens and cost since the agent was created
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.internalStructAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.internalStructAgent.SetUsageEstimation(enabled)
+}
+
 // GetContext returns the agent's context
 func (agent *Agent) GetContext() context.Context {
 	return agent.internalStructAgent.GetContext()
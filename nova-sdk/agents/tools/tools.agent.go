@@ -7,12 +7,14 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
 	"github.com/snipwise/nova/nova-sdk/mcptools"
 	"github.com/snipwise/nova/nova-sdk/messages"
 	"github.com/snipwise/nova/nova-sdk/messages/roles"
 	"github.com/snipwise/nova/nova-sdk/models"
 	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
 	"github.com/snipwise/nova/nova-sdk/toolbox/logger"
+	"slices"
 )
 
 const (
@@ -40,11 +42,12 @@ type Agent struct {
 	modelConfig    models.Config
 	internalAgent  *BaseAgent
 	log            logger.Logger
-	toolsFunctions map[string]func(args ...any) (any, error)
+	toolsFunctions map[string]ToolHandler
 
 	// Tool execution callbacks (can be set via options)
-	executeFunction            ToolCallback
-	confirmationPromptFunction ConfirmationCallback
+	executeFunction              ToolCallback
+	confirmationPromptFunction   ConfirmationCallback
+	confirmationDecisionFunction ConfirmationDecisionCallback
 
 	// Lifecycle hooks
 	beforeCompletion func(*Agent)
@@ -87,19 +90,43 @@ func WithConfirmationPromptFn(fn ConfirmationCallback) ToolsAgentOption {
 	}
 }
 
-// WithTools sets custom tools for the agent
+// WithBudget sets per-call, per-session and per-agent token and cost budgets.
+// A call covers a whole tool calls loop; with budget.ActionStop the loop ends with
+// the finish reason budget.FinishReasonExceeded and the results gathered so far.
+func WithBudget(config budget.Config) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetBudgetConfig(config)
+	}
+}
+
+// WithOpenAITools sets custom tools for the agent
 func WithOpenAITools(tools []openai.ChatCompletionToolUnionParam) ToolAgentOption {
 	return func(params *openai.ChatCompletionNewParams) {
 		params.Tools = tools
 	}
 }
 
+// WithTools sets custom tools for the agent, using the fluent builder API.
+// Their SideEffects and Cacheable settings need WithToolSettings.
 func WithTools(tools []*Tool) ToolAgentOption {
 	return func(params *openai.ChatCompletionNewParams) {
 		params.Tools = ToOpenAITools(tools)
 	}
 }
 
+// WithToolSettings applies the SideEffects and Cacheable settings of the tools given to WithTools,
+// as WithSideEffectingTools and WithCacheableTools do
+//
+//	tools.WithTools(toolsIndex),
+//	tools.WithToolSettings(toolsIndex),
+func WithToolSettings(tools []*Tool) ToolsAgentOption {
+	return func(a *Agent) {
+		for _, tool := range tools {
+			a.applyToolSettings(tool)
+		}
+	}
+}
+
 func WithMCPTools(tools []mcp.Tool) ToolAgentOption {
 	return func(params *openai.ChatCompletionNewParams) {
 		params.Tools = mcptools.ConvertMCPToolsToOpenAITools(tools)
@@ -145,13 +172,16 @@ func NewAgent(
 		modelConfig:    modelConfig,
 		internalAgent:  internalAgent,
 		log:            log,
-		toolsFunctions: make(map[string]func(args ...any) (any, error)),
+		toolsFunctions: make(map[string]ToolHandler),
 	}
+	// The BaseAgent calls the registered handlers with the context of each call
+	internalAgent.toolHandlers = agent.toolsFunctions
 
 	// Apply ToolsAgentOption configurations (hooks)
 	for _, opt := range agentOptions {
 		opt(agent)
 	}
+	internalAgent.checkToolCache()
 
 	return agent, nil
 }
@@ -190,9 +220,13 @@ func (agent *Agent) GetContextSize() int {
 	return agent.internalAgent.GetCurrentContextSize()
 }
 
-// ResetMessages clears all messages except the system instruction
+// ResetMessages clears all messages except the system instruction.
+// The approvals given for the session by the confirmation callback are forgotten too,
+// and a new audit session starts.
 func (agent *Agent) ResetMessages() {
 	agent.internalAgent.ResetMessages()
+	agent.internalAgent.confirmationPolicy.ResetSession()
+	agent.internalAgent.renewAuditSession()
 }
 
 // AddMessage adds a message to the conversation history
@@ -221,14 +255,15 @@ func (agent *Agent) DetectParallelToolCalls(
 		return nil, errors.New(errNoMessages)
 	}
 
-	// Determine which callback to use: parameter takes priority over option
-	var callback ToolCallback
+	// Determine which callback to use: parameter takes priority over option,
+	// registered tools are dispatched to their handlers
+	callback := agent.executeFunction
 	if len(toolCallback) > 0 && toolCallback[0] != nil {
 		callback = toolCallback[0]
-	} else if agent.executeFunction != nil {
-		callback = agent.executeFunction
-	} else {
-		return nil, errors.New(errNoToolCallback)
+	}
+	callback, err := agent.withRegisteredHandlers(callback)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call before completion hook if set
@@ -284,12 +319,13 @@ func (agent *Agent) DetectParallelToolCallsWithConfirmation(
 	}
 
 	// Extract callbacks by position (order matters!)
-	callback, err := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, _ := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, err := agent.withRegisteredHandlers(callback)
 	if err != nil {
 		return nil, err
 	}
 
-	confirmation, err := extractConfirmationCallback(callbacks, 1, agent.confirmationPromptFunction)
+	confirmation, err := agent.confirmationCallback(callbacks, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +339,7 @@ func (agent *Agent) DetectParallelToolCallsWithConfirmation(
 	openaiMessages := messages.ConvertToOpenAIMessages(userMessages)
 
 	// Call internal agent
-	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectParallelToolCallsWitConfirmation(
+	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectParallelToolCallsWithDecision(
 		openaiMessages,
 		callback,
 		confirmation,
@@ -347,14 +383,15 @@ func (agent *Agent) DetectToolCallsLoop(
 		return nil, errors.New(errNoMessages)
 	}
 
-	// Determine which callback to use: parameter takes priority over option
-	var callback ToolCallback
+	// Determine which callback to use: parameter takes priority over option,
+	// registered tools are dispatched to their handlers
+	callback := agent.executeFunction
 	if len(toolCallback) > 0 && toolCallback[0] != nil {
 		callback = toolCallback[0]
-	} else if agent.executeFunction != nil {
-		callback = agent.executeFunction
-	} else {
-		return nil, errors.New(errNoToolCallback)
+	}
+	callback, err := agent.withRegisteredHandlers(callback)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call before completion hook if set
@@ -412,12 +449,13 @@ func (agent *Agent) DetectToolCallsLoopWithConfirmation(
 	}
 
 	// Extract callbacks by position (order matters!)
-	callback, err := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, _ := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, err := agent.withRegisteredHandlers(callback)
 	if err != nil {
 		return nil, err
 	}
 
-	confirmation, err := extractConfirmationCallback(callbacks, 1, agent.confirmationPromptFunction)
+	confirmation, err := agent.confirmationCallback(callbacks, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -431,7 +469,7 @@ func (agent *Agent) DetectToolCallsLoopWithConfirmation(
 	openaiMessages := messages.ConvertToOpenAIMessages(userMessages)
 
 	// Call internal agent
-	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectToolCallsLoopWithConfirmation(
+	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectToolCallsLoopWithDecision(
 		openaiMessages,
 		callback,
 		confirmation,
@@ -481,14 +519,15 @@ func (agent *Agent) DetectToolCallsLoopStream(
 		return nil, errors.New("streamCallback is required for DetectToolCallsLoopStream")
 	}
 
-	// Determine which callback to use: parameter takes priority over option
-	var callback ToolCallback
+	// Determine which callback to use: parameter takes priority over option,
+	// registered tools are dispatched to their handlers
+	callback := agent.executeFunction
 	if len(toolCallback) > 0 && toolCallback[0] != nil {
 		callback = toolCallback[0]
-	} else if agent.executeFunction != nil {
-		callback = agent.executeFunction
-	} else {
-		return nil, errors.New(errNoToolCallback)
+	}
+	callback, err := agent.withRegisteredHandlers(callback)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call before completion hook if set
@@ -553,12 +592,13 @@ func (agent *Agent) DetectToolCallsLoopWithConfirmationStream(
 	}
 
 	// Extract callbacks by position (order matters!)
-	callback, err := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, _ := extractToolCallback(callbacks, 0, agent.executeFunction)
+	callback, err := agent.withRegisteredHandlers(callback)
 	if err != nil {
 		return nil, err
 	}
 
-	confirmation, err := extractConfirmationCallback(callbacks, 1, agent.confirmationPromptFunction)
+	confirmation, err := agent.confirmationCallback(callbacks, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -572,7 +612,7 @@ func (agent *Agent) DetectToolCallsLoopWithConfirmationStream(
 	openaiMessages := messages.ConvertToOpenAIMessages(userMessages)
 
 	// Call internal agent with streaming
-	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectToolCallsLoopWithConfirmationStream(
+	finishReason, results, lastAssistantMessage, err := agent.internalAgent.DetectToolCallsLoopWithDecisionStream(
 		openaiMessages,
 		callback,
 		confirmation,
@@ -676,6 +716,79 @@ func (agent *Agent) SetContext(ctx context.Context) {
 }
 
 // GetTools returns the tools configured for this agent
+// (including the tools set with SetOpenAITools that are not applied yet)
 func (agent *Agent) GetTools() []openai.ChatCompletionToolUnionParam {
+	agent.internalAgent.stateMutex.Lock()
+	defer agent.internalAgent.stateMutex.Unlock()
+	if agent.internalAgent.pendingTools != nil {
+		return *agent.internalAgent.pendingTools
+	}
 	return agent.internalAgent.Agent.ChatCompletionParams.Tools
 }
+
+// SetOpenAITools replaces the tools sent to the model at runtime (the typed tools registered
+// with RegisterTools are kept). It can be called during a tool calls loop: the change applies
+// from the next tool calls detection.
+func (agent *Agent) SetOpenAITools(tools []openai.ChatCompletionToolUnionParam) {
+	updated := slices.Clone(tools)
+	for _, registeredTool := range agent.internalAgent.registeredTools {
+		name := registeredTool.Tool.GetName()
+		if !slices.ContainsFunc(updated, func(tool openai.ChatCompletionToolUnionParam) bool {
+			function := tool.GetFunction()
+			return function != nil && function.Name == name
+		}) {
+			updated = append(updated, registeredTool.Tool.ToOpenAI())
+		}
+	}
+	agent.internalAgent.stateMutex.Lock()
+	defer agent.internalAgent.stateMutex.Unlock()
+	agent.internalAgent.pendingTools = &updated
+}
+
+// SetMCPTools replaces the tools sent to the model with MCP tools at runtime, e.g. from the
+// tools changed handler of an MCP client:
+//
+//	mcpClient.OnToolsChanged(agent.SetMCPTools)
+func (agent *Agent) SetMCPTools(tools []mcp.Tool) {
+	agent.SetOpenAITools(mcptools.ConvertMCPToolsToOpenAITools(tools))
+}
+
+// SetBudgetConfig updates the token and cost budgets (the usage already recorded is kept)
+func (agent *Agent) SetBudgetConfig(config budget.Config) {
+	agent.internalAgent.SetBudgetConfig(config)
+}
+
+// GetBudgetConfig returns the token and cost budgets
+func (agent *Agent) GetBudgetConfig() budget.Config {
+	return agent.internalAgent.GetBudgetConfig()
+}
+
+// GetCallUsage returns the tokens and cost of the last call
+func (agent *Agent) GetCallUsage() budget.Usage {
+	return agent.internalAgent.GetCallUsage()
+}
+
+// GetSessionUsage returns the tokens and cost of the current conversation
+func (agent *Agent) GetSessionUsage() budget.Usage {
+	return agent.internalAgent.GetSessionUsage()
+}
+
+// GetTotalUsage returns the tokens and cost since the agent was created
+func (agent *Agent) GetTotalUsage() budget.Usage {
+	return agent.internalAgent.GetTotalUsage()
+}
+
+// SetUsageEstimation estimates the usage the engine doesn't report, even without budget
+func (agent *Agent) SetUsageEstimation(enabled bool) {
+	agent.internalAgent.SetUsageEstimation(enabled)
+}
+
+// GetRemainingBudget returns the tightest remaining session or agent budget
+func (agent *Agent) GetRemainingBudget() budget.Remaining {
+	return agent.internalAgent.GetRemainingBudget()
+}
+
+// ResetBudgetSession starts a new budget session (ResetMessages does it too)
+func (agent *Agent) ResetBudgetSession() {
+	agent.internalAgent.ResetBudgetSession()
+}
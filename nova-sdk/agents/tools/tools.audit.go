@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"github.com/snipwise/nova/nova-sdk/audit"
+)
+
+// auditState holds the audit settings and the current session of the agent
+type auditState struct {
+	config  audit.Config
+	session string
+	// fixedSession: the session was set with SetAuditSession and is kept by ResetMessages
+	fixedSession bool
+}
+
+// WithAudit emits an audit event for every tool call of the agent (executed, failed or denied)
+func WithAudit(config audit.Config) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetAudit(config)
+	}
+}
+
+// SetAudit sets the audit settings of the tool calls
+func (agent *BaseAgent) SetAudit(config audit.Config) {
+	agent.audit.config = config.WithSource(audit.SourceToolsAgent)
+}
+
+// SetAuditSession sets the session of the audit events. Without it, a random session
+// is used and renewed by ResetMessages; a session carried by the context of the agent
+// (audit.WithSession) takes precedence.
+func (agent *BaseAgent) SetAuditSession(session string) {
+	agent.audit.session = session
+	agent.audit.fixedSession = session != ""
+}
+
+// GetAuditSession returns the session of the audit events
+func (agent *BaseAgent) GetAuditSession() string {
+	if session := audit.SessionFromContext(agent.Ctx); session != "" {
+		return session
+	}
+	return agent.audit.session
+}
+
+// renewAuditSession starts a new audit session, unless it was set with SetAuditSession
+func (agent *BaseAgent) renewAuditSession() {
+	if !agent.audit.fixedSession {
+		agent.audit.session = audit.NewSessionID()
+	}
+}
+
+// auditToolCall emits the audit event of a tool call
+func (agent *BaseAgent) auditToolCall(event audit.Event) {
+	if !agent.audit.config.Enabled() {
+		return
+	}
+	event.Agent = agent.Config.Name
+	event.Session = agent.GetAuditSession()
+	agent.audit.config.Emit(event)
+}
+
+// auditConfirmation returns the audit label of a confirmation response
+func auditConfirmation(response ConfirmationResponse) string {
+	switch response {
+	case Confirmed:
+		return audit.ConfirmationConfirmed
+	case ConfirmedForSession:
+		return audit.ConfirmationForSession
+	case ConfirmedAlways:
+		return audit.ConfirmationAlways
+	case EditedAndConfirmed:
+		return audit.ConfirmationEdited
+	case Denied:
+		return audit.ConfirmationDenied
+	case Quit:
+		return audit.ConfirmationQuit
+	default:
+		return ""
+	}
+}
+
+// SetAudit sets the audit settings of the tool calls
+func (agent *Agent) SetAudit(config audit.Config) {
+	agent.internalAgent.SetAudit(config)
+}
+
+// SetAuditSession sets the session of the audit events (see BaseAgent.SetAuditSession)
+func (agent *Agent) SetAuditSession(session string) {
+	agent.internalAgent.SetAuditSession(session)
+}
+
+// GetAuditSession returns the session of the audit events
+func (agent *Agent) GetAuditSession() string {
+	return agent.internalAgent.GetAuditSession()
+}
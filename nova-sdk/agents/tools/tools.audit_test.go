@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+// auditRecorder is a callback sink keeping the events
+type auditRecorder struct {
+	mutex  sync.Mutex
+	events []audit.Event
+}
+
+func (recorder *auditRecorder) config() audit.Config {
+	return audit.Config{Sinks: []audit.Sink{audit.SinkFunc(func(event audit.Event) error {
+		recorder.mutex.Lock()
+		defer recorder.mutex.Unlock()
+		recorder.events = append(recorder.events, event)
+		return nil
+	})}}
+}
+
+func TestAudit_ToolCallsLoop(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("read_file", `{"path":"notes.txt"}`),
+		novatest.Call("read_file", `{"path":"missing.txt"}`),
+	))
+
+	recorder := &auditRecorder{}
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("read_file").AddParameter("path", "string", "", true)},
+		withHistory,
+		WithAudit(recorder.config()),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			if arguments == `{"path":"missing.txt"}` {
+				return "", errors.New("file not found")
+			}
+			return "hello", nil
+		}),
+	)
+
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "read"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", recorder.events)
+	}
+	success, failure := recorder.events[0], recorder.events[1]
+	if success.Source != audit.SourceToolsAgent || success.Agent != "tools" || success.Session == "" ||
+		success.Tool != "read_file" || success.CallID == "" || success.Status != audit.StatusSuccess ||
+		success.Result != "hello" || success.Confirmation != audit.ConfirmationNotRequired {
+		t.Errorf("unexpected success event %+v", success)
+	}
+	if failure.Status != audit.StatusError || failure.Error != "file not found" {
+		t.Errorf("unexpected failure event %+v", failure)
+	}
+
+	session := agent.GetAuditSession()
+	agent.ResetMessages()
+	if agent.GetAuditSession() == session {
+		t.Error("ResetMessages should start a new audit session")
+	}
+	agent.SetAuditSession("user-42")
+	agent.ResetMessages()
+	if agent.GetAuditSession() != "user-42" {
+		t.Error("a session set explicitly should be kept")
+	}
+}
+
+func TestAudit_Confirmations(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("read_file", `{"path":"notes.txt"}`),
+		novatest.Call("shell", `{"command":"rm -rf /"}`),
+		novatest.Call("shell", `{"command":"ls"}`),
+	))
+
+	recorder := &auditRecorder{}
+	toolsIndex := []*Tool{
+		NewTool("read_file").AddParameter("path", "string", "", true),
+		NewTool("shell").AddParameter("command", "string", "", true),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		withHistory,
+		WithAudit(recorder.config()),
+		WithConfirmationPolicy(NewConfirmationPolicy(AllowTools("read_file"), DenyArguments(`rm\s+-rf`))),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "ok", nil }),
+		WithConfirmationPromptFn(func(functionName string, arguments string) ConfirmationResponse { return Denied }),
+	)
+
+	if _, err := agent.DetectToolCallsLoopWithConfirmation([]messages.Message{{Role: roles.User, Content: "go"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct{ confirmation, status string }{
+		{audit.ConfirmationPolicyAllowed, audit.StatusSuccess},
+		{audit.ConfirmationPolicyDenied, audit.StatusDenied},
+		{audit.ConfirmationDenied, audit.StatusDenied},
+	}
+	if len(recorder.events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), recorder.events)
+	}
+	for index, event := range recorder.events {
+		if event.Confirmation != expected[index].confirmation || event.Status != expected[index].status {
+			t.Errorf("event %d: unexpected %s/%s", index, event.Confirmation, event.Status)
+		}
+	}
+}
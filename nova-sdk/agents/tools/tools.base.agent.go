@@ -3,10 +3,13 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/base"
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/budget"
 )
 
 const (
@@ -21,7 +24,7 @@ const (
 // GOAL: be able to check state of tool calls across multiple invocations
 type LastToolCallsState struct {
 	// If a tool call is awaiting user confirmation
-	// Possible values: `Confirmed`, `Denied`, `Quit`
+	// Possible values: `Confirmed`, `Denied`, `Quit`, `ConfirmedForSession`, `ConfirmedAlways`, `EditedAndConfirmed`
 	// Denied: do not execute the tool call, but continue the flow
 	// Quit: stop the entire agent execution (exit loop)
 	Confirmation    ConfirmationResponse
@@ -33,6 +36,48 @@ type BaseAgent struct {
 	*base.Agent
 	// State of the last tool calls processed
 	lastState LastToolCallsState
+
+	// Handling of the failing tool calls
+	retryPolicy             retryPolicy
+	skipArgumentsValidation bool
+
+	// Handlers of the registered tools (Agent.toolsFunctions), called with the context of each call
+	toolHandlers map[string]ToolHandler
+	// Registered tools, in registration order
+	registeredTools []*RegisteredTool
+	// Tools set at runtime, sent from the next detection (see SetOpenAITools)
+	pendingTools *[]openai.ChatCompletionToolUnionParam
+
+	// Guards of the tool calls loops
+	guards     loopGuards
+	guardState guardState
+
+	// Concurrent execution of the parallel tool calls
+	concurrency concurrencyPolicy
+
+	// Rules and approvals applied before the confirmation callback
+	confirmationPolicy *ConfirmationPolicy
+
+	// Audit events of the tool calls
+	audit auditState
+
+	// Selection of the tools sent with each request (nil: all the tools are sent)
+	toolSelection *toolSelection
+
+	// Tool calls emulated in the prompt (nil: native function calling)
+	promptToolCalling *PromptToolCalling
+
+	// Memoized results of the cacheable tools
+	toolCache toolCache
+
+	// Traces of the sub-agents called by the tools during the current detection
+	nestedCalls []NestedCall
+
+	// Images returned by the tools, sent to vision models after the tool results
+	toolImages toolImages
+
+	// stateMutex protects lastState, retryPolicy.failures, nestedCalls and toolImages when tool calls run concurrently
+	stateMutex sync.Mutex
 }
 
 type AgentOption func(*BaseAgent)
@@ -52,7 +97,11 @@ func NewBaseAgent(
 	}
 
 	toolsAgent = &BaseAgent{
-		Agent: baseAgent,
+		Agent:       baseAgent,
+		retryPolicy: retryPolicy{defaultRetries: DefaultToolRetries},
+
+		confirmationPolicy: NewConfirmationPolicy(),
+		audit:              auditState{session: audit.NewSessionID()},
 	}
 
 	// Apply tools-specific options
@@ -78,18 +127,26 @@ func (agent *BaseAgent) DetectParallelToolCalls(messages []openai.ChatCompletion
 	// Prepare messages: combine system message with user messages
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+	workingMessages, stop, err := agent.checkBudget(workingMessages)
+	if stop {
+		return budget.FinishReasonExceeded, results, lastAssistantMessage, err
+	}
+
 	agent.Log.Info("⏳ [DetectParallelToolCalls] Making function call request...")
 
 	// Create params for this call
 	paramsForCall := agent.ChatCompletionParams
 	paramsForCall.Messages = workingMessages
+	paramsForCall.Tools = agent.selectTools(workingMessages)
 
 	agent.SaveLastRequest()
 
-	completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+	completion, err := agent.newToolCallsCompletion(paramsForCall)
 	if err != nil {
 		agent.Log.Error(errFunctionCallRequest, err)
-		return "", results, "", err
+		reason, err := agent.requestFailure(err)
+		return reason, results, "", err
 	}
 
 	agent.SaveLastResponse(completion)
@@ -135,6 +192,15 @@ func (agent *BaseAgent) DetectParallelToolCallsWitConfirmation(
 	messages []openai.ChatCompletionMessageParamUnion,
 	toolCallBack func(functionName string, arguments string) (string, error),
 	confirmationCallBack func(functionName string, arguments string) ConfirmationResponse) (string, []string, string, error) {
+	return agent.DetectParallelToolCallsWithDecision(messages, toolCallBack, decisionOf(confirmationCallBack))
+}
+
+// DetectParallelToolCallsWithDecision is DetectParallelToolCallsWitConfirmation with a
+// confirmation callback able to edit the arguments of the calls
+func (agent *BaseAgent) DetectParallelToolCallsWithDecision(
+	messages []openai.ChatCompletionMessageParamUnion,
+	toolCallBack func(functionName string, arguments string) (string, error),
+	confirmationCallBack ConfirmationDecisionCallback) (string, []string, string, error) {
 
 	results := []string{}
 	lastAssistantMessage := ""
@@ -143,18 +209,26 @@ func (agent *BaseAgent) DetectParallelToolCallsWitConfirmation(
 	// Prepare messages: combine system message with user messages
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+	workingMessages, stop, err := agent.checkBudget(workingMessages)
+	if stop {
+		return budget.FinishReasonExceeded, results, lastAssistantMessage, err
+	}
+
 	agent.Log.Info("⏳ [DetectParallelToolCallsWitConfirmation] Making function call request...")
 
 	// Create params for this call
 	paramsForCall := agent.ChatCompletionParams
 	paramsForCall.Messages = workingMessages
+	paramsForCall.Tools = agent.selectTools(workingMessages)
 
 	agent.SaveLastRequest()
 
-	completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+	completion, err := agent.newToolCallsCompletion(paramsForCall)
 	if err != nil {
 		agent.Log.Error(errFunctionCallRequest, err)
-		return "", results, "", err
+		reason, err := agent.requestFailure(err)
+		return reason, results, "", err
 	}
 
 	agent.SaveLastResponse(completion)
@@ -206,19 +280,34 @@ func (agent *BaseAgent) DetectToolCallsLoop(messages []openai.ChatCompletionMess
 	// Build on top of existing messages (which include system message)
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+
 	for !stopped {
+		var stop bool
+		var budgetErr error
+		if workingMessages, stop, budgetErr = agent.checkBudget(workingMessages); stop {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return budget.FinishReasonExceeded, results, lastAssistantMessage, budgetErr
+		}
+		if guardReason := agent.checkIteration(); guardReason != "" {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return guardReason, results, lastAssistantMessage, nil
+		}
+
 		agent.Log.Info("⏳ [DetectToolCallsLoop] Making function call request...")
 
 		// Create params for this call with current working messages
 		paramsForCall := agent.ChatCompletionParams
 		paramsForCall.Messages = workingMessages
+		paramsForCall.Tools = agent.selectTools(workingMessages)
 
 		agent.SaveLastRequest()
 
-		completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+		completion, err := agent.newToolCallsCompletion(paramsForCall)
 		if err != nil {
 			agent.Log.Error(errFunctionCallRequest, err)
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		agent.SaveLastResponse(completion)
@@ -258,6 +347,15 @@ func (agent *BaseAgent) DetectToolCallsLoopWithConfirmation(
 	messages []openai.ChatCompletionMessageParamUnion,
 	toolCallBack func(functionName string, arguments string) (string, error),
 	confirmationCallBack func(functionName string, arguments string) ConfirmationResponse) (string, []string, string, error) {
+	return agent.DetectToolCallsLoopWithDecision(messages, toolCallBack, decisionOf(confirmationCallBack))
+}
+
+// DetectToolCallsLoopWithDecision is DetectToolCallsLoopWithConfirmation with a
+// confirmation callback able to edit the arguments of the calls
+func (agent *BaseAgent) DetectToolCallsLoopWithDecision(
+	messages []openai.ChatCompletionMessageParamUnion,
+	toolCallBack func(functionName string, arguments string) (string, error),
+	confirmationCallBack ConfirmationDecisionCallback) (string, []string, string, error) {
 
 	stopped := false
 	results := []string{}
@@ -267,19 +365,34 @@ func (agent *BaseAgent) DetectToolCallsLoopWithConfirmation(
 	// Prepare messages: combine system message with user messages
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+
 	for !stopped {
+		var stop bool
+		var budgetErr error
+		if workingMessages, stop, budgetErr = agent.checkBudget(workingMessages); stop {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return budget.FinishReasonExceeded, results, lastAssistantMessage, budgetErr
+		}
+		if guardReason := agent.checkIteration(); guardReason != "" {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return guardReason, results, lastAssistantMessage, nil
+		}
+
 		agent.Log.Info("⏳ [LOOP][DetectToolCallsLoopWithConfirmation] Making function call request...")
 
 		// Create params for this call with current working messages
 		paramsForCall := agent.ChatCompletionParams
 		paramsForCall.Messages = workingMessages
+		paramsForCall.Tools = agent.selectTools(workingMessages)
 
 		agent.SaveLastRequest()
 
-		completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+		completion, err := agent.newToolCallsCompletion(paramsForCall)
 		if err != nil {
 			agent.Log.Error(errFunctionCallRequest, err)
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		agent.SaveLastResponse(completion)
@@ -322,24 +435,40 @@ func (agent *BaseAgent) DetectToolCallsLoopStream(messages []openai.ChatCompleti
 	// Prepare messages: combine system message with user messages
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+
 	for !stopped {
+		var stop bool
+		var budgetErr error
+		if workingMessages, stop, budgetErr = agent.checkBudget(workingMessages); stop {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return budget.FinishReasonExceeded, results, lastAssistantMessage, budgetErr
+		}
+		if guardReason := agent.checkIteration(); guardReason != "" {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return guardReason, results, lastAssistantMessage, nil
+		}
+
 		agent.Log.Info("⏳ [LOOP][DetectToolCallsLoopStream] Making function call request...")
 
 		// Create params for this call with current working messages
 		paramsForCall := agent.ChatCompletionParams
 		paramsForCall.Messages = workingMessages
+		paramsForCall.Tools = agent.selectTools(workingMessages)
 
 		agent.SaveLastRequest()
 
 		response, err := agent.collectStreamResponse(paramsForCall, streamCallback)
 		if err != nil {
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		// Make a non-streaming call to get tool calls
-		completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+		completion, err := agent.newToolCallsCompletion(paramsForCall)
 		if err != nil {
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		finishReason = completion.Choices[0].FinishReason
@@ -373,6 +502,16 @@ func (agent *BaseAgent) DetectToolCallsLoopWithConfirmationStream(
 	toolCallback func(functionName string, arguments string) (string, error),
 	confirmationCallBack func(functionName string, arguments string) ConfirmationResponse,
 	streamCallback func(content string) error) (string, []string, string, error) {
+	return agent.DetectToolCallsLoopWithDecisionStream(messages, toolCallback, decisionOf(confirmationCallBack), streamCallback)
+}
+
+// DetectToolCallsLoopWithDecisionStream is DetectToolCallsLoopWithConfirmationStream with a
+// confirmation callback able to edit the arguments of the calls
+func (agent *BaseAgent) DetectToolCallsLoopWithDecisionStream(
+	messages []openai.ChatCompletionMessageParamUnion,
+	toolCallback func(functionName string, arguments string) (string, error),
+	confirmationCallBack ConfirmationDecisionCallback,
+	streamCallback func(content string) error) (string, []string, string, error) {
 
 	stopped := false
 	results := []string{}
@@ -382,24 +521,40 @@ func (agent *BaseAgent) DetectToolCallsLoopWithConfirmationStream(
 	// Prepare messages: combine system message with user messages
 	workingMessages := append(agent.ChatCompletionParams.Messages, messages...)
 
+	agent.beginToolCallsDetection()
+
 	for !stopped {
+		var stop bool
+		var budgetErr error
+		if workingMessages, stop, budgetErr = agent.checkBudget(workingMessages); stop {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return budget.FinishReasonExceeded, results, lastAssistantMessage, budgetErr
+		}
+		if guardReason := agent.checkIteration(); guardReason != "" {
+			agent.saveHistoryIfNeeded(workingMessages)
+			return guardReason, results, lastAssistantMessage, nil
+		}
+
 		agent.Log.Info("⏳ [LOOP][DetectToolCallsLoopWithConfirmationStream] Making function call request...")
 
 		// Create params for this call with current working messages
 		paramsForCall := agent.ChatCompletionParams
 		paramsForCall.Messages = workingMessages
+		paramsForCall.Tools = agent.selectTools(workingMessages)
 
 		agent.SaveLastRequest()
 
 		response, err := agent.collectStreamResponse(paramsForCall, streamCallback)
 		if err != nil {
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		// Make a non-streaming call to get tool calls
-		completion, err := agent.OpenaiClient.Chat.Completions.New(agent.Ctx, paramsForCall)
+		completion, err := agent.newToolCallsCompletion(paramsForCall)
 		if err != nil {
-			return "", results, "", err
+			reason, err := agent.requestFailure(err)
+			return reason, results, "", err
 		}
 
 		finishReason = completion.Choices[0].FinishReason
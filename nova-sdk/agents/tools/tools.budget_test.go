@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func TestBudget_StopsRunawayToolCallsLoop(t *testing.T) {
+	// The engine never stops calling the tool
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Response{
+		ToolCalls: []novatest.ToolCall{{Name: "ping", Arguments: `{}`}},
+		Usage:     &novatest.Usage{PromptTokens: 40, CompletionTokens: 10},
+	}))
+	defer engine.Close()
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("ping").SetDescription("ping")},
+		WithBudget(budget.Config{PerCall: budget.Limits{MaxTokens: 120}, OnExceeded: budget.ActionStop}),
+	)
+
+	calls := 0
+	result, err := agent.DetectToolCallsLoop(
+		[]messages.Message{{Role: roles.User, Content: "ping forever"}},
+		func(functionName string, arguments string) (string, error) {
+			calls++
+			return `{"pong":true}`, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinishReason != budget.FinishReasonExceeded {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+	// 50 tokens per request: the third request reaches 150 >= 120
+	if len(engine.ChatRequests()) != 3 || calls != 3 {
+		t.Errorf("want 3 requests and 3 tool calls, got %d and %d", len(engine.ChatRequests()), calls)
+	}
+	if agent.GetCallUsage().TotalTokens() != 150 {
+		t.Errorf("unexpected call usage %+v", agent.GetCallUsage())
+	}
+}
@@ -56,3 +56,30 @@ func extractConfirmationCallback(callbacks []any, position int, fallback Confirm
 	}
 	return nil, errors.New("no confirmation callback provided: either pass ConfirmationCallback parameter or set it via WithConfirmationPromptFn option")
 }
+
+// extractConfirmationDecisionCallback resolves a ConfirmationDecisionCallback from a variadic
+// callbacks slice at the given position. A positional ConfirmationCallback is adapted; without
+// positional callback, fallbackDecision is preferred to fallback.
+func extractConfirmationDecisionCallback(
+	callbacks []any,
+	position int,
+	fallbackDecision ConfirmationDecisionCallback,
+	fallback ConfirmationCallback,
+) (ConfirmationDecisionCallback, error) {
+	if position < len(callbacks) && callbacks[position] != nil {
+		switch dc := callbacks[position].(type) {
+		case ConfirmationDecisionCallback:
+			return dc, nil
+		case func(string, string) ConfirmationDecision:
+			return dc, nil
+		}
+	} else if fallbackDecision != nil {
+		return fallbackDecision, nil
+	}
+
+	confirmation, err := extractConfirmationCallback(callbacks, position, fallback)
+	if err != nil {
+		return nil, err
+	}
+	return decisionOf(confirmation), nil
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/snipwise/nova/nova-sdk/audit"
+)
+
+// concurrencyPolicy controls the concurrent execution of the parallel tool calls
+type concurrencyPolicy struct {
+	maxConcurrency int
+	// Tools whose calls are executed one at a time, in the call order
+	serialTools map[string]bool
+}
+
+// enabled reports whether calls should run concurrently
+func (policy concurrencyPolicy) enabled(calls int) bool {
+	return policy.maxConcurrency > 1 && calls > 1
+}
+
+// toolCallExecution is the outcome of one tool call executed concurrently
+type toolCallExecution struct {
+	result ToolExecutionResult
+	err    error
+}
+
+// WithConcurrentToolCalls executes the tool calls of a same model response concurrently,
+// with at most maxConcurrency calls at a time (0 or 1: sequential execution, the default).
+// The results are still added to the conversation in the call order.
+// The calls are executed sequentially when a confirmation callback is used, and the calls of
+// side-effecting tools (see WithSideEffectingTools and Tool.SetSideEffects) are always serialized.
+// The tool callback and the registered handlers must be safe for concurrent use.
+func WithConcurrentToolCalls(maxConcurrency int) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.concurrency.maxConcurrency = maxConcurrency
+	}
+}
+
+// WithSideEffectingTools marks tools as side-effecting: their calls are never executed concurrently
+func WithSideEffectingTools(toolNames ...string) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetSideEffectingTools(toolNames...)
+	}
+}
+
+// SetMaxToolCallsConcurrency updates the maximum number of tool calls executed at a time
+func (agent *BaseAgent) SetMaxToolCallsConcurrency(maxConcurrency int) {
+	agent.concurrency.maxConcurrency = maxConcurrency
+}
+
+// SetSideEffectingTools marks tools as side-effecting: their calls are never executed concurrently
+func (agent *BaseAgent) SetSideEffectingTools(toolNames ...string) {
+	if agent.concurrency.serialTools == nil {
+		agent.concurrency.serialTools = make(map[string]bool)
+	}
+	for _, name := range toolNames {
+		agent.concurrency.serialTools[name] = true
+	}
+}
+
+// IsSideEffectingTool reports whether the calls of a tool are serialized
+func (agent *BaseAgent) IsSideEffectingTool(toolName string) bool {
+	return agent.concurrency.serialTools[toolName]
+}
+
+// executeToolCallsConcurrently executes the calls (except the skipped ones) with a pool of
+// maxConcurrency workers.
+// The calls of side-effecting tools run one after the other, in the call order,
+// each one taking a slot of the pool.
+func (agent *BaseAgent) executeToolCallsConcurrently(
+	detectedToolCalls []openai.ChatCompletionMessageToolCallUnion,
+	skipped []*ToolExecutionResult,
+	toolCallBack func(string, string) (string, error),
+) []toolCallExecution {
+	executions := make([]toolCallExecution, len(detectedToolCalls))
+	execute := func(index int) {
+		toolCall := detectedToolCalls[index]
+		result, err := agent.executeToolCall(toolCall.Function.Name, toolCall.Function.Arguments, toolCall.ID, toolCallBack, audit.ConfirmationNotRequired)
+		executions[index] = toolCallExecution{result: result, err: err}
+	}
+
+	var serial []int
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, agent.concurrency.maxConcurrency)
+
+	agent.Log.Info("⚡ Executing %d tool calls concurrently (max %d at a time)", len(detectedToolCalls), agent.concurrency.maxConcurrency)
+
+	for index, toolCall := range detectedToolCalls {
+		if skipped[index] != nil {
+			continue
+		}
+		if agent.IsSideEffectingTool(toolCall.Function.Name) {
+			serial = append(serial, index)
+			continue
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			execute(index)
+		}(index)
+	}
+
+	if len(serial) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, index := range serial {
+				slots <- struct{}{}
+				execute(index)
+				<-slots
+			}
+		}()
+	}
+
+	wg.Wait()
+	return executions
+}
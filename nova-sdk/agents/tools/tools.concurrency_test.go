@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+// inFlight tracks the maximum number of concurrent executions per tool
+type inFlight struct {
+	mutex   sync.Mutex
+	current map[string]int
+	maximum map[string]int
+}
+
+func (tracker *inFlight) enter(name string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.current[name]++
+	tracker.maximum[name] = max(tracker.maximum[name], tracker.current[name])
+}
+
+func (tracker *inFlight) leave(name string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.current[name]--
+}
+
+func TestConcurrentToolCalls_KeepCallOrder(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("fetch", `{"id":1}`),
+		novatest.Call("fetch", `{"id":2}`),
+		novatest.Call("fetch", `{"id":3}`),
+		novatest.Call("fetch", `{"id":4}`),
+	))
+
+	var running, maxRunning atomic.Int32
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("fetch")},
+		WithConcurrentToolCalls(2),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			// The first call is the slowest: it must still come first
+			if arguments == `{"id":1}` {
+				time.Sleep(60 * time.Millisecond)
+			} else {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return arguments, nil
+		}),
+	)
+
+	result, err := agent.DetectParallelToolCalls([]messages.Message{{Role: roles.User, Content: "fetch 1 to 4"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`}
+	if len(result.Results) != len(want) {
+		t.Fatalf("unexpected results %v", result.Results)
+	}
+	for index := range want {
+		if result.Results[index] != want[index] {
+			t.Errorf("results should keep the call order, got %v", result.Results)
+			break
+		}
+	}
+	if maxRunning.Load() != 2 {
+		t.Errorf("want 2 calls at a time, got %d", maxRunning.Load())
+	}
+}
+
+func TestConcurrentToolCalls_SerializeSideEffects(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("write", `{"n":1}`),
+		novatest.Call("fetch", `{"n":2}`),
+		novatest.Call("write", `{"n":3}`),
+		novatest.Call("fetch", `{"n":4}`),
+		novatest.Call("broken", `{}`),
+	))
+
+	tracker := &inFlight{current: map[string]int{}, maximum: map[string]int{}}
+	var writes []string
+	var writesMutex sync.Mutex
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("fetch"), NewTool("write"), NewTool("broken")},
+		WithConcurrentToolCalls(8),
+		WithSideEffectingTools("write"),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			tracker.enter(functionName)
+			defer tracker.leave(functionName)
+			time.Sleep(20 * time.Millisecond)
+			switch functionName {
+			case "broken":
+				return "", errors.New("boom")
+			case "write":
+				writesMutex.Lock()
+				writes = append(writes, arguments)
+				writesMutex.Unlock()
+			}
+			return "ok " + arguments, nil
+		}),
+	)
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "go"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracker.maximum["write"] != 1 || tracker.maximum["fetch"] != 2 {
+		t.Errorf("unexpected concurrency %v", tracker.maximum)
+	}
+	if len(writes) != 2 || writes[0] != `{"n":1}` || writes[1] != `{"n":3}` {
+		t.Errorf("side-effecting calls should run in the call order, got %v", writes)
+	}
+	// The failing call is reported to the model, the other results are kept
+	if result.FinishReason != "stop" || len(result.Results) != 5 || result.Results[3] != `ok {"n":4}` {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestConcurrentToolCalls_SideEffectsTakeASlot(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("write", `{"n":1}`),
+		novatest.Call("write", `{"n":2}`),
+		novatest.Call("fetch", `{"n":3}`),
+		novatest.Call("fetch", `{"n":4}`),
+		novatest.Call("fetch", `{"n":5}`),
+	))
+
+	var running, maxRunning atomic.Int32
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("fetch"), NewTool("write")},
+		WithConcurrentToolCalls(2),
+		WithSideEffectingTools("write"),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return "ok", nil
+		}),
+	)
+
+	if _, err := agent.DetectParallelToolCalls([]messages.Message{{Role: roles.User, Content: "go"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if maxRunning.Load() != 2 {
+		t.Errorf("the side-effecting calls should count in the 2 calls at a time, got %d", maxRunning.Load())
+	}
+}
+
+func TestConcurrentToolCalls_ConfirmationIsSequential(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("fetch", `{"n":1}`), novatest.Call("fetch", `{"n":2}`)))
+
+	tracker := &inFlight{current: map[string]int{}, maximum: map[string]int{}}
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("fetch")},
+		WithConcurrentToolCalls(4),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			tracker.enter(functionName)
+			defer tracker.leave(functionName)
+			time.Sleep(20 * time.Millisecond)
+			return "ok", nil
+		}),
+		WithConfirmationPromptFn(func(functionName string, arguments string) ConfirmationResponse {
+			return Confirmed
+		}),
+	)
+
+	if _, err := agent.DetectParallelToolCallsWithConfirmation([]messages.Message{{Role: roles.User, Content: "go"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracker.maximum["fetch"] != 1 {
+		t.Errorf("confirmed calls should run one at a time, got %d", tracker.maximum["fetch"])
+	}
+}
+
+func TestRegisterTools_SideEffects(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+
+	save := Register("save", "Save a note", func(ctx context.Context, input struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return "saved", nil
+	})
+	save.Tool.SetSideEffects(true)
+
+	agent := newToolsAgent(t, engine.URL, nil, WithRegisteredTools(save))
+	if !agent.internalAgent.IsSideEffectingTool("save") {
+		t.Error("registered side-effecting tools should be serialized")
+	}
+}
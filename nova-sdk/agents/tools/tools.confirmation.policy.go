@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// FinishReasonPolicyDenied is the ExecFinishReason of a call denied by the confirmation policy
+const FinishReasonPolicyDenied = "policy_denied"
+
+// ConfirmationDecision is the complete answer to a confirmation request
+type ConfirmationDecision struct {
+	Response ConfirmationResponse
+	// Arguments replace the arguments of the call when not empty (EditedAndConfirmed)
+	Arguments string
+}
+
+// ConfirmationDecisionCallback is a confirmation callback able to edit the arguments of the call
+type ConfirmationDecisionCallback func(functionName string, arguments string) ConfirmationDecision
+
+// decisionOf adapts a confirmation callback returning a simple response
+func decisionOf(callback func(string, string) ConfirmationResponse) ConfirmationDecisionCallback {
+	if callback == nil {
+		return nil
+	}
+	return func(functionName string, arguments string) ConfirmationDecision {
+		return ConfirmationDecision{Response: callback(functionName, arguments)}
+	}
+}
+
+// executes reports whether the response runs the call
+func (response ConfirmationResponse) executes() bool {
+	switch response {
+	case Confirmed, ConfirmedForSession, ConfirmedAlways, EditedAndConfirmed:
+		return true
+	default:
+		return false
+	}
+}
+
+// PolicyAction is what a confirmation policy does with a tool call
+type PolicyAction int
+
+const (
+	// PolicyAsk asks the confirmation callback (the default)
+	PolicyAsk PolicyAction = iota
+	// PolicyAllow executes the call without confirmation
+	PolicyAllow
+	// PolicyDeny never executes the call: the model is told it was denied
+	PolicyDeny
+)
+
+// PolicyRule applies an action to the calls matching its tools and arguments
+type PolicyRule struct {
+	Action PolicyAction
+	// Tool names matched by the rule, path.Match patterns are allowed ("read_*").
+	// No tools: every tool.
+	Tools []string
+	// Arguments matched by the rule: the rule applies when one of the values of the decoded
+	// JSON arguments matches (the raw arguments when they are not valid JSON). Nil: any arguments.
+	Arguments *regexp.Regexp
+	// Reason sent to the model when the rule denies a call
+	Reason string
+}
+
+// AllowTools auto-approves the calls of tools, typically the read-only ones
+func AllowTools(toolNames ...string) PolicyRule {
+	return PolicyRule{Action: PolicyAllow, Tools: toolNames}
+}
+
+// AskTools requires a confirmation for the calls of tools, even when the default action allows them
+func AskTools(toolNames ...string) PolicyRule {
+	return PolicyRule{Action: PolicyAsk, Tools: toolNames}
+}
+
+// DenyTools always denies the calls of tools
+func DenyTools(toolNames ...string) PolicyRule {
+	return PolicyRule{Action: PolicyDeny, Tools: toolNames, Reason: "This tool is not allowed"}
+}
+
+// DenyArguments always denies the calls with an argument value matching pattern (a regular expression,
+// e.g. `rm\s+-rf`), for the given tools or for every tool. The values are decoded first, so the
+// JSON escapes of the model ("rm \u002drf") don't bypass the rule.
+// It panics if pattern doesn't compile, like regexp.MustCompile.
+func DenyArguments(pattern string, toolNames ...string) PolicyRule {
+	return PolicyRule{
+		Action:    PolicyDeny,
+		Tools:     toolNames,
+		Arguments: regexp.MustCompile(pattern),
+		Reason:    "These arguments are not allowed",
+	}
+}
+
+// matches reports whether the rule applies to a call with the given argument values
+func (rule PolicyRule) matches(toolName string, values []string) bool {
+	if rule.Arguments != nil && !slices.ContainsFunc(values, rule.Arguments.MatchString) {
+		return false
+	}
+	if len(rule.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Tools {
+		if matched, err := path.Match(pattern, toolName); pattern == toolName || (err == nil && matched) {
+			return true
+		}
+	}
+	return false
+}
+
+// argumentValues returns the keys and the scalar values of the JSON arguments of a call,
+// or the raw arguments when they are not valid JSON
+func argumentValues(arguments string) []string {
+	var decoded any
+	if err := json.Unmarshal([]byte(arguments), &decoded); err != nil {
+		return []string{arguments}
+	}
+	var values []string
+	var collect func(value any)
+	collect = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for key, element := range value {
+				values = append(values, key)
+				collect(element)
+			}
+		case []any:
+			for _, element := range value {
+				collect(element)
+			}
+		case string:
+			values = append(values, value)
+		case nil:
+		default:
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+	collect(decoded)
+	return values
+}
+
+// ConfirmationPolicy decides which tool calls need a confirmation.
+// The deny rules always win; then the approvals given with ConfirmedForSession and
+// ConfirmedAlways; then the first matching allow or ask rule; then the default action.
+// A policy is safe for concurrent use and can be shared by several agents.
+type ConfirmationPolicy struct {
+	mutex         sync.Mutex
+	rules         []PolicyRule
+	defaultAction PolicyAction
+
+	sessionApprovals map[string]bool
+	alwaysApprovals  map[string]bool
+}
+
+// NewConfirmationPolicy creates a policy asking for the confirmation of the calls
+// not matched by its rules
+func NewConfirmationPolicy(rules ...PolicyRule) *ConfirmationPolicy {
+	return &ConfirmationPolicy{
+		rules:            rules,
+		defaultAction:    PolicyAsk,
+		sessionApprovals: make(map[string]bool),
+		alwaysApprovals:  make(map[string]bool),
+	}
+}
+
+// AddRules appends rules to the policy
+func (policy *ConfirmationPolicy) AddRules(rules ...PolicyRule) *ConfirmationPolicy {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.rules = append(policy.rules, rules...)
+	return policy
+}
+
+// SetDefaultAction sets the action of the calls not matched by any rule
+func (policy *ConfirmationPolicy) SetDefaultAction(action PolicyAction) *ConfirmationPolicy {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.defaultAction = action
+	return policy
+}
+
+// Evaluate returns the action applied to a call, and the reason of a denial
+func (policy *ConfirmationPolicy) Evaluate(toolName string, arguments string) (PolicyAction, string) {
+	values := argumentValues(arguments)
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+
+	for _, rule := range policy.rules {
+		if rule.Action == PolicyDeny && rule.matches(toolName, values) {
+			return PolicyDeny, rule.Reason
+		}
+	}
+	if policy.sessionApprovals[toolName] || policy.alwaysApprovals[toolName] {
+		return PolicyAllow, ""
+	}
+	for _, rule := range policy.rules {
+		if rule.Action != PolicyDeny && rule.matches(toolName, values) {
+			return rule.Action, ""
+		}
+	}
+	return policy.defaultAction, ""
+}
+
+// ApproveForSession approves the calls of a tool until ResetSession
+func (policy *ConfirmationPolicy) ApproveForSession(toolName string) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.sessionApprovals[toolName] = true
+}
+
+// ApproveAlways approves the calls of a tool for the lifetime of the policy
+func (policy *ConfirmationPolicy) ApproveAlways(toolName string) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.alwaysApprovals[toolName] = true
+}
+
+// Revoke removes the session and permanent approvals of a tool
+func (policy *ConfirmationPolicy) Revoke(toolName string) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	delete(policy.sessionApprovals, toolName)
+	delete(policy.alwaysApprovals, toolName)
+}
+
+// ResetSession forgets the approvals given for the session
+func (policy *ConfirmationPolicy) ResetSession() {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.sessionApprovals = make(map[string]bool)
+}
+
+// IsApproved reports whether the calls of a tool were approved for the session or always
+func (policy *ConfirmationPolicy) IsApproved(toolName string) bool {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	return policy.sessionApprovals[toolName] || policy.alwaysApprovals[toolName]
+}
+
+// record keeps the approval given by a confirmation response
+func (policy *ConfirmationPolicy) record(toolName string, response ConfirmationResponse) {
+	switch response {
+	case ConfirmedForSession:
+		policy.ApproveForSession(toolName)
+	case ConfirmedAlways:
+		policy.ApproveAlways(toolName)
+	}
+}
+
+// WithConfirmationPolicy sets the policy applied before the confirmation callback
+// (by default every call is confirmed by the callback).
+// Its deny rules apply to the detections without confirmation callback too.
+func WithConfirmationPolicy(policy *ConfirmationPolicy) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetConfirmationPolicy(policy)
+	}
+}
+
+// WithConfirmationDecisionFn sets the default confirmation callback able to edit the arguments.
+// It is used instead of the callback of WithConfirmationPromptFn when no callback is passed.
+func WithConfirmationDecisionFn(fn ConfirmationDecisionCallback) ToolsAgentOption {
+	return func(a *Agent) {
+		a.confirmationDecisionFunction = fn
+	}
+}
+
+// SetConfirmationPolicy sets the policy applied before the confirmation callback
+func (agent *BaseAgent) SetConfirmationPolicy(policy *ConfirmationPolicy) {
+	if policy == nil {
+		policy = NewConfirmationPolicy()
+	}
+	agent.confirmationPolicy = policy
+}
+
+// GetConfirmationPolicy returns the policy applied before the confirmation callback
+func (agent *BaseAgent) GetConfirmationPolicy() *ConfirmationPolicy {
+	return agent.confirmationPolicy
+}
+
+// ConfirmationPolicy returns the policy applied before the confirmation callback
+func (agent *Agent) ConfirmationPolicy() *ConfirmationPolicy {
+	return agent.internalAgent.confirmationPolicy
+}
+
+// confirmationCallback resolves the confirmation callback of a detection
+func (agent *Agent) confirmationCallback(callbacks []any, position int) (ConfirmationDecisionCallback, error) {
+	return extractConfirmationDecisionCallback(callbacks, position, agent.confirmationDecisionFunction, agent.confirmationPromptFunction)
+}
@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func TestConfirmationPolicy_Evaluate(t *testing.T) {
+	policy := NewConfirmationPolicy(
+		AllowTools("read_*", "list_files"),
+		DenyTools("format_disk"),
+		DenyArguments(`rm\s+-rf`, "shell"),
+	)
+
+	tests := []struct {
+		tool      string
+		arguments string
+		expected  PolicyAction
+	}{
+		{"read_file", `{"path":"a.txt"}`, PolicyAllow},
+		{"list_files", `{}`, PolicyAllow},
+		{"format_disk", `{}`, PolicyDeny},
+		{"shell", `{"command":"rm -rf /"}`, PolicyDeny},
+		{"shell", `{"command":"rm \u002drf /"}`, PolicyDeny},
+		{"shell", `{"command":"rm\t-rf /"}`, PolicyDeny},
+		{"shell", `{"commands":["ls","rm -rf /"]}`, PolicyDeny},
+		{"shell", `rm -rf / (not JSON)`, PolicyDeny},
+		{"shell", `{"command":"ls"}`, PolicyAsk},
+		{"write_file", `{}`, PolicyAsk},
+	}
+	for _, test := range tests {
+		if action, _ := policy.Evaluate(test.tool, test.arguments); action != test.expected {
+			t.Errorf("%s %s: expected %v, got %v", test.tool, test.arguments, test.expected, action)
+		}
+	}
+
+	policy.ApproveForSession("shell")
+	if action, _ := policy.Evaluate("shell", `{"command":"ls"}`); action != PolicyAllow {
+		t.Errorf("expected the session approval to allow the call, got %v", action)
+	}
+	if action, reason := policy.Evaluate("shell", `{"command":"rm \u002drf /"}`); action != PolicyDeny || reason == "" {
+		t.Errorf("the deny rules must win over the approvals, got %v %q", action, reason)
+	}
+
+	policy.ApproveAlways("write_file")
+	policy.ResetSession()
+	if policy.IsApproved("shell") || !policy.IsApproved("write_file") {
+		t.Error("ResetSession should only forget the session approvals")
+	}
+}
+
+func TestConfirmationPolicy_RulesAreAppliedBeforeTheCallback(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("read_file", `{"path":"notes.txt"}`),
+		novatest.Call("shell", `{"command":"rm -rf /"}`),
+		novatest.Call("shell", `{"command":"ls"}`),
+	))
+
+	var executed, asked []string
+	toolsIndex := []*Tool{
+		NewTool("read_file").AddParameter("path", "string", "", true),
+		NewTool("shell").AddParameter("command", "string", "", true),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		withHistory,
+		WithConfirmationPolicy(NewConfirmationPolicy(AllowTools("read_file"), DenyArguments(`rm\s+-rf`))),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed = append(executed, arguments)
+			return "ok", nil
+		}),
+		WithConfirmationPromptFn(func(functionName string, arguments string) ConfirmationResponse {
+			asked = append(asked, arguments)
+			return Confirmed
+		}),
+	)
+
+	result, err := agent.DetectToolCallsLoopWithConfirmation([]messages.Message{{Role: roles.User, Content: "clean up"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinishReason != "stop" {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+	if len(asked) != 1 || asked[0] != `{"command":"ls"}` {
+		t.Errorf("only the call without rule should be confirmed, got %v", asked)
+	}
+	if len(executed) != 2 || strings.Contains(strings.Join(executed, ""), "rm -rf") {
+		t.Errorf("the denied call should not be executed, got %v", executed)
+	}
+
+	denial := engine.ChatRequests()[1].Messages
+	if content := denial[len(denial)-2].Content; !strings.Contains(content, "denied by policy") {
+		t.Errorf("the model should be told the call was denied, got %q", content)
+	}
+}
+
+func TestConfirmationPolicy_SessionApproval(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("shell", `{"command":"ls"}`)))
+	engine.When(novatest.CallIndex(1)).Reply(novatest.ToolCalls(novatest.Call("shell", `{"command":"pwd"}`)))
+	engine.When(novatest.CallIndex(3)).Reply(novatest.ToolCalls(novatest.Call("shell", `{"command":"ls"}`)))
+
+	asked := 0
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("shell").AddParameter("command", "string", "", true)},
+		withHistory,
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "ok", nil }),
+		WithConfirmationPromptFn(func(functionName string, arguments string) ConfirmationResponse {
+			asked++
+			return ConfirmedForSession
+		}),
+	)
+
+	if _, err := agent.DetectToolCallsLoopWithConfirmation([]messages.Message{{Role: roles.User, Content: "look around"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if asked != 1 || !agent.ConfirmationPolicy().IsApproved("shell") {
+		t.Errorf("the tool should be approved after the first confirmation, asked %d times", asked)
+	}
+
+	agent.ResetMessages()
+	if _, err := agent.DetectToolCallsLoopWithConfirmation([]messages.Message{{Role: roles.User, Content: "again"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if asked != 2 {
+		t.Errorf("a new session should ask again, asked %d times", asked)
+	}
+}
+
+func TestConfirmationPolicy_EditedArguments(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("shell", `{"command":"ls /"}`)))
+
+	var executed string
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("shell").AddParameter("command", "string", "", true)},
+		withHistory,
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed = arguments
+			return "ok", nil
+		}),
+		WithConfirmationDecisionFn(func(functionName string, arguments string) ConfirmationDecision {
+			return ConfirmationDecision{Response: EditedAndConfirmed, Arguments: `{"command":"ls ."}`}
+		}),
+	)
+
+	if _, err := agent.DetectToolCallsLoopWithConfirmation([]messages.Message{{Role: roles.User, Content: "list"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if executed != `{"command":"ls ."}` {
+		t.Errorf("the edited arguments should be executed, got %q", executed)
+	}
+
+	second := engine.ChatRequests()[1].Messages
+	assistant := second[len(second)-2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Arguments != `{"command":"ls ."}` {
+		t.Errorf("the history should keep the edited arguments, got %+v", assistant.ToolCalls)
+	}
+}
+
+func TestConfirmationPolicy_DenyRulesWithoutConfirmationCallback(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("shell", `{"command":"rm -rf /"}`),
+		novatest.Call("read_file", `{"path":"notes.txt"}`),
+	))
+
+	var executed []string
+	toolsIndex := []*Tool{
+		NewTool("read_file").AddParameter("path", "string", "", true),
+		NewTool("shell").AddParameter("command", "string", "", true),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		withHistory,
+		WithConfirmationPolicy(NewConfirmationPolicy(DenyTools("shell"))),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed = append(executed, functionName)
+			return "ok", nil
+		}),
+	)
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "clean up"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executed) != 1 || executed[0] != "read_file" {
+		t.Errorf("the denied call should not be executed, got %v", executed)
+	}
+	if result.FinishReason != "stop" || !strings.Contains(result.Results[0], "denied by policy") {
+		t.Errorf("the model should be told the call was denied, got %+v", result)
+	}
+}
@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Finish reasons of the tool calls loops ended by a guard
+const (
+	// FinishReasonMaxIterations: the loop made the maximum number of requests
+	FinishReasonMaxIterations = "max_iterations"
+	// FinishReasonRepeatedToolCalls: the model kept making the same call after a warning
+	FinishReasonRepeatedToolCalls = "repeated_tool_calls"
+	// FinishReasonToolTimeout: a tool kept exceeding its timeout (no retries left)
+	FinishReasonToolTimeout = "tool_timeout"
+	// FinishReasonLoopTimeout: the loop exceeded its global timeout
+	FinishReasonLoopTimeout = "loop_timeout"
+)
+
+// ErrToolTimeout is returned (wrapped) when a tool call exceeds its timeout
+var ErrToolTimeout = errors.New("tool call timed out")
+
+// loopGuards holds the limits of the tool calls loops (zero values: no limit)
+type loopGuards struct {
+	maxIterations int
+	// Number of identical calls allowed before a warning (the next one stops the loop)
+	maxIdenticalCalls int
+	toolTimeout       time.Duration
+	toolTimeouts      map[string]time.Duration
+	loopTimeout       time.Duration
+}
+
+// guardState is the state of the guards during one detection
+type guardState struct {
+	iterations     int
+	identicalCalls map[string]int
+	deadline       time.Time
+}
+
+// WithMaxIterations caps the number of requests of a tool calls loop.
+// The loop ends with FinishReasonMaxIterations (0: no limit, the default).
+func WithMaxIterations(maxIterations int) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.guards.maxIterations = maxIterations
+	}
+}
+
+// WithRepeatedCallsGuard detects a model calling the same tool with the same arguments
+// again and again: after maxIdenticalCalls identical calls, the next one is not executed and
+// the model gets a warning; one more identical call ends the loop with
+// FinishReasonRepeatedToolCalls (0: no detection, the default).
+func WithRepeatedCallsGuard(maxIdenticalCalls int) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.guards.maxIdenticalCalls = maxIdenticalCalls
+	}
+}
+
+// WithToolTimeout sets the execution timeout of every tool call.
+// A call exceeding it fails like a tool error (it is sent back to the model while the tool has
+// retries left, then the loop ends with FinishReasonToolTimeout). Registered handlers receive
+// a context canceled at the timeout; a plain callback keeps running in the background but its
+// result is ignored.
+func WithToolTimeout(timeout time.Duration) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.guards.toolTimeout = timeout
+	}
+}
+
+// WithToolTimeoutFor sets the execution timeout of one tool (it overrides WithToolTimeout)
+func WithToolTimeoutFor(toolName string, timeout time.Duration) ToolsAgentOption {
+	return func(a *Agent) {
+		if a.internalAgent.guards.toolTimeouts == nil {
+			a.internalAgent.guards.toolTimeouts = make(map[string]time.Duration)
+		}
+		a.internalAgent.guards.toolTimeouts[toolName] = timeout
+	}
+}
+
+// WithLoopTimeout sets the global timeout of a tool calls detection, requests and tool
+// executions included: a model request still running at the deadline is canceled.
+// The loop ends with FinishReasonLoopTimeout (0: no timeout, the default).
+func WithLoopTimeout(timeout time.Duration) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.guards.loopTimeout = timeout
+	}
+}
+
+// beginToolCallsDetection starts the per-call budget window and resets the retries
+// and the guards (called on entry of each detection)
+func (agent *BaseAgent) beginToolCallsDetection() {
+	agent.BeginBudgetCall()
+	agent.resetToolFailures()
+	agent.beginToolSelection()
+	agent.beginToolCache()
+	agent.stateMutex.Lock()
+	agent.nestedCalls = nil
+	agent.toolImages.pending = nil
+	if agent.pendingTools != nil {
+		agent.ChatCompletionParams.Tools = *agent.pendingTools
+		agent.pendingTools = nil
+	}
+	agent.stateMutex.Unlock()
+	agent.guardState = guardState{identicalCalls: make(map[string]int)}
+	if agent.guards.loopTimeout > 0 {
+		agent.guardState.deadline = time.Now().Add(agent.guards.loopTimeout)
+	}
+}
+
+// checkIteration is called before each request of a loop. It returns the finish reason
+// of the guard ending the loop, or "".
+func (agent *BaseAgent) checkIteration() string {
+	if !agent.guardState.deadline.IsZero() && time.Now().After(agent.guardState.deadline) {
+		agent.Log.Warn("⏱️ The tool calls loop exceeded its timeout (%v)", agent.guards.loopTimeout)
+		return FinishReasonLoopTimeout
+	}
+	if agent.guards.maxIterations > 0 && agent.guardState.iterations >= agent.guards.maxIterations {
+		agent.Log.Warn("🔁 The tool calls loop reached its maximum number of iterations (%d)", agent.guards.maxIterations)
+		return FinishReasonMaxIterations
+	}
+	agent.guardState.iterations++
+	return ""
+}
+
+// checkRepeatedCall returns the result replacing an identical call made too many times,
+// or nil when the call can be executed
+func (agent *BaseAgent) checkRepeatedCall(functionName string, functionArgs string) *ToolExecutionResult {
+	if agent.guards.maxIdenticalCalls <= 0 {
+		return nil
+	}
+
+	key := functionName + "\x00" + normalizeArguments(functionArgs)
+	agent.guardState.identicalCalls[key]++
+	count := agent.guardState.identicalCalls[key]
+
+	switch {
+	case count <= agent.guards.maxIdenticalCalls:
+		return nil
+	case count == agent.guards.maxIdenticalCalls+1:
+		agent.Log.Warn("🔁 Identical call to %s repeated %d times: warning the model", functionName, count)
+		return &ToolExecutionResult{
+			Content: fmt.Sprintf(
+				`{"warning": "The tool %s was already called %d times with the same arguments. It was not executed again: use the previous results or change the arguments."}`,
+				functionName, count-1),
+			ShouldStop:       false,
+			ExecFinishReason: "repeated_call_warning",
+		}
+	default:
+		agent.Log.Error("🛑 Identical call to %s repeated %d times: stopping the loop", functionName, count)
+		return &ToolExecutionResult{
+			Content:          fmt.Sprintf(`{"error": "The tool %s was called too many times with the same arguments."}`, functionName),
+			ShouldStop:       true,
+			ExecFinishReason: FinishReasonRepeatedToolCalls,
+		}
+	}
+}
+
+// normalizeArguments compacts JSON arguments so that formatting doesn't hide a repetition
+func normalizeArguments(arguments string) string {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(arguments)); err != nil {
+		return arguments
+	}
+	return compacted.String()
+}
+
+// requestContext returns the context of a model request, bounded by the loop deadline
+func (agent *BaseAgent) requestContext() (context.Context, context.CancelFunc) {
+	ctx := agent.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if agent.guardState.deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, agent.guardState.deadline)
+}
+
+// requestFailure returns the finish reason and the error of a detection whose model request
+// failed: a request interrupted by the loop deadline ends it with FinishReasonLoopTimeout
+func (agent *BaseAgent) requestFailure(err error) (string, error) {
+	if agent.loopDeadlineExceeded() {
+		agent.Log.Warn("⏱️ The tool calls loop exceeded its timeout (%v) during a request", agent.guards.loopTimeout)
+		return FinishReasonLoopTimeout, nil
+	}
+	return "", err
+}
+
+// toolContext returns the context of a tool execution, bounded by the tool timeout and
+// the loop deadline
+func (agent *BaseAgent) toolContext(functionName string) (context.Context, context.CancelFunc) {
+	ctx := agent.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timeout, found := agent.guards.toolTimeouts[functionName]
+	if !found {
+		timeout = agent.guards.toolTimeout
+	}
+	deadline := agent.guardState.deadline
+	if timeout > 0 && (deadline.IsZero() || time.Now().Add(timeout).Before(deadline)) {
+		deadline = time.Now().Add(timeout)
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// runTool executes a tool call within its timeout: a callback still running at the
+// deadline is abandoned and the call fails with ErrToolTimeout
+func (agent *BaseAgent) runTool(
+	functionName string,
+	functionArgs string,
+	toolCallBack func(string, string) (string, error),
+) (string, error) {
+	ctx, cancel := agent.toolContext(functionName)
+	defer cancel()
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		return agent.invokeTool(ctx, functionName, functionArgs, toolCallBack)
+	}
+
+	type outcome struct {
+		content string
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		content, err := agent.invokeTool(ctx, functionName, functionArgs, toolCallBack)
+		done <- outcome{content, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil && errors.Is(result.err, context.DeadlineExceeded) {
+			return "", fmt.Errorf("%w: %s", ErrToolTimeout, functionName)
+		}
+		return result.content, result.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("%w: %s", ErrToolTimeout, functionName)
+		}
+		return "", ctx.Err()
+	}
+}
+
+// loopDeadlineExceeded reports whether the global timeout of the loop is over
+func (agent *BaseAgent) loopDeadlineExceeded() bool {
+	return !agent.guardState.deadline.IsZero() && !time.Now().Before(agent.guardState.deadline)
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func detect(t *testing.T, agent *Agent) *ToolCallResult {
+	t.Helper()
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "look it up"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestGuards_MaxIterations(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+	calls := 0
+	engine.Reply(novatest.Response{ToolCalls: []novatest.ToolCall{{Name: "lookup", Arguments: `{"id":1}`}}})
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("lookup").AddParameter("id", "integer", "", true)},
+		WithMaxIterations(3), WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			calls++
+			return "found", nil
+		}))
+
+	result := detect(t, agent)
+	if result.FinishReason != FinishReasonMaxIterations || len(engine.ChatRequests()) != 3 || calls != 3 {
+		t.Errorf("want %s after 3 requests, got %q after %d requests and %d calls",
+			FinishReasonMaxIterations, result.FinishReason, len(engine.ChatRequests()), calls)
+	}
+
+	// The counter restarts with each detection
+	engine.Reset()
+	engine.Reply(novatest.Text("done"))
+	if result := detect(t, agent); result.FinishReason != "stop" {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+}
+
+func TestGuards_RepeatedCallsWarnThenStop(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.ToolCalls(novatest.Call("lookup", `{"id": 7}`))))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(1)).Reply(novatest.ToolCalls(novatest.Call("lookup", `{"id":7}`)))
+
+	calls := 0
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("lookup").AddParameter("id", "integer", "", true)},
+		WithRepeatedCallsGuard(2), WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			calls++
+			return "found", nil
+		}))
+
+	result := detect(t, agent)
+	if result.FinishReason != FinishReasonRepeatedToolCalls {
+		t.Fatalf("unexpected finish reason %q", result.FinishReason)
+	}
+	// 2 executions, 1 warning, then the stop (the formatting of the arguments doesn't matter)
+	if calls != 2 || len(engine.ChatRequests()) != 4 {
+		t.Errorf("want 2 executions and 4 requests, got %d and %d", calls, len(engine.ChatRequests()))
+	}
+	if len(result.Results) != 4 || !strings.Contains(result.Results[2], "warning") {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestGuards_ToolTimeout(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.ToolCalls(novatest.Call("lookup", `{"id":1}`))))
+	defer engine.Close()
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("lookup").AddParameter("id", "integer", "", true)},
+		WithToolTimeoutFor("lookup", 20*time.Millisecond),
+		WithToolRetries(1),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			time.Sleep(200 * time.Millisecond)
+			return "too late", nil
+		}),
+	)
+
+	start := time.Now()
+	result := detect(t, agent)
+	if result.FinishReason != FinishReasonToolTimeout {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("the loop should not wait for the hung callback, took %v", elapsed)
+	}
+	if len(result.Results) != 2 || !strings.Contains(result.Results[0], "timed out") {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestGuards_HandlersReceiveTheDeadline(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("wait", `{}`)))
+
+	canceled := make(chan bool, 1)
+	wait := Register("wait", "Wait", func(ctx context.Context, input struct{}) (string, error) {
+		select {
+		case <-ctx.Done():
+			canceled <- true
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+			canceled <- false
+			return "done", nil
+		}
+	})
+	agent := newToolsAgent(t, engine.URL, nil, WithRegisteredTools(wait), WithToolTimeout(20*time.Millisecond))
+
+	result := detect(t, agent)
+	if !<-canceled {
+		t.Error("the handler context should be canceled at the timeout")
+	}
+	if result.FinishReason != "stop" || !strings.Contains(result.Results[0], "timed out") {
+		t.Errorf("a timeout with retries left should be sent back to the model, got %+v", result)
+	}
+}
+
+func TestGuards_LoopTimeout(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.ToolCalls(novatest.Call("lookup", `{"id":1}`))))
+	defer engine.Close()
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("lookup").AddParameter("id", "integer", "", true)},
+		WithLoopTimeout(50*time.Millisecond),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			time.Sleep(20 * time.Millisecond)
+			return "found", nil
+		}),
+	)
+
+	result := detect(t, agent)
+	if result.FinishReason != FinishReasonLoopTimeout {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+}
+
+func TestGuards_LoopTimeoutCancelsTheRequest(t *testing.T) {
+	// The chat completions of the engine answer only when the request is canceled
+	backend := novatest.NewServer()
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	proxy := httputil.NewSingleHostReverseProxy(backendURL)
+	release := make(chan struct{})
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer engine.Close()
+	defer close(release)
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("lookup")},
+		WithLoopTimeout(50*time.Millisecond),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "found", nil }),
+	)
+
+	start := time.Now()
+	result := detect(t, agent)
+	if result.FinishReason != FinishReasonLoopTimeout {
+		t.Errorf("unexpected finish reason %q", result.FinishReason)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the request should be canceled at the loop deadline, took %v", elapsed)
+	}
+}
@@ -1,10 +1,13 @@
 package tools
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/shared/constant"
+	"github.com/snipwise/nova/nova-sdk/audit"
 )
 
 // createToolCallParams converts detected tool calls to the proper parameter format
@@ -39,40 +42,62 @@ type ToolExecutionResult struct {
 	Content    string
 	ShouldStop bool
 	// Possible values: "function_executed", "user_denied", "user_quit", "error", "exit_loop"
+	// "error": the call failed and the error was sent back to the model for a retry
+	// "exit_loop": the call failed with a fatal error or without retries left
+	// "policy_denied": the call was denied by the confirmation policy
 	ExecFinishReason string
+	// Arguments of the executed call when they were edited during the confirmation
+	Arguments string
 }
 
-// executeToolCall executes a single tool call without confirmation
+// executeToolCall executes a single tool call (already confirmed, confirmation is its audit label).
+// The calls denied by the confirmation policy are not executed, with or without confirmation callback.
 func (agent *BaseAgent) executeToolCall(
 	functionName string,
 	functionArgs string,
 	callID string,
 	toolCallBack func(string, string) (string, error),
+	confirmation string,
 ) (ToolExecutionResult, error) {
 	agent.Log.Info(fmt.Sprintf("▶️ Executing function: %s with args: %s\n", functionName, functionArgs))
 
-	resultContent, errExec := toolCallBack(functionName, functionArgs)
+	event := audit.Event{Tool: functionName, CallID: callID, Arguments: functionArgs, Confirmation: confirmation}
 
-	if errExec != nil {
-		agent.Log.Error(fmt.Sprintf("🔴 Error executing function %s: %s\n", functionName, errExec.Error()))
+	if errArgs := agent.validateToolArguments(functionName, functionArgs); errArgs != nil {
+		agent.Log.Error(fmt.Sprintf("🔴 %s\n", errArgs.Error()))
+		event.Status, event.Error = audit.StatusError, errArgs.Error()
+		agent.auditToolCall(event)
+		return agent.toolFailure(functionName, errArgs), nil
+	}
+	if action, reason := agent.confirmationPolicy.Evaluate(functionName, functionArgs); action == PolicyDeny {
+		return agent.policyDenial(functionName, functionArgs, callID, reason), nil
+	}
 
-		toolExecRes := ToolExecutionResult{
-			Content:          fmt.Sprintf(`{"error": "Function execution failed: %s"}`, errExec.Error()),
-			ShouldStop:       true,
-			ExecFinishReason: "exit_loop",
-		}
-		// Store the last state of tool calls with confirmation
-		agent.lastState = LastToolCallsState{
-			Confirmation: Confirmed,
-			ExecutionResult: ToolExecutionResult{
-				Content:          toolExecRes.Content,
-				ShouldStop:       toolExecRes.ShouldStop,
-				ExecFinishReason: toolExecRes.ExecFinishReason,
-			},
-		}
+	start := time.Now()
+	resultContent, cacheKey, cached := agent.cachedToolResult(functionName, functionArgs)
+	var errExec error
+	if cached {
+		agent.Log.Info(fmt.Sprintf("💾 Result of %s served from the tool cache\n", functionName))
+		event.Cached = true
+	} else {
+		resultContent, errExec = agent.runTool(functionName, functionArgs, toolCallBack)
+	}
+	event.DurationMs = audit.Milliseconds(time.Since(start))
 
-		return toolExecRes, nil
+	if errExec != nil {
+		agent.Log.Error(fmt.Sprintf("🔴 Error executing function %s: %s\n", functionName, errExec.Error()))
+		event.Status, event.Error = audit.StatusError, errExec.Error()
+		agent.auditToolCall(event)
+		return agent.toolFailure(functionName, errExec), nil
+	}
+	event.Status, event.Result = audit.StatusSuccess, resultContent
+	agent.auditToolCall(event)
+	if !cached {
+		agent.memoizeToolResult(cacheKey, functionName, resultContent)
 	}
+	agent.stateMutex.Lock()
+	delete(agent.retryPolicy.failures, functionName)
+	agent.stateMutex.Unlock()
 
 	if resultContent == "" {
 		resultContent = `{"error": "Function execution returned empty result"}`
@@ -86,6 +111,7 @@ func (agent *BaseAgent) executeToolCall(
 		ExecFinishReason: "function_executed",
 	}
 	// Store the last state of tool calls with confirmation
+	agent.stateMutex.Lock()
 	agent.lastState = LastToolCallsState{
 		Confirmation: Confirmed,
 		ExecutionResult: ToolExecutionResult{
@@ -94,29 +120,68 @@ func (agent *BaseAgent) executeToolCall(
 			ExecFinishReason: toolExecRes.ExecFinishReason,
 		},
 	}
+	agent.stateMutex.Unlock()
 	return toolExecRes, nil
 }
 
-// executeToolCallWithConfirmation executes a single tool call with confirmation
+// executeToolCallWithConfirmation executes a single tool call with confirmation.
+// The confirmation policy is applied first: denied calls are not executed and
+// approved calls are executed without asking the confirmation callback.
 func (agent *BaseAgent) executeToolCallWithConfirmation(
 	functionName string,
 	functionArgs string,
 	callID string,
 	toolCallBack func(string, string) (string, error),
-	confirmationCallBack func(string, string) ConfirmationResponse,
+	confirmationCallBack ConfirmationDecisionCallback,
 ) (ToolExecutionResult, error) {
-	// Ask for confirmation before executing the tool
-	agent.Log.Info(fmt.Sprintf("⁉️ Requesting confirmation for function: %s with args: %s\n", functionName, functionArgs))
-	confirmation := confirmationCallBack(functionName, functionArgs)
+	// Invalid arguments go back to the model without bothering the user
+	if errArgs := agent.validateToolArguments(functionName, functionArgs); errArgs != nil {
+		agent.Log.Error(fmt.Sprintf("🔴 %s\n", errArgs.Error()))
+		agent.auditToolCall(audit.Event{
+			Tool: functionName, CallID: callID, Arguments: functionArgs,
+			Confirmation: audit.ConfirmationNotRequired, Status: audit.StatusError, Error: errArgs.Error(),
+		})
+		return agent.toolFailure(functionName, errArgs), nil
+	}
+
+	decision := ConfirmationDecision{Response: Confirmed}
+	confirmation := ""
+	switch action, reason := agent.confirmationPolicy.Evaluate(functionName, functionArgs); action {
+	case PolicyDeny:
+		return agent.policyDenial(functionName, functionArgs, callID, reason), nil
+	case PolicyAllow:
+		agent.Log.Info(fmt.Sprintf("✅ Function %s approved by the confirmation policy\n", functionName))
+		confirmation = audit.ConfirmationPolicyAllowed
+	default:
+		// Ask for confirmation before executing the tool
+		agent.Log.Info(fmt.Sprintf("⁉️ Requesting confirmation for function: %s with args: %s\n", functionName, functionArgs))
+		decision = confirmationCallBack(functionName, functionArgs)
+		confirmation = auditConfirmation(decision.Response)
+	}
+
+	switch {
+	case decision.Response.executes():
+		agent.confirmationPolicy.record(functionName, decision.Response)
+
+		// Edited arguments are checked by the deny rules again
+		edited := decision.Arguments != "" && decision.Arguments != functionArgs
+		if edited {
+			agent.Log.Info(fmt.Sprintf("✏️ Arguments of function %s edited: %s\n", functionName, decision.Arguments))
+			functionArgs = decision.Arguments
+			if action, reason := agent.confirmationPolicy.Evaluate(functionName, functionArgs); action == PolicyDeny {
+				return agent.policyDenial(functionName, functionArgs, callID, reason), nil
+			}
+		}
 
-	switch confirmation {
-	case Confirmed:
 		// Proceed with tool execution
-		toolExecRes, err := agent.executeToolCall(functionName, functionArgs, callID, toolCallBack)
+		toolExecRes, err := agent.executeToolCall(functionName, functionArgs, callID, toolCallBack, confirmation)
+		if edited {
+			toolExecRes.Arguments = functionArgs
+		}
 
 		// Store the last state of tool calls with confirmation
 		agent.lastState = LastToolCallsState{
-			Confirmation: Confirmed,
+			Confirmation: decision.Response,
 			ExecutionResult: ToolExecutionResult{
 				Content:          toolExecRes.Content,
 				ShouldStop:       toolExecRes.ShouldStop,
@@ -127,7 +192,12 @@ func (agent *BaseAgent) executeToolCallWithConfirmation(
 		agent.Log.Info(fmt.Sprintf("✅ Tool execution confirmed for function: %s\n", functionName))
 		return toolExecRes, err
 
-	case Denied:
+	case decision.Response == Denied:
+		agent.auditToolCall(audit.Event{
+			Tool: functionName, CallID: callID, Arguments: functionArgs,
+			Confirmation: audit.ConfirmationDenied, Status: audit.StatusDenied,
+		})
+
 		// Skip execution but add a message indicating the tool was denied (cancel in the vscode extension)
 		toolExecRes := ToolExecutionResult{
 			Content:          `{"status": "denied", "message": "Tool execution was denied by user"}`,
@@ -148,7 +218,12 @@ func (agent *BaseAgent) executeToolCallWithConfirmation(
 		agent.Log.Warn(fmt.Sprintf("⛔ Tool execution denied for function: %s\n", functionName))
 		return toolExecRes, nil
 
-	case Quit:
+	case decision.Response == Quit:
+		agent.auditToolCall(audit.Event{
+			Tool: functionName, CallID: callID, Arguments: functionArgs,
+			Confirmation: audit.ConfirmationQuit, Status: audit.StatusDenied,
+		})
+
 		// Exit the function immediately (reset in the vscode extension)
 		toolExecRes := ToolExecutionResult{
 			Content:          `{"status": "quit", "message": "Tool execution was quit by user"}`,
@@ -173,23 +248,65 @@ func (agent *BaseAgent) executeToolCallWithConfirmation(
 	return ToolExecutionResult{}, nil
 }
 
+// policyDenial builds the result of a call denied by the confirmation policy
+func (agent *BaseAgent) policyDenial(functionName string, functionArgs string, callID string, reason string) ToolExecutionResult {
+	message := "Tool execution was denied by policy"
+	if reason != "" {
+		message += ": " + reason
+	}
+	content, _ := json.Marshal(map[string]string{"status": "denied", "message": message})
+
+	toolExecRes := ToolExecutionResult{
+		Content:          string(content),
+		ShouldStop:       false,
+		ExecFinishReason: FinishReasonPolicyDenied,
+	}
+	agent.stateMutex.Lock()
+	agent.lastState = LastToolCallsState{
+		Confirmation:    Denied,
+		ExecutionResult: toolExecRes,
+	}
+	agent.stateMutex.Unlock()
+
+	agent.auditToolCall(audit.Event{
+		Tool: functionName, CallID: callID, Arguments: functionArgs,
+		Confirmation: audit.ConfirmationPolicyDenied, Status: audit.StatusDenied, Error: message,
+	})
+
+	agent.Log.Warn(fmt.Sprintf("⛔ Tool execution denied by policy for function: %s\n", functionName))
+	return toolExecRes
+}
+
 // processToolCalls processes all detected tool calls and updates the message history
 func (agent *BaseAgent) processToolCalls(
 	messages []openai.ChatCompletionMessageParamUnion,
 	detectedToolCalls []openai.ChatCompletionMessageToolCallUnion,
 	results *[]string,
 	toolCallBack func(string, string) (string, error),
-	confirmationCallBack func(string, string) ConfirmationResponse,
+	confirmationCallBack ConfirmationDecisionCallback,
 ) ([]openai.ChatCompletionMessageParamUnion, bool, string) {
 	agent.Log.Info("🚀 Processing tool calls...")
+	agent.expandToolSelection(detectedToolCalls)
 
 	// Create tool call params and add assistant message
 	toolCallParams := createToolCallParams(detectedToolCalls)
 	assistantMessage := createAssistantMessageWithToolCalls(toolCallParams)
 	messages = append(messages, assistantMessage)
 
+	// Identical calls repeated too many times are not executed
+	guarded := make([]*ToolExecutionResult, len(detectedToolCalls))
+	for index, toolCall := range detectedToolCalls {
+		guarded[index] = agent.checkRepeatedCall(toolCall.Function.Name, toolCall.Function.Arguments)
+	}
+
+	// Execute the calls concurrently when enabled (the results are still handled in the call order)
+	var executions []toolCallExecution
+	if confirmationCallBack == nil && agent.concurrency.enabled(len(detectedToolCalls)) {
+		executions = agent.executeToolCallsConcurrently(detectedToolCalls, guarded, toolCallBack)
+	}
+
 	// Process each detected tool call
-	for _, toolCall := range detectedToolCalls {
+	for index, toolCall := range detectedToolCalls {
 		functionName := toolCall.Function.Name
 		functionArgs := toolCall.Function.Arguments
 		callID := toolCall.ID
@@ -198,16 +315,25 @@ func (agent *BaseAgent) processToolCalls(
 		var err error
 
 		// Execute with or without confirmation
-		if confirmationCallBack != nil {
+		if guarded[index] != nil {
+			result = *guarded[index]
+		} else if executions != nil {
+			result, err = executions[index].result, executions[index].err
+		} else if confirmationCallBack != nil {
 			result, err = agent.executeToolCallWithConfirmation(functionName, functionArgs, callID, toolCallBack, confirmationCallBack)
 		} else {
-			result, err = agent.executeToolCall(functionName, functionArgs, callID, toolCallBack)
+			result, err = agent.executeToolCall(functionName, functionArgs, callID, toolCallBack, audit.ConfirmationNotRequired)
 		}
 
 		if err != nil {
 			return messages, true, "error"
 		}
 
+		// The assistant message keeps the arguments actually used
+		if result.Arguments != "" {
+			toolCallParams[index].OfFunction.Function.Arguments = result.Arguments
+		}
+
 		// Handle quit case
 		if result.ShouldStop && result.ExecFinishReason == "user_quit" {
 			return messages, true, result.ExecFinishReason
@@ -227,6 +353,11 @@ func (agent *BaseAgent) processToolCalls(
 		}
 	}
 
+	// The images of the results follow the tool messages (see WithToolResultImages)
+	if images := agent.takeToolImages(); len(images) > 0 {
+		messages = append(messages, toolImagesMessage(images))
+	}
+
 	return messages, false, ""
 }
 
@@ -251,12 +382,28 @@ func (agent *BaseAgent) collectStreamResponse(
 	paramsForCall openai.ChatCompletionNewParams,
 	streamCallback func(content string) error,
 ) (string, error) {
-	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(agent.Ctx, paramsForCall)
+	// With prompt-based tool calling, the text of the tool calls is not streamed
+	var filter *promptStreamFilter
+	tools := paramsForCall.Tools
+	if agent.promptToolCalling != nil {
+		paramsForCall = agent.promptToolCalling.PrepareRequest(paramsForCall)
+		filter = &promptStreamFilter{callback: streamCallback}
+		streamCallback = filter.write
+	}
+
+	paramsForCall = agent.WithStreamUsage(paramsForCall)
+	ctx, cancel := agent.requestContext()
+	defer cancel()
+	stream := agent.OpenaiClient.Chat.Completions.NewStreaming(ctx, paramsForCall)
 	var response string
 	var cbkRes error
+	var usage openai.CompletionUsage
 
 	for stream.Next() {
 		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
 		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
 			agent.SaveLastChunkResponse(&chunk)
 		}
@@ -269,6 +416,7 @@ func (agent *BaseAgent) collectStreamResponse(
 			break
 		}
 	}
+	agent.RecordStreamUsage(paramsForCall, usage, response)
 
 	if cbkRes != nil {
 		return "", cbkRes
@@ -281,6 +429,12 @@ func (agent *BaseAgent) collectStreamResponse(
 		agent.Log.Error("Stream close error: %v", err)
 		return "", err
 	}
+	if filter != nil {
+		_, calls := agent.promptToolCalling.ParseToolCalls(response, tools)
+		if err := filter.finish(len(calls) > 0); err != nil {
+			return "", err
+		}
+	}
 	return response, nil
 }
 
@@ -291,3 +445,17 @@ func (agent *BaseAgent) saveHistoryIfNeeded(workingMessages []openai.ChatComplet
 		agent.ChatCompletionParams.Messages = workingMessages
 	}
 }
+
+// checkBudget runs the budget check before a request of a tool calls loop.
+// With budget.ActionCompress, the working messages of the loop are compressed
+// (they become the history when KeepConversationHistory is enabled).
+func (agent *BaseAgent) checkBudget(
+	workingMessages []openai.ChatCompletionMessageParamUnion,
+) ([]openai.ChatCompletionMessageParamUnion, bool, error) {
+	history := agent.ChatCompletionParams.Messages
+	agent.ChatCompletionParams.Messages = workingMessages
+	stop, err := agent.CheckBudget()
+	workingMessages = agent.ChatCompletionParams.Messages
+	agent.ChatCompletionParams.Messages = history
+	return workingMessages, stop, err
+}
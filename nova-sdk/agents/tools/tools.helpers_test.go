@@ -1,11 +1,13 @@
 package tools
 
 import (
+	"context"
 	"testing"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/snipwise/nova/nova-sdk/agents"
 	"github.com/snipwise/nova/nova-sdk/agents/base"
+	"github.com/snipwise/nova/nova-sdk/models"
 )
 
 // newTestBaseAgent creates a minimal BaseAgent for unit testing helpers.
@@ -19,6 +21,31 @@ func newTestBaseAgent(keepHistory bool) *BaseAgent {
 	}
 }
 
+// newToolsAgent creates a tools agent with the given tools, using the test model of the engine
+func newToolsAgent(t *testing.T, engineURL string, toolsIndex []*Tool, options ...ToolsAgentOption) *Agent {
+	t.Helper()
+	agentOptions := []any{WithTools(toolsIndex)}
+	for _, option := range options {
+		agentOptions = append(agentOptions, option)
+	}
+	agent, err := NewAgent(context.Background(),
+		agents.Config{Name: "tools", EngineURL: engineURL, SystemInstructions: "You call tools"},
+		models.Config{Name: "test-model", Temperature: models.Float64(0.0)},
+		agentOptions...,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return agent
+}
+
+// withHistory keeps the conversation history of the test agent
+func withHistory(a *Agent) {
+	config := a.GetConfig()
+	config.KeepConversationHistory = true
+	a.SetConfig(config)
+}
+
 // ── saveHistoryIfNeeded ────────────────────────────────────────────────────────
 
 func TestSaveHistoryIfNeeded_StoresMessages_WhenEnabled(t *testing.T) {
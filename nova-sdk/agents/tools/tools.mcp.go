@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/openai/openai-go/v3"
+	"github.com/snipwise/nova/nova-sdk/mcptools"
+	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
+)
+
+// MCPToolCaller exposes MCP tools and returns their complete results
+// (implemented by mcptools.MCPClient and mcptools.MCPHub)
+type MCPToolCaller interface {
+	GetTools() []mcp.Tool
+	CallToolWithContext(ctx context.Context, toolName string, input map[string]any) (*mcptools.ToolResult, error)
+}
+
+// ToolImage is an image returned by a tool
+type ToolImage struct {
+	Tool     string
+	MIMEType string
+	// Data is the base64-encoded image
+	Data string
+}
+
+// toolImages holds the images attached by the tools during the current tool calls
+type toolImages struct {
+	enabled bool
+	pending []ToolImage
+}
+
+// WithToolResultImages sends the images returned by the tools to the model (for vision
+// models): after the tool results, a user message carries the images attached with
+// AttachImages. Disabled by default: the model only gets the text of the results.
+func WithToolResultImages(enabled bool) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.toolImages.enabled = enabled
+	}
+}
+
+// WithMCPToolCaller registers the tools of an MCP client or hub with handlers keeping their
+// complete results: the text (or the structured content) is sent to the model, the images
+// are attached for the vision models (see WithToolResultImages) and the results flagged
+// isError are tool errors the model can react to (see WithToolRetries).
+// A tool whose input schema is invalid is skipped.
+//
+// Usage:
+//
+//	agent, err := tools.NewAgent(ctx, agentConfig, modelConfig,
+//		tools.WithMCPToolCaller(mcpClient),
+//		tools.WithToolResultImages(true),
+//	)
+func WithMCPToolCaller(caller MCPToolCaller) ToolsAgentOption {
+	return func(a *Agent) {
+		registeredTools, errs := MCPRegisteredTools(caller)
+		for _, err := range errs {
+			a.log.Warn("⚠️ MCP tool skipped: %v", err)
+		}
+		a.RegisterTools(registeredTools...)
+	}
+}
+
+// MCPRegisteredTools converts the tools of an MCP client or hub to registered tools
+// (see WithMCPToolCaller). The tools with an invalid input schema are returned as errors.
+func MCPRegisteredTools(caller MCPToolCaller) ([]*RegisteredTool, []error) {
+	var registeredTools []*RegisteredTool
+	var errs []error
+	for _, mcpTool := range caller.GetTools() {
+		schema := []byte(mcpTool.RawInputSchema)
+		if len(schema) == 0 {
+			encoded, err := json.Marshal(mcpTool.InputSchema)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid input schema for tool %s: %w", mcpTool.Name, err))
+				continue
+			}
+			schema = encoded
+		}
+		tool, err := NewToolFromJSONSchema(mcpTool.Name, mcpTool.Description, schema)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// The tools not annotated as read-only are never executed concurrently
+		readOnly := mcpTool.Annotations.ReadOnlyHint
+		tool.SetSideEffects(readOnly == nil || !*readOnly)
+		registeredTools = append(registeredTools, &RegisteredTool{Tool: tool, Handler: mcpToolHandler(caller, mcpTool.Name)})
+	}
+	return registeredTools, errs
+}
+
+// mcpToolHandler calls an MCP tool and converts its result
+func mcpToolHandler(caller MCPToolCaller, toolName string) ToolHandler {
+	return func(ctx context.Context, arguments string) (string, error) {
+		input, err := conversion.JsonStringToMap(arguments)
+		if err != nil {
+			return "", fmt.Errorf("error converting arguments to map: %w", err)
+		}
+		result, err := caller.CallToolWithContext(ctx, toolName, input)
+		if err != nil {
+			return "", err
+		}
+		if err := result.Err(); err != nil {
+			return "", err
+		}
+		images := make([]ToolImage, 0, len(result.Images()))
+		for _, image := range result.Images() {
+			images = append(images, ToolImage{Tool: toolName, MIMEType: image.MIMEType, Data: image.Data})
+		}
+		AttachImages(ctx, images...)
+		return result.ModelContent(), nil
+	}
+}
+
+// AttachImages attaches images to the result of the tool call being executed: they are sent
+// to the model after the tool results when the agent enables WithToolResultImages.
+// ctx is the context received by the tool handler; it reports false when the handler was not
+// called by a tools agent or when the agent doesn't send the images.
+func AttachImages(ctx context.Context, images ...ToolImage) bool {
+	agent, found := ctx.Value(callingAgentKey{}).(*BaseAgent)
+	if !found || !agent.toolImages.enabled {
+		return false
+	}
+	agent.stateMutex.Lock()
+	defer agent.stateMutex.Unlock()
+	agent.toolImages.pending = append(agent.toolImages.pending, images...)
+	return true
+}
+
+// takeToolImages returns and clears the images attached by the tool calls
+func (agent *BaseAgent) takeToolImages() []ToolImage {
+	agent.stateMutex.Lock()
+	defer agent.stateMutex.Unlock()
+	images := agent.toolImages.pending
+	agent.toolImages.pending = nil
+	return images
+}
+
+// toolImagesMessage builds the user message carrying the images of the tool results
+// (tool messages only support text)
+func toolImagesMessage(images []ToolImage) openai.ChatCompletionMessageParamUnion {
+	parts := []openai.ChatCompletionContentPartUnionParam{
+		openai.TextContentPart("Images returned by the tool calls:"),
+	}
+	for _, image := range images {
+		parts = append(parts,
+			openai.TextContentPart(fmt.Sprintf("Image returned by %s:", image.Tool)),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: fmt.Sprintf("data:%s;base64,%s", image.MIMEType, image.Data),
+			}),
+		)
+	}
+	return openai.UserMessage(parts)
+}
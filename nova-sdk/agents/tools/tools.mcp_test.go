@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/mcptools"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+// fakeMCPCaller returns canned MCP results
+type fakeMCPCaller struct {
+	tools   []mcp.Tool
+	results map[string]*mcp.CallToolResult
+}
+
+func (caller *fakeMCPCaller) GetTools() []mcp.Tool {
+	return caller.tools
+}
+
+func (caller *fakeMCPCaller) CallToolWithContext(ctx context.Context, toolName string, input map[string]any) (*mcptools.ToolResult, error) {
+	return mcptools.NewToolResult(toolName, caller.results[toolName]), nil
+}
+
+func newFakeMCPCaller() *fakeMCPCaller {
+	return &fakeMCPCaller{
+		tools: []mcp.Tool{
+			mcp.NewTool("screenshot", mcp.WithString("url", mcp.Required()), mcp.WithReadOnlyHintAnnotation(true)),
+			mcp.NewTool("deploy"),
+		},
+		results: map[string]*mcp.CallToolResult{
+			"screenshot": {Content: []mcp.Content{
+				mcp.NewTextContent("Screenshot of the page"),
+				mcp.NewImageContent("aGVsbG8=", "image/png"),
+			}},
+			"deploy": {Content: []mcp.Content{mcp.NewTextContent("permission denied")}, IsError: true},
+		},
+	}
+}
+
+func TestMCPRegisteredTools(t *testing.T) {
+	registeredTools, errs := MCPRegisteredTools(newFakeMCPCaller())
+	if len(errs) != 0 || len(registeredTools) != 2 {
+		t.Fatalf("unexpected tools %v, errors %v", registeredTools, errs)
+	}
+	screenshot, deploy := registeredTools[0].Tool, registeredTools[1].Tool
+	if screenshot.SideEffects || !deploy.SideEffects {
+		t.Error("only the read-only tools should run concurrently")
+	}
+	if required := screenshot.GetRequired(); len(required) != 1 || required[0] != "url" {
+		t.Errorf("unexpected required parameters %v", required)
+	}
+
+	if _, err := registeredTools[1].Handler(context.Background(), `{}`); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("an isError result should be a tool error, got %v", err)
+	}
+	result, err := registeredTools[0].Handler(context.Background(), `{"url":"https://example.com"}`)
+	if err != nil || result != "Screenshot of the page\n[image: image/png]" {
+		t.Errorf("unexpected result %q, error %v", result, err)
+	}
+}
+
+func TestWithMCPToolCaller_SendsImagesAndErrors(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("screenshot", `{"url":"https://example.com"}`),
+		novatest.Call("deploy", `{}`),
+	))
+
+	agent := newToolsAgent(t, engine.URL, nil,
+		WithMCPToolCaller(newFakeMCPCaller()),
+		WithToolResultImages(true),
+	)
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "check the page"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Results) != 2 || !strings.Contains(result.Results[1], "permission denied") {
+		t.Errorf("the failure should be sent back to the model, got %v", result.Results)
+	}
+
+	requests := engine.Requests()
+	lastRequest := requests[len(requests)-1]
+	last := lastRequest.Chat.Messages[len(lastRequest.Chat.Messages)-1]
+	if last.Role != "user" || !strings.Contains(last.Content, "Image returned by screenshot") {
+		t.Errorf("the images should follow the tool results, got %+v", last)
+	}
+	if !strings.Contains(string(lastRequest.Body), "data:image/png;base64,aGVsbG8=") {
+		t.Error("the image should be sent to the model")
+	}
+}
+
+func TestWithMCPToolCaller_ImagesDisabled(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("screenshot", `{"url":"https://example.com"}`)))
+
+	agent := newToolsAgent(t, engine.URL, nil,
+		WithMCPToolCaller(newFakeMCPCaller()),
+	)
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "check the page"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	requests := engine.Requests()
+	if strings.Contains(string(requests[len(requests)-1].Body), "base64") {
+		t.Error("the images should not be sent without WithToolResultImages")
+	}
+}
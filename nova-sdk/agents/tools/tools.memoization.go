@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/cache"
+)
+
+// ToolCacheScope is the lifetime of the memoized tool results
+type ToolCacheScope string
+
+const (
+	// ToolCacheCall: the results are shared during one tool calls detection
+	ToolCacheCall ToolCacheScope = "call"
+	// ToolCacheSession: the results are shared until ResetMessages (the audit session)
+	ToolCacheSession ToolCacheScope = "session"
+	// ToolCacheGlobal: the results are shared by every detection (and by the agents using the same store)
+	ToolCacheGlobal ToolCacheScope = "global"
+)
+
+// DefaultToolCacheCapacity is the capacity of the memory store created when ToolCacheConfig.Store is nil
+const DefaultToolCacheCapacity = 1000
+
+// ToolCacheConfig holds the memoization settings of the tool results
+type ToolCacheConfig struct {
+	// Store of the results (default: a memory store of DefaultToolCacheCapacity entries).
+	// It can be shared with the response cache: the keys don't collide.
+	Store cache.Store
+	// Scope of the results (default: ToolCacheSession)
+	Scope ToolCacheScope
+}
+
+// toolCache memoizes the results of the cacheable tools
+type toolCache struct {
+	config ToolCacheConfig
+	// TTL of the cacheable tools (0: until invalidated)
+	ttls map[string]time.Duration
+
+	mutex sync.Mutex
+	// Identifier of the current detection (ToolCacheCall)
+	callID string
+	// Generations invalidating the previous entries of a tool, or of every tool
+	generation      int
+	toolGenerations map[string]int
+	// checked is set once the options are applied: the cacheable tools set later are checked at once
+	checked bool
+}
+
+// toolCacheKey is the canonical content of a cache key
+type toolCacheKey struct {
+	Scope      string          `json:"scope"`
+	Generation [2]int          `json:"generation"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments"`
+}
+
+// WithToolCache memoizes the results of the cacheable tools (see WithCacheableTools and
+// Tool.SetCacheable): a call with the same arguments (compared as canonical JSON) in the scope
+// returns the cached result without executing the tool. Only the successful results are cached.
+func WithToolCache(config ToolCacheConfig) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetToolCache(config)
+	}
+}
+
+// WithCacheableTools marks idempotent tools whose results are memoized for ttl (0: until invalidated).
+// It needs WithToolCache: without tool cache, NewAgent logs a warning and the tools are always executed.
+func WithCacheableTools(ttl time.Duration, toolNames ...string) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetCacheableTools(ttl, toolNames...)
+	}
+}
+
+// SetToolCache updates the memoization settings of the tool results
+// (the cacheable tools are kept)
+func (agent *BaseAgent) SetToolCache(config ToolCacheConfig) {
+	if config.Store == nil {
+		config.Store = cache.NewMemoryStore(DefaultToolCacheCapacity)
+	}
+	if config.Scope == "" {
+		config.Scope = ToolCacheSession
+	}
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	agent.toolCache.config = config
+}
+
+// GetToolCacheConfig returns the memoization settings of the tool results
+func (agent *BaseAgent) GetToolCacheConfig() ToolCacheConfig {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	return agent.toolCache.config
+}
+
+// SetCacheableTools marks idempotent tools whose results are memoized for ttl (0: until invalidated)
+func (agent *BaseAgent) SetCacheableTools(ttl time.Duration, toolNames ...string) {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	if agent.toolCache.ttls == nil {
+		agent.toolCache.ttls = make(map[string]time.Duration)
+	}
+	for _, name := range toolNames {
+		agent.toolCache.ttls[name] = ttl
+	}
+	if agent.toolCache.checked && agent.toolCache.config.Store == nil {
+		agent.Log.Warn("💾 Cacheable tools %v without tool cache: use WithToolCache to memoize their results", toolNames)
+	}
+}
+
+// checkToolCache warns when cacheable tools are set without tool cache (their results are not memoized).
+// NewAgent calls it once the options are applied, whatever their order.
+func (agent *BaseAgent) checkToolCache() {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	agent.toolCache.checked = true
+	if len(agent.toolCache.ttls) > 0 && agent.toolCache.config.Store == nil {
+		agent.Log.Warn("💾 Cacheable tools %v without tool cache: use WithToolCache to memoize their results",
+			slices.Sorted(maps.Keys(agent.toolCache.ttls)))
+	}
+}
+
+// IsCacheableTool reports whether the results of a tool are memoized
+func (agent *BaseAgent) IsCacheableTool(toolName string) bool {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	_, found := agent.toolCache.ttls[toolName]
+	return found && agent.toolCache.config.Store != nil
+}
+
+// InvalidateToolResult removes the cached result of a call in the current scope
+func (agent *BaseAgent) InvalidateToolResult(toolName string, arguments string) error {
+	key, err := agent.toolCacheKey(toolName, arguments)
+	if err != nil || key == "" {
+		return err
+	}
+	return agent.toolCache.config.Store.Delete(key)
+}
+
+// InvalidateTool invalidates the cached results of a tool, in every scope
+func (agent *BaseAgent) InvalidateTool(toolName string) {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	if agent.toolCache.toolGenerations == nil {
+		agent.toolCache.toolGenerations = make(map[string]int)
+	}
+	agent.toolCache.toolGenerations[toolName]++
+}
+
+// ClearToolCache invalidates the cached results of every tool, in every scope.
+// The entries are left to expire: the store is not cleared, so it can be shared.
+func (agent *BaseAgent) ClearToolCache() {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	agent.toolCache.generation++
+}
+
+// beginToolCache starts the call scope of a detection
+func (agent *BaseAgent) beginToolCache() {
+	agent.toolCache.mutex.Lock()
+	defer agent.toolCache.mutex.Unlock()
+	agent.toolCache.callID = audit.NewSessionID()
+}
+
+// toolCacheKey returns the key of a call in the current scope ("" when the tool is not cacheable)
+func (agent *BaseAgent) toolCacheKey(toolName string, arguments string) (string, error) {
+	if !agent.IsCacheableTool(toolName) {
+		return "", nil
+	}
+	agent.toolCache.mutex.Lock()
+	scope := string(agent.toolCache.config.Scope)
+	callID := agent.toolCache.callID
+	generation := [2]int{agent.toolCache.generation, agent.toolCache.toolGenerations[toolName]}
+	agent.toolCache.mutex.Unlock()
+
+	switch ToolCacheScope(scope) {
+	case ToolCacheCall:
+		scope += ":" + callID
+	case ToolCacheSession:
+		scope += ":" + agent.GetAuditSession()
+	}
+
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("invalid arguments for tool %s", toolName)
+	}
+	return cache.Key("tool", toolCacheKey{Scope: scope, Generation: generation, Tool: toolName, Arguments: json.RawMessage(arguments)})
+}
+
+// cachedToolResult returns the memoized result of a call
+func (agent *BaseAgent) cachedToolResult(toolName string, arguments string) (string, string, bool) {
+	key, err := agent.toolCacheKey(toolName, arguments)
+	if err != nil || key == "" {
+		return "", "", false
+	}
+	value, found, err := agent.toolCache.config.Store.Get(key)
+	if err != nil {
+		agent.Log.Warn("💾 Tool cache lookup failed: %v", err)
+		return "", key, false
+	}
+	return string(value), key, found
+}
+
+// memoizeToolResult stores the result of a call
+func (agent *BaseAgent) memoizeToolResult(key string, toolName string, result string) {
+	if key == "" {
+		return
+	}
+	agent.toolCache.mutex.Lock()
+	ttl := agent.toolCache.ttls[toolName]
+	agent.toolCache.mutex.Unlock()
+	if err := agent.toolCache.config.Store.Set(key, []byte(result), ttl); err != nil {
+		agent.Log.Warn("💾 Tool cache write failed: %v", err)
+	}
+}
+
+// InvalidateToolResult removes the cached result of a call in the current scope
+func (agent *Agent) InvalidateToolResult(toolName string, arguments string) error {
+	return agent.internalAgent.InvalidateToolResult(toolName, arguments)
+}
+
+// InvalidateTool invalidates the cached results of a tool, in every scope
+func (agent *Agent) InvalidateTool(toolName string) {
+	agent.internalAgent.InvalidateTool(toolName)
+}
+
+// ClearToolCache invalidates the cached results of every tool, in every scope
+func (agent *Agent) ClearToolCache() {
+	agent.internalAgent.ClearToolCache()
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func TestToolCache_SessionScope(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("read_file", `{"path":"notes.txt"}`),
+		novatest.Call("read_file", ` { "path" : "notes.txt" } `),
+		novatest.Call("shell", `{"command":"date"}`),
+		novatest.Call("shell", `{"command":"date"}`),
+	))
+	for _, index := range []int{2, 4} {
+		engine.When(novatest.CallIndex(index)).Reply(novatest.ToolCalls(novatest.Call("read_file", `{"path":"notes.txt"}`)))
+	}
+
+	executed := map[string]int{}
+	recorder := &auditRecorder{}
+	toolsIndex := []*Tool{
+		NewTool("read_file").AddParameter("path", "string", "", true),
+		NewTool("shell").AddParameter("command", "string", "", true),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		withHistory,
+		WithToolCache(ToolCacheConfig{}),
+		WithCacheableTools(0, "read_file"),
+		WithAudit(recorder.config()),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed[functionName]++
+			return "content of " + functionName, nil
+		}),
+	)
+
+	detect := func() {
+		t.Helper()
+		if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "read"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	detect()
+	if executed["read_file"] != 1 || executed["shell"] != 2 {
+		t.Errorf("only the cacheable tool should be memoized, got %v", executed)
+	}
+	if !recorder.events[1].Cached || recorder.events[0].Cached || recorder.events[1].Result != "content of read_file" {
+		t.Errorf("the cache hit should be audited, got %+v", recorder.events[:2])
+	}
+
+	// Same session: still cached
+	detect()
+	if executed["read_file"] != 1 {
+		t.Errorf("the result should be cached for the session, got %v", executed)
+	}
+
+	// New session
+	agent.ResetMessages()
+	detect()
+	if executed["read_file"] != 2 {
+		t.Errorf("a new session should execute the tool again, got %v", executed)
+	}
+}
+
+func TestToolCache_ScopesAndInvalidation(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+
+	executed := 0
+	agent := newToolsAgent(t, engine.URL, nil,
+		WithToolCache(ToolCacheConfig{Scope: ToolCacheCall}),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed++
+			return "ok", nil
+		}),
+	)
+	agent.RegisterTools(Register("get_weather", "Get the weather",
+		func(ctx context.Context, input struct {
+			City string `json:"city"`
+		}) (string, error) {
+			executed++
+			return "sunny", nil
+		}))
+	agent.RegisterTools(&RegisteredTool{
+		Tool:    NewTool("lookup").AddParameter("key", "string", "", true).SetCacheable(0),
+		Handler: func(ctx context.Context, arguments string) (string, error) { executed++; return "value", nil },
+	})
+	if !agent.internalAgent.IsCacheableTool("lookup") || agent.internalAgent.IsCacheableTool("get_weather") {
+		t.Fatal("only the tools flagged with SetCacheable should be cacheable")
+	}
+
+	run := func(arguments string) string {
+		t.Helper()
+		agent.internalAgent.beginToolCallsDetection()
+		result, err := agent.internalAgent.executeToolCall("lookup", arguments, "call_1", nil, audit.ConfirmationNotRequired)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.Content
+	}
+
+	agent.internalAgent.beginToolCallsDetection()
+	for range 2 {
+		if _, err := agent.internalAgent.executeToolCall("lookup", `{"key":"a"}`, "call_1", nil, audit.ConfirmationNotRequired); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if executed != 1 {
+		t.Errorf("the second call of the detection should be cached, executed %d times", executed)
+	}
+
+	if run(`{"key":"a"}`) != "value" || executed != 2 {
+		t.Errorf("a call scoped cache should not survive the detection, executed %d times", executed)
+	}
+
+	agent.internalAgent.SetToolCache(ToolCacheConfig{Scope: ToolCacheGlobal})
+	run(`{"key":"b"}`)
+	run(`{"key":"b"}`)
+	if executed != 3 {
+		t.Errorf("a global cache should survive the detections, executed %d times", executed)
+	}
+
+	if err := agent.InvalidateToolResult("lookup", `{ "key": "b" }`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	run(`{"key":"b"}`)
+	agent.InvalidateTool("lookup")
+	run(`{"key":"b"}`)
+	agent.ClearToolCache()
+	run(`{"key":"b"}`)
+	run(`{"key":"b"}`)
+	if executed != 6 {
+		t.Errorf("each invalidation should execute the tool again, executed %d times", executed)
+	}
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/budget"
+	"github.com/snipwise/nova/nova-sdk/messages"
+)
+
+// NestedCall is the trace of a sub-agent invoked by a registered tool (see the agenttool package)
+type NestedCall struct {
+	Tool     string
+	Agent    string
+	Kind     agents.Kind
+	Input    string
+	Output   string
+	Error    string
+	Messages []messages.Message // Messages exchanged with the sub-agent during the call (none for a search)
+	Usage    budget.Usage       // Usage of the sub-agent during the call
+	Duration time.Duration
+}
+
+type callingAgentKey struct{}
+
+// withCallingAgent returns the context given to the registered handlers, carrying the agent
+// executing the tool call
+func withCallingAgent(ctx context.Context, agent *BaseAgent) context.Context {
+	return context.WithValue(ctx, callingAgentKey{}, agent)
+}
+
+// RecordNestedCall attaches the trace of a sub-agent call to the agent executing the tool:
+// the usage is added to its budgets and the trace to its nested calls.
+// ctx is the context received by the tool handler; it reports false when the handler was not
+// called by a tools agent.
+func RecordNestedCall(ctx context.Context, call NestedCall) bool {
+	agent, found := ctx.Value(callingAgentKey{}).(*BaseAgent)
+	if !found {
+		return false
+	}
+	agent.AddUsage(call.Usage)
+	agent.stateMutex.Lock()
+	defer agent.stateMutex.Unlock()
+	agent.nestedCalls = append(agent.nestedCalls, call)
+	return true
+}
+
+// GetNestedCalls returns the traces of the sub-agents called during the last tool calls detection
+func (agent *BaseAgent) GetNestedCalls() []NestedCall {
+	agent.stateMutex.Lock()
+	defer agent.stateMutex.Unlock()
+	return slices.Clone(agent.nestedCalls)
+}
+
+// GetNestedCalls returns the traces of the sub-agents called during the last tool calls detection
+func (agent *Agent) GetNestedCalls() []NestedCall {
+	return agent.internalAgent.GetNestedCalls()
+}
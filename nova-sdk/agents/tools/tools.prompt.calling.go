@@ -0,0 +1,519 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// PromptToolFormat is the format of the tool calls a model emits in prompt-based tool calling
+type PromptToolFormat string
+
+const (
+	// PromptToolFormatJSON: the model answers with {"tool_calls": [{"name": ..., "arguments": {...}}]}
+	PromptToolFormatJSON PromptToolFormat = "json"
+	// PromptToolFormatXML: the model answers with one <tool_call>{"name": ..., "arguments": {...}}</tool_call>
+	// tag per call (the format of the Hermes and Qwen chat templates)
+	PromptToolFormatXML PromptToolFormat = "xml"
+)
+
+// PromptToolCalling emulates function calling for the models without native support of the
+// tools parameter: the tools are described in the system prompt, the model is asked to answer
+// with a tool call in a strict format, and the answer is parsed back into tool calls.
+// The tool calls and results of the history are rendered as text messages.
+type PromptToolCalling struct {
+	format PromptToolFormat
+}
+
+// NewPromptToolCalling creates a prompt-based tool calling (PromptToolFormatJSON by default)
+func NewPromptToolCalling(format PromptToolFormat) *PromptToolCalling {
+	if format != PromptToolFormatXML {
+		format = PromptToolFormatJSON
+	}
+	return &PromptToolCalling{format: format}
+}
+
+// Format returns the format of the tool calls
+func (calling *PromptToolCalling) Format() PromptToolFormat {
+	return calling.format
+}
+
+// WithPromptToolCalling replaces native function calling with prompt-based tool calling
+// (see PromptToolCalling), for the models ignoring the tools parameter.
+// The loops, confirmations and parallel calls work the same way.
+func WithPromptToolCalling(format PromptToolFormat) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetPromptToolCalling(format)
+	}
+}
+
+// SetPromptToolCalling enables prompt-based tool calling ("" restores native function calling)
+func (agent *BaseAgent) SetPromptToolCalling(format PromptToolFormat) {
+	if format == "" {
+		agent.promptToolCalling = nil
+		return
+	}
+	agent.promptToolCalling = NewPromptToolCalling(format)
+}
+
+// GetPromptToolCalling returns the prompt-based tool calling of the agent (nil: native function calling)
+func (agent *BaseAgent) GetPromptToolCalling() *PromptToolCalling {
+	return agent.promptToolCalling
+}
+
+// SetPromptToolCalling enables prompt-based tool calling ("" restores native function calling)
+func (agent *Agent) SetPromptToolCalling(format PromptToolFormat) {
+	agent.internalAgent.SetPromptToolCalling(format)
+}
+
+// GetPromptToolCalling returns the prompt-based tool calling of the agent (nil: native function calling)
+func (agent *Agent) GetPromptToolCalling() *PromptToolCalling {
+	return agent.internalAgent.GetPromptToolCalling()
+}
+
+// newToolCallsCompletion makes a request of a tool calls detection, natively or with
+// prompt-based tool calling (the request is canceled at the loop deadline)
+func (agent *BaseAgent) newToolCallsCompletion(params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	ctx, cancel := agent.requestContext()
+	defer cancel()
+	if agent.promptToolCalling == nil {
+		return agent.NewChatCompletionWithContext(ctx, params)
+	}
+	completion, err := agent.NewChatCompletionWithContext(ctx, agent.promptToolCalling.PrepareRequest(params))
+	if err != nil {
+		return completion, err
+	}
+	if agent.promptToolCalling.ParseCompletion(completion, params.Tools) {
+		agent.Log.Info("🧩 Tool calls parsed from the response (%s format)", agent.promptToolCalling.format)
+	}
+	return completion, nil
+}
+
+// PrepareRequest returns the request without the tools parameter: the tools are described in
+// the system prompt, and the tool calls and results of the messages are rendered as text
+func (calling *PromptToolCalling) PrepareRequest(params openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+	if len(params.Tools) == 0 {
+		return params
+	}
+	instructions := calling.instructions(params.Tools)
+
+	toolNames := make(map[string]string)
+	prepared := make([]openai.ChatCompletionMessageParamUnion, 0, len(params.Messages)+1)
+	for index, message := range params.Messages {
+		switch {
+		case index == 0 && message.OfSystem != nil:
+			system := textContent(message.OfSystem.Content.OfString.Value, message.OfSystem.Content.OfArrayOfContentParts)
+			prepared = append(prepared, openai.SystemMessage(system+"\n\n"+instructions))
+		case message.OfAssistant != nil && len(message.OfAssistant.ToolCalls) > 0:
+			calls := make([]promptToolCall, 0, len(message.OfAssistant.ToolCalls))
+			for _, toolCall := range message.OfAssistant.ToolCalls {
+				if toolCall.OfFunction == nil {
+					continue
+				}
+				toolNames[toolCall.OfFunction.ID] = toolCall.OfFunction.Function.Name
+				calls = append(calls, promptToolCall{
+					Name:      toolCall.OfFunction.Function.Name,
+					Arguments: json.RawMessage(validJSONOrEmpty(toolCall.OfFunction.Function.Arguments)),
+				})
+			}
+			content := strings.TrimSpace(assistantTextContent(message.OfAssistant.Content) + "\n" + calling.render(calls))
+			prepared = append(prepared, openai.AssistantMessage(content))
+		case message.OfTool != nil:
+			result := textContent(message.OfTool.Content.OfString.Value, message.OfTool.Content.OfArrayOfContentParts)
+			prepared = append(prepared, openai.UserMessage(calling.renderResult(toolNames[message.OfTool.ToolCallID], result)))
+		default:
+			prepared = append(prepared, message)
+		}
+	}
+	if len(params.Messages) == 0 || params.Messages[0].OfSystem == nil {
+		prepared = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(instructions)}, prepared...)
+	}
+
+	params.Messages = prepared
+	params.Tools = nil
+	params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+	params.ParallelToolCalls = param.Opt[bool]{}
+	return params
+}
+
+// ParseCompletion parses the tool calls of the first choice of a completion: when the content
+// holds calls of the declared tools, they become the tool calls of the message (with the
+// "tool_calls" finish reason) and are removed from the content. It reports whether calls were found.
+func (calling *PromptToolCalling) ParseCompletion(completion *openai.ChatCompletion, tools []openai.ChatCompletionToolUnionParam) bool {
+	if completion == nil || len(completion.Choices) == 0 {
+		return false
+	}
+	message := &completion.Choices[0].Message
+	text, calls := calling.ParseToolCalls(message.Content, tools)
+	if len(calls) == 0 {
+		return false
+	}
+	message.Content = text
+	message.ToolCalls = calls
+	completion.Choices[0].FinishReason = finishReasonToolCalls
+	return true
+}
+
+// ParseToolCalls extracts the calls of the declared tools from a model answer (in either
+// format, repairing malformed JSON). It returns the remaining text and the calls.
+func (calling *PromptToolCalling) ParseToolCalls(content string, tools []openai.ChatCompletionToolUnionParam) (string, []openai.ChatCompletionMessageToolCallUnion) {
+	declared := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		if function := tool.GetFunction(); function != nil {
+			declared[function.Name] = true
+		}
+	}
+
+	var parsed []promptToolCall
+	text := content
+	if tags := toolCallTagExpression.FindAllStringSubmatchIndex(content, -1); len(tags) > 0 {
+		// <tool_call>...</tool_call> tags (the closing tag may be missing at the end)
+		var remaining strings.Builder
+		previous := 0
+		for _, tag := range tags {
+			remaining.WriteString(content[previous:tag[0]])
+			previous = tag[1]
+			if value, err := repairJSON(content[tag[2]:tag[3]]); err == nil {
+				parsed = append(parsed, promptToolCallsOf(value)...)
+			}
+		}
+		remaining.WriteString(content[previous:])
+		text = remaining.String()
+	} else if start, end, value, found := findJSONValue(content); found {
+		parsed = promptToolCallsOf(value)
+		text = content[:start] + content[end:]
+	}
+
+	var calls []openai.ChatCompletionMessageToolCallUnion
+	for _, call := range parsed {
+		if !declared[call.Name] {
+			continue
+		}
+		calls = append(calls, openai.ChatCompletionMessageToolCallUnion{
+			ID:   newPromptToolCallID(),
+			Type: "function",
+			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
+				Name:      call.Name,
+				Arguments: string(call.Arguments),
+			},
+		})
+	}
+	if len(calls) == 0 {
+		return content, nil
+	}
+	return strings.TrimSpace(text), calls
+}
+
+// promptToolCall is a tool call as written by the model
+type promptToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+var (
+	toolCallTagExpression = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+	codeFenceExpression   = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)\\s*(?:```|$)")
+)
+
+// instructions describes the tools and the expected format of the calls
+func (calling *PromptToolCalling) instructions(tools []openai.ChatCompletionToolUnionParam) string {
+	var builder strings.Builder
+	builder.WriteString("# Tools\n\nYou can call the following tools:\n\n")
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		description, _ := json.Marshal(map[string]any{
+			"name":        function.Name,
+			"description": function.Description.Value,
+			"parameters":  function.Parameters,
+		})
+		builder.Write(description)
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("\nTo call tools, answer ONLY with the calls, in this exact format:\n")
+	example := []promptToolCall{{Name: "<tool name>", Arguments: json.RawMessage(`{"<parameter>": "<value>"}`)}}
+	builder.WriteString(calling.render(example))
+	if calling.format == PromptToolFormatXML {
+		builder.WriteString("\n\nUse one <tool_call> tag per call; you can make several calls at once.")
+	} else {
+		builder.WriteString("\n\nYou can make several calls at once by adding them to the tool_calls list.")
+	}
+	builder.WriteString(" The arguments must be valid JSON matching the parameters of the tool." +
+		" The results of the calls will be sent to you in the next messages." +
+		" When no tool is needed, answer normally, without tool calls.")
+	return builder.String()
+}
+
+// render writes tool calls in the format
+func (calling *PromptToolCalling) render(calls []promptToolCall) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	if calling.format == PromptToolFormatXML {
+		rendered := make([]string, 0, len(calls))
+		for _, call := range calls {
+			encoded, _ := json.Marshal(call)
+			rendered = append(rendered, "<tool_call>"+string(encoded)+"</tool_call>")
+		}
+		return strings.Join(rendered, "\n")
+	}
+	encoded, _ := json.Marshal(map[string]any{"tool_calls": calls})
+	return string(encoded)
+}
+
+// renderResult writes the result of a tool call, sent back as a user message
+func (calling *PromptToolCalling) renderResult(toolName string, content string) string {
+	if calling.format == PromptToolFormatXML {
+		return fmt.Sprintf("<tool_response name=%q>\n%s\n</tool_response>", toolName, content)
+	}
+	return fmt.Sprintf("Result of the tool %s:\n%s", toolName, content)
+}
+
+// findJSONValue finds the JSON value of an answer: the content of a code fence, or the text
+// starting at the first { or [. It returns its bounds in the content.
+func findJSONValue(content string) (int, int, any, bool) {
+	if fence := codeFenceExpression.FindStringSubmatchIndex(content); fence != nil {
+		if value, err := repairJSON(content[fence[2]:fence[3]]); err == nil {
+			return fence[0], fence[1], value, true
+		}
+	}
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return 0, 0, nil, false
+	}
+	value, err := repairJSON(content[start:])
+	if err != nil {
+		return 0, 0, nil, false
+	}
+	return start, len(content), value, true
+}
+
+// promptToolCallsOf reads the calls of a decoded value:
+// {"tool_calls": [...]}, {"name": ..., "arguments": ...}, {"function": {...}} or a list of calls
+func promptToolCallsOf(value any) []promptToolCall {
+	switch typed := value.(type) {
+	case []any:
+		var calls []promptToolCall
+		for _, item := range typed {
+			calls = append(calls, promptToolCallsOf(item)...)
+		}
+		return calls
+	case map[string]any:
+		if list, found := typed["tool_calls"]; found {
+			return promptToolCallsOf(list)
+		}
+		if function, found := typed["function"].(map[string]any); found {
+			return promptToolCallsOf(function)
+		}
+		name, _ := typed["name"].(string)
+		if name == "" {
+			return nil
+		}
+		arguments, found := typed["arguments"]
+		if !found {
+			arguments = typed["parameters"]
+		}
+		return []promptToolCall{{Name: name, Arguments: argumentsJSON(arguments)}}
+	default:
+		return nil
+	}
+}
+
+// argumentsJSON encodes the arguments of a call (an object, or an object encoded as a string)
+func argumentsJSON(arguments any) json.RawMessage {
+	switch typed := arguments.(type) {
+	case nil:
+		return json.RawMessage("{}")
+	case string:
+		if value, err := repairJSON(typed); err == nil {
+			arguments = value
+		} else {
+			return json.RawMessage(jsonString(typed))
+		}
+	}
+	encoded, err := json.Marshal(arguments)
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return encoded
+}
+
+func jsonString(text string) string {
+	encoded, _ := json.Marshal(text)
+	return string(encoded)
+}
+
+// textContent returns the text of a message content: the string, or the text parts joined by new lines
+func textContent(text string, parts []openai.ChatCompletionContentPartTextParam) string {
+	if len(parts) == 0 {
+		return text
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// assistantTextContent returns the text of an assistant message content (the refusals are skipped)
+func assistantTextContent(content openai.ChatCompletionAssistantMessageParamContentUnion) string {
+	parts := make([]openai.ChatCompletionContentPartTextParam, 0, len(content.OfArrayOfContentParts))
+	for _, part := range content.OfArrayOfContentParts {
+		if part.OfText != nil {
+			parts = append(parts, *part.OfText)
+		}
+	}
+	return textContent(content.OfString.Value, parts)
+}
+
+// validJSONOrEmpty returns the arguments, or {} when they are not valid JSON
+func validJSONOrEmpty(arguments string) string {
+	if json.Valid([]byte(arguments)) {
+		return arguments
+	}
+	return "{}"
+}
+
+var (
+	trailingCommaExpression = regexp.MustCompile(`,\s*([}\]])`)
+	pythonLiteralExpression = regexp.MustCompile(`\b(True|False|None)\b`)
+)
+
+// repairJSON decodes the first JSON value of a text, repairing the usual mistakes of the
+// models: text after the value, smart or single quotes, trailing commas, Python literals,
+// and missing closing quotes, brackets or braces
+func repairJSON(text string) (any, error) {
+	if value, err := decodeFirstJSONValue(text); err == nil {
+		return value, nil
+	}
+
+	repaired := strings.NewReplacer("“", `"`, "”", `"`, "‘", "'", "’", "'").Replace(text)
+	if !strings.Contains(repaired, `"`) {
+		repaired = strings.ReplaceAll(repaired, "'", `"`)
+	}
+	repaired = pythonLiteralExpression.ReplaceAllStringFunc(repaired, func(literal string) string {
+		return map[string]string{"True": "true", "False": "false", "None": "null"}[literal]
+	})
+	repaired = trailingCommaExpression.ReplaceAllString(closeJSON(repaired), "$1")
+	return decodeFirstJSONValue(repaired)
+}
+
+func decodeFirstJSONValue(text string) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(text))))
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// closeJSON closes the string, arrays and objects left open at the end of a JSON text
+// (e.g. an answer cut by the maximum number of tokens)
+func closeJSON(text string) string {
+	var open []byte
+	inString, escaped := false, false
+	for index := 0; index < len(text); index++ {
+		character := text[index]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && character == '\\':
+			escaped = true
+		case character == '"':
+			inString = !inString
+		case inString:
+		case character == '{' || character == '[':
+			open = append(open, character)
+		case (character == '}' || character == ']') && len(open) > 0:
+			open = open[:len(open)-1]
+			if len(open) == 0 {
+				// The first value is complete
+				return text
+			}
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(strings.TrimRight(text, " \t\r\n"))
+	if inString {
+		builder.WriteByte('"')
+	}
+	for index := len(open) - 1; index >= 0; index-- {
+		if open[index] == '{' {
+			builder.WriteByte('}')
+		} else {
+			builder.WriteByte(']')
+		}
+	}
+	return builder.String()
+}
+
+// newPromptToolCallID returns an identifier for a parsed tool call
+func newPromptToolCallID() string {
+	buffer := make([]byte, 12)
+	_, _ = rand.Read(buffer)
+	return "call_" + hex.EncodeToString(buffer)
+}
+
+// promptStreamFilter holds back the streamed text that starts like a tool call, so the
+// callback only receives the answers
+type promptStreamFilter struct {
+	callback func(content string) error
+	held     strings.Builder
+	// 0: undecided, 1: plain answer (passed through), 2: tool call (held back)
+	state int
+}
+
+func (filter *promptStreamFilter) write(content string) error {
+	switch filter.state {
+	case 1:
+		return filter.callback(content)
+	case 2:
+		filter.held.WriteString(content)
+		return nil
+	}
+	filter.held.WriteString(content)
+	trimmed := strings.TrimLeft(filter.held.String(), " \t\r\n")
+	if trimmed == "" {
+		return nil
+	}
+	for _, opening := range []string{"<tool_call>", "```", "{", "["} {
+		if strings.HasPrefix(trimmed, opening) {
+			filter.state = 2
+			return nil
+		}
+		if strings.HasPrefix(opening, trimmed) {
+			// Could still become a tool call
+			return nil
+		}
+	}
+	filter.state = 1
+	return filter.flush()
+}
+
+// finish sends the held back text when the answer had no tool calls
+func (filter *promptStreamFilter) finish(hasToolCalls bool) error {
+	if hasToolCalls {
+		return nil
+	}
+	return filter.flush()
+}
+
+func (filter *promptStreamFilter) flush() error {
+	if filter.held.Len() == 0 {
+		return nil
+	}
+	content := filter.held.String()
+	filter.held.Reset()
+	return filter.callback(content)
+}
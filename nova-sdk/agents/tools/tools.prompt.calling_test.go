@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func TestPromptToolCalling_ParseToolCalls(t *testing.T) {
+	declared := ToOpenAITools([]*Tool{
+		NewTool("read_file").AddParameter("path", "string", "", true),
+		NewTool("shell").AddParameter("command", "string", "", true),
+	})
+	calling := NewPromptToolCalling(PromptToolFormatJSON)
+
+	tests := []struct {
+		name      string
+		content   string
+		text      string
+		calls     []string
+		arguments string
+	}{
+		{"wrapper", `{"tool_calls": [{"name": "read_file", "arguments": {"path": "a.txt"}}]}`, "", []string{"read_file"}, `{"path":"a.txt"}`},
+		{"single call after text", `Let me read it. {"name": "read_file", "arguments": {"path": "a.txt"}}`, "Let me read it.", []string{"read_file"}, `{"path":"a.txt"}`},
+		{"code fence", "```json\n[{\"name\": \"shell\", \"arguments\": \"{\\\"command\\\": \\\"ls\\\"}\"}]\n```", "", []string{"shell"}, `{"command":"ls"}`},
+		{"xml tags", "<tool_call>{\"name\": \"read_file\", \"arguments\": {\"path\": \"a\"}}</tool_call>\n<tool_call>{\"name\": \"shell\", \"arguments\": {\"command\": \"ls\"}}", "", []string{"read_file", "shell"}, `{"path":"a"}`},
+		{"trailing comma and missing braces", `{"tool_calls": [{"name": "read_file", "arguments": {"path": "a.txt",}`, "", []string{"read_file"}, `{"path":"a.txt"}`},
+		{"single quotes", `{'name': 'shell', 'arguments': {'command': 'pwd'}}`, "", []string{"shell"}, `{"command":"pwd"}`},
+		{"unknown tool", `{"name": "format_disk", "arguments": {}}`, `{"name": "format_disk", "arguments": {}}`, nil, ""},
+		{"plain answer", "The answer is [42].", "The answer is [42].", nil, ""},
+	}
+	for _, test := range tests {
+		text, calls := calling.ParseToolCalls(test.content, declared)
+		if text != test.text {
+			t.Errorf("%s: expected the text %q, got %q", test.name, test.text, text)
+		}
+		if len(calls) != len(test.calls) {
+			t.Errorf("%s: expected the calls %v, got %+v", test.name, test.calls, calls)
+			continue
+		}
+		for index, call := range calls {
+			if call.Function.Name != test.calls[index] || call.ID == "" {
+				t.Errorf("%s: unexpected call %+v", test.name, call)
+			}
+		}
+		if len(calls) > 0 && calls[0].Function.Arguments != test.arguments {
+			t.Errorf("%s: expected the arguments %s, got %s", test.name, test.arguments, calls[0].Function.Arguments)
+		}
+	}
+}
+
+func TestPromptToolCalling_PrepareRequestKeepsTheContentParts(t *testing.T) {
+	calling := NewPromptToolCalling(PromptToolFormatJSON)
+	toolCall := openai.ChatCompletionMessageToolCallUnionParam{OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+		ID:       "call_1",
+		Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "read_file", Arguments: `{"path":"a.txt"}`},
+	}}
+	prepared := calling.PrepareRequest(openai.ChatCompletionNewParams{
+		Tools: ToOpenAITools([]*Tool{NewTool("read_file").AddParameter("path", "string", "", true)}),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage([]openai.ChatCompletionContentPartTextParam{{Text: "You are"}, {Text: "a reader"}}),
+			openai.UserMessage("read a.txt"),
+			{OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+				Content: openai.ChatCompletionAssistantMessageParamContentUnion{
+					OfArrayOfContentParts: []openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
+						{OfText: &openai.ChatCompletionContentPartTextParam{Text: "Reading it."}},
+					},
+				},
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{toolCall},
+			}},
+			openai.ToolMessage([]openai.ChatCompletionContentPartTextParam{{Text: "first line"}, {Text: "second line"}}, "call_1"),
+		},
+	})
+
+	for index, expected := range map[int]string{
+		0: "You are\na reader",
+		2: "Reading it.",
+		3: "first line\nsecond line",
+	} {
+		converted := messages.ConvertFromOpenAIMessages(prepared.Messages[index : index+1])
+		if len(converted) != 1 || !strings.Contains(converted[0].Content, expected) {
+			t.Errorf("message %d: expected the content %q, got %+v", index, expected, converted)
+		}
+	}
+}
+
+func TestPromptToolCalling_Loop(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("The file says hello")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.Text(`<tool_call>{"name": "read_file", "arguments": {"path": "notes.txt"}}</tool_call>`))
+
+	var executed string
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("read_file").AddParameter("path", "string", "", true)},
+		withHistory,
+		WithPromptToolCalling(PromptToolFormatXML),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			executed = functionName + " " + arguments
+			return "hello", nil
+		}),
+	)
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "read notes.txt"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if executed != `read_file {"path":"notes.txt"}` || result.LastAssistantMessage != "The file says hello" {
+		t.Errorf("unexpected execution %q, result %+v", executed, result)
+	}
+
+	first := engine.ChatRequests()[0]
+	if len(first.Tools) != 0 || !strings.Contains(first.Messages[0].Content, `"name":"read_file"`) {
+		t.Errorf("the tools should be described in the system prompt, not sent as parameters: %+v", first)
+	}
+	second := engine.ChatRequests()[1].Messages
+	call, response := second[len(second)-2], second[len(second)-1]
+	if call.Role != "assistant" || len(call.ToolCalls) != 0 || !strings.Contains(call.Content, "<tool_call>") {
+		t.Errorf("the tool call should be rendered as text, got %+v", call)
+	}
+	if response.Role != "user" || !strings.Contains(response.Content, "<tool_response name=\"read_file\">\nhello") {
+		t.Errorf("the tool result should be sent as a user message, got %+v", response)
+	}
+}
+
+func TestPromptToolCalling_StreamHoldsBackTheToolCalls(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("Done.")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.Text(`{"tool_calls": [{"name": "shell", "arguments": {"command": "ls"}}]}`))
+	engine.When(novatest.CallIndex(1)).Reply(novatest.Text(`{"tool_calls": [{"name": "shell", "arguments": {"command": "ls"}}]}`))
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("shell").AddParameter("command", "string", "", true)},
+		withHistory,
+		WithPromptToolCalling(PromptToolFormatJSON),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "a.txt", nil }),
+	)
+
+	var streamed strings.Builder
+	_, err := agent.DetectToolCallsLoopStream(
+		[]messages.Message{{Role: roles.User, Content: "list the files"}},
+		func(content string) error {
+			streamed.WriteString(content)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamed.String() != "Done." {
+		t.Errorf("only the answer should be streamed, got %q", streamed.String())
+	}
+}
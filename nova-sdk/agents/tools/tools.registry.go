@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ToolHandler executes a registered tool: it receives the JSON arguments of the call
+// and returns the tool result sent back to the model
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// RegisteredTool is a tool definition bound to its Go handler
+type RegisteredTool struct {
+	Tool    *Tool
+	Handler ToolHandler
+}
+
+// Register creates a tool whose parameters are reflected from the In struct and whose
+// calls are dispatched to handler. The arguments are decoded into In and the Out value
+// is encoded to JSON (a string Out is returned as is).
+//
+// Parameters come from the exported fields of In:
+//   - the name is the json tag (fields with `json:"-"` are skipped)
+//   - the description is the `description` tag, the allowed values the `enum` tag (comma-separated)
+//   - slices and nested structs are described with items and properties
+//   - a field is required unless its json tag has omitempty
+//
+// Usage:
+//
+//	type WeatherInput struct {
+//		City string `json:"city" description:"Name of the city"`
+//	}
+//	weather := tools.Register("get_weather", "Get the weather of a city",
+//		func(ctx context.Context, input WeatherInput) (string, error) {
+//			return "sunny in " + input.City, nil
+//		})
+//	agent, err := tools.NewAgent(ctx, agentConfig, modelConfig, tools.WithRegisteredTools(weather))
+func Register[In any, Out any](
+	name string,
+	description string,
+	handler func(ctx context.Context, input In) (Out, error),
+) *RegisteredTool {
+	tool := NewTool(name).SetDescription(description)
+	addStructParameters(tool, reflect.TypeOf((*In)(nil)).Elem())
+
+	return &RegisteredTool{
+		Tool: tool,
+		Handler: func(ctx context.Context, arguments string) (string, error) {
+			var input In
+			if strings.TrimSpace(arguments) != "" {
+				if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+					return "", fmt.Errorf("invalid arguments for tool %s: %w", name, err)
+				}
+			}
+
+			output, err := handler(ctx, input)
+			if err != nil {
+				return "", err
+			}
+
+			if text, ok := any(output).(string); ok {
+				return text, nil
+			}
+			result, err := json.Marshal(output)
+			if err != nil {
+				return "", fmt.Errorf("unable to encode the result of tool %s: %w", name, err)
+			}
+			return string(result), nil
+		},
+	}
+}
+
+// addStructParameters adds the exported fields of a struct type as tool parameters
+func addStructParameters(tool *Tool, t reflect.Type) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	object := structParameter(t)
+	for name, property := range object.Properties {
+		tool.Parameters[name] = property
+	}
+	tool.Required = append(tool.Required, object.Required...)
+}
+
+// structParameter reflects the exported fields of a struct type into an object schema:
+// the json tag gives the name, the description and enum (comma-separated) tags the hints,
+// and a field is required unless its json tag has omitempty
+func structParameter(t reflect.Type) Parameter {
+	object := Parameter{Type: "object", Properties: map[string]Parameter{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := structParameter(field.Type)
+			maps.Copy(object.Properties, embedded.Properties)
+			object.Required = append(object.Required, embedded.Required...)
+			continue
+		}
+
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		fieldName, options, _ := strings.Cut(jsonTag, ",")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+
+		property := typeParameter(field.Type)
+		property.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, value := range strings.Split(enum, ",") {
+				property.Enum = append(property.Enum, strings.TrimSpace(value))
+			}
+		}
+		object.Properties[fieldName] = property
+		if !strings.Contains(options, "omitempty") {
+			object.Required = append(object.Required, fieldName)
+		}
+	}
+	return object
+}
+
+// typeParameter returns the JSON Schema of a Go type
+func typeParameter(t reflect.Type) Parameter {
+	switch t.Kind() {
+	case reflect.String:
+		return Parameter{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Parameter{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Parameter{Type: "number"}
+	case reflect.Bool:
+		return Parameter{Type: "boolean"}
+	case reflect.Slice, reflect.Array:
+		return Parameter{Type: "array"}.WithItems(typeParameter(t.Elem()))
+	case reflect.Struct:
+		return structParameter(t)
+	case reflect.Map:
+		return Parameter{Type: "object"}
+	case reflect.Ptr:
+		return typeParameter(t.Elem())
+	default:
+		return Parameter{Type: "string"}
+	}
+}
+
+// WithRegisteredTools adds typed tools to the agent: their definitions are appended to the
+// tools sent to the model and their calls are dispatched to their handlers.
+// They can be combined with WithTools, WithMCPTools and WithExecuteFn (the execute
+// callback receives the calls of the tools without a registered handler).
+func WithRegisteredTools(registeredTools ...*RegisteredTool) ToolsAgentOption {
+	return func(a *Agent) {
+		a.RegisterTools(registeredTools...)
+	}
+}
+
+// RegisterTools adds typed tools to the agent (a tool with the same name is replaced)
+func (agent *Agent) RegisterTools(registeredTools ...*RegisteredTool) {
+	params := &agent.internalAgent.ChatCompletionParams
+	for _, registeredTool := range registeredTools {
+		name := registeredTool.Tool.GetName()
+		if _, found := agent.toolsFunctions[name]; found {
+			for index, existing := range params.Tools {
+				if function := existing.GetFunction(); function != nil && function.Name == name {
+					params.Tools = append(params.Tools[:index], params.Tools[index+1:]...)
+					break
+				}
+			}
+			agent.internalAgent.registeredTools = slices.DeleteFunc(agent.internalAgent.registeredTools, func(existing *RegisteredTool) bool {
+				return existing.Tool.GetName() == name
+			})
+		}
+		params.Tools = append(params.Tools, registeredTool.Tool.ToOpenAI())
+		agent.toolsFunctions[name] = registeredTool.Handler
+		agent.internalAgent.registeredTools = append(agent.internalAgent.registeredTools, registeredTool)
+		agent.applyToolSettings(registeredTool.Tool)
+	}
+}
+
+// applyToolSettings applies the SideEffects and Cacheable settings of a tool
+func (agent *Agent) applyToolSettings(tool *Tool) {
+	if tool.SideEffects {
+		agent.internalAgent.SetSideEffectingTools(tool.GetName())
+	}
+	if tool.Cacheable {
+		agent.internalAgent.SetCacheableTools(tool.CacheTTL, tool.GetName())
+	}
+}
+
+// GetRegisteredTools returns the typed tools of the agent and their handlers, in registration order
+func (agent *Agent) GetRegisteredTools() []*RegisteredTool {
+	return slices.Clone(agent.internalAgent.registeredTools)
+}
+
+// HasToolHandler reports whether a handler is registered for the tool
+func (agent *Agent) HasToolHandler(name string) bool {
+	_, found := agent.toolsFunctions[name]
+	return found
+}
+
+// withRegisteredHandlers returns the callback receiving the calls of the tools without a
+// registered handler (the registered handlers are called by the BaseAgent). It fails when
+// there is neither a registered tool nor a fallback.
+func (agent *Agent) withRegisteredHandlers(fallback ToolCallback) (ToolCallback, error) {
+	if fallback != nil {
+		return fallback, nil
+	}
+	if len(agent.toolsFunctions) == 0 {
+		return nil, errors.New(errNoToolCallback)
+	}
+	return func(functionName string, arguments string) (string, error) {
+		return "", fmt.Errorf("no handler registered for tool %s", functionName)
+	}, nil
+}
+
+// invokeTool calls the registered handler of the tool, or callback for the other tools
+func (agent *BaseAgent) invokeTool(
+	ctx context.Context,
+	functionName string,
+	functionArgs string,
+	toolCallBack func(string, string) (string, error),
+) (string, error) {
+	if handler, found := agent.toolHandlers[functionName]; found {
+		return handler(withCallingAgent(ctx, agent), functionArgs)
+	}
+	return toolCallBack(functionName, functionArgs)
+}
@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+type additionInput struct {
+	A    int    `json:"a" description:"First number"`
+	B    int    `json:"b" description:"Second number"`
+	Note string `json:"note,omitempty"`
+	skip bool
+}
+
+type additionOutput struct {
+	Sum int `json:"sum"`
+}
+
+func newAdditionTool() *RegisteredTool {
+	return Register("add", "Add two numbers",
+		func(ctx context.Context, input additionInput) (additionOutput, error) {
+			return additionOutput{Sum: input.A + input.B}, nil
+		})
+}
+
+func TestRegister_ReflectsParameters(t *testing.T) {
+	tool := newAdditionTool().Tool
+
+	if len(tool.GetParameters()) != 3 {
+		t.Fatalf("want 3 parameters, got %+v", tool.GetParameters())
+	}
+	if parameter := tool.GetParameters()["a"]; parameter.Type != "integer" || parameter.Description != "First number" {
+		t.Errorf("unexpected parameter %+v", parameter)
+	}
+	if required := tool.GetRequired(); len(required) != 2 || required[0] != "a" || required[1] != "b" {
+		t.Errorf("unexpected required parameters %v", required)
+	}
+}
+
+func TestRegister_DecodesArgumentsAndEncodesResult(t *testing.T) {
+	handler := newAdditionTool().Handler
+
+	result, err := handler(context.Background(), `{"a":2,"b":3}`)
+	if err != nil || result != `{"sum":5}` {
+		t.Errorf("unexpected result %q, %v", result, err)
+	}
+	if _, err := handler(context.Background(), `{"a":"two"}`); err == nil {
+		t.Error("invalid arguments should fail")
+	}
+
+	echo := Register("echo", "Echo a text", func(ctx context.Context, input struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return input.Text, nil
+	})
+	if result, _ := echo.Handler(context.Background(), `{"text":"hello"}`); result != "hello" {
+		t.Errorf("a string result should be returned as is, got %q", result)
+	}
+}
+
+func TestRegisteredTools_CoexistWithExecuteFn(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(
+		novatest.Call("add", `{"a":2,"b":3}`),
+		novatest.Call("legacy", `{}`),
+	))
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("legacy").SetDescription("legacy tool")},
+		WithRegisteredTools(newAdditionTool()),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			if functionName != "legacy" {
+				return "", errors.New("unexpected tool " + functionName)
+			}
+			return "legacy result", nil
+		}),
+	)
+	if len(agent.GetTools()) != 2 || !agent.HasToolHandler("add") {
+		t.Fatalf("unexpected tools %d", len(agent.GetTools()))
+	}
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "add 2 and 3"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Results) != 2 || result.Results[0] != `{"sum":5}` || result.Results[1] != "legacy result" {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestRegisteredTools_WithoutExecuteFn(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("add", `{"a":1,"b":1}`)))
+
+	agent := newToolsAgent(t, engine.URL, nil)
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "hi"}}); err == nil {
+		t.Error("without handler nor callback the detection should fail")
+	}
+
+	agent.RegisterTools(newAdditionTool())
+	agent.RegisterTools(newAdditionTool())
+	if len(agent.GetTools()) != 1 {
+		t.Errorf("registering a tool twice should replace it, got %d tools", len(agent.GetTools()))
+	}
+
+	result, err := agent.DetectParallelToolCalls([]messages.Message{{Role: roles.User, Content: "add 1 and 1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0] != `{"sum":2}` {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestSetMCPTools_KeepsRegisteredTools(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("search", `{"query":"go"}`)))
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("legacy").SetDescription("legacy tool")},
+		WithRegisteredTools(newAdditionTool()),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			return functionName + " result", nil
+		}),
+	)
+
+	agent.SetMCPTools([]mcp.Tool{mcp.NewTool("search", mcp.WithString("query", mcp.Required()))})
+
+	var names []string
+	for _, tool := range agent.GetTools() {
+		names = append(names, tool.GetFunction().Name)
+	}
+	if len(names) != 2 || names[0] != "search" || names[1] != "add" {
+		t.Fatalf("the MCP tools should replace the other tools but the registered ones, got %v", names)
+	}
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "search go"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0] != "search result" {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestWithToolSettings(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+
+	toolsIndex := []*Tool{
+		NewTool("read").SetCacheable(0),
+		NewTool("write").SetSideEffects(true),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		WithToolCache(ToolCacheConfig{}),
+		WithRegisteredTools(newAdditionTool()),
+		WithToolSettings(toolsIndex),
+	)
+	if len(agent.GetTools()) != 3 {
+		t.Errorf("the registered tools should be kept, got %d tools", len(agent.GetTools()))
+	}
+	if !agent.internalAgent.IsCacheableTool("read") || agent.internalAgent.IsCacheableTool("write") {
+		t.Error("the cacheable tools should be memoized")
+	}
+	if !agent.internalAgent.IsSideEffectingTool("write") || agent.internalAgent.IsSideEffectingTool("read") {
+		t.Error("the side-effecting tools should be serialized")
+	}
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultToolRetries is the number of times a tool call may fail (invalid arguments or
+// execution error) and be retried by the model before the loop exits
+const DefaultToolRetries = 2
+
+// FatalError marks a tool error that must end the tool calls loop without retry
+type FatalError struct {
+	Err error
+}
+
+func (err *FatalError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *FatalError) Unwrap() error {
+	return err.Err
+}
+
+// Fatal marks err as fatal: returned by a tool callback, it ends the loop with "exit_loop"
+// instead of being sent back to the model
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FatalError{Err: err}
+}
+
+// retryPolicy holds the retry counts and the consecutive failures of the tools
+type retryPolicy struct {
+	defaultRetries int
+	retries        map[string]int
+	failures       map[string]int
+}
+
+// WithToolRetries sets how many times a failing tool call is sent back to the model
+// before the loop exits (0: exit on the first failure, like the previous behaviour)
+func WithToolRetries(retries int) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.retryPolicy.defaultRetries = retries
+	}
+}
+
+// WithToolRetriesFor sets the retry count of one tool
+func WithToolRetriesFor(toolName string, retries int) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetToolRetries(toolName, retries)
+	}
+}
+
+// WithArgumentsValidation enables or disables the validation of the tool call arguments
+// against the tool schemas (enabled by default)
+func WithArgumentsValidation(enabled bool) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.skipArgumentsValidation = !enabled
+	}
+}
+
+// SetToolRetries sets the retry count of one tool
+func (agent *BaseAgent) SetToolRetries(toolName string, retries int) {
+	if agent.retryPolicy.retries == nil {
+		agent.retryPolicy.retries = make(map[string]int)
+	}
+	agent.retryPolicy.retries[toolName] = retries
+}
+
+// toolRetries returns the retry count of a tool
+func (agent *BaseAgent) toolRetries(toolName string) int {
+	if retries, found := agent.retryPolicy.retries[toolName]; found {
+		return retries
+	}
+	return agent.retryPolicy.defaultRetries
+}
+
+// resetToolFailures forgets the failures of the previous calls (called on entry of each detection)
+func (agent *BaseAgent) resetToolFailures() {
+	agent.retryPolicy.failures = make(map[string]int)
+}
+
+// validateToolArguments checks the arguments against the schema of the declared tool.
+// Tools not declared in the request parameters are not validated.
+func (agent *BaseAgent) validateToolArguments(functionName string, functionArgs string) error {
+	if agent.skipArgumentsValidation {
+		return nil
+	}
+	for _, tool := range agent.ChatCompletionParams.Tools {
+		if function := tool.GetFunction(); function != nil && function.Name == functionName {
+			return ValidateArguments(functionName, map[string]any(function.Parameters), functionArgs)
+		}
+	}
+	return nil
+}
+
+// toolErrorContent is the tool result sent back to the model when a call fails
+type toolErrorContent struct {
+	Error       string   `json:"error"`
+	Issues      []string `json:"issues,omitempty"`
+	RetriesLeft int      `json:"retries_left"`
+	Hint        string   `json:"hint,omitempty"`
+}
+
+// toolFailure builds the result of a failed tool call: the error goes back to the model
+// while the tool has retries left, the loop exits with "exit_loop" otherwise or when
+// the error is fatal
+func (agent *BaseAgent) toolFailure(functionName string, err error) ToolExecutionResult {
+	agent.stateMutex.Lock()
+	defer agent.stateMutex.Unlock()
+
+	if agent.retryPolicy.failures == nil {
+		agent.retryPolicy.failures = make(map[string]int)
+	}
+	agent.retryPolicy.failures[functionName]++
+	retriesLeft := agent.toolRetries(functionName) - agent.retryPolicy.failures[functionName]
+
+	content := toolErrorContent{
+		Error:       fmt.Sprintf("Function execution failed: %s", err.Error()),
+		RetriesLeft: retriesLeft,
+	}
+	var argumentsErr *ArgumentsError
+	if errors.As(err, &argumentsErr) {
+		content.Error = "Invalid arguments for " + functionName
+		content.Issues = argumentsErr.Issues
+		content.Hint = "Fix the arguments to match the tool parameters and call the tool again"
+	}
+
+	var fatalErr *FatalError
+	fatal := errors.As(err, &fatalErr)
+
+	result := ToolExecutionResult{ShouldStop: false, ExecFinishReason: "error"}
+	if fatal || retriesLeft < 0 || agent.loopDeadlineExceeded() {
+		content.RetriesLeft = 0
+		content.Hint = ""
+		result.ShouldStop = true
+		switch {
+		case agent.loopDeadlineExceeded():
+			result.ExecFinishReason = FinishReasonLoopTimeout
+		case !fatal && errors.Is(err, ErrToolTimeout):
+			result.ExecFinishReason = FinishReasonToolTimeout
+		default:
+			result.ExecFinishReason = "exit_loop"
+		}
+		agent.Log.Error("🔴 Giving up on function %s: %s", functionName, err.Error())
+	} else {
+		agent.Log.Warn("🔁 Function %s failed, sending the error back to the model (%d retries left): %s",
+			functionName, content.RetriesLeft, err.Error())
+	}
+
+	encoded, _ := json.Marshal(content)
+	result.Content = string(encoded)
+
+	// Store the last state of tool calls
+	agent.lastState = LastToolCallsState{
+		Confirmation:    Confirmed,
+		ExecutionResult: result,
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func divide(functionName string, arguments string) (string, error) {
+	if strings.Contains(arguments, `"b":0`) {
+		return "", errors.New("division by zero")
+	}
+	return "2", nil
+}
+
+func TestRetries_InvalidArgumentsAreSentBackToTheModel(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("the result is 2")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("divide", `{"a":"four","b":2}`)))
+	engine.When(novatest.CallIndex(1)).Reply(novatest.ToolCalls(novatest.Call("divide", `{"a":4,"b":2}`)))
+
+	calls := 0
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("divide").AddParameter("a", "number", "", true).AddParameter("b", "number", "", true)},
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			calls++
+			return divide(functionName, arguments)
+		}))
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "4 / 2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("invalid arguments should not reach the callback, got %d calls", calls)
+	}
+	if result.FinishReason != "stop" || len(result.Results) != 2 || result.Results[1] != "2" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	second := engine.ChatRequests()[1]
+	feedback := second.Messages[len(second.Messages)-1]
+	if feedback.Role != "tool" || !strings.Contains(feedback.Content, `arguments.a: expected number, got string`) ||
+		!strings.Contains(feedback.Content, `"retries_left":1`) {
+		t.Errorf("unexpected feedback %+v", feedback)
+	}
+}
+
+func TestRetries_ExitLoopWhenRetriesAreExhausted(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.ToolCalls(novatest.Call("divide", `{"a":4,"b":0}`))))
+	defer engine.Close()
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("divide").AddParameter("a", "number", "", true).AddParameter("b", "number", "", true)},
+		WithExecuteFn(divide), WithToolRetriesFor("divide", 1))
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "4 / 0"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinishReason != "exit_loop" || len(engine.ChatRequests()) != 2 {
+		t.Errorf("want exit_loop after 2 requests, got %q after %d", result.FinishReason, len(engine.ChatRequests()))
+	}
+	if len(result.Results) != 2 || !strings.Contains(result.Results[0], "division by zero") {
+		t.Errorf("unexpected results %v", result.Results)
+	}
+}
+
+func TestRetries_FatalErrorsExitImmediately(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.ToolCalls(novatest.Call("divide", `{"a":4,"b":2}`))))
+	defer engine.Close()
+
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("divide").AddParameter("a", "number", "", true).AddParameter("b", "number", "", true)},
+		WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			return "", Fatal(errors.New("calculator unavailable"))
+		}))
+
+	result, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "4 / 2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinishReason != "exit_loop" || len(engine.ChatRequests()) != 1 {
+		t.Errorf("want exit_loop after 1 request, got %q after %d", result.FinishReason, len(engine.ChatRequests()))
+	}
+}
+
+func TestRetries_ValidationCanBeDisabled(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("divide", `{"a":"four"}`)))
+
+	var received string
+	agent := newToolsAgent(t, engine.URL, []*Tool{NewTool("divide").AddParameter("a", "number", "", true).AddParameter("b", "number", "", true)},
+		WithArgumentsValidation(false), WithExecuteFn(func(functionName string, arguments string) (string, error) {
+			received = arguments
+			return "ok", nil
+		}))
+
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "4 / ?"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received != `{"a":"four"}` {
+		t.Errorf("the callback should receive the raw arguments, got %q", received)
+	}
+}
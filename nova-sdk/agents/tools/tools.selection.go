@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/snipwise/nova/nova-sdk/agents/rag/stores"
+	"github.com/snipwise/nova/nova-sdk/messages"
+)
+
+// ToolEmbedder generates the embeddings of the tool selection (a *rag.Agent satisfies it)
+type ToolEmbedder interface {
+	GenerateEmbedding(content string) ([]float64, error)
+}
+
+// toolSelection sends only the tools relevant to the request when the agent has many tools
+type toolSelection struct {
+	embedder ToolEmbedder
+	topK     int
+	// Tools sent with every request
+	pinned map[string]bool
+
+	mutex sync.Mutex
+	// Embeddings of the tools, keyed by name, with the text they were computed from
+	embeddings map[string]toolEmbedding
+	// Tools added to the selection during the current detection (called while not offered)
+	expanded map[string]bool
+	// Names of the tools sent with the last request
+	lastSelection []string
+}
+
+type toolEmbedding struct {
+	text   string
+	vector []float64
+}
+
+// WithToolSelection sends with each request only the topK tools whose name and description
+// are the most similar to the last user message, plus the pinned tools.
+// The tools are embedded once with embedder (e.g. a rag.Agent); the embeddings are refreshed
+// when a tool is added or its description changes. When the model calls a tool that was not
+// offered, the tool is added to the selection until the end of the detection.
+// All the tools are sent when there are no more than topK + pinned tools, or when the
+// embeddings fail.
+func WithToolSelection(embedder ToolEmbedder, topK int, pinnedTools ...string) ToolsAgentOption {
+	return func(a *Agent) {
+		a.internalAgent.SetToolSelection(embedder, topK, pinnedTools...)
+	}
+}
+
+// SetToolSelection enables the selection of the tools sent with each request
+// (see WithToolSelection). A nil embedder or a topK <= 0 disables it.
+func (agent *BaseAgent) SetToolSelection(embedder ToolEmbedder, topK int, pinnedTools ...string) {
+	if embedder == nil || topK <= 0 {
+		agent.toolSelection = nil
+		return
+	}
+	pinned := make(map[string]bool, len(pinnedTools))
+	for _, name := range pinnedTools {
+		pinned[name] = true
+	}
+	agent.toolSelection = &toolSelection{
+		embedder:   embedder,
+		topK:       topK,
+		pinned:     pinned,
+		embeddings: make(map[string]toolEmbedding),
+		expanded:   make(map[string]bool),
+	}
+}
+
+// GetLastToolSelection returns the names of the tools sent with the last request
+// (nil when the selection is disabled)
+func (agent *BaseAgent) GetLastToolSelection() []string {
+	if agent.toolSelection == nil {
+		return nil
+	}
+	agent.toolSelection.mutex.Lock()
+	defer agent.toolSelection.mutex.Unlock()
+	return slices.Clone(agent.toolSelection.lastSelection)
+}
+
+// GetLastToolSelection returns the names of the tools sent with the last request
+// (nil when the selection is disabled)
+func (agent *Agent) GetLastToolSelection() []string {
+	return agent.internalAgent.GetLastToolSelection()
+}
+
+// beginToolSelection forgets the tools added to the selection by the previous detection
+func (agent *BaseAgent) beginToolSelection() {
+	if agent.toolSelection == nil {
+		return
+	}
+	agent.toolSelection.mutex.Lock()
+	defer agent.toolSelection.mutex.Unlock()
+	agent.toolSelection.expanded = make(map[string]bool)
+}
+
+// expandToolSelection adds the called tools that were not offered to the selection
+func (agent *BaseAgent) expandToolSelection(detectedToolCalls []openai.ChatCompletionMessageToolCallUnion) {
+	if agent.toolSelection == nil {
+		return
+	}
+	agent.toolSelection.mutex.Lock()
+	defer agent.toolSelection.mutex.Unlock()
+	for _, toolCall := range detectedToolCalls {
+		name := toolCall.Function.Name
+		if !slices.Contains(agent.toolSelection.lastSelection, name) && agent.hasTool(name) {
+			agent.Log.Info("🧰 Tool %s called while not offered: added to the selection", name)
+			agent.toolSelection.expanded[name] = true
+		}
+	}
+}
+
+// hasTool reports whether a tool is declared in the request parameters
+func (agent *BaseAgent) hasTool(name string) bool {
+	for _, tool := range agent.ChatCompletionParams.Tools {
+		if function := tool.GetFunction(); function != nil && function.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTools returns the tools to send with a request
+func (agent *BaseAgent) selectTools(workingMessages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionToolUnionParam {
+	allTools := agent.ChatCompletionParams.Tools
+	selection := agent.toolSelection
+	if selection == nil {
+		return allTools
+	}
+	selection.mutex.Lock()
+	defer selection.mutex.Unlock()
+
+	selected, err := selection.selectTools(allTools, lastUserContent(workingMessages))
+	if err != nil {
+		agent.Log.Warn("🧰 Tool selection failed, all the tools are sent: %v", err)
+		selected = allTools
+	}
+
+	selection.lastSelection = make([]string, 0, len(selected))
+	for _, tool := range selected {
+		if function := tool.GetFunction(); function != nil {
+			selection.lastSelection = append(selection.lastSelection, function.Name)
+		}
+	}
+	return selected
+}
+
+// selectTools keeps the pinned and expanded tools and the topK tools most similar to query,
+// in their declaration order
+func (selection *toolSelection) selectTools(allTools []openai.ChatCompletionToolUnionParam, query string) ([]openai.ChatCompletionToolUnionParam, error) {
+	kept := make(map[string]bool)
+	candidates := 0
+	for _, tool := range allTools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		if selection.pinned[function.Name] || selection.expanded[function.Name] {
+			kept[function.Name] = true
+		} else {
+			candidates++
+		}
+	}
+	if candidates <= selection.topK || strings.TrimSpace(query) == "" {
+		return allTools, nil
+	}
+
+	queryVector, err := selection.embedder.GenerateEmbedding(query)
+	if err != nil {
+		return nil, err
+	}
+
+	type scoredTool struct {
+		name  string
+		score float64
+	}
+	scored := make([]scoredTool, 0, candidates)
+	for _, tool := range allTools {
+		function := tool.GetFunction()
+		if function == nil || kept[function.Name] {
+			continue
+		}
+		vector, err := selection.embedding(function.Name, toolEmbeddingText(function.Name, function.Description.Value))
+		if err != nil {
+			return nil, err
+		}
+		scored = append(scored, scoredTool{name: function.Name, score: stores.CosineSimilarity(queryVector, vector)})
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	for _, tool := range scored[:selection.topK] {
+		kept[tool.name] = true
+	}
+
+	selected := make([]openai.ChatCompletionToolUnionParam, 0, len(kept))
+	for _, tool := range allTools {
+		if function := tool.GetFunction(); function == nil || kept[function.Name] {
+			selected = append(selected, tool)
+		}
+	}
+	return selected, nil
+}
+
+// embedding returns the embedding of a tool, computed on first use or when its text changed
+func (selection *toolSelection) embedding(name string, text string) ([]float64, error) {
+	if cached, found := selection.embeddings[name]; found && cached.text == text {
+		return cached.vector, nil
+	}
+	vector, err := selection.embedder.GenerateEmbedding(text)
+	if err != nil {
+		return nil, err
+	}
+	selection.embeddings[name] = toolEmbedding{text: text, vector: vector}
+	return vector, nil
+}
+
+// toolEmbeddingText is the text embedded for a tool: its name (with spaces instead of
+// underscores, so the words match) and its description
+func toolEmbeddingText(name string, description string) string {
+	words := strings.NewReplacer("_", " ", "-", " ", ".", " ").Replace(name)
+	if description == "" {
+		return words
+	}
+	return words + ": " + description
+}
+
+// lastUserContent returns the content of the last user message, the query of the selection
+func lastUserContent(workingMessages []openai.ChatCompletionMessageParamUnion) string {
+	for index := len(workingMessages) - 1; index >= 0; index-- {
+		if workingMessages[index].OfUser != nil {
+			converted := messages.ConvertFromOpenAIMessages(workingMessages[index : index+1])
+			if len(converted) > 0 {
+				return converted[0].Content
+			}
+		}
+	}
+	return ""
+}
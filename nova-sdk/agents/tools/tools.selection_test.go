@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/rag"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func requestedTools(request novatest.ChatRequest) []string {
+	var names []string
+	for _, tool := range request.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestToolSelection_SendsTheRelevantAndPinnedTools(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+
+	embedder, err := rag.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultEmbeddingModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	toolsIndex := []*Tool{
+		NewTool("get_weather").SetDescription("Get the weather forecast of a city"),
+		NewTool("send_email").SetDescription("Send an email message to a recipient"),
+		NewTool("search_flights").SetDescription("Search flights between two airports"),
+		NewTool("translate_text").SetDescription("Translate a text to another language"),
+		NewTool("convert_currency").SetDescription("Convert an amount of money to another currency"),
+		NewTool("get_time").SetDescription("Get the current time"),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		WithToolSelection(embedder, 1, "get_time"),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "ok", nil }),
+	)
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "What is the weather forecast in Paris?"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"get_weather", "get_time"}
+	if tools := requestedTools(engine.ChatRequests()[0]); !slices.Equal(tools, expected) {
+		t.Errorf("expected %v, got %v", expected, tools)
+	}
+	if selection := agent.GetLastToolSelection(); !slices.Equal(selection, expected) {
+		t.Errorf("expected the last selection %v, got %v", expected, selection)
+	}
+	if len(agent.GetTools()) != 6 {
+		t.Errorf("the agent should keep all its tools, got %d", len(agent.GetTools()))
+	}
+}
+
+func TestToolSelection_ExpandsToCalledTools(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+	engine.When(novatest.CallIndex(0)).Reply(novatest.ToolCalls(novatest.Call("send_email", `{}`)))
+
+	embedder, err := rag.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultEmbeddingModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	toolsIndex := []*Tool{
+		NewTool("get_weather").SetDescription("Get the weather forecast of a city"),
+		NewTool("send_email").SetDescription("Send an email message to a recipient"),
+		NewTool("search_flights").SetDescription("Search flights between two airports"),
+		NewTool("translate_text").SetDescription("Translate a text to another language"),
+		NewTool("convert_currency").SetDescription("Convert an amount of money to another currency"),
+		NewTool("get_time").SetDescription("Get the current time"),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		WithToolSelection(embedder, 1, "get_time"),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "ok", nil }),
+	)
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "What is the weather forecast in Paris?"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"get_weather", "send_email", "get_time"}
+	if tools := requestedTools(engine.ChatRequests()[1]); !slices.Equal(tools, expected) {
+		t.Errorf("the called tool should be offered, expected %v, got %v", expected, tools)
+	}
+
+	// The expansion only lasts for the detection
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "And the weather forecast in Rome?"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tools := requestedTools(engine.ChatRequests()[2]); slices.Contains(tools, "send_email") {
+		t.Errorf("a new detection should start from the similarity selection, got %v", tools)
+	}
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) GenerateEmbedding(content string) ([]float64, error) {
+	return nil, errors.New("embedding model unavailable")
+}
+
+func TestToolSelection_FallsBackToAllTools(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+
+	toolsIndex := []*Tool{
+		NewTool("get_weather").SetDescription("Get the weather forecast of a city"),
+		NewTool("send_email").SetDescription("Send an email message to a recipient"),
+		NewTool("search_flights").SetDescription("Search flights between two airports"),
+		NewTool("translate_text").SetDescription("Translate a text to another language"),
+		NewTool("convert_currency").SetDescription("Convert an amount of money to another currency"),
+		NewTool("get_time").SetDescription("Get the current time"),
+	}
+	agent := newToolsAgent(t, engine.URL, toolsIndex,
+		WithToolSelection(failingEmbedder{}, 2),
+		WithExecuteFn(func(functionName string, arguments string) (string, error) { return "ok", nil }),
+	)
+	if _, err := agent.DetectToolCallsLoop([]messages.Message{{Role: roles.User, Content: "Translate hello"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tools := requestedTools(engine.ChatRequests()[0]); len(tools) != 6 {
+		t.Errorf("all the tools should be sent when the embeddings fail, got %v", tools)
+	}
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// NewParameter creates a parameter schema
+// paramType should be one of: "string", "number", "integer", "boolean", "object", "array"
+func NewParameter(paramType, description string) Parameter {
+	return Parameter{Type: paramType, Description: description}
+}
+
+// WithEnum restricts the parameter to a list of values
+func (p Parameter) WithEnum(values ...any) Parameter {
+	p.Enum = values
+	return p
+}
+
+// WithFormat sets the format of a string parameter (e.g. "date-time", "email", "uri")
+func (p Parameter) WithFormat(format string) Parameter {
+	p.Format = format
+	return p
+}
+
+// WithDefault sets the value used when the parameter is omitted
+func (p Parameter) WithDefault(value any) Parameter {
+	p.Default = value
+	return p
+}
+
+// WithItems sets the schema of the items of an array parameter
+func (p Parameter) WithItems(items Parameter) Parameter {
+	p.Items = &items
+	return p
+}
+
+// WithItemsRange sets the minimum and maximum number of items of an array parameter
+// (a negative value means no constraint)
+func (p Parameter) WithItemsRange(minItems, maxItems int) Parameter {
+	p.MinItems = optionalInt(minItems)
+	p.MaxItems = optionalInt(maxItems)
+	return p
+}
+
+// WithProperty adds a property to an object parameter
+func (p Parameter) WithProperty(name string, property Parameter, isRequired bool) Parameter {
+	properties := make(map[string]Parameter, len(p.Properties)+1)
+	maps.Copy(properties, p.Properties)
+	properties[name] = property
+	p.Properties = properties
+	if isRequired {
+		p.Required = append(append([]string{}, p.Required...), name)
+	}
+	return p
+}
+
+// WithRange sets the minimum and maximum of a numeric parameter
+func (p Parameter) WithRange(minimum, maximum float64) Parameter {
+	p.Minimum = &minimum
+	p.Maximum = &maximum
+	return p
+}
+
+// WithMinimum sets the minimum of a numeric parameter
+func (p Parameter) WithMinimum(minimum float64) Parameter {
+	p.Minimum = &minimum
+	return p
+}
+
+// WithMaximum sets the maximum of a numeric parameter
+func (p Parameter) WithMaximum(maximum float64) Parameter {
+	p.Maximum = &maximum
+	return p
+}
+
+// WithLength sets the minimum and maximum length of a string parameter
+// (a negative value means no constraint)
+func (p Parameter) WithLength(minLength, maxLength int) Parameter {
+	p.MinLength = optionalInt(minLength)
+	p.MaxLength = optionalInt(maxLength)
+	return p
+}
+
+// WithPattern sets the regular expression a string parameter must match
+func (p Parameter) WithPattern(pattern string) Parameter {
+	p.Pattern = pattern
+	return p
+}
+
+// WithOneOf makes the parameter accept exactly one of several schemas
+func (p Parameter) WithOneOf(options ...Parameter) Parameter {
+	p.OneOf = options
+	return p
+}
+
+// propertySchema returns the schema of a top-level parameter of a tool: it always has a
+// description, like the tools have always been sent (nested schemas omit an empty one)
+func (p Parameter) propertySchema() map[string]any {
+	schema := p.Schema()
+	schema["description"] = p.Description
+	return schema
+}
+
+// Schema returns the parameter as a JSON Schema object
+func (p Parameter) Schema() map[string]any {
+	schema := map[string]any{}
+	if p.Type != "" {
+		schema["type"] = p.Type
+	}
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	if p.Format != "" {
+		schema["format"] = p.Format
+	}
+	if p.Default != nil {
+		schema["default"] = p.Default
+	}
+	if p.Items != nil {
+		schema["items"] = p.Items.Schema()
+	}
+	if p.MinItems != nil {
+		schema["minItems"] = *p.MinItems
+	}
+	if p.MaxItems != nil {
+		schema["maxItems"] = *p.MaxItems
+	}
+	if len(p.Properties) > 0 {
+		properties := make(map[string]any, len(p.Properties))
+		for name, property := range p.Properties {
+			properties[name] = property.Schema()
+		}
+		schema["properties"] = properties
+	}
+	if len(p.Required) > 0 {
+		schema["required"] = p.Required
+	}
+	if p.Minimum != nil {
+		schema["minimum"] = *p.Minimum
+	}
+	if p.Maximum != nil {
+		schema["maximum"] = *p.Maximum
+	}
+	if p.MinLength != nil {
+		schema["minLength"] = *p.MinLength
+	}
+	if p.MaxLength != nil {
+		schema["maxLength"] = *p.MaxLength
+	}
+	if p.Pattern != "" {
+		schema["pattern"] = p.Pattern
+	}
+	if len(p.OneOf) > 0 {
+		oneOf := make([]any, len(p.OneOf))
+		for index, option := range p.OneOf {
+			oneOf[index] = option.Schema()
+		}
+		schema["oneOf"] = oneOf
+	}
+	return schema
+}
+
+// NewToolFromJSONSchema creates a tool from the JSON Schema of its parameters, for tools
+// defined elsewhere (OpenAPI documents, other frameworks, configuration files).
+// The schema is sent to the model as is; Parameters and Required are filled on a best-effort
+// basis for inspection.
+func NewToolFromJSONSchema(name, description string, schema []byte) (*Tool, error) {
+	var rawSchema map[string]any
+	if err := json.Unmarshal(schema, &rawSchema); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema for tool %s: %w", name, err)
+	}
+	if schemaType, found := rawSchema["type"]; found && schemaType != "object" {
+		return nil, fmt.Errorf("invalid JSON Schema for tool %s: the parameters must be an object, got %v", name, schemaType)
+	}
+	if _, found := rawSchema["type"]; !found {
+		rawSchema["type"] = "object"
+	}
+	if _, found := rawSchema["properties"]; !found {
+		rawSchema["properties"] = map[string]any{}
+	}
+
+	tool := NewTool(name).SetDescription(description)
+	tool.rawSchema = rawSchema
+
+	// Best effort: keywords Parameter doesn't know (e.g. "type": ["string", "null"]) are only kept in the raw schema
+	var parsed Parameter
+	if err := json.Unmarshal(schema, &parsed); err == nil {
+		if parsed.Properties != nil {
+			tool.Parameters = parsed.Properties
+		}
+		tool.Required = append(tool.Required, parsed.Required...)
+	}
+	return tool, nil
+}
+
+func optionalInt(value int) *int {
+	if value < 0 {
+		return nil
+	}
+	return &value
+}
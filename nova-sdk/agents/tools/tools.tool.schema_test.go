@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func schemaJSON(t *testing.T, tool *Tool) string {
+	t.Helper()
+	data, err := json.Marshal(tool.ToOpenAI().GetFunction().Parameters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(data)
+}
+
+func TestTool_AddParameterKeepsSimpleSchema(t *testing.T) {
+	tool := NewTool("say_hello").AddParameter("name", "string", "Name to greet", true)
+
+	want := `{"properties":{"name":{"description":"Name to greet","type":"string"}},"required":["name"],"type":"object"}`
+	if got := schemaJSON(t, tool); got != want {
+		t.Errorf("unexpected schema\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestTool_AddParameterKeepsEmptyDescription(t *testing.T) {
+	// The tools have always been sent with a description, even an empty one
+	tool := NewTool("ping").AddParameter("host", "string", "", false)
+	if got := schemaJSON(t, tool); got != `{"properties":{"host":{"description":"","type":"string"}},"type":"object"}` {
+		t.Errorf("unexpected schema %s", got)
+	}
+	data, _ := json.Marshal(NewParameter("string", ""))
+	if string(data) != `{"type":"string","description":""}` {
+		t.Errorf("unexpected parameter JSON %s", data)
+	}
+}
+
+func TestTool_AddSchemaParameter(t *testing.T) {
+	tool := NewTool("book_flight").
+		AddSchemaParameter("class", NewParameter("string", "Travel class").
+			WithEnum("economy", "business").WithDefault("economy"), false).
+		AddSchemaParameter("passengers", NewParameter("array", "Passengers").
+			WithItems(NewParameter("object", "").
+				WithProperty("name", NewParameter("string", "Full name").WithLength(1, 80), true).
+				WithProperty("age", NewParameter("integer", "").WithRange(0, 120), false)).
+			WithItemsRange(1, -1), true).
+		AddSchemaParameter("date", NewParameter("string", "Departure").WithFormat("date").WithPattern(`^\d{4}-\d{2}-\d{2}$`), true).
+		AddSchemaParameter("seat", NewParameter("", "Seat number or preference").
+			WithOneOf(NewParameter("integer", ""), NewParameter("string", "").WithEnum("window", "aisle")), false)
+
+	want := `{"properties":{` +
+		`"class":{"default":"economy","description":"Travel class","enum":["economy","business"],"type":"string"},` +
+		`"date":{"description":"Departure","format":"date","pattern":"^\\d{4}-\\d{2}-\\d{2}$","type":"string"},` +
+		`"passengers":{"description":"Passengers","items":{"properties":{"age":{"maximum":120,"minimum":0,"type":"integer"},` +
+		`"name":{"description":"Full name","maxLength":80,"minLength":1,"type":"string"}},"required":["name"],"type":"object"},` +
+		`"minItems":1,"type":"array"},` +
+		`"seat":{"description":"Seat number or preference","oneOf":[{"type":"integer"},{"enum":["window","aisle"],"type":"string"}]}},` +
+		`"required":["passengers","date"],"type":"object"}`
+	if got := schemaJSON(t, tool); got != want {
+		t.Errorf("unexpected schema\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestParameter_WithPropertyDoesNotAlias(t *testing.T) {
+	base := NewParameter("object", "").WithProperty("a", NewParameter("string", ""), true)
+	first := base.WithProperty("b", NewParameter("string", ""), true)
+	second := base.WithProperty("c", NewParameter("string", ""), true)
+
+	if len(base.Properties) != 1 || len(first.Properties) != 2 || len(second.Properties) != 2 {
+		t.Errorf("properties should be copied: %v %v %v", base.Properties, first.Properties, second.Properties)
+	}
+	if first.Required[1] != "b" || second.Required[1] != "c" {
+		t.Errorf("required should be copied: %v %v", first.Required, second.Required)
+	}
+}
+
+func TestNewToolFromJSONSchema(t *testing.T) {
+	schema := `{"type":"object","properties":{"query":{"type":"string","description":"Search query"},` +
+		`"limit":{"type":["integer","null"]}},"required":["query"],"additionalProperties":false}`
+
+	tool, err := NewToolFromJSONSchema("search", "Search the docs", []byte(schema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The raw schema is sent as is
+	if got := schemaJSON(t, tool); got != `{"additionalProperties":false,"properties":{"limit":{"type":["integer","null"]},"query":{"description":"Search query","type":"string"}},"required":["query"],"type":"object"}` {
+		t.Errorf("unexpected schema %s", got)
+	}
+
+	tool.AddParameter("lang", "string", "", true)
+	if got := schemaJSON(t, tool); got != `{"additionalProperties":false,"properties":{"lang":{"description":"","type":"string"},"limit":{"type":["integer","null"]},"query":{"description":"Search query","type":"string"}},"required":["query","lang"],"type":"object"}` {
+		t.Errorf("unexpected schema after AddParameter %s", got)
+	}
+
+	empty, err := NewToolFromJSONSchema("search", "", []byte(`{"type":"object"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	empty.AddParameter("query", "string", "Search query", true)
+	if got := schemaJSON(t, empty); got != `{"properties":{"query":{"description":"Search query","type":"string"}},"required":["query"],"type":"object"}` {
+		t.Errorf("a schema without properties should get them, got %s", got)
+	}
+
+	simple, err := NewToolFromJSONSchema("search", "", []byte(`{"properties":{"query":{"type":"string"}},"required":["query"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if simple.GetParameters()["query"].Type != "string" || len(simple.GetRequired()) != 1 {
+		t.Errorf("parameters should be parsed, got %+v %v", simple.GetParameters(), simple.GetRequired())
+	}
+
+	if _, err := NewToolFromJSONSchema("bad", "", []byte(`{"type":"string"}`)); err == nil {
+		t.Error("a non-object schema should be rejected")
+	}
+	if _, err := NewToolFromJSONSchema("bad", "", []byte(`{`)); err == nil {
+		t.Error("an invalid document should be rejected")
+	}
+}
+
+func TestRegister_ReflectsNestedSchemas(t *testing.T) {
+	type address struct {
+		City    string `json:"city"`
+		Country string `json:"country,omitempty" enum:"FR, US"`
+	}
+	tool := Register("locate", "", func(ctx context.Context, input struct {
+		Addresses []address `json:"addresses" description:"Addresses to locate"`
+		Tags      []string  `json:"tags,omitempty"`
+	}) (string, error) {
+		return "", nil
+	}).Tool
+
+	want := `{"properties":{"addresses":{"description":"Addresses to locate","items":{"properties":{"city":{"type":"string"},` +
+		`"country":{"enum":["FR","US"],"type":"string"}},"required":["city"],"type":"object"},"type":"array"},` +
+		`"tags":{"description":"","items":{"type":"string"},"type":"array"}},"required":["addresses"],"type":"object"}`
+	if got := schemaJSON(t, tool); got != want {
+		t.Errorf("unexpected schema\n got: %s\nwant: %s", got, want)
+	}
+}
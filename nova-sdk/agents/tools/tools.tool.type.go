@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"time"
+
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/shared"
 )
@@ -11,6 +13,12 @@ const (
 	Confirmed ConfirmationResponse = iota
 	Denied
 	Quit
+	// ConfirmedForSession executes the call and approves the tool until the session is reset
+	ConfirmedForSession
+	// ConfirmedAlways executes the call and approves the tool for the lifetime of the policy
+	ConfirmedAlways
+	// EditedAndConfirmed executes the call with the arguments of the ConfirmationDecision
+	EditedAndConfirmed
 )
 
 // Tool represents a function tool with a fluent builder API
@@ -19,13 +27,50 @@ type Tool struct {
 	Description string
 	Parameters  map[string]Parameter
 	Required    []string
+	// SideEffects marks a tool that changes something (files, databases, remote services):
+	// its calls are never executed concurrently
+	SideEffects bool
+	// Cacheable marks an idempotent tool whose results are memoized for CacheTTL
+	// (0: until invalidated) when the agent has a tool cache (see WithToolCache)
+	Cacheable bool
+	CacheTTL  time.Duration
 	//Function   func(string) (string, error)
+
+	// rawSchema is the parameters schema given to NewToolFromJSONSchema, sent as is
+	rawSchema map[string]any
 }
 
-// Parameter represents a function parameter
+// Parameter represents a function parameter as a JSON Schema.
+// Only Type and Description are needed for simple parameters; the other fields
+// describe enums, arrays, nested objects, defaults and constraints.
 type Parameter struct {
-	Type        string `json:"type"`
+	Type        string `json:"type,omitempty"`
 	Description string `json:"description"`
+
+	Enum    []any  `json:"enum,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Default any    `json:"default,omitempty"`
+
+	// Arrays
+	Items    *Parameter `json:"items,omitempty"`
+	MinItems *int       `json:"minItems,omitempty"`
+	MaxItems *int       `json:"maxItems,omitempty"`
+
+	// Objects
+	Properties map[string]Parameter `json:"properties,omitempty"`
+	Required   []string             `json:"required,omitempty"`
+
+	// Numbers
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	// Strings
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+
+	// Alternatives
+	OneOf []Parameter `json:"oneOf,omitempty"`
 }
 
 // NewTool creates a new Tool with the given name
@@ -43,45 +88,83 @@ func (t *Tool) SetDescription(description string) *Tool {
 	return t
 }
 
+// SetSideEffects marks the tool as changing something (its calls are never executed concurrently)
+func (t *Tool) SetSideEffects(sideEffects bool) *Tool {
+	t.SideEffects = sideEffects
+	return t
+}
+
+// SetCacheable marks the tool as idempotent: its results are memoized for ttl (0: until invalidated)
+func (t *Tool) SetCacheable(ttl time.Duration) *Tool {
+	t.Cacheable = true
+	t.CacheTTL = ttl
+	return t
+}
+
 // AddParameter adds a parameter to the tool
 // paramType should be one of: "string", "number", "boolean", "object", "array"
 // isRequired indicates whether the parameter is required
 func (t *Tool) AddParameter(name, paramType, description string, isRequired bool) *Tool {
-	t.Parameters[name] = Parameter{
-		Type:        paramType,
-		Description: description,
-	}
+	return t.AddSchemaParameter(name, NewParameter(paramType, description), isRequired)
+}
+
+// AddSchemaParameter adds a parameter described by a full schema to the tool
+//
+// Usage:
+//
+//	tool.AddSchemaParameter("unit",
+//		tools.NewParameter("string", "Temperature unit").WithEnum("celsius", "fahrenheit").WithDefault("celsius"),
+//		false)
+func (t *Tool) AddSchemaParameter(name string, parameter Parameter, isRequired bool) *Tool {
+	t.Parameters[name] = parameter
 	if isRequired {
 		t.Required = append(t.Required, name)
 	}
+	if t.rawSchema != nil {
+		properties, ok := t.rawSchema["properties"].(map[string]any)
+		if !ok {
+			properties = map[string]any{}
+			t.rawSchema["properties"] = properties
+		}
+		properties[name] = parameter.propertySchema()
+		if isRequired {
+			required, _ := t.rawSchema["required"].([]any)
+			t.rawSchema["required"] = append(required, name)
+		}
+	}
 	return t
 }
 
 // ToOpenAI converts the Tool to an OpenAI ChatCompletionToolUnionParam
 func (t *Tool) ToOpenAI() openai.ChatCompletionToolUnionParam {
+	return openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
+		Name:        t.Name,
+		Description: openai.String(t.Description),
+		Parameters:  shared.FunctionParameters(t.Schema()),
+	})
+}
+
+// Schema returns the JSON Schema of the tool parameters
+func (t *Tool) Schema() map[string]any {
+	if t.rawSchema != nil {
+		return t.rawSchema
+	}
+
 	properties := make(map[string]any)
 	for name, param := range t.Parameters {
-		properties[name] = map[string]string{
-			"type":        param.Type,
-			"description": param.Description,
-		}
+		properties[name] = param.propertySchema()
 	}
 
-	functionParams := shared.FunctionParameters{
+	schema := map[string]any{
 		"type":       "object",
 		"properties": properties,
 	}
 
 	// Only add required field if there are required parameters
 	if len(t.Required) > 0 {
-		functionParams["required"] = t.Required
+		schema["required"] = t.Required
 	}
-
-	return openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
-		Name:        t.Name,
-		Description: openai.String(t.Description),
-		Parameters:  functionParams,
-	})
+	return schema
 }
 
 // GetName returns the name of the tool
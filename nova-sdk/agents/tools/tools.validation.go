@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ArgumentsError is returned when the arguments of a tool call don't match the tool schema.
+// It is sent back to the model so it can fix its call.
+type ArgumentsError struct {
+	Tool   string
+	Issues []string
+}
+
+func (err *ArgumentsError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool %s: %s", err.Tool, strings.Join(err.Issues, "; "))
+}
+
+// ValidateArguments checks the JSON arguments of a tool call against the JSON Schema of the
+// tool parameters. It supports the keywords emitted by Tool.ToOpenAI (type, enum, items,
+// properties, required, additionalProperties, minimum, maximum, minLength, maxLength,
+// pattern, minItems, maxItems, oneOf); unknown keywords are ignored.
+func ValidateArguments(toolName string, schema map[string]any, arguments string) error {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(arguments))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return &ArgumentsError{Tool: toolName, Issues: []string{"arguments are not valid JSON: " + err.Error()}}
+	}
+	if _, isObject := value.(map[string]any); !isObject {
+		return &ArgumentsError{Tool: toolName, Issues: []string{"arguments must be a JSON object"}}
+	}
+
+	var issues []string
+	validateValue("arguments", schema, value, &issues)
+	if len(issues) > 0 {
+		return &ArgumentsError{Tool: toolName, Issues: issues}
+	}
+	return nil
+}
+
+// validateValue appends to issues the violations of schema by value
+func validateValue(path string, schema map[string]any, value any, issues *[]string) {
+	if !matchesType(schema["type"], value) {
+		*issues = append(*issues, fmt.Sprintf("%s: expected %v, got %s", path, schema["type"], jsonType(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		*issues = append(*issues, fmt.Sprintf("%s: must be one of %v", path, enum))
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		validateObject(path, schema, typed, issues)
+
+	case []any:
+		if minItems, ok := schemaNumber(schema, "minItems"); ok && float64(len(typed)) < minItems {
+			*issues = append(*issues, fmt.Sprintf("%s: must have at least %v items", path, minItems))
+		}
+		if maxItems, ok := schemaNumber(schema, "maxItems"); ok && float64(len(typed)) > maxItems {
+			*issues = append(*issues, fmt.Sprintf("%s: must have at most %v items", path, maxItems))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for index, item := range typed {
+				validateValue(fmt.Sprintf("%s[%d]", path, index), items, item, issues)
+			}
+		}
+
+	case string:
+		length := float64(utf8.RuneCountInString(typed))
+		if minLength, ok := schemaNumber(schema, "minLength"); ok && length < minLength {
+			*issues = append(*issues, fmt.Sprintf("%s: must be at least %v characters long", path, minLength))
+		}
+		if maxLength, ok := schemaNumber(schema, "maxLength"); ok && length > maxLength {
+			*issues = append(*issues, fmt.Sprintf("%s: must be at most %v characters long", path, maxLength))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if expression, err := regexp.Compile(pattern); err == nil && !expression.MatchString(typed) {
+				*issues = append(*issues, fmt.Sprintf("%s: must match %s", path, pattern))
+			}
+		}
+
+	case json.Number:
+		number, _ := typed.Float64()
+		if minimum, ok := schemaNumber(schema, "minimum"); ok && number < minimum {
+			*issues = append(*issues, fmt.Sprintf("%s: must be >= %v", path, minimum))
+		}
+		if maximum, ok := schemaNumber(schema, "maximum"); ok && number > maximum {
+			*issues = append(*issues, fmt.Sprintf("%s: must be <= %v", path, maximum))
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, option := range oneOf {
+			optionSchema, ok := option.(map[string]any)
+			if !ok {
+				continue
+			}
+			var optionIssues []string
+			validateValue(path, optionSchema, value, &optionIssues)
+			if len(optionIssues) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			*issues = append(*issues, fmt.Sprintf("%s: must match exactly one of the allowed schemas", path))
+		}
+	}
+}
+
+// validateObject checks the required, properties and additionalProperties keywords
+func validateObject(path string, schema map[string]any, object map[string]any, issues *[]string) {
+	properties, _ := schema["properties"].(map[string]any)
+
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, found := object[name]; !found {
+			*issues = append(*issues, fmt.Sprintf("%s.%s: is required", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertySchema, declared := properties[name].(map[string]any)
+		if !declared {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				*issues = append(*issues, fmt.Sprintf("%s.%s: is not an allowed property", path, name))
+			}
+			continue
+		}
+		validateValue(path+"."+name, propertySchema, object[name], issues)
+	}
+}
+
+// matchesType reports whether value has one of the types of the schema (no type matches everything)
+func matchesType(schemaType any, value any) bool {
+	switch typed := schemaType.(type) {
+	case nil:
+		return true
+	case string:
+		return isType(typed, value)
+	case []any:
+		for _, candidate := range typed {
+			if name, ok := candidate.(string); ok && isType(name, value) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, name := range typed {
+			if isType(name, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func isType(name string, value any) bool {
+	switch name {
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		float, err := number.Float64()
+		return err == nil && float == math.Trunc(float)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonType(value) == name
+	}
+}
+
+// jsonType returns the JSON Schema type name of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// containsValue reports whether enum contains value (numbers are compared by value)
+func containsValue(enum []any, value any) bool {
+	for _, candidate := range enum {
+		if number, ok := value.(json.Number); ok {
+			float, _ := number.Float64()
+			if candidateFloat, ok := toFloat(candidate); ok && candidateFloat == float {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaNumber reads a numeric keyword of a schema (built in Go or decoded from JSON)
+func schemaNumber(schema map[string]any, keyword string) (float64, bool) {
+	return toFloat(schema[keyword])
+}
+
+func toFloat(value any) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case float32:
+		return float64(typed), true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case json.Number:
+		float, err := typed.Float64()
+		return float, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// schemaStrings reads a list of strings of a schema (built in Go or decoded from JSON)
+func schemaStrings(value any) []string {
+	switch typed := value.(type) {
+	case []string:
+		return typed
+	case []any:
+		var result []string
+		for _, item := range typed {
+			if text, ok := item.(string); ok {
+				result = append(result, text)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func validationTool() *Tool {
+	return NewTool("book").
+		AddSchemaParameter("city", NewParameter("string", "").WithLength(2, 20), true).
+		AddSchemaParameter("nights", NewParameter("integer", "").WithRange(1, 14), true).
+		AddSchemaParameter("room", NewParameter("string", "").WithEnum("single", "double"), false).
+		AddSchemaParameter("guests", NewParameter("array", "").
+			WithItems(NewParameter("object", "").WithProperty("name", NewParameter("string", ""), true)).
+			WithItemsRange(1, 4), false).
+		AddSchemaParameter("code", NewParameter("string", "").WithPattern(`^[A-Z]{3}$`), false)
+}
+
+func TestValidateArguments_Valid(t *testing.T) {
+	schema := validationTool().Schema()
+	arguments := `{"city":"Lyon","nights":2,"room":"double","guests":[{"name":"Bob"}],"code":"LYS"}`
+
+	if err := ValidateArguments("book", schema, arguments); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateArguments_Issues(t *testing.T) {
+	schema := validationTool().Schema()
+	tests := []struct {
+		arguments string
+		issue     string
+	}{
+		{`{"city":"Lyon"`, "not valid JSON"},
+		{`["Lyon"]`, "must be a JSON object"},
+		{`{"city":"Lyon"}`, "arguments.nights: is required"},
+		{`{"city":"Lyon","nights":"2"}`, "arguments.nights: expected integer, got string"},
+		{`{"city":"Lyon","nights":2.5}`, "arguments.nights: expected integer, got number"},
+		{`{"city":"Lyon","nights":30}`, "arguments.nights: must be <= 14"},
+		{`{"city":"L","nights":2}`, "arguments.city: must be at least 2 characters long"},
+		{`{"city":"Lyon","nights":2,"room":"suite"}`, "arguments.room: must be one of [single double]"},
+		{`{"city":"Lyon","nights":2,"guests":[]}`, "arguments.guests: must have at least 1 items"},
+		{`{"city":"Lyon","nights":2,"guests":[{"age":3}]}`, "arguments.guests[0].name: is required"},
+		{`{"city":"Lyon","nights":2,"code":"lys"}`, "arguments.code: must match ^[A-Z]{3}$"},
+	}
+
+	for _, test := range tests {
+		err := ValidateArguments("book", schema, test.arguments)
+		var argumentsErr *ArgumentsError
+		if !errors.As(err, &argumentsErr) {
+			t.Errorf("%s: want an ArgumentsError, got %v", test.arguments, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.issue) {
+			t.Errorf("%s: want %q in %q", test.arguments, test.issue, err.Error())
+		}
+	}
+}
+
+func TestValidateArguments_RawSchema(t *testing.T) {
+	tool, err := NewToolFromJSONSchema("search", "", []byte(`{"type":"object",
+		"properties":{"query":{"type":"string"},"limit":{"type":["integer","null"],"minimum":1},
+		"mode":{"oneOf":[{"type":"string","enum":["fast"]},{"type":"integer"}]}},
+		"required":["query"],"additionalProperties":false}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schema := tool.Schema()
+
+	for _, valid := range []string{`{"query":"go"}`, `{"query":"go","limit":null}`, `{"query":"go","limit":3,"mode":"fast"}`, `{"query":"go","mode":2}`} {
+		if err := ValidateArguments("search", schema, valid); err != nil {
+			t.Errorf("%s: unexpected error %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{`{"query":"go","limit":0}`, `{"query":"go","page":2}`, `{"query":"go","mode":"slow"}`, ``} {
+		if err := ValidateArguments("search", schema, invalid); err == nil {
+			t.Errorf("%s: want an error", invalid)
+		}
+	}
+}
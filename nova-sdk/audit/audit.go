@@ -0,0 +1,172 @@
+// Package audit records a structured event for every tool execution (tools agent, server,
+// crew and gateway server-side tools, MCP calls and MCP server) and writes it to pluggable sinks:
+// JSONL file, callback or Redis stream. Arguments and results can be redacted before writing.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Sources of the audit events
+const (
+	SourceToolsAgent = "tools_agent"
+	SourceServer     = "server"
+	SourceCrewServer = "crew_server"
+	SourceGateway    = "gateway"
+	SourceMCP        = "mcp"
+	SourceMCPServer  = "mcp_server"
+)
+
+// Confirmations of the audited calls
+const (
+	// ConfirmationNotRequired: the call was executed without confirmation flow
+	ConfirmationNotRequired   = "not_required"
+	ConfirmationConfirmed     = "confirmed"
+	ConfirmationForSession    = "confirmed_for_session"
+	ConfirmationAlways        = "confirmed_always"
+	ConfirmationEdited        = "edited"
+	ConfirmationDenied        = "denied"
+	ConfirmationQuit          = "quit"
+	ConfirmationPolicyAllowed = "policy_allowed"
+	ConfirmationPolicyDenied  = "policy_denied"
+)
+
+// Statuses of the audited calls
+const (
+	// StatusSuccess: the tool returned a result
+	StatusSuccess = "success"
+	// StatusError: the tool (or the validation of its arguments) failed
+	StatusError = "error"
+	// StatusDenied: the call was not executed (denied by the user or the policy, or quit)
+	StatusDenied = "denied"
+)
+
+// Event is the audit record of one tool call
+type Event struct {
+	Time         time.Time `json:"time"`
+	Source       string    `json:"source"`
+	Agent        string    `json:"agent,omitempty"`
+	Session      string    `json:"session,omitempty"`
+	Tool         string    `json:"tool"`
+	CallID       string    `json:"call_id,omitempty"`
+	Arguments    string    `json:"arguments"`
+	Confirmation string    `json:"confirmation"`
+	Status       string    `json:"status"`
+	DurationMs   float64   `json:"duration_ms"`
+	Result       string    `json:"result,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	// Cached: the result was served from the tool cache, the tool was not executed
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Sink receives the audit events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(event Event) error
+}
+
+// SinkFunc is a callback sink
+type SinkFunc func(event Event) error
+
+// Write calls the callback
+func (fn SinkFunc) Write(event Event) error {
+	return fn(event)
+}
+
+// Config holds the audit settings of an agent
+type Config struct {
+	Sinks []Sink // Destinations of the events (no sinks disables the audit)
+	// Source of the events (default: set by the emitter, e.g. SourceToolsAgent)
+	Source string
+	// RedactArguments and RedactResult rewrite the arguments and the result (or error)
+	// before they are written
+	RedactArguments Redactor
+	RedactResult    Redactor
+	// MaxResultLength truncates the results and errors (0: no truncation)
+	MaxResultLength int
+	// OnError is called when a sink fails (default: the error is ignored)
+	OnError func(err error)
+}
+
+// Enabled reports whether at least one sink is configured
+func (config Config) Enabled() bool {
+	return len(config.Sinks) > 0
+}
+
+// WithSource returns a copy of the config emitting its events with source
+// (unless a source is already set)
+func (config Config) WithSource(source string) Config {
+	if config.Source == "" {
+		config.Source = source
+	}
+	return config
+}
+
+// Emit redacts the event and writes it to every sink
+func (config Config) Emit(event Event) {
+	if !config.Enabled() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Source == "" {
+		event.Source = config.Source
+	}
+	if config.RedactArguments != nil {
+		event.Arguments = config.RedactArguments(event.Tool, event.Arguments)
+	}
+	if config.RedactResult != nil {
+		event.Result = config.RedactResult(event.Tool, event.Result)
+		event.Error = config.RedactResult(event.Tool, event.Error)
+	}
+	event.Result = truncate(event.Result, config.MaxResultLength)
+	event.Error = truncate(event.Error, config.MaxResultLength)
+
+	for _, sink := range config.Sinks {
+		if err := sink.Write(event); err != nil && config.OnError != nil {
+			config.OnError(err)
+		}
+	}
+}
+
+// Milliseconds converts a duration for Event.DurationMs
+func Milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+func truncate(text string, maxLength int) string {
+	if maxLength <= 0 || len(text) <= maxLength {
+		return text
+	}
+	// Don't cut a UTF-8 sequence
+	for maxLength > 0 && text[maxLength]&0xC0 == 0x80 {
+		maxLength--
+	}
+	return text[:maxLength] + "…[truncated]"
+}
+
+// NewSessionID returns a random session identifier
+func NewSessionID() string {
+	buffer := make([]byte, 8)
+	_, _ = rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context carrying the audit session of the calls made with it
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the audit session carried by the context, or ""
+func SessionFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	session, _ := ctx.Value(sessionKey{}).(string)
+	return session
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONLSink writes one JSON event per line to a file or a writer
+type JSONLSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	file   *os.File
+}
+
+// NewJSONLSink appends the events to a JSONL file (created with its directory if needed)
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &JSONLSink{writer: file, file: file}, nil
+}
+
+// NewJSONLWriterSink writes the events to writer (e.g. os.Stdout)
+func NewJSONLWriterSink(writer io.Writer) *JSONLSink {
+	return &JSONLSink{writer: writer}
+}
+
+// Write appends the event as a JSON line
+func (sink *JSONLSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	_, err = sink.writer.Write(line)
+	return err
+}
+
+// Close closes the file of a sink created with NewJSONLSink
+func (sink *JSONLSink) Close() error {
+	if sink.file == nil {
+		return nil
+	}
+	return sink.file.Close()
+}
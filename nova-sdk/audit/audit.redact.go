@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the redacted values
+const Redacted = "[REDACTED]"
+
+// Redactor rewrites the arguments or the result of a tool call before it is written
+type Redactor func(tool string, text string) string
+
+// RedactKeys replaces the values of the JSON object keys with Redacted, at any depth
+// (case-insensitive, e.g. "password", "token", "api_key"). Texts that are not JSON are kept.
+func RedactKeys(keys ...string) Redactor {
+	redacted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		redacted[strings.ToLower(key)] = true
+	}
+	return func(tool string, text string) string {
+		var value any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return text
+		}
+		encoded, err := json.Marshal(redactValue(value, redacted))
+		if err != nil {
+			return text
+		}
+		return string(encoded)
+	}
+}
+
+func redactValue(value any, keys map[string]bool) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			if keys[strings.ToLower(key)] {
+				typed[key] = Redacted
+				continue
+			}
+			typed[key] = redactValue(item, keys)
+		}
+		return typed
+	case []any:
+		for index, item := range typed {
+			typed[index] = redactValue(item, keys)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// RedactPattern replaces the matches of a regular expression with Redacted
+// (e.g. `sk-[A-Za-z0-9]+`). It panics if pattern doesn't compile, like regexp.MustCompile.
+func RedactPattern(pattern string) Redactor {
+	expression := regexp.MustCompile(pattern)
+	return func(tool string, text string) string {
+		return expression.ReplaceAllString(text, Redacted)
+	}
+}
+
+// ChainRedactors applies redactors one after the other
+func ChainRedactors(redactors ...Redactor) Redactor {
+	return func(tool string, text string) string {
+		for _, redactor := range redactors {
+			text = redactor(tool, text)
+		}
+		return text
+	}
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig holds the configuration of the Redis stream sink
+type RedisConfig struct {
+	Address  string // Redis server address (e.g., "localhost:6379")
+	Password string // Redis password (empty string for no password)
+	DB       int    // Redis database number (default: 0)
+	Stream   string // Name of the stream (default: "nova:audit")
+	MaxLen   int64  // Approximate maximum length of the stream (0: unbounded)
+}
+
+// RedisSink adds the events to a Redis stream (XADD), one "event" field holding the JSON event
+type RedisSink struct {
+	client *redis.Client
+	ctx    context.Context
+	config RedisConfig
+}
+
+// NewRedisSink creates a Redis stream sink and verifies the connection with a PING
+func NewRedisSink(ctx context.Context, config RedisConfig) (*RedisSink, error) {
+	if config.Stream == "" {
+		config.Stream = "nova:audit"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Address,
+		Password: config.Password,
+		DB:       config.DB,
+		Protocol: 2,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisSink{client: client, ctx: ctx, config: config}, nil
+}
+
+// Write adds the event to the stream
+func (sink *RedisSink) Write(event Event) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	args := &redis.XAddArgs{
+		Stream: sink.config.Stream,
+		Values: map[string]any{"event": string(encoded)},
+	}
+	if sink.config.MaxLen > 0 {
+		args.MaxLen = sink.config.MaxLen
+		args.Approx = true
+	}
+	return sink.client.XAdd(sink.ctx, args).Err()
+}
+
+// Close closes the Redis connection
+func (sink *RedisSink) Close() error {
+	return sink.client.Close()
+}
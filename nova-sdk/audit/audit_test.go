@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmit_RedactsAndWritesToEverySink(t *testing.T) {
+	var first, second []Event
+	var sinkErrors []error
+	config := Config{
+		Sinks: []Sink{
+			SinkFunc(func(event Event) error { first = append(first, event); return nil }),
+			SinkFunc(func(event Event) error { second = append(second, event); return errors.New("sink down") }),
+		},
+		Source:          SourceToolsAgent,
+		RedactArguments: RedactKeys("password"),
+		RedactResult:    RedactPattern(`sk-[A-Za-z0-9]+`),
+		OnError:         func(err error) { sinkErrors = append(sinkErrors, err) },
+	}
+
+	config.Emit(Event{
+		Tool:      "login",
+		Arguments: `{"user":"bob","auth":{"Password":"secret"}}`,
+		Status:    StatusSuccess,
+		Result:    `your key is sk-abc123`,
+	})
+
+	if len(first) != 1 || len(second) != 1 || len(sinkErrors) != 1 {
+		t.Fatalf("expected one event per sink and one sink error, got %d %d %d", len(first), len(second), len(sinkErrors))
+	}
+	event := first[0]
+	if event.Source != SourceToolsAgent || event.Time.IsZero() {
+		t.Errorf("source and time should be set, got %+v", event)
+	}
+	if strings.Contains(event.Arguments, "secret") || !strings.Contains(event.Arguments, `"user":"bob"`) {
+		t.Errorf("unexpected redacted arguments %s", event.Arguments)
+	}
+	if event.Result != "your key is "+Redacted {
+		t.Errorf("unexpected redacted result %s", event.Result)
+	}
+}
+
+func TestEmit_TruncatesResults(t *testing.T) {
+	var events []Event
+	config := Config{
+		Sinks:           []Sink{SinkFunc(func(event Event) error { events = append(events, event); return nil })},
+		MaxResultLength: 2,
+	}
+	config.Emit(Event{Tool: "read", Result: "héllo world"})
+
+	if events[0].Result != "h…[truncated]" {
+		t.Errorf("unexpected truncated result %q", events[0].Result)
+	}
+}
+
+func TestEmit_DisabledWithoutSinks(t *testing.T) {
+	config := Config{RedactArguments: func(tool string, text string) string {
+		t.Error("a config without sinks should not process the events")
+		return text
+	}}
+	config.Emit(Event{Tool: "read"})
+}
+
+func TestJSONLSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	sink, err := NewJSONLSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := Config{Sinks: []Sink{sink}, Source: SourceMCP}
+	config.Emit(Event{Tool: "a", Status: StatusSuccess})
+	config.Emit(Event{Tool: "b", Status: StatusError, Error: "boom"})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	var tools []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		if event.Source != SourceMCP {
+			t.Errorf("unexpected source %q", event.Source)
+		}
+		tools = append(tools, event.Tool)
+	}
+	if strings.Join(tools, ",") != "a,b" {
+		t.Errorf("unexpected events %v", tools)
+	}
+}
+
+func TestSessionFromContext(t *testing.T) {
+	if SessionFromContext(context.Background()) != "" || SessionFromContext(nil) != "" {
+		t.Error("expected no session")
+	}
+	if session := SessionFromContext(WithSession(context.Background(), "s-1")); session != "s-1" {
+		t.Errorf("unexpected session %q", session)
+	}
+	if NewSessionID() == NewSessionID() {
+		t.Error("session identifiers should be random")
+	}
+}
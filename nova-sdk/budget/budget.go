@@ -0,0 +1,207 @@
+// Package budget tracks the token usage and estimated cost of agents and enforces limits
+// per call, per session, per agent lifetime and per client key (server and gateway agents).
+package budget
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// FinishReasonExceeded is the finish reason returned when a call is stopped gracefully
+// because a budget is exhausted (Action == ActionStop or ActionCompress)
+const FinishReasonExceeded = "budget_exceeded"
+
+// Unlimited is the remaining amount reported when no limit is set
+const Unlimited = -1
+
+// ErrExceeded is wrapped by every *ExceededError
+var ErrExceeded = errors.New("budget exceeded")
+
+// Scope identifies the budget that was exhausted
+type Scope string
+
+const (
+	// ScopeCall covers one call of the agent (all the requests of a completion or a tool calls loop)
+	ScopeCall Scope = "call"
+	// ScopeSession covers a conversation, until ResetMessages, ResetBudgetSession or a budget compression
+	ScopeSession Scope = "session"
+	// ScopeAgent covers the whole lifetime of the agent
+	ScopeAgent Scope = "agent"
+	// ScopeClient covers the requests of a client key on a server or gateway agent
+	ScopeClient Scope = "client"
+)
+
+// Action is the behaviour of an agent when one of its budgets is exhausted
+type Action string
+
+const (
+	// ActionError makes the call fail with an *ExceededError (default)
+	ActionError Action = "error"
+	// ActionStop stops the call gracefully: the partial result is returned
+	// with FinishReasonExceeded and no error
+	ActionStop Action = "stop"
+	// ActionCompress compresses the conversation with Config.Compressor when the session budget
+	// is exhausted and starts a new session. Call and agent budgets cannot be relieved by
+	// compression: they behave like ActionStop.
+	ActionCompress Action = "compress"
+)
+
+// Usage is an amount of tokens and its estimated cost
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	Requests         int     `json:"requests"`
+}
+
+// TotalTokens returns the prompt and completion tokens
+func (usage Usage) TotalTokens() int {
+	return usage.PromptTokens + usage.CompletionTokens
+}
+
+// Add returns the sum of two usages
+func (usage Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     usage.PromptTokens + other.PromptTokens,
+		CompletionTokens: usage.CompletionTokens + other.CompletionTokens,
+		Cost:             usage.Cost + other.Cost,
+		Requests:         usage.Requests + other.Requests,
+	}
+}
+
+// Sub returns the usage consumed since other (other must be an earlier snapshot)
+func (usage Usage) Sub(other Usage) Usage {
+	return Usage{
+		PromptTokens:     usage.PromptTokens - other.PromptTokens,
+		CompletionTokens: usage.CompletionTokens - other.CompletionTokens,
+		Cost:             usage.Cost - other.Cost,
+		Requests:         usage.Requests - other.Requests,
+	}
+}
+
+// Limits caps the tokens and the estimated cost of a scope (0: no limit)
+type Limits struct {
+	MaxTokens int
+	MaxCost   float64
+}
+
+// IsZero reports whether no limit is set
+func (limits Limits) IsZero() bool {
+	return limits.MaxTokens <= 0 && limits.MaxCost <= 0
+}
+
+// Exceeded reports whether usage reached one of the limits.
+// Limits are checked before each request, so the last request of a budget may overshoot it.
+func (limits Limits) Exceeded(usage Usage) bool {
+	return (limits.MaxTokens > 0 && usage.TotalTokens() >= limits.MaxTokens) ||
+		(limits.MaxCost > 0 && usage.Cost >= limits.MaxCost)
+}
+
+// Remaining returns what is left of the limits after usage (Unlimited when no limit is set)
+func (limits Limits) Remaining(usage Usage) Remaining {
+	remaining := Remaining{Tokens: Unlimited, Cost: Unlimited}
+	if limits.MaxTokens > 0 {
+		remaining.Tokens = max(limits.MaxTokens-usage.TotalTokens(), 0)
+	}
+	if limits.MaxCost > 0 {
+		remaining.Cost = max(limits.MaxCost-usage.Cost, 0)
+	}
+	return remaining
+}
+
+// Remaining is what is left of a budget; Unlimited (-1) when no limit is set
+type Remaining struct {
+	Tokens int     `json:"remaining_tokens"`
+	Cost   float64 `json:"remaining_cost"`
+}
+
+// Min returns the tightest of two remaining budgets
+func (remaining Remaining) Min(other Remaining) Remaining {
+	if other.Tokens != Unlimited && (remaining.Tokens == Unlimited || other.Tokens < remaining.Tokens) {
+		remaining.Tokens = other.Tokens
+	}
+	if other.Cost != Unlimited && (remaining.Cost == Unlimited || other.Cost < remaining.Cost) {
+		remaining.Cost = other.Cost
+	}
+	return remaining
+}
+
+// ExceededError is returned when a budget is exhausted and Action is ActionError
+type ExceededError struct {
+	Scope  Scope
+	Limits Limits
+	Usage  Usage
+}
+
+func (err *ExceededError) Error() string {
+	return fmt.Sprintf("%v (%s): %d tokens used (limit %d), cost %.6f (limit %.6f)",
+		ErrExceeded, err.Scope, err.Usage.TotalTokens(), err.Limits.MaxTokens, err.Usage.Cost, err.Limits.MaxCost)
+}
+
+// Unwrap makes errors.Is(err, ErrExceeded) work
+func (err *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// Compressor shrinks a conversation; used by ActionCompress
+type Compressor func(messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, error)
+
+// Config holds the budget settings of an agent
+type Config struct {
+	PerCall    Limits // One call (completion with its continuations, or a whole tool calls loop)
+	PerSession Limits // One conversation
+	PerAgent   Limits // The agent lifetime
+
+	// Prices overrides the global price table (see RegisterPrice) for this agent
+	Prices PriceTable
+
+	// OnExceeded is the behaviour when a budget is exhausted (default: ActionError)
+	OnExceeded Action
+	// Compressor is used by ActionCompress to shrink the conversation history
+	Compressor Compressor
+}
+
+// Enabled reports whether a limit or a price is configured
+func (config Config) Enabled() bool {
+	return !config.PerCall.IsZero() || !config.PerSession.IsZero() || !config.PerAgent.IsZero() ||
+		len(config.Prices) > 0
+}
+
+// EstimateTokens returns a rough token count for a number of characters (~4 chars per token),
+// used when the engine does not report usage
+func EstimateTokens(chars int) int {
+	return (chars + 3) / 4
+}
+
+// Reporter is implemented by the agents that track their usage
+type Reporter interface {
+	GetTotalUsage() Usage
+}
+
+// UsageEstimator is implemented by the agents that can estimate the usage the engine
+// doesn't report when they have no budget (see the client budgets of the servers)
+type UsageEstimator interface {
+	SetUsageEstimation(enabled bool)
+}
+
+// EnableUsageEstimation enables the usage estimation of the reporters implementing UsageEstimator
+func EnableUsageEstimation(reporters ...Reporter) {
+	for _, reporter := range reporters {
+		if estimator, ok := reporter.(UsageEstimator); ok {
+			estimator.SetUsageEstimation(true)
+		}
+	}
+}
+
+// Sum returns the total usage of several agents
+func Sum(reporters ...Reporter) Usage {
+	var total Usage
+	for _, reporter := range reporters {
+		if reporter != nil {
+			total = total.Add(reporter.GetTotalUsage())
+		}
+	}
+	return total
+}
@@ -0,0 +1,70 @@
+package budget
+
+import (
+	"strings"
+	"sync"
+)
+
+// Price is the price of a model in currency units per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Cost returns the estimated cost of a request
+func (price Price) Cost(promptTokens int, completionTokens int) float64 {
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1_000_000
+}
+
+// PriceTable maps model names to prices.
+// A model matches its exact name first, then the longest name it starts with
+// (e.g. "gpt-4o" matches "gpt-4o-2024-08-06").
+type PriceTable map[string]Price
+
+// Lookup returns the price of a model
+func (table PriceTable) Lookup(model string) (Price, bool) {
+	if price, found := table[model]; found {
+		return price, true
+	}
+	bestLength := -1
+	var best Price
+	for name, price := range table {
+		if strings.HasPrefix(model, name) && len(name) > bestLength {
+			bestLength = len(name)
+			best = price
+		}
+	}
+	return best, bestLength >= 0
+}
+
+var (
+	globalPricesMutex sync.RWMutex
+	globalPrices      = PriceTable{}
+)
+
+// RegisterPrice sets the price of a model for all the agents (local models are free by default)
+func RegisterPrice(model string, price Price) {
+	globalPricesMutex.Lock()
+	defer globalPricesMutex.Unlock()
+	globalPrices[model] = price
+}
+
+// LookupPrice returns the price registered with RegisterPrice
+func LookupPrice(model string) (Price, bool) {
+	globalPricesMutex.RLock()
+	defer globalPricesMutex.RUnlock()
+	return globalPrices.Lookup(model)
+}
+
+// Cost returns the estimated cost of a request, using table first and the global prices second.
+// Models without a price cost 0.
+func Cost(table PriceTable, model string, promptTokens int, completionTokens int) float64 {
+	price, found := table.Lookup(model)
+	if !found {
+		price, found = LookupPrice(model)
+	}
+	if !found {
+		return 0
+	}
+	return price.Cost(promptTokens, completionTokens)
+}
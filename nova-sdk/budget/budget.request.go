@@ -0,0 +1,53 @@
+package budget
+
+import "context"
+
+// requestTrackerKey is the context key of the tracker of a request
+type requestTrackerKey struct{}
+
+// WithRequestTracker returns a copy of ctx carrying the tracker of a request: the agents
+// whose requests use the context add the usage of these requests to it
+func WithRequestTracker(ctx context.Context, tracker *Tracker) context.Context {
+	return context.WithValue(ctx, requestTrackerKey{}, tracker)
+}
+
+// RequestTracker returns the tracker of the request carried by ctx, or nil
+func RequestTracker(ctx context.Context) *Tracker {
+	if ctx == nil {
+		return nil
+	}
+	tracker, _ := ctx.Value(requestTrackerKey{}).(*Tracker)
+	return tracker
+}
+
+// Contextual is implemented by the agents whose requests use the context set by SetContext
+type Contextual interface {
+	GetContext() context.Context
+	SetContext(ctx context.Context)
+}
+
+// TrackRequest adds the tracker of a request to the contexts of the reporters implementing
+// Contextual and returns the function restoring their contexts.
+// The tracker only records the usage of this request as long as the agents serve no other
+// request before restore is called.
+func TrackRequest(tracker *Tracker, reporters ...Reporter) (restore func()) {
+	var restores []func()
+	for _, reporter := range reporters {
+		agent, ok := reporter.(Contextual)
+		if !ok {
+			continue
+		}
+		previous := agent.GetContext()
+		ctx := previous
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		agent.SetContext(WithRequestTracker(ctx, tracker))
+		restores = append(restores, func() { agent.SetContext(previous) })
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package budget
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Tracker accumulates the usage of a scope and checks it against limits.
+// The zero value is ready to use and has no limits.
+type Tracker struct {
+	mutex  sync.Mutex
+	limits Limits
+	usage  Usage
+}
+
+// NewTracker creates a tracker with limits
+func NewTracker(limits Limits) *Tracker {
+	return &Tracker{limits: limits}
+}
+
+// Add records a usage
+func (tracker *Tracker) Add(usage Usage) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.usage = tracker.usage.Add(usage)
+}
+
+// Usage returns the recorded usage
+func (tracker *Tracker) Usage() Usage {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.usage
+}
+
+// Limits returns the limits of the tracker
+func (tracker *Tracker) Limits() Limits {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.limits
+}
+
+// SetLimits updates the limits of the tracker
+func (tracker *Tracker) SetLimits(limits Limits) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.limits = limits
+}
+
+// Reset forgets the recorded usage
+func (tracker *Tracker) Reset() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.usage = Usage{}
+}
+
+// Check returns an *ExceededError for scope when the usage reached the limits
+func (tracker *Tracker) Check(scope Scope) error {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.limits.Exceeded(tracker.usage) {
+		return &ExceededError{Scope: scope, Limits: tracker.limits, Usage: tracker.usage}
+	}
+	return nil
+}
+
+// Remaining returns what is left of the limits
+func (tracker *Tracker) Remaining() Remaining {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.limits.Remaining(tracker.usage)
+}
+
+// DefaultMaxClients is the default number of client keys a ledger tracks
+const DefaultMaxClients = 10_000
+
+// Ledger enforces a budget per client key on server and gateway agents.
+// The keys are stored as SHA-256 hashes, so the ledger doesn't keep the credentials of the
+// clients. Beyond the maximum number of clients, the tracker of the least recently used key
+// is dropped (its usage is forgotten); the limits set with SetLimits are kept.
+type Ledger struct {
+	mutex         sync.Mutex
+	defaultLimits Limits
+	maxClients    int
+	limits        map[string]Limits
+	trackers      map[string]*list.Element
+	// recent orders the ledgerEntry of the trackers, most recently used first
+	recent *list.List
+}
+
+// ledgerEntry is the tracker of a hashed client key
+type ledgerEntry struct {
+	hash    string
+	tracker *Tracker
+}
+
+// NewLedger creates a ledger applying defaultLimits to every client key
+func NewLedger(defaultLimits Limits) *Ledger {
+	return &Ledger{
+		defaultLimits: defaultLimits,
+		maxClients:    DefaultMaxClients,
+		limits:        make(map[string]Limits),
+		trackers:      make(map[string]*list.Element),
+		recent:        list.New(),
+	}
+}
+
+// SetMaxClients sets the number of client keys tracked (default: DefaultMaxClients)
+func (ledger *Ledger) SetMaxClients(maxClients int) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ledger.maxClients = max(maxClients, 1)
+	ledger.evict()
+}
+
+// SetLimits overrides the limits of a client key
+func (ledger *Ledger) SetLimits(key string, limits Limits) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	hash := hashClientKey(key)
+	ledger.limits[hash] = limits
+	if element, found := ledger.trackers[hash]; found {
+		element.Value.(*ledgerEntry).tracker.SetLimits(limits)
+	}
+}
+
+// Tracker returns the tracker of a client key, creating it on first use
+func (ledger *Ledger) Tracker(key string) *Tracker {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	hash := hashClientKey(key)
+	if element, found := ledger.trackers[hash]; found {
+		ledger.recent.MoveToFront(element)
+		return element.Value.(*ledgerEntry).tracker
+	}
+	limits, overridden := ledger.limits[hash]
+	if !overridden {
+		limits = ledger.defaultLimits
+	}
+	tracker := NewTracker(limits)
+	ledger.trackers[hash] = ledger.recent.PushFront(&ledgerEntry{hash: hash, tracker: tracker})
+	ledger.evict()
+	return tracker
+}
+
+// evict drops the least recently used trackers beyond the maximum number of clients
+func (ledger *Ledger) evict() {
+	for ledger.recent.Len() > ledger.maxClients {
+		oldest := ledger.recent.Back()
+		ledger.recent.Remove(oldest)
+		delete(ledger.trackers, oldest.Value.(*ledgerEntry).hash)
+	}
+}
+
+// hashClientKey returns the SHA-256 hash of a client key
+func hashClientKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return string(sum[:])
+}
+
+// Allow returns an *ExceededError (ScopeClient) when the budget of the client key is exhausted
+func (ledger *Ledger) Allow(key string) error {
+	return ledger.Tracker(key).Check(ScopeClient)
+}
+
+// Charge records the usage of a request and returns the remaining budget of the client key
+func (ledger *Ledger) Charge(key string, usage Usage) Remaining {
+	tracker := ledger.Tracker(key)
+	tracker.Add(usage)
+	return tracker.Remaining()
+}
+
+// Remaining returns the remaining budget of the client key
+func (ledger *Ledger) Remaining(key string) Remaining {
+	return ledger.Tracker(key).Remaining()
+}
+
+// Usage returns the usage of the client key
+func (ledger *Ledger) Usage(key string) Usage {
+	return ledger.Tracker(key).Usage()
+}
+
+// Reset forgets the usage of the client key
+func (ledger *Ledger) Reset(key string) {
+	ledger.Tracker(key).Reset()
+}
+
+// AnonymousClientKey is the key of the requests without credentials
+const AnonymousClientKey = "anonymous"
+
+// ClientKeyFromRequest returns the client key of an HTTP request: the bearer token of the
+// Authorization header, then the X-API-Key header, then AnonymousClientKey.
+// The credentials are not verified: any caller gets a budget of its own by sending a new
+// token. When the server doesn't authenticate its clients, map the requests to the known
+// clients with a ClientKeyFn (e.g. the user of a validated session, or AnonymousClientKey).
+func ClientKeyFromRequest(r *http.Request) string {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && strings.TrimSpace(token) != "" {
+		return strings.TrimSpace(token)
+	}
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	return AnonymousClientKey
+}
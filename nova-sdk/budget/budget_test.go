@@ -0,0 +1,149 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLimits_ExceededAndRemaining(t *testing.T) {
+	limits := Limits{MaxTokens: 100, MaxCost: 0.5}
+	usage := Usage{PromptTokens: 60, CompletionTokens: 30, Cost: 0.2}
+
+	if limits.Exceeded(usage) {
+		t.Error("90 tokens should not exceed 100")
+	}
+	if remaining := limits.Remaining(usage); remaining.Tokens != 10 || remaining.Cost != 0.3 {
+		t.Errorf("unexpected remaining %+v", remaining)
+	}
+	if !limits.Exceeded(usage.Add(Usage{CompletionTokens: 10})) {
+		t.Error("100 tokens should exceed 100")
+	}
+	if !limits.Exceeded(Usage{Cost: 0.5}) {
+		t.Error("the cost limit should be reached")
+	}
+
+	if (Limits{}).Exceeded(Usage{PromptTokens: 1_000_000}) {
+		t.Error("zero limits should never be exceeded")
+	}
+	if remaining := (Limits{}).Remaining(usage); remaining.Tokens != Unlimited || remaining.Cost != Unlimited {
+		t.Errorf("zero limits should be unlimited, got %+v", remaining)
+	}
+}
+
+func TestRemaining_Min(t *testing.T) {
+	unlimited := Remaining{Tokens: Unlimited, Cost: Unlimited}
+	tight := Remaining{Tokens: 5, Cost: 1}
+
+	if got := unlimited.Min(tight); got != tight {
+		t.Errorf("unexpected %+v", got)
+	}
+	if got := tight.Min(unlimited); got != tight {
+		t.Errorf("unexpected %+v", got)
+	}
+	if got := (Remaining{Tokens: 3, Cost: Unlimited}).Min(tight); got != (Remaining{Tokens: 3, Cost: 1}) {
+		t.Errorf("unexpected %+v", got)
+	}
+}
+
+func TestCost_PriceLookup(t *testing.T) {
+	table := PriceTable{
+		"gpt-4o":      {Prompt: 2.5, Completion: 10},
+		"gpt-4o-mini": {Prompt: 0.15, Completion: 0.6},
+	}
+
+	if price, _ := table.Lookup("gpt-4o-mini-2024-07-18"); price.Prompt != 0.15 {
+		t.Errorf("the longest prefix should match, got %+v", price)
+	}
+	if got := Cost(table, "gpt-4o", 1_000_000, 100_000); got != 3.5 {
+		t.Errorf("unexpected cost %v", got)
+	}
+
+	RegisterPrice("budget-test-model", Price{Prompt: 1, Completion: 1})
+	if got := Cost(table, "budget-test-model", 500_000, 500_000); got != 1 {
+		t.Errorf("the global price should be used, got %v", got)
+	}
+	if got := Cost(table, "ai/qwen3", 1_000, 1_000); got != 0 {
+		t.Errorf("unknown models should be free, got %v", got)
+	}
+}
+
+func TestLedger_AllowAndCharge(t *testing.T) {
+	ledger := NewLedger(Limits{MaxTokens: 100})
+	ledger.SetLimits("vip", Limits{})
+
+	if err := ledger.Allow("alice"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining := ledger.Charge("alice", Usage{PromptTokens: 80, CompletionTokens: 20}); remaining.Tokens != 0 {
+		t.Errorf("unexpected remaining %+v", remaining)
+	}
+
+	err := ledger.Allow("alice")
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Scope != ScopeClient || !errors.Is(err, ErrExceeded) {
+		t.Fatalf("want a client ExceededError, got %v", err)
+	}
+
+	ledger.Charge("vip", Usage{PromptTokens: 1_000})
+	if err := ledger.Allow("vip"); err != nil {
+		t.Errorf("overridden limits should apply: %v", err)
+	}
+	if err := ledger.Allow("bob"); err != nil {
+		t.Errorf("client keys should not share their budget: %v", err)
+	}
+
+	ledger.Reset("alice")
+	if err := ledger.Allow("alice"); err != nil {
+		t.Errorf("unexpected error after reset: %v", err)
+	}
+}
+
+func TestClientKeyFromRequest(t *testing.T) {
+	request := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	if key := ClientKeyFromRequest(request); key != AnonymousClientKey {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	request.Header.Set("X-API-Key", "key-1")
+	if key := ClientKeyFromRequest(request); key != "key-1" {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	request.Header.Set("Authorization", "Bearer token-1")
+	if key := ClientKeyFromRequest(request); key != "token-1" {
+		t.Errorf("the bearer token should win, got %q", key)
+	}
+}
+
+func TestLedger_HashesAndBoundsTheClientKeys(t *testing.T) {
+	ledger := NewLedger(Limits{MaxTokens: 100})
+	ledger.SetMaxClients(2)
+	ledger.SetLimits("secret-token", Limits{MaxTokens: 10})
+
+	ledger.Charge("secret-token", Usage{PromptTokens: 10})
+	if err := ledger.Allow("secret-token"); err == nil {
+		t.Error("the limits of the key should apply")
+	}
+	for hash := range ledger.limits {
+		if hash == "secret-token" {
+			t.Error("the ledger should not keep the client keys")
+		}
+	}
+
+	// Random tokens don't grow the ledger: the least recently used trackers are dropped
+	for index := range 100 {
+		ledger.Charge(fmt.Sprintf("random-%d", index), Usage{PromptTokens: 1})
+	}
+	if len(ledger.trackers) != 2 || ledger.recent.Len() != 2 {
+		t.Errorf("want 2 trackers, got %d", len(ledger.trackers))
+	}
+	if usage := ledger.Usage("random-99"); usage.PromptTokens != 1 {
+		t.Errorf("the most recent tracker should be kept, got %+v", usage)
+	}
+	// A dropped tracker starts again from zero, with the limits of its key
+	if tracker := ledger.Tracker("secret-token"); tracker.Usage().PromptTokens != 0 || tracker.Limits().MaxTokens != 10 {
+		t.Errorf("unexpected tracker %+v / %+v", tracker.Usage(), tracker.Limits())
+	}
+}
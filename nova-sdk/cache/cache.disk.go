@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskStore implements Store with one JSON file per entry in a directory
+type DiskStore struct {
+	directory string
+}
+
+type diskEntry struct {
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Value     []byte    `json:"value"`
+}
+
+// NewDiskStore creates an on-disk cache in the given directory (created if needed)
+func NewDiskStore(directory string) (*DiskStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskStore{directory: directory}, nil
+}
+
+// Get returns the cached value; expired entries are removed
+func (store *DiskStore) Get(key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(store.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupted entry is treated as a miss and overwritten on the next Set
+		return nil, false, nil
+	}
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		_ = store.Delete(key)
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// Set writes the entry atomically (temporary file then rename)
+func (store *DiskStore) Set(key string, value []byte, ttl time.Duration) error {
+	entry := diskEntry{Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(store.directory, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), store.path(key))
+}
+
+// Delete removes a single entry
+func (store *DiskStore) Delete(key string) error {
+	err := os.Remove(store.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Clear removes all the entries of the directory
+func (store *DiskStore) Clear() error {
+	files, err := filepath.Glob(filepath.Join(store.directory, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path maps a key ("kind:hash") to a file name safe on every platform
+func (store *DiskStore) path(key string) string {
+	return filepath.Join(store.directory, strings.ReplaceAll(key, ":", "_")+".json")
+}
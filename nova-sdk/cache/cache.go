@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Kinds of cached requests, used as key prefixes so completions and embeddings never collide
+const (
+	KindChat      = "chat"
+	KindEmbedding = "embedding"
+)
+
+// DefaultReplayChunkSize is the number of characters sent per simulated chunk
+// when a cached response is replayed to a streaming callback
+const DefaultReplayChunkSize = 16
+
+// Store defines the interface of a response cache backend
+type Store interface {
+	// Get returns the cached value and true, or nil and false when the key is missing or expired
+	Get(key string) ([]byte, bool, error)
+	// Set stores a value; a ttl of 0 means the entry never expires
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes a single entry
+	Delete(key string) error
+	// Clear removes all the entries of the cache
+	Clear() error
+}
+
+// Config holds the response cache settings of an agent
+type Config struct {
+	Store Store         // Cache backend (nil disables the cache)
+	TTL   time.Duration // Lifetime of the entries (0: no expiry)
+	// Force caches every request, even when the temperature is not 0 and no seed is set
+	Force bool
+	// ReplayChunkSize is the number of characters per simulated chunk when replaying a cached stream
+	// (default: DefaultReplayChunkSize)
+	ReplayChunkSize int
+}
+
+// Enabled reports whether a cache backend is configured
+func (config Config) Enabled() bool {
+	return config.Store != nil
+}
+
+// GetReplayChunkSize returns the replay chunk size, falling back to DefaultReplayChunkSize
+func (config Config) GetReplayChunkSize() int {
+	if config.ReplayChunkSize <= 0 {
+		return DefaultReplayChunkSize
+	}
+	return config.ReplayChunkSize
+}
+
+// IsDeterministic reports whether a chat completion request is expected to always return
+// the same answer: its temperature is explicitly 0 or a seed is set
+func IsDeterministic(params openai.ChatCompletionNewParams) bool {
+	return (params.Temperature.Valid() && params.Temperature.Value == 0) || params.Seed.Valid()
+}
+
+// Key builds the cache key of a request from its canonical JSON form
+// (object keys sorted, no insignificant whitespace), prefixed with the request kind.
+// Two requests with the same model, parameters, messages and tools get the same key.
+func Key(kind string, request any) (string, error) {
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request for cache key: %w", err)
+	}
+
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return kind + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// Canonicalize re-encodes a JSON document with sorted object keys and no extra whitespace
+func Canonicalize(raw []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to canonicalize JSON: %w", err)
+	}
+	return json.Marshal(value)
+}
+
+// SplitChunks splits a cached response into chunks of at most size bytes,
+// never cutting a UTF-8 sequence, to simulate a streamed answer
+func SplitChunks(content string, size int) []string {
+	if size <= 0 {
+		size = DefaultReplayChunkSize
+	}
+
+	var chunks []string
+	start := 0
+	for index := range content {
+		if index-start >= size {
+			chunks = append(chunks, content[start:index])
+			start = index
+		}
+	}
+	if start < len(content) {
+		chunks = append(chunks, content[start:])
+	}
+	return chunks
+}
+
+type bypassKey struct{}
+
+// WithBypass returns a context that makes agents skip the cache (no lookup, no write)
+// for the requests issued with it
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypassed reports whether the context asks to skip the cache
+func IsBypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
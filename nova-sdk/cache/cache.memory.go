@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryCapacity is the default maximum number of entries of a MemoryStore
+const DefaultMemoryCapacity = 1000
+
+// MemoryStore implements Store as an in-memory LRU cache
+type MemoryStore struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // front: most recently used
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // zero: never expires
+}
+
+// NewMemoryStore creates an in-memory LRU cache holding at most capacity entries
+// (DefaultMemoryCapacity when capacity <= 0). The least recently used entry is evicted first.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached value and marks the entry as recently used
+func (store *MemoryStore) Get(key string) ([]byte, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	element, ok := store.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		store.removeElement(element)
+		return nil, false, nil
+	}
+	store.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores a value, evicting the least recently used entry when the cache is full
+func (store *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, ok := store.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		store.order.MoveToFront(element)
+		return nil
+	}
+
+	store.entries[key] = store.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for store.order.Len() > store.capacity {
+		store.removeElement(store.order.Back())
+	}
+	return nil
+}
+
+// Delete removes a single entry
+func (store *MemoryStore) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if element, ok := store.entries[key]; ok {
+		store.removeElement(element)
+	}
+	return nil
+}
+
+// Clear removes all the entries
+func (store *MemoryStore) Clear() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.entries = make(map[string]*list.Element)
+	store.order.Init()
+	return nil
+}
+
+// Len returns the number of entries currently held (expired entries included until accessed)
+func (store *MemoryStore) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.order.Len()
+}
+
+func (store *MemoryStore) removeElement(element *list.Element) {
+	store.order.Remove(element)
+	delete(store.entries, element.Value.(*memoryEntry).key)
+}
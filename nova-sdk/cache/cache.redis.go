@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig holds the configuration for the Redis cache connection
+type RedisConfig struct {
+	Address  string // Redis server address (e.g., "localhost:6379")
+	Password string // Redis password (empty string for no password)
+	DB       int    // Redis database number (default: 0)
+	Prefix   string // Prefix of the cache keys (default: "nova:cache:")
+}
+
+// RedisStore implements Store using Redis strings with native expiration
+type RedisStore struct {
+	client *redis.Client
+	ctx    context.Context
+	config RedisConfig
+}
+
+// NewRedisStore creates a Redis-backed cache and verifies the connection with a PING
+func NewRedisStore(ctx context.Context, config RedisConfig) (*RedisStore, error) {
+	if config.Prefix == "" {
+		config.Prefix = "nova:cache:"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Address,
+		Password: config.Password,
+		DB:       config.DB,
+		Protocol: 2,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStore{client: client, ctx: ctx, config: config}, nil
+}
+
+// Close closes the Redis connection
+func (store *RedisStore) Close() error {
+	return store.client.Close()
+}
+
+// Get returns the cached value
+func (store *RedisStore) Get(key string) ([]byte, bool, error) {
+	value, err := store.client.Get(store.ctx, store.config.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores a value; Redis takes care of the expiration
+func (store *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	return store.client.Set(store.ctx, store.config.Prefix+key, value, ttl).Err()
+}
+
+// Delete removes a single entry
+func (store *RedisStore) Delete(key string) error {
+	return store.client.Del(store.ctx, store.config.Prefix+key).Err()
+}
+
+// Clear removes all the keys with the cache prefix
+func (store *RedisStore) Clear() error {
+	iter := store.client.Scan(store.ctx, 0, store.config.Prefix+"*", 100).Iterator()
+	for iter.Next(store.ctx) {
+		if err := store.client.Del(store.ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
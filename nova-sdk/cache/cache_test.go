@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ── Key / Canonicalize ────────────────────────────────────────────────────────
+
+func TestKey_IgnoresObjectKeyOrder(t *testing.T) {
+	a, err := Key(KindChat, map[string]any{"model": "m", "temperature": 0, "messages": []string{"hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := Key(KindChat, rawJSON(`{ "temperature": 0, "messages": ["hi"], "model": "m" }`))
+	if a != b {
+		t.Errorf("keys must not depend on object key order or whitespace: %s != %s", a, b)
+	}
+}
+
+func TestKey_DependsOnKindAndContent(t *testing.T) {
+	params := openai.ChatCompletionNewParams{
+		Model:    "m",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hello")},
+	}
+	chatKey, _ := Key(KindChat, params)
+	embeddingKey, _ := Key(KindEmbedding, params)
+	if chatKey == embeddingKey {
+		t.Error("kinds must not collide")
+	}
+
+	params.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hello!")}
+	otherKey, _ := Key(KindChat, params)
+	if chatKey == otherKey {
+		t.Error("different messages must produce different keys")
+	}
+	if !strings.HasPrefix(chatKey, KindChat+":") {
+		t.Errorf("key must be prefixed with its kind, got %s", chatKey)
+	}
+}
+
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) { return []byte(r), nil }
+
+// ── IsDeterministic ───────────────────────────────────────────────────────────
+
+func TestIsDeterministic(t *testing.T) {
+	if IsDeterministic(openai.ChatCompletionNewParams{}) {
+		t.Error("default temperature is not deterministic")
+	}
+	if !IsDeterministic(openai.ChatCompletionNewParams{Temperature: openai.Float(0)}) {
+		t.Error("temperature 0 is deterministic")
+	}
+	if IsDeterministic(openai.ChatCompletionNewParams{Temperature: openai.Float(0.7)}) {
+		t.Error("temperature 0.7 is not deterministic")
+	}
+	if !IsDeterministic(openai.ChatCompletionNewParams{Temperature: openai.Float(0.7), Seed: openai.Int(42)}) {
+		t.Error("a seed makes the request deterministic")
+	}
+}
+
+// ── SplitChunks ───────────────────────────────────────────────────────────────
+
+func TestSplitChunks_RebuildsContent(t *testing.T) {
+	content := "héllo wörld, ça va? 🙂 fine"
+	chunks := SplitChunks(content, 4)
+	if strings.Join(chunks, "") != content {
+		t.Errorf("chunks must rebuild the content, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk must not split a UTF-8 sequence: %q", chunk)
+		}
+	}
+}
+
+func TestSplitChunks_Empty(t *testing.T) {
+	if chunks := SplitChunks("", 4); len(chunks) != 0 {
+		t.Errorf("want no chunk, got %q", chunks)
+	}
+}
+
+// ── Bypass ────────────────────────────────────────────────────────────────────
+
+func TestBypass(t *testing.T) {
+	ctx := context.Background()
+	if IsBypassed(ctx) {
+		t.Error("plain context must not bypass the cache")
+	}
+	if !IsBypassed(WithBypass(ctx)) {
+		t.Error("WithBypass context must bypass the cache")
+	}
+}
+
+// ── MemoryStore ───────────────────────────────────────────────────────────────
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStore(2)
+	_ = store.Set("a", []byte("1"), 0)
+	_ = store.Set("b", []byte("2"), 0)
+	_, _, _ = store.Get("a") // "b" becomes the least recently used
+	_ = store.Set("c", []byte("3"), 0)
+
+	if _, found, _ := store.Get("b"); found {
+		t.Error("b should have been evicted")
+	}
+	if value, found, _ := store.Get("a"); !found || string(value) != "1" {
+		t.Error("a should still be cached")
+	}
+	if store.Len() != 2 {
+		t.Errorf("want 2 entries, got %d", store.Len())
+	}
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	store := NewMemoryStore(0)
+	_ = store.Set("k", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, found, _ := store.Get("k"); found {
+		t.Error("expired entry must be a miss")
+	}
+}
+
+func TestMemoryStore_DeleteAndClear(t *testing.T) {
+	store := NewMemoryStore(0)
+	_ = store.Set("a", []byte("1"), 0)
+	_ = store.Set("b", []byte("2"), 0)
+	_ = store.Delete("a")
+	if _, found, _ := store.Get("a"); found {
+		t.Error("deleted entry must be a miss")
+	}
+	_ = store.Clear()
+	if store.Len() != 0 {
+		t.Errorf("want empty store, got %d entries", store.Len())
+	}
+}
+
+// ── DiskStore ─────────────────────────────────────────────────────────────────
+
+func TestDiskStore_RoundTripAndTTL(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.Set("chat:abc", []byte(`{"x":1}`), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, found, _ := store.Get("chat:abc"); !found || string(value) != `{"x":1}` {
+		t.Errorf("want cached value, got %q (found=%v)", value, found)
+	}
+
+	_ = store.Set("chat:short", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, found, _ := store.Get("chat:short"); found {
+		t.Error("expired entry must be a miss")
+	}
+
+	_ = store.Clear()
+	if _, found, _ := store.Get("chat:abc"); found {
+		t.Error("cleared entry must be a miss")
+	}
+}
@@ -0,0 +1,59 @@
+package cassette
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Environment variables enabling a process-wide cassette without code changes:
+//
+//	export NOVA_CASSETTE=./testdata/session.cassette.json
+//	export NOVA_CASSETTE_MODE=replay   # record, replay or auto (default)
+//	export NOVA_CASSETTE_IGNORE=seed   # comma-separated body fields ignored when matching
+const (
+	EnvCassette       = "NOVA_CASSETTE"
+	EnvCassetteMode   = "NOVA_CASSETTE_MODE"
+	EnvCassetteIgnore = "NOVA_CASSETTE_IGNORE"
+)
+
+var (
+	envOnce     sync.Once
+	envRecorder *Recorder
+	envErr      error
+)
+
+// FromEnv returns the process-wide Recorder configured by NOVA_CASSETTE, or nil when the
+// variable is not set. Every caller shares the same Recorder so all the agents of a test
+// write to (or read from) the same cassette. Tool call IDs are always normalized.
+func FromEnv() (*Recorder, error) {
+	envOnce.Do(func() {
+		path := os.Getenv(EnvCassette)
+		if path == "" {
+			return
+		}
+
+		options := []Option{WithNormalizer(NormalizeToolCallIDs)}
+		if ignore := os.Getenv(EnvCassetteIgnore); ignore != "" {
+			for _, field := range strings.Split(ignore, ",") {
+				if field = strings.TrimSpace(field); field != "" {
+					options = append(options, WithIgnoredFields(field))
+				}
+			}
+		}
+
+		envRecorder, envErr = New(path, Mode(strings.ToLower(os.Getenv(EnvCassetteMode))), options...)
+	})
+	return envRecorder, envErr
+}
+
+// HTTPClientFromEnv returns an http.Client using the NOVA_CASSETTE recorder,
+// or nil when no cassette is configured
+func HTTPClientFromEnv() (*http.Client, error) {
+	recorder, err := FromEnv()
+	if err != nil || recorder == nil {
+		return nil, err
+	}
+	return recorder.Client(), nil
+}
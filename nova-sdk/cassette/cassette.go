@@ -0,0 +1,363 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode defines whether a Recorder talks to the real server or serves recorded interactions
+type Mode string
+
+const (
+	// ModeRecord forwards every request to the real server and saves the interactions
+	ModeRecord Mode = "record"
+	// ModeReplay serves recorded interactions and fails on unmatched requests
+	ModeReplay Mode = "replay"
+	// ModeAuto replays when the cassette file exists, records otherwise
+	ModeAuto Mode = "auto"
+)
+
+// ErrNoMatch is returned (wrapped) in replay mode when no recorded interaction matches a request
+var ErrNoMatch = errors.New("cassette: no recorded interaction matches the request")
+
+// Request is the recorded form of an HTTP request.
+// Headers are never recorded so API keys don't end up in cassette files.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"` // path and query only, so cassettes do not depend on the host
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is the recorded form of an HTTP response (streamed bodies are stored whole)
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// Interaction is a request/response pair
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Matcher decides whether a recorded request matches an incoming one.
+// Both requests are already normalized (ignored fields removed, normalizers applied).
+type Matcher func(recorded Request, incoming Request) bool
+
+// Normalizer rewrites a decoded JSON body before matching (e.g. to replace generated IDs)
+type Normalizer func(body any) any
+
+// Option is a functional option for configuring a Recorder
+type Option func(*Recorder)
+
+// Recorder is an http.RoundTripper recording or replaying HTTP interactions from a cassette file
+type Recorder struct {
+	mutex        sync.Mutex
+	path         string
+	mode         Mode
+	transport    http.RoundTripper
+	interactions []Interaction
+	used         []bool
+
+	ignoredFields []string
+	normalizers   []Normalizer
+	matcher       Matcher
+	allowRepeats  bool
+
+	// saveErr is the first error of the automatic writes, returned by the next Save call
+	saveErr error
+}
+
+// WithIgnoredFields removes JSON body fields before matching. Nested fields use dots
+// (e.g. "seed", "stream_options.include_usage").
+func WithIgnoredFields(paths ...string) Option {
+	return func(recorder *Recorder) {
+		recorder.ignoredFields = append(recorder.ignoredFields, paths...)
+	}
+}
+
+// WithNormalizer adds a function rewriting JSON bodies before matching
+func WithNormalizer(normalizer Normalizer) Option {
+	return func(recorder *Recorder) {
+		recorder.normalizers = append(recorder.normalizers, normalizer)
+	}
+}
+
+// WithMatcher replaces the default matching rule (same method, same path and query, same normalized body)
+func WithMatcher(matcher Matcher) Option {
+	return func(recorder *Recorder) {
+		recorder.matcher = matcher
+	}
+}
+
+// WithRepeats allows a recorded interaction to be replayed several times.
+// By default each interaction is served once, in recording order.
+func WithRepeats() Option {
+	return func(recorder *Recorder) {
+		recorder.allowRepeats = true
+	}
+}
+
+// WithTransport sets the transport used to reach the real server in record mode
+// (default: http.DefaultTransport)
+func WithTransport(transport http.RoundTripper) Option {
+	return func(recorder *Recorder) {
+		recorder.transport = transport
+	}
+}
+
+// New creates a Recorder for the cassette file at path.
+// In replay mode the file must exist; in record mode it is overwritten by the new interactions.
+func New(path string, mode Mode, options ...Option) (*Recorder, error) {
+	if mode == "" || mode == ModeAuto {
+		mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = ModeReplay
+		}
+	}
+	if mode != ModeRecord && mode != ModeReplay {
+		return nil, fmt.Errorf("cassette: unknown mode %q", mode)
+	}
+
+	recorder := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		matcher:   DefaultMatcher,
+	}
+	for _, option := range options {
+		option(recorder)
+	}
+
+	if mode == ModeReplay {
+		if err := recorder.load(); err != nil {
+			return nil, err
+		}
+	}
+	return recorder, nil
+}
+
+// Mode returns the effective mode of the recorder
+func (recorder *Recorder) Mode() Mode {
+	return recorder.mode
+}
+
+// Client returns an http.Client using the recorder as transport
+func (recorder *Recorder) Client() *http.Client {
+	return &http.Client{Transport: recorder}
+}
+
+// Interactions returns a copy of the recorded interactions
+func (recorder *Recorder) Interactions() []Interaction {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]Interaction(nil), recorder.interactions...)
+}
+
+// Remaining returns the number of recorded interactions not replayed yet
+func (recorder *Recorder) Remaining() int {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	remaining := 0
+	for _, used := range recorder.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// RoundTrip implements http.RoundTripper
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	incoming := Request{Method: req.Method, URL: req.URL.RequestURI(), Body: body}
+
+	if recorder.mode == ModeReplay {
+		return recorder.replay(req, incoming)
+	}
+	return recorder.record(req, incoming)
+}
+
+// DefaultMatcher matches requests with the same method, path, query and body
+func DefaultMatcher(recorded Request, incoming Request) bool {
+	return recorded.Method == incoming.Method &&
+		recorded.URL == incoming.URL &&
+		recorded.Body == incoming.Body
+}
+
+// ── replay ──────────────────────────────────────────────────────
+
+func (recorder *Recorder) replay(req *http.Request, incoming Request) (*http.Response, error) {
+	normalized := recorder.normalize(incoming)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	for index, interaction := range recorder.interactions {
+		if recorder.used[index] && !recorder.allowRepeats {
+			continue
+		}
+		if !recorder.matcher(recorder.normalize(interaction.Request), normalized) {
+			continue
+		}
+		recorder.used[index] = true
+		return buildResponse(req, interaction.Response), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, incoming.Method, incoming.URL)
+}
+
+func buildResponse(req *http.Request, recorded Response) *http.Response {
+	header := http.Header{}
+	for name, values := range recorded.Headers {
+		header[name] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
+
+// ── record ──────────────────────────────────────────────────────
+
+func (recorder *Recorder) record(req *http.Request, incoming Request) (*http.Response, error) {
+	resp, err := recorder.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string][]string{}
+	for name, values := range resp.Header {
+		if name == "Date" || name == "Set-Cookie" {
+			continue
+		}
+		headers[name] = append([]string(nil), values...)
+	}
+
+	// The body is teed so streams reach the caller live; the interaction is saved
+	// once the caller has read the whole body or closed it.
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onDone: func(body string) {
+			recorder.save(Interaction{
+				Request:  incoming,
+				Response: Response{Status: resp.StatusCode, Headers: headers, Body: body},
+			})
+		},
+	}
+	return resp, nil
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	buffer bytes.Buffer
+	once   sync.Once
+	onDone func(body string)
+}
+
+func (body *recordingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.buffer.Write(p[:n])
+	if err == io.EOF {
+		body.done()
+	}
+	return n, err
+}
+
+func (body *recordingBody) Close() error {
+	body.done()
+	return body.ReadCloser.Close()
+}
+
+func (body *recordingBody) done() {
+	body.once.Do(func() { body.onDone(body.buffer.String()) })
+}
+
+// save appends an interaction and rewrites the cassette file
+func (recorder *Recorder) save(interaction Interaction) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.interactions = append(recorder.interactions, interaction)
+	recorder.used = append(recorder.used, true)
+	// Errors can't be reported through the response body: they surface on the next Save call
+	if err := recorder.writeFile(); err != nil && recorder.saveErr == nil {
+		recorder.saveErr = err
+	}
+}
+
+// Save writes the recorded interactions to the cassette file.
+// It also returns the first failure of the automatic writes since the previous Save call.
+func (recorder *Recorder) Save() error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	saveErr := recorder.saveErr
+	recorder.saveErr = nil
+	if err := recorder.writeFile(); err != nil {
+		return err
+	}
+	return saveErr
+}
+
+func (recorder *Recorder) writeFile() error {
+	data, err := json.MarshalIndent(struct {
+		Interactions []Interaction `json:"interactions"`
+	}{recorder.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(recorder.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(recorder.path, data, 0644)
+}
+
+func (recorder *Recorder) load() error {
+	data, err := os.ReadFile(recorder.path)
+	if err != nil {
+		return fmt.Errorf("cassette: failed to read %s: %w", recorder.path, err)
+	}
+	var file struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cassette: failed to parse %s: %w", recorder.path, err)
+	}
+	recorder.interactions = file.Interactions
+	recorder.used = make([]bool, len(file.Interactions))
+	return nil
+}
+
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
@@ -0,0 +1,100 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// normalize applies the ignored fields and the normalizers to a JSON body
+// and re-encodes it canonically (sorted keys, no whitespace). Other bodies are left untouched.
+func (recorder *Recorder) normalize(request Request) Request {
+	if request.Body == "" {
+		return request
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(request.Body))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return request
+	}
+
+	for _, path := range recorder.ignoredFields {
+		removeField(body, strings.Split(path, "."))
+	}
+	for _, normalizer := range recorder.normalizers {
+		body = normalizer(body)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		return request
+	}
+	request.Body = strings.TrimSuffix(buffer.String(), "\n")
+	return request
+}
+
+// removeField deletes a dotted path from a decoded JSON value.
+// Arrays are traversed, so "messages.name" removes the name of every message.
+func removeField(value any, path []string) {
+	switch node := value.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			delete(node, path[0])
+			return
+		}
+		if child, ok := node[path[0]]; ok {
+			removeField(child, path[1:])
+		}
+	case []any:
+		for _, item := range node {
+			removeField(item, path)
+		}
+	}
+}
+
+// NormalizeToolCallIDs replaces the generated tool call IDs of chat completion requests
+// (messages[].tool_calls[].id and messages[].tool_call_id) with stable placeholders
+// numbered by order of appearance, so replays match even though IDs differ between runs.
+func NormalizeToolCallIDs(body any) any {
+	request, ok := body.(map[string]any)
+	if !ok {
+		return body
+	}
+	messages, ok := request["messages"].([]any)
+	if !ok {
+		return body
+	}
+
+	placeholders := map[string]string{}
+	placeholder := func(id string) string {
+		if _, exists := placeholders[id]; !exists {
+			placeholders[id] = fmt.Sprintf("call_%d", len(placeholders)+1)
+		}
+		return placeholders[id]
+	}
+
+	for _, item := range messages {
+		message, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if toolCalls, ok := message["tool_calls"].([]any); ok {
+			for _, call := range toolCalls {
+				if toolCall, ok := call.(map[string]any); ok {
+					if id, ok := toolCall["id"].(string); ok {
+						toolCall["id"] = placeholder(id)
+					}
+				}
+			}
+		}
+		if id, ok := message["tool_call_id"].(string); ok {
+			message["tool_call_id"] = placeholder(id)
+		}
+	}
+	return body
+}
@@ -0,0 +1,229 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ── helpers ───────────────────────────────────────────────────────────────────
+
+// newEngine returns a minimal OpenAI-compatible server and a counter of received requests
+func newEngine(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, word := range []string{"Hello", " from", " the", " stream"} {
+				fmt.Fprintf(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", word)
+			}
+			fmt.Fprint(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"c1","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"answer %d"},"finish_reason":"stop"}]}`, calls)
+	}))
+	return server, &calls
+}
+
+func newOpenAIClient(baseURL string, recorder *Recorder) *openai.Client {
+	client := openai.NewClient(
+		option.WithBaseURL(baseURL),
+		option.WithAPIKey("secret-key"),
+		option.WithHTTPClient(recorder.Client()),
+	)
+	return &client
+}
+
+func chatParams(content string, seed int64) openai.ChatCompletionNewParams {
+	return openai.ChatCompletionNewParams{
+		Model:    "m",
+		Seed:     openai.Int(seed),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(content)},
+	}
+}
+
+func streamContent(t *testing.T, client *openai.Client, params openai.ChatCompletionNewParams) string {
+	t.Helper()
+	stream := client.Chat.Completions.NewStreaming(context.Background(), params)
+	var content strings.Builder
+	for stream.Next() {
+		if chunk := stream.Current(); len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	return content.String()
+}
+
+// ── record / replay ───────────────────────────────────────────────────────────
+
+func TestRecordThenReplay_CompletionAndStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server, calls := newEngine(t)
+
+	recorder, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := newOpenAIClient(server.URL, recorder)
+	completion, err := client.Chat.Completions.New(context.Background(), chatParams("hi", 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorded := completion.Choices[0].Message.Content
+	if got := streamContent(t, client, chatParams("stream please", 1)); got != "Hello from the stream" {
+		t.Fatalf("unexpected live stream %q", got)
+	}
+	server.Close()
+
+	replayer, err := New(path, ModeAuto)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayer.Mode() != ModeReplay {
+		t.Fatalf("auto mode should replay an existing cassette, got %s", replayer.Mode())
+	}
+	client = newOpenAIClient(server.URL, replayer)
+	completion, err = client.Chat.Completions.New(context.Background(), chatParams("hi", 1))
+	if err != nil {
+		t.Fatalf("replay error: %v", err)
+	}
+	if completion.Choices[0].Message.Content != recorded {
+		t.Errorf("want %q, got %q", recorded, completion.Choices[0].Message.Content)
+	}
+	if got := streamContent(t, client, chatParams("stream please", 1)); got != "Hello from the stream" {
+		t.Errorf("unexpected replayed stream %q", got)
+	}
+	if *calls != 2 || replayer.Remaining() != 0 {
+		t.Errorf("want 2 live calls and no remaining interaction, got %d / %d", *calls, replayer.Remaining())
+	}
+}
+
+func TestRecord_DoesNotStoreHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server, _ := newEngine(t)
+	defer server.Close()
+
+	recorder, _ := New(path, ModeRecord)
+	_, _ = newOpenAIClient(server.URL, recorder).Chat.Completions.New(context.Background(), chatParams("hi", 1))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cassette should be written after the interaction: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") || strings.Contains(string(data), "Authorization") {
+		t.Error("request headers (API key) must never be recorded")
+	}
+	if len(recorder.Interactions()) != 1 {
+		t.Errorf("want 1 interaction, got %d", len(recorder.Interactions()))
+	}
+}
+
+func TestRecord_SaveReturnsTheAutomaticWriteError(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := newEngine(t)
+	defer server.Close()
+
+	// The parent of the cassette is a file: the automatic write fails
+	recorder, _ := New(filepath.Join(blocker, "session.json"), ModeRecord)
+	if _, err := newOpenAIClient(server.URL, recorder).Chat.Completions.New(context.Background(), chatParams("hi", 1)); err != nil {
+		t.Fatalf("the response should not depend on the cassette write: %v", err)
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err == nil {
+		t.Error("Save should return the error of the failed automatic write")
+	}
+	if err := recorder.Save(); err != nil {
+		t.Errorf("the error should be returned once, got %v", err)
+	}
+}
+
+func TestReplay_UnmatchedRequestFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server, _ := newEngine(t)
+	recorder, _ := New(path, ModeRecord)
+	_, _ = newOpenAIClient(server.URL, recorder).Chat.Completions.New(context.Background(), chatParams("hi", 1))
+	server.Close()
+
+	replayer, _ := New(path, ModeReplay)
+	client := newOpenAIClient(server.URL, replayer)
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("something else", 1), option.WithMaxRetries(0))
+	if !errors.Is(err, ErrNoMatch) {
+		t.Errorf("want ErrNoMatch, got %v", err)
+	}
+
+	// Interactions are served once unless repeats are allowed
+	_, _ = client.Chat.Completions.New(context.Background(), chatParams("hi", 1), option.WithMaxRetries(0))
+	_, err = client.Chat.Completions.New(context.Background(), chatParams("hi", 1), option.WithMaxRetries(0))
+	if !errors.Is(err, ErrNoMatch) {
+		t.Errorf("want ErrNoMatch on second replay, got %v", err)
+	}
+}
+
+func TestReplay_IgnoredFieldsAndRepeats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	server, _ := newEngine(t)
+	recorder, _ := New(path, ModeRecord)
+	_, _ = newOpenAIClient(server.URL, recorder).Chat.Completions.New(context.Background(), chatParams("hi", 1))
+	server.Close()
+
+	replayer, _ := New(path, ModeReplay, WithIgnoredFields("seed"), WithRepeats())
+	client := newOpenAIClient(server.URL, replayer)
+	for _, seed := range []int64{2, 3} {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("hi", seed), option.WithMaxRetries(0)); err != nil {
+			t.Errorf("seed %d should be ignored, got %v", seed, err)
+		}
+	}
+}
+
+func TestNew_ReplayWithoutFileFails(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Error("replay mode requires an existing cassette")
+	}
+}
+
+// ── normalization ─────────────────────────────────────────────────────────────
+
+func TestNormalizeToolCallIDs(t *testing.T) {
+	recorder := &Recorder{normalizers: []Normalizer{NormalizeToolCallIDs}}
+	first := recorder.normalize(Request{Body: `{"messages":[{"role":"assistant","tool_calls":[{"id":"call_abc","type":"function"}]},{"role":"tool","tool_call_id":"call_abc","content":"42"}]}`})
+	second := recorder.normalize(Request{Body: `{"messages":[{"role":"assistant","tool_calls":[{"id":"call_xyz","type":"function"}]},{"role":"tool","tool_call_id":"call_xyz","content":"42"}]}`})
+	if first.Body != second.Body {
+		t.Errorf("generated IDs should be normalized:\n%s\n%s", first.Body, second.Body)
+	}
+	if !strings.Contains(first.Body, `"tool_call_id":"call_1"`) {
+		t.Errorf("want placeholder IDs, got %s", first.Body)
+	}
+}
+
+func TestRemoveField_NestedAndArrays(t *testing.T) {
+	recorder := &Recorder{ignoredFields: []string{"stream_options.include_usage", "messages.name"}}
+	got := recorder.normalize(Request{Body: `{"stream_options":{"include_usage":true},"messages":[{"role":"user","name":"bob"}]}`})
+	if got.Body != `{"messages":[{"role":"user"}],"stream_options":{}}` {
+		t.Errorf("unexpected normalized body %s", got.Body)
+	}
+}
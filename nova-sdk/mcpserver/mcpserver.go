@@ -0,0 +1,229 @@
+// Package mcpserver publishes nova tools and agents as an MCP server, so they can be used
+// from any MCP client (IDE assistants, other agents...). The typed tools of a tools agent keep
+// their handlers, and chat, rag and structured agents become tools (see agenttool).
+// The server is served over stdio or streamable HTTP with mark3labs/mcp-go.
+//
+//	mcpServer, err := mcpserver.NewServer("nova-tools", "1.0.0",
+//		mcpserver.WithToolsAgent(toolsAgent),
+//		mcpserver.WithChatAgent(sqlExpert),
+//	)
+//	err = mcpServer.ServeStdio()
+//
+// The tools are annotated as read-only or destructive from tools.Tool.SideEffects. The tools
+// with side effects (e.g. the run_command and write_file tools of the tooling package) are
+// published only with WithSideEffectingTools: any client of the server can call them.
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/snipwise/nova/nova-sdk/agents/agenttool"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/agents/rag"
+	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+)
+
+// DefaultEndpointPath is the path of the streamable HTTP endpoint
+const DefaultEndpointPath = "/mcp"
+
+// Server is an MCP server publishing registered tools
+type Server struct {
+	mcpServer    *server.MCPServer
+	name         string
+	version      string
+	instructions string
+	tools        []*tools.RegisteredTool
+	audit        audit.Config
+	// sideEffects allows the publication of the tools with side effects
+	sideEffects bool
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithInstructions sets the instructions sent to the clients at initialization
+func WithInstructions(instructions string) Option {
+	return func(s *Server) {
+		s.instructions = instructions
+	}
+}
+
+// WithTools publishes typed tools (see tools.Register)
+func WithTools(registeredTools ...*tools.RegisteredTool) Option {
+	return func(s *Server) {
+		s.tools = append(s.tools, registeredTools...)
+	}
+}
+
+// WithSideEffectingTools allows the publication of the tools with side effects (see
+// tools.Tool.SideEffects): without it, NewServer and AddTools reject them
+func WithSideEffectingTools() Option {
+	return func(s *Server) {
+		s.sideEffects = true
+	}
+}
+
+// WithToolsAgent publishes the typed tools registered on a tools agent, with their handlers
+// (the tools without a handler, executed by the callback of the agent, are not published)
+func WithToolsAgent(agent *tools.Agent) Option {
+	return WithTools(agent.GetRegisteredTools()...)
+}
+
+// WithChatAgent publishes a chat agent as a tool (see agenttool.Chat)
+func WithChatAgent(agent *chat.Agent, options ...agenttool.Option) Option {
+	return WithTools(agenttool.Chat(agent, options...))
+}
+
+// WithRagAgent publishes the similarity search of a rag agent as a tool (see agenttool.Rag)
+func WithRagAgent(agent *rag.Agent, similarityLimit float64, options ...agenttool.Option) Option {
+	return WithTools(agenttool.Rag(agent, similarityLimit, options...))
+}
+
+// WithStructuredAgent publishes a structured agent as an extraction tool (see agenttool.Structured)
+func WithStructuredAgent[Output any](agent *structured.Agent[Output], options ...agenttool.Option) Option {
+	return WithTools(agenttool.Structured(agent, options...))
+}
+
+// WithAudit emits an audit event for every tool call received by the server
+// (the session is the MCP session of the client)
+func WithAudit(config audit.Config) Option {
+	return func(s *Server) {
+		s.audit = config.WithSource(audit.SourceMCPServer)
+	}
+}
+
+// NewServer creates an MCP server publishing the tools of the options
+func NewServer(name string, version string, options ...Option) (*Server, error) {
+	if name == "" {
+		return nil, errors.New("the MCP server needs a name")
+	}
+	s := &Server{name: name, version: version}
+	for _, option := range options {
+		option(s)
+	}
+
+	serverOptions := []server.ServerOption{server.WithToolCapabilities(true)}
+	if s.instructions != "" {
+		serverOptions = append(serverOptions, server.WithInstructions(s.instructions))
+	}
+	s.mcpServer = server.NewMCPServer(name, version, serverOptions...)
+
+	registeredTools := s.tools
+	s.tools = nil
+	if err := s.AddTools(registeredTools...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddTools publishes typed tools (a tool with the same name is replaced).
+// The connected clients are notified of the change. The tools with side effects are rejected
+// without WithSideEffectingTools.
+func (s *Server) AddTools(registeredTools ...*tools.RegisteredTool) error {
+	serverTools := make([]server.ServerTool, 0, len(registeredTools))
+	for _, registeredTool := range registeredTools {
+		if registeredTool == nil || registeredTool.Tool == nil || registeredTool.Handler == nil {
+			return errors.New("a published tool needs a definition and a handler")
+		}
+		if registeredTool.Tool.SideEffects && !s.sideEffects {
+			return fmt.Errorf("tool %s has side effects: use WithSideEffectingTools to publish it", registeredTool.Tool.GetName())
+		}
+		schema, err := json.Marshal(registeredTool.Tool.Schema())
+		if err != nil {
+			return fmt.Errorf("invalid schema for tool %s: %w", registeredTool.Tool.GetName(), err)
+		}
+		mcpTool := mcp.NewToolWithRawSchema(registeredTool.Tool.GetName(), registeredTool.Tool.GetDescription(), schema)
+		mcpTool.Annotations.ReadOnlyHint = mcp.ToBoolPtr(!registeredTool.Tool.SideEffects)
+		mcpTool.Annotations.DestructiveHint = mcp.ToBoolPtr(registeredTool.Tool.SideEffects)
+		serverTools = append(serverTools, server.ServerTool{Tool: mcpTool, Handler: s.toolHandler(registeredTool)})
+		s.tools = slices.DeleteFunc(s.tools, func(existing *tools.RegisteredTool) bool {
+			return existing.Tool.GetName() == registeredTool.Tool.GetName()
+		})
+		s.tools = append(s.tools, registeredTool)
+	}
+	s.mcpServer.AddTools(serverTools...)
+	return nil
+}
+
+// GetTools returns the published tools
+func (s *Server) GetTools() []*tools.RegisteredTool {
+	return slices.Clone(s.tools)
+}
+
+// GetMCPServer returns the underlying mcp-go server (e.g. to add resources and prompts)
+func (s *Server) GetMCPServer() *server.MCPServer {
+	return s.mcpServer
+}
+
+// ServeStdio serves the tools over stdin/stdout until stdin is closed
+func (s *Server) ServeStdio() error {
+	return server.ServeStdio(s.mcpServer)
+}
+
+// StreamableHTTPHandler returns the http.Handler of the streamable HTTP transport,
+// to mount on an existing mux
+func (s *Server) StreamableHTTPHandler() http.Handler {
+	return server.NewStreamableHTTPServer(s.mcpServer)
+}
+
+// ServeStreamableHTTP serves the tools over streamable HTTP on addr (e.g. ":9090"),
+// at DefaultEndpointPath
+func (s *Server) ServeStreamableHTTP(addr string) error {
+	return server.NewStreamableHTTPServer(s.mcpServer, server.WithEndpointPath(DefaultEndpointPath)).Start(addr)
+}
+
+// toolHandler dispatches the MCP calls of a tool to its handler. The handler errors
+// are returned as tool errors, so the client model can read them.
+func (s *Server) toolHandler(registeredTool *tools.RegisteredTool) server.ToolHandlerFunc {
+	name := registeredTool.Tool.GetName()
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := "{}"
+		if request.Params.Arguments != nil {
+			raw, err := json.Marshal(request.Params.Arguments)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid arguments for tool %s: %v", name, err)), nil
+			}
+			arguments = string(raw)
+		}
+
+		start := time.Now()
+		result, err := registeredTool.Handler(ctx, arguments)
+		s.auditCall(ctx, name, arguments, result, err, time.Since(start))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(result), nil
+	}
+}
+
+// auditCall emits the audit event of a tool call
+func (s *Server) auditCall(ctx context.Context, name string, arguments string, result string, err error, duration time.Duration) {
+	if !s.audit.Enabled() {
+		return
+	}
+	event := audit.Event{
+		Tool:         name,
+		Arguments:    arguments,
+		Confirmation: audit.ConfirmationNotRequired,
+		Status:       audit.StatusSuccess,
+		DurationMs:   audit.Milliseconds(duration),
+		Result:       result,
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		event.Session = session.SessionID()
+	}
+	if err != nil {
+		event.Status, event.Error = audit.StatusError, err.Error()
+	}
+	s.audit.Emit(event)
+}
@@ -0,0 +1,180 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/audit"
+	"github.com/snipwise/nova/nova-sdk/mcptools"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+type WeatherInput struct {
+	City string `json:"city" description:"Name of the city"`
+}
+
+func newTestServer(t *testing.T, engine *novatest.Server, options ...Option) *Server {
+	t.Helper()
+	toolsAgent, err := tools.NewAgent(context.Background(),
+		agents.Config{Name: "tools", EngineURL: engine.URL, SystemInstructions: "You call tools"},
+		models.Config{Name: "test-model"},
+		tools.WithRegisteredTools(
+			tools.Register("get_weather", "Get the weather of a city",
+				func(ctx context.Context, input WeatherInput) (string, error) {
+					return "sunny in " + input.City, nil
+				}),
+			tools.Register("fail", "Always fails",
+				func(ctx context.Context, input struct{}) (string, error) {
+					return "", errors.New("boom")
+				}),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chatAgent, err := chat.NewAgent(context.Background(),
+		agents.Config{Name: "Poet", EngineURL: engine.URL, SystemInstructions: "You write haikus."},
+		models.Config{Name: "test-model"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	options = append([]Option{WithToolsAgent(toolsAgent), WithChatAgent(chatAgent)}, options...)
+	mcpServer, err := NewServer("nova-test", "1.0.0", options...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return mcpServer
+}
+
+func TestServer_PublishesToolsAndAgents(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("Autumn moonlight")))
+	defer engine.Close()
+
+	var events []audit.Event
+	mcpServer := newTestServer(t, engine, WithAudit(audit.Config{Sinks: []audit.Sink{audit.SinkFunc(func(event audit.Event) error {
+		events = append(events, event)
+		return nil
+	})}}))
+
+	mcpClient, err := client.NewInProcessClient(mcpServer.GetMCPServer())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+	ctx := context.Background()
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	listed, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := map[string]mcp.Tool{}
+	for _, tool := range listed.Tools {
+		names[tool.Name] = tool
+	}
+	if len(names) != 3 || names["ask_poet"].Name == "" {
+		t.Fatalf("unexpected tools %+v", listed.Tools)
+	}
+	if schema := names["get_weather"].InputSchema; schema.Properties["city"] == nil || len(schema.Required) != 1 {
+		t.Error("the schema of the typed tool should be published")
+	}
+
+	call := func(name string, arguments map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = arguments
+		result, err := mcpClient.CallTool(ctx, request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	if result := call("get_weather", map[string]any{"city": "Lyon"}); result.IsError || result.Content[0].(mcp.TextContent).Text != "sunny in Lyon" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := call("ask_poet", map[string]any{"task": "a haiku about the moon"}); result.Content[0].(mcp.TextContent).Text != "Autumn moonlight" {
+		t.Errorf("the chat agent should answer, got %+v", result)
+	}
+	if result := call("fail", nil); !result.IsError || result.Content[0].(mcp.TextContent).Text != "boom" {
+		t.Errorf("the handler error should be a tool error, got %+v", result)
+	}
+
+	if len(events) != 3 || events[0].Source != audit.SourceMCPServer || events[0].Arguments != `{"city":"Lyon"}` ||
+		events[2].Status != audit.StatusError {
+		t.Errorf("unexpected audit events %+v", events)
+	}
+}
+
+func TestServer_StreamableHTTP(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("done")))
+	defer engine.Close()
+
+	mcpServer := newTestServer(t, engine)
+	httpServer := httptest.NewServer(mcpServer.StreamableHTTPHandler())
+	defer httpServer.Close()
+
+	mcpClient, err := mcptools.NewStreamableHttpMCPClient(context.Background(), httpServer.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	text, err := mcpClient.ExecToolWithAny("get_weather", WeatherInput{City: "Paris"})
+	if err != nil || text != "sunny in Paris" {
+		t.Errorf("unexpected result %q, error %v", text, err)
+	}
+}
+
+func TestServer_SideEffectingTools(t *testing.T) {
+	deploy := tools.Register("deploy", "Deploy the application",
+		func(ctx context.Context, input struct{}) (string, error) {
+			return "deployed", nil
+		})
+	deploy.Tool.SetSideEffects(true)
+	lookup := tools.Register("lookup", "Look up a value",
+		func(ctx context.Context, input struct{}) (string, error) {
+			return "value", nil
+		})
+
+	if _, err := NewServer("nova-test", "1.0.0", WithTools(lookup, deploy)); err == nil {
+		t.Error("a tool with side effects should not be published without WithSideEffectingTools")
+	}
+
+	mcpServer, err := NewServer("nova-test", "1.0.0", WithTools(lookup, deploy), WithSideEffectingTools())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mcpClient, err := client.NewInProcessClient(mcpServer.GetMCPServer())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+	ctx := context.Background()
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	listed, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tool := range listed.Tools {
+		readOnly, destructive := tool.Annotations.ReadOnlyHint, tool.Annotations.DestructiveHint
+		if readOnly == nil || destructive == nil || *readOnly != (tool.Name == "lookup") || *destructive != (tool.Name == "deploy") {
+			t.Errorf("%s: unexpected annotations %+v", tool.Name, tool.Annotations)
+		}
+	}
+}
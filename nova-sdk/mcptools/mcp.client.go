@@ -0,0 +1,382 @@
+package mcptools
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/cassette"
+)
+
+// Transports of the MCP clients
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "streamable_http"
+)
+
+// Default client information sent to the MCP servers
+const (
+	DefaultClientName    = "nova"
+	DefaultClientVersion = "0.0.0"
+)
+
+// TokenProvider returns the bearer token sent with every HTTP request
+// (called for each request, so it can refresh an OAuth access token). It must be safe for
+// concurrent use: the listening stream of the streamable HTTP transport runs with the requests.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// MCPClientOption configures an MCP client created with NewMCPClient
+type MCPClientOption func(*mcpClientConfig)
+
+type mcpClientConfig struct {
+	transport string
+	// stdio
+	command string
+	env     []string
+	args    []string
+	// SSE and streamable HTTP
+	url                   string
+	headers               map[string]string
+	tokenProvider         TokenProvider
+	httpClient            *http.Client
+	tlsConfig             *tls.Config
+	streamableHTTPOptions []transport.StreamableHTTPCOption
+
+	timeout       time.Duration
+	clientName    string
+	clientVersion string
+
+	reconnect            ReconnectPolicy
+	toolsChangedHandlers []ToolsChangedHandler
+
+	// Handlers of the server requests (sampling, elicitation)
+	clientOptions []client.ClientOption
+}
+
+// WithStdioTransport starts the MCP server as a subprocess and talks to it over stdin/stdout
+func WithStdioTransport(command string, env []string, args ...string) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.transport = TransportStdio
+		config.command, config.env, config.args = command, env, args
+	}
+}
+
+// WithSSETransport connects to an MCP server over the (legacy) HTTP+SSE transport
+func WithSSETransport(url string) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.transport = TransportSSE
+		config.url = url
+	}
+}
+
+// WithStreamableHTTPTransport connects to an MCP server over the streamable HTTP transport.
+// Low-level transport options of mcp-go can be appended. The client listens to the
+// notifications and requests of the server (tools list changes, resource updates, sampling)
+// on a continuous stream, when the server supports it.
+func WithStreamableHTTPTransport(url string, options ...transport.StreamableHTTPCOption) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.transport = TransportStreamableHTTP
+		config.url = url
+		config.streamableHTTPOptions = append(config.streamableHTTPOptions, options...)
+	}
+}
+
+// WithHeaders adds headers to every HTTP request (SSE and streamable HTTP)
+func WithHeaders(headers map[string]string) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		if config.headers == nil {
+			config.headers = make(map[string]string)
+		}
+		maps.Copy(config.headers, headers)
+	}
+}
+
+// WithHeader adds a header to every HTTP request (SSE and streamable HTTP)
+func WithHeader(key string, value string) MCPClientOption {
+	return WithHeaders(map[string]string{key: value})
+}
+
+// WithBearerToken sends a static bearer token in the Authorization header
+func WithBearerToken(token string) MCPClientOption {
+	return WithTokenProvider(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// WithTokenProvider sends the token returned by provider in the Authorization header
+// of every HTTP request. A provider error fails the request.
+func WithTokenProvider(provider TokenProvider) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.tokenProvider = provider
+	}
+}
+
+// WithHTTPClient uses a custom http.Client for the SSE and streamable HTTP transports.
+// Don't set its Timeout with the SSE transport (it would close the event stream),
+// use WithRequestTimeout instead.
+func WithHTTPClient(httpClient *http.Client) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.httpClient = httpClient
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the HTTP connections
+// (client certificates, custom root CAs...)
+func WithTLSConfig(tlsConfig *tls.Config) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.tlsConfig = tlsConfig
+	}
+}
+
+// WithRequestTimeout bounds every MCP request (initialization, listing, tool calls)
+func WithRequestTimeout(timeout time.Duration) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.timeout = timeout
+	}
+}
+
+// WithClientInfo sets the name and version of the client sent to the server
+// (default: DefaultClientName and DefaultClientVersion)
+func WithClientInfo(name string, version string) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.clientName, config.clientVersion = name, version
+	}
+}
+
+// NewMCPClient creates, initializes and lists the tools of an MCP client.
+// A transport option is required: WithStdioTransport, WithSSETransport or WithStreamableHTTPTransport.
+// When NOVA_CASSETTE is set and no http.Client is given, the HTTP traffic goes through the cassette recorder.
+//
+// Usage:
+//
+//	mcpClient, err := mcptools.NewMCPClient(ctx,
+//		mcptools.WithSSETransport("https://mcp.example.com/sse"),
+//		mcptools.WithBearerToken(os.Getenv("MCP_TOKEN")),
+//		mcptools.WithRequestTimeout(30*time.Second),
+//	)
+func NewMCPClient(ctx context.Context, options ...MCPClientOption) (*MCPClient, error) {
+	config := &mcpClientConfig{
+		clientName:    DefaultClientName,
+		clientVersion: DefaultClientVersion,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	c := &MCPClient{
+		ctx:                  ctx,
+		config:               config,
+		timeout:              config.timeout,
+		toolsChangedHandlers: config.toolsChangedHandlers,
+	}
+	mcpClient, mcpTools, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.mcpclient, c.ToolsResult = mcpClient, mcpTools
+	return c, nil
+}
+
+// connect creates, starts and initializes a connection, and lists the tools of the server
+func (c *MCPClient) connect() (*client.Client, *mcp.ListToolsResult, error) {
+	mcpClient, err := c.config.newClient(c.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	mcpClient.OnNotification(c.handleNotification)
+	mcpClient.OnConnectionLost(func(err error) {
+		c.handleConnectionLost(mcpClient, err)
+	})
+
+	mcpTools, err := c.initialize(mcpClient)
+	if err != nil {
+		_ = mcpClient.Close()
+		return nil, nil, err
+	}
+	return mcpClient, mcpTools, nil
+}
+
+// newClient creates and starts the mcp-go client of the transport
+func (config *mcpClientConfig) newClient(ctx context.Context) (*client.Client, error) {
+	switch config.transport {
+	case TransportStdio:
+		mcpClient := client.NewClient(transport.NewStdio(config.command, config.env, config.args...), config.clientOptions...)
+		// The subprocess lives until the client is closed
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to start stdio transport: %w", err)
+		}
+		return mcpClient, nil
+
+	case TransportSSE:
+		if len(config.clientOptions) > 0 {
+			return nil, errors.New("the SSE transport doesn't support the server requests (sampling, elicitation): use the stdio or streamable HTTP transport")
+		}
+		httpClient, err := config.newHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		mcpClient, err := client.NewSSEMCPClient(config.url, transport.WithHTTPClient(httpClient))
+		if err != nil {
+			return nil, err
+		}
+		// The context of Start holds the event stream: it must outlive the requests
+		if err := mcpClient.Start(ctx); err != nil {
+			return nil, err
+		}
+		return mcpClient, nil
+
+	case TransportStreamableHTTP:
+		httpClient, err := config.newHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		// Prepend so an explicit transport.WithHTTPBasicClient still takes precedence
+		httpOptions := append([]transport.StreamableHTTPCOption{transport.WithHTTPBasicClient(httpClient)}, config.streamableHTTPOptions...)
+		// The server sends its notifications (e.g. tools/list_changed, refreshing the tools)
+		// and its requests on the listening stream
+		httpOptions = append(httpOptions, transport.WithContinuousListening())
+		streamableHTTP, err := transport.NewStreamableHTTP(config.url, httpOptions...)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions := config.clientOptions
+		if streamableHTTP.GetSessionId() != "" {
+			// Resumed session (transport.WithSession)
+			clientOptions = append(slices.Clone(clientOptions), client.WithSession())
+		}
+		mcpClient := client.NewClient(streamableHTTP, clientOptions...)
+		if err := mcpClient.Start(ctx); err != nil {
+			return nil, err
+		}
+		return mcpClient, nil
+
+	case "":
+		return nil, errors.New("no MCP transport configured: use WithStdioTransport, WithSSETransport or WithStreamableHTTPTransport")
+	default:
+		return nil, fmt.Errorf("unknown MCP transport %q", config.transport)
+	}
+}
+
+// newHTTPClient returns the http.Client of the HTTP transports, adding the headers
+// and the bearer token to its requests
+func (config *mcpClientConfig) newHTTPClient() (*http.Client, error) {
+	httpClient := config.httpClient
+	if httpClient == nil {
+		cassetteClient, err := cassette.HTTPClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		httpClient = cassetteClient
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	if len(config.headers) == 0 && config.tokenProvider == nil && config.tlsConfig == nil {
+		return httpClient, nil
+	}
+
+	base := httpClient.Transport
+	if config.tlsConfig != nil {
+		defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+		if base != nil {
+			defaultTransport, ok = base.(*http.Transport)
+		}
+		if !ok {
+			return nil, errors.New("a TLS config requires an http.Client using an *http.Transport")
+		}
+		transportWithTLS := defaultTransport.Clone()
+		transportWithTLS.TLSClientConfig = config.tlsConfig
+		base = transportWithTLS
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// Copy the client so the caller's one is not modified
+	withAuth := *httpClient
+	withAuth.Transport = &authTransport{base: base, headers: config.headers, tokenProvider: config.tokenProvider}
+	return &withAuth, nil
+}
+
+// authTransport adds the configured headers and bearer token to the requests
+type authTransport struct {
+	base          http.RoundTripper
+	headers       map[string]string
+	tokenProvider TokenProvider
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request
+	request = request.Clone(request.Context())
+	for key, value := range t.headers {
+		request.Header.Set(key, value)
+	}
+	if t.tokenProvider != nil {
+		token, err := t.tokenProvider(request.Context())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the MCP bearer token: %w", err)
+		}
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return t.base.RoundTrip(request)
+}
+
+// initialize sends the initialization request and lists the tools of the server
+func (c *MCPClient) initialize(mcpClient *client.Client) (*mcp.ListToolsResult, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    c.config.clientName,
+		Version: c.config.clientVersion,
+	}
+	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
+		return nil, err
+	}
+	return mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+}
+
+// current returns the current connection (replaced by the reconnections)
+func (c *MCPClient) current() *client.Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.mcpclient
+}
+
+// requestContext returns the context of a request, bounded by the request timeout
+func (c *MCPClient) requestContext() (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(c.ctx, c.timeout)
+	}
+	return context.WithCancel(c.ctx)
+}
+
+// callContext returns the context of a request made for a caller: it is canceled with ctx or
+// with the context of the client, and bounded by the request timeout
+func (c *MCPClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	stop := context.AfterFunc(c.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,263 @@
+package mcptools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Kinds of the tool call errors (see ToolCallError)
+var (
+	// ErrConnection: the server could not be reached (and the reconnection failed)
+	ErrConnection = errors.New("MCP connection failed")
+	// ErrToolFailed: the tool returned an error result
+	ErrToolFailed = errors.New("MCP tool failed")
+	// ErrNoContent: the tool returned an empty result
+	ErrNoContent = errors.New("no content returned")
+	// ErrProtocol: the server rejected the request (unknown tool, invalid arguments...)
+	ErrProtocol = errors.New("MCP request rejected")
+)
+
+// ToolCallError is the error of a tool executed through an MCP client.
+// Use errors.Is with its Kind (ErrConnection, ErrToolFailed, ErrNoContent, ErrProtocol).
+type ToolCallError struct {
+	Tool string
+	Kind error
+	Err  error
+}
+
+// Error implements error
+func (e *ToolCallError) Error() string {
+	switch {
+	case e.Kind == ErrNoContent:
+		return fmt.Sprintf("no content returned from tool %s", e.Tool)
+	case e.Kind == ErrToolFailed:
+		return fmt.Sprintf("tool %s failed: %v", e.Tool, e.Err)
+	default:
+		return fmt.Sprintf("error calling tool %s: %v", e.Tool, e.Err)
+	}
+}
+
+// Unwrap returns the kind and the cause of the error
+func (e *ToolCallError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ReconnectPolicy sets the reconnection of a client whose connection is lost
+// (crashed stdio subprocess, restarted HTTP server...)
+type ReconnectPolicy struct {
+	// MaxAttempts of a reconnection (0: no reconnection)
+	MaxAttempts int
+	// InitialBackoff between the attempts, doubled after each failure up to MaxBackoff
+	// (default: 500ms and 30s)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultReconnectPolicy retries 5 times, from 500ms to 30s between the attempts
+var DefaultReconnectPolicy = ReconnectPolicy{MaxAttempts: 5, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+
+// ToolsChangedHandler receives the new tool list of the server
+// (e.g. tools.Agent.SetMCPTools to update the tools of an agent at runtime)
+type ToolsChangedHandler func(tools []mcp.Tool)
+
+// WithReconnect reconnects the client when its connection is lost: the failed tool call is
+// sent again once after the reconnection, so the tools should be idempotent
+func WithReconnect(policy ReconnectPolicy) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.reconnect = policy
+	}
+}
+
+// WithToolsChangedHandler calls handler when the tool list of the server changes
+// (notifications/tools/list_changed, or a different list after a reconnection)
+func WithToolsChangedHandler(handler ToolsChangedHandler) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.toolsChangedHandlers = append(config.toolsChangedHandlers, handler)
+	}
+}
+
+// OnToolsChanged calls handler when the tool list of the server changes
+func (c *MCPClient) OnToolsChanged(handler ToolsChangedHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.toolsChangedHandlers = append(c.toolsChangedHandlers, handler)
+}
+
+// Ping checks that the server answers
+func (c *MCPClient) Ping() error {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	if err := c.current().Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	return nil
+}
+
+// RefreshTools lists the tools of the server again and calls the handlers when they changed
+func (c *MCPClient) RefreshTools() error {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	mcpTools, err := c.current().ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("error listing tools: %w", err)
+	}
+	c.setTools(mcpTools)
+	return nil
+}
+
+// Reconnect replaces the connection with a new one, following the reconnect policy
+// (a single attempt when the policy doesn't allow reconnections)
+func (c *MCPClient) Reconnect() error {
+	return c.reconnect(c.current())
+}
+
+// setTools replaces the tool list and calls the handlers when it changed
+func (c *MCPClient) setTools(mcpTools *mcp.ListToolsResult) {
+	c.mutex.Lock()
+	changed := !sameTools(c.ToolsResult, mcpTools)
+	c.ToolsResult = mcpTools
+	handlers := c.toolsChangedHandlers
+	c.mutex.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler(mcpTools.Tools)
+		}
+	}
+}
+
+// callTool calls a tool, reconnecting and calling it again once when the connection is lost
+func (c *MCPClient) callTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	call := func(mcpClient *client.Client) (*mcp.CallToolResult, error) {
+		requestCtx, cancel := c.callContext(ctx)
+		defer cancel()
+		return mcpClient.CallTool(requestCtx, request)
+	}
+
+	mcpClient := c.current()
+	result, err := call(mcpClient)
+	if err != nil && isConnectionError(err) && c.config.reconnect.MaxAttempts > 0 {
+		if errReconnect := c.reconnect(mcpClient); errReconnect != nil {
+			return nil, &ToolCallError{Tool: request.Params.Name, Kind: ErrConnection, Err: errReconnect}
+		}
+		result, err = call(c.current())
+	}
+	if err != nil {
+		kind := ErrProtocol
+		if isConnectionError(err) {
+			kind = ErrConnection
+		}
+		return nil, &ToolCallError{Tool: request.Params.Name, Kind: kind, Err: err}
+	}
+	return result, nil
+}
+
+// handleConnectionLost reconnects in the background when the transport reports a lost connection
+func (c *MCPClient) handleConnectionLost(mcpClient *client.Client, err error) {
+	if c.config.reconnect.MaxAttempts == 0 {
+		return
+	}
+	go func() {
+		_ = c.reconnect(mcpClient)
+	}()
+}
+
+// reconnect replaces the failed connection (nothing is done when another call already replaced it)
+func (c *MCPClient) reconnect(failed *client.Client) error {
+	c.reconnectMutex.Lock()
+	defer c.reconnectMutex.Unlock()
+
+	c.mutex.Lock()
+	closed, replaced := c.closed, c.mcpclient != failed
+	c.mutex.Unlock()
+	if closed {
+		return errors.New("the MCP client is closed")
+	}
+	if replaced {
+		return nil
+	}
+	_ = failed.Close()
+
+	policy := c.config.reconnect
+	attempts := max(policy.MaxAttempts, 1)
+	backoff := policy.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultReconnectPolicy.InitialBackoff
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultReconnectPolicy.MaxBackoff
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var mcpClient *client.Client
+		var mcpTools *mcp.ListToolsResult
+		mcpClient, mcpTools, err = c.connect()
+		if err == nil {
+			c.mutex.Lock()
+			if c.closed {
+				c.mutex.Unlock()
+				_ = mcpClient.Close()
+				return errors.New("the MCP client is closed")
+			}
+			c.mcpclient = mcpClient
+			uris := make([]string, 0, len(c.resourceHandlers))
+			for uri := range c.resourceHandlers {
+				uris = append(uris, uri)
+			}
+			c.mutex.Unlock()
+			c.resubscribe(uris)
+			c.setTools(mcpTools)
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return fmt.Errorf("unable to reconnect after %d attempts: %w", attempts, err)
+}
+
+// resubscribe subscribes the new connection to the resources of the previous one
+func (c *MCPClient) resubscribe(uris []string) {
+	for _, uri := range uris {
+		ctx, cancel := c.requestContext()
+		request := mcp.SubscribeRequest{}
+		request.Params.URI = uri
+		_ = c.current().Subscribe(ctx, request)
+		cancel()
+	}
+}
+
+// isConnectionError reports whether a request failed in the transport (and not because
+// the server rejected it or the request timed out)
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var transportError *transport.Error
+	return errors.As(err, &transportError)
+}
+
+// sameTools reports whether two tool lists are identical
+func sameTools(previous *mcp.ListToolsResult, next *mcp.ListToolsResult) bool {
+	if previous == nil || next == nil {
+		return previous == next
+	}
+	previousJSON, errPrevious := json.Marshal(previous.Tools)
+	nextJSON, errNext := json.Marshal(next.Tools)
+	return errPrevious == nil && errNext == nil && string(previousJSON) == string(nextJSON)
+}
@@ -0,0 +1,171 @@
+package mcptools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// restartableServer is an SSE test server that can be restarted (the sessions are lost)
+type restartableServer struct {
+	mutex      sync.Mutex
+	sseServer  *server.SSEServer
+	httpServer *httptest.Server
+}
+
+func newRestartableServer(t *testing.T) *restartableServer {
+	t.Helper()
+	restartable := &restartableServer{}
+	restartable.httpServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restartable.mutex.Lock()
+		sseServer := restartable.sseServer
+		restartable.mutex.Unlock()
+		sseServer.ServeHTTP(w, r)
+	}))
+	t.Cleanup(restartable.httpServer.Close)
+	restartable.restart()
+	return restartable
+}
+
+func (r *restartableServer) restart() {
+	recorder := &testServer{}
+	recorder.mcpServer = recorder.newMCPServer()
+	r.mutex.Lock()
+	r.sseServer = server.NewSSEServer(recorder.mcpServer, server.WithBaseURL(r.httpServer.URL))
+	r.mutex.Unlock()
+	r.httpServer.CloseClientConnections()
+}
+
+func TestMCPClient_ToolsListChanged(t *testing.T) {
+	mcpClient, recorder := newTestClient(t)
+
+	changes := make(chan []mcp.Tool, 1)
+	mcpClient.OnToolsChanged(func(tools []mcp.Tool) {
+		changes <- tools
+	})
+
+	recorder.mcpServer.AddTool(mcp.NewTool("broken"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("disk full"), nil
+		})
+
+	select {
+	case tools := <-changes:
+		if len(tools) != 3 {
+			t.Errorf("expected the new tool list, got %+v", tools)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tools changed handler was not called")
+	}
+	if len(mcpClient.GetTools()) != 3 || len(mcpClient.OpenAITools()) != 3 {
+		t.Errorf("the tools of the client should be refreshed, got %+v", mcpClient.GetTools())
+	}
+
+	_, err := mcpClient.ExecToolWithMap("broken", nil)
+	var toolCallError *ToolCallError
+	if !errors.As(err, &toolCallError) || !errors.Is(err, ErrToolFailed) || toolCallError.Tool != "broken" {
+		t.Errorf("expected a tool failure, got %v", err)
+	}
+	if _, err := mcpClient.ExecToolWithMap("unknown", nil); !errors.Is(err, ErrProtocol) {
+		t.Errorf("expected a rejected request, got %v", err)
+	}
+}
+
+func TestMCPClient_ToolsListChangedStreamableHTTP(t *testing.T) {
+	recorder := &testServer{}
+	httpServer := recorder.startStreamableHTTP(t)
+	changes := make(chan []mcp.Tool, 1)
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithToolsChangedHandler(func(tools []mcp.Tool) {
+			changes <- tools
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	// The notification is sent on the listening stream of the session, opened after the
+	// initialization: the tool is added again until the stream is listened to
+	addTool := func() {
+		recorder.mcpServer.AddTool(mcp.NewTool("broken"),
+			func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultError("disk full"), nil
+			})
+	}
+	addTool()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for changed := false; !changed; {
+		select {
+		case tools := <-changes:
+			if len(tools) != 3 {
+				t.Errorf("expected the new tool list, got %+v", tools)
+			}
+			changed = true
+		case <-ticker.C:
+			addTool()
+		case <-timeout:
+			t.Fatal("the tools changed handler was not called")
+		}
+	}
+	if len(mcpClient.GetTools()) != 3 {
+		t.Errorf("the tools of the client should be refreshed, got %+v", mcpClient.GetTools())
+	}
+}
+
+func TestMCPClient_ReconnectsAfterServerRestart(t *testing.T) {
+	restartable := newRestartableServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mcpClient, err := NewMCPClient(ctx,
+		WithSSETransport(restartable.httpServer.URL+"/sse"),
+		WithReconnect(ReconnectPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	restartable.restart()
+
+	result, err := mcpClient.ExecToolWithString("hello", `{"name": "Bob"}`)
+	if err != nil || result != "Hello Bob" {
+		t.Fatalf("the call should succeed after the reconnection, got %q, error %v", result, err)
+	}
+	if err := mcpClient.Ping(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMCPClient_ConnectionErrorWithoutReconnect(t *testing.T) {
+	restartable := newRestartableServer(t)
+
+	mcpClient, err := NewMCPClient(context.Background(), WithSSETransport(restartable.httpServer.URL+"/sse"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	restartable.restart()
+
+	if _, err := mcpClient.ExecToolWithMap("hello", map[string]any{"name": "Bob"}); !errors.Is(err, ErrConnection) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+	if err := mcpClient.Reconnect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result, err := mcpClient.ExecToolWithMap("hello", map[string]any{"name": "Bob"}); err != nil || result != "Hello Bob" {
+		t.Errorf("unexpected result %q, error %v", result, err)
+	}
+}
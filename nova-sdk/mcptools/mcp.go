@@ -2,107 +2,73 @@ package mcptools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/shared"
+	"github.com/snipwise/nova/nova-sdk/audit"
 	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
 )
 
 // MCPClient wraps an MCP client connection with available tools
 type MCPClient struct {
-	mcpclient   *client.Client
+	mcpclient *client.Client
+	// ToolsResult holds the tools of the server, refreshed when the server notifies
+	// a change and after a reconnection (prefer GetTools, safe for concurrent use)
 	ToolsResult *mcp.ListToolsResult
 	ctx         context.Context
+	audit       audit.Config
+	config      *mcpClientConfig
+	// Bound of every request (0: no timeout)
+	timeout time.Duration
+
+	mutex sync.Mutex
+	// Handlers of the subscribed resources, by URI
+	resourceHandlers map[string]ResourceUpdateHandler
+	// Handlers of the changes of the tool list
+	toolsChangedHandlers []ToolsChangedHandler
+	// Serializes the reconnections
+	reconnectMutex sync.Mutex
+	closed         bool
 }
 
+// NewStdioMCPClient creates and initializes a new MCP client over stdio
+// (see NewMCPClient for the other options)
 func NewStdioMCPClient(ctx context.Context, command string, env []string, args ...string) (*MCPClient, error) {
-	mcpClient, err := client.NewStdioMCPClient(
-		command,
-		env,
-		args...,
-	)
-	if err != nil {
-		return nil, err
-	}
-	initRequest := mcp.InitializeRequest{}
-	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest.Params.ClientInfo = mcp.Implementation{
-		Name:    "nova",
-		Version: "0.0.0",
-	}
-	_, err = mcpClient.Initialize(ctx, initRequest)
-	if err != nil {
-		return nil, err
-	}
-	toolsRequest := mcp.ListToolsRequest{}
-	mcpTools, err := mcpClient.ListTools(ctx, toolsRequest)
-	if err != nil {
-		return nil, err
-	}
-
-	return &MCPClient{
-		mcpclient:   mcpClient,
-		ToolsResult: mcpTools,
-		ctx:         ctx,
-	}, nil
-
+	return NewMCPClient(ctx, WithStdioTransport(command, env, args...))
 }
 
-// NewStreamableHttpMCPClient creates and initializes a new MCP client over HTTP
-func NewStreamableHttpMCPClient(ctx context.Context, mcpHostURL string) (*MCPClient, error) {
-	mcpClient, err := client.NewStreamableHttpClient(
-		mcpHostURL, // Use environment variable for MCP host
-	)
-	//defer mcpClient.Close()
-	if err != nil {
-		return nil, err
-	}
-	// Start the connection to the server
-	err = mcpClient.Start(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	initRequest := mcp.InitializeRequest{}
-	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest.Params.ClientInfo = mcp.Implementation{
-		Name:    "nova",
-		Version: "0.0.0",
-	}
-	_, err = mcpClient.Initialize(ctx, initRequest)
-	if err != nil {
-		return nil, err
-	}
-	//fmt.Println("Streamable HTTP client connected & initialized with server!", result)
-	//ui.Println(ui.Yellow, "Streamable HTTP client connected & initialized with server!")
-
-	toolsRequest := mcp.ListToolsRequest{}
-	mcpTools, err := mcpClient.ListTools(ctx, toolsRequest)
-	if err != nil {
-		return nil, err
-	}
-
-	return &MCPClient{
-		mcpclient:   mcpClient,
-		ToolsResult: mcpTools,
-		ctx:         ctx,
-	}, nil
+// NewStreamableHttpMCPClient creates and initializes a new MCP client over HTTP.
+// Transport options (headers, timeout, custom http.Client...) can be passed after the URL.
+// When NOVA_CASSETTE is set, the traffic goes through the cassette recorder.
+// (see NewMCPClient for the other options)
+func NewStreamableHttpMCPClient(ctx context.Context, mcpHostURL string, httpOptions ...transport.StreamableHTTPCOption) (*MCPClient, error) {
+	return NewMCPClient(ctx, WithStreamableHTTPTransport(mcpHostURL, httpOptions...))
 }
 
 // OpenAITools converts the MCP client's tools to OpenAI-compatible format
 func (c *MCPClient) OpenAITools() []openai.ChatCompletionToolUnionParam {
-	return ConvertMCPListToolsResultToOpenAITools(c.ToolsResult)
+	return ConvertMCPToolsToOpenAITools(c.GetTools())
 }
 
 // OpenAIToolsWithFilter converts only the filtered MCP tools to OpenAI-compatible format
 func (c *MCPClient) OpenAIToolsWithFilter(toolsFilter []string) []openai.ChatCompletionToolUnionParam {
-	return ConvertMCPListToolsResultToOpenAIToolsWithFilter(c.ToolsResult, toolsFilter)
+	return ConvertMCPToolsToOpenAIToolsWithFilter(c.GetTools(), toolsFilter)
 }
 
+// GetTools returns the tools of the server
 func (c *MCPClient) GetTools() []mcp.Tool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.ToolsResult == nil {
+		return nil
+	}
 	return c.ToolsResult.Tools
 }
 
@@ -118,7 +84,7 @@ func (c *MCPClient) GetToolsWithFilter(toolsFilter []string) []mcp.Tool {
 	filteredTools := &mcp.ListToolsResult{
 		Tools: []mcp.Tool{},
 	}
-	for _, tool := range c.ToolsResult.Tools {
+	for _, tool := range c.GetTools() {
 		if allowedTools[tool.Name] {
 			filteredTools.Tools = append(filteredTools.Tools, tool)
 		}
@@ -127,32 +93,54 @@ func (c *MCPClient) GetToolsWithFilter(toolsFilter []string) []mcp.Tool {
 	return filteredTools.Tools
 }
 
-// Close safely closes the MCP client connection
+// Close safely closes the MCP client connection (no reconnection is attempted afterwards)
 func (c *MCPClient) Close() error {
-	if c.mcpclient != nil {
-		return c.mcpclient.Close()
+	c.mutex.Lock()
+	c.closed = true
+	mcpClient := c.mcpclient
+	c.mutex.Unlock()
+	if mcpClient != nil {
+		return mcpClient.Close()
 	}
 	return nil
 }
 
-func (c *MCPClient) ExecToolWithMap(functionName string, input map[string]any) (string, error) {
-	// NOTE: Call the MCP tool with the arguments
-	request := mcp.CallToolRequest{}
-	request.Params.Name = functionName
-	request.Params.Arguments = input
+// SetAudit emits an audit event for every tool executed through the client
+// (the session comes from the context of the client, see audit.WithSession)
+func (c *MCPClient) SetAudit(config audit.Config) {
+	c.audit = config.WithSource(audit.SourceMCP)
+}
 
-	// NOTE: Call the tool using the MCP client
-	toolResponse, err := c.mcpclient.CallTool(c.ctx, request)
+// ExecToolWithMap calls a tool and returns its result as text (see ToolResult.ModelContent).
+// A tool failure is returned as a ToolCallError of kind ErrToolFailed (use CallTool to get
+// the complete result).
+func (c *MCPClient) ExecToolWithMap(functionName string, input map[string]any) (string, error) {
+	result, err := c.CallTool(functionName, input)
 	if err != nil {
-		return "", fmt.Errorf("error calling tool %s: %w", functionName, err)
+		return "", err
 	}
-	if toolResponse == nil || len(toolResponse.Content) == 0 {
-		return "", fmt.Errorf("no content returned from tool %s", functionName)
+	if err := result.Err(); err != nil {
+		return "", err
 	}
+	return result.ModelContent(), nil
+}
 
-	// Take the first content item and return its text
-	resultContent := toolResponse.Content[0].(mcp.TextContent).Text
-	return resultContent, nil
+// auditExec emits the audit event of a tool execution
+func (c *MCPClient) auditExec(functionName string, input map[string]any, result string, err error, duration time.Duration) {
+	arguments, _ := json.Marshal(input)
+	event := audit.Event{
+		Session:      audit.SessionFromContext(c.ctx),
+		Tool:         functionName,
+		Arguments:    string(arguments),
+		Confirmation: audit.ConfirmationNotRequired,
+		Status:       audit.StatusSuccess,
+		DurationMs:   audit.Milliseconds(duration),
+		Result:       result,
+	}
+	if err != nil {
+		event.Status, event.Error = audit.StatusError, err.Error()
+	}
+	c.audit.Emit(event)
 }
 
 func (c *MCPClient) ExecToolWithString(functionName string, input string) (string, error) {
@@ -174,6 +162,8 @@ func (c *MCPClient) ExecToolWithAny(functionName string, input any) (string, err
 	return c.ExecToolWithMap(functionName, args)
 }
 
+// Exec calls a tool with a typed input and decodes its result into O
+// (the structured content, or the JSON text when there is none)
 func Exec[I, O any](mcpClient *MCPClient, functionName string, input I) (O, error) {
 	var output O
 	// Convert input to map[string]any
@@ -181,17 +171,17 @@ func Exec[I, O any](mcpClient *MCPClient, functionName string, input I) (O, erro
 	if err != nil {
 		return output, fmt.Errorf("error converting input to map: %w", err)
 	}
-	resultContent, err := mcpClient.ExecToolWithMap(functionName, args)
+	result, err := mcpClient.CallTool(functionName, args)
 	if err != nil {
 		return output, err
 	}
-
-	// Convert resultContent (string) to output type O
-	output, err = conversion.FromJSON[O](resultContent)
-	if err != nil {
-		return output, fmt.Errorf("error converting output from JSON string: %w", err)
+	if err := result.Err(); err != nil {
+		return output, err
 	}
 
+	if err := result.Decode(&output); err != nil {
+		return output, err
+	}
 	return output, nil
 }
 
@@ -0,0 +1,348 @@
+package mcptools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/openai/openai-go/v3"
+	"github.com/snipwise/nova/nova-sdk/toolbox/conversion"
+)
+
+// DefaultNamespaceSeparator joins the name of a server and the name of its tool
+// (e.g. github__search_issues)
+const DefaultNamespaceSeparator = "__"
+
+// Namespacing of the tool names of a hub
+type Namespacing int
+
+const (
+	// NamespaceOnCollision: only the tools with the same name on several servers are prefixed.
+	// An exposed name doesn't change: a tool added later with the name of an exposed tool is
+	// prefixed, the exposed tool is not.
+	NamespaceOnCollision Namespacing = iota
+	// NamespaceAlways: every tool is prefixed with the name of its server
+	NamespaceAlways
+)
+
+// MCPHub aggregates the tools of several MCP clients: the tool names are namespaced with the
+// name of their server to resolve the collisions, and the calls are routed to the owning client.
+//
+// Usage:
+//
+//	hub := mcptools.NewMCPHub()
+//	hub.AddServer("github", githubClient, mcptools.WithExcludedTools("delete_*"))
+//	hub.AddServer("search", searchClient)
+//	agent, err := tools.NewAgent(ctx, agentConfig, modelConfig,
+//		tools.WithOpenAITools(hub.OpenAITools()),
+//		tools.WithExecuteFn(hub.ExecuteFn()),
+//	)
+type MCPHub struct {
+	mutex       sync.RWMutex
+	servers     []*hubServer
+	namespacing Namespacing
+	separator   string
+	// assigned are the exposed names of the tools, kept while the tools exist
+	assigned map[hubTool]string
+}
+
+// hubTool identifies a tool of a server of a hub
+type hubTool struct {
+	server *hubServer
+	name   string
+}
+
+// serverNameExpression validates the names of the servers: they prefix the exposed tool
+// names, which only allow letters, digits, underscores and dashes
+var serverNameExpression = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// hubServer is a client of a hub with its tool filters
+type hubServer struct {
+	name    string
+	client  *MCPClient
+	include []string
+	exclude []string
+}
+
+// hubRoute is the owner of an exposed tool
+type hubRoute struct {
+	server *hubServer
+	tool   mcp.Tool
+}
+
+// MCPHubOption configures an MCPHub
+type MCPHubOption func(*MCPHub)
+
+// HubServerOption configures a server of an MCPHub
+type HubServerOption func(*hubServer)
+
+// WithNamespacing sets when the tool names are prefixed with the name of their server
+// (default: NamespaceOnCollision)
+func WithNamespacing(namespacing Namespacing) MCPHubOption {
+	return func(hub *MCPHub) {
+		hub.namespacing = namespacing
+	}
+}
+
+// WithNamespaceSeparator sets the separator between the name of the server and the name
+// of the tool (default: DefaultNamespaceSeparator). The OpenAI tool names only allow
+// letters, digits, underscores and dashes.
+func WithNamespaceSeparator(separator string) MCPHubOption {
+	return func(hub *MCPHub) {
+		hub.separator = separator
+	}
+}
+
+// WithIncludedTools only exposes the tools of the server matching one of the patterns
+// (path.Match syntax, e.g. "get_*")
+func WithIncludedTools(patterns ...string) HubServerOption {
+	return func(server *hubServer) {
+		server.include = append(server.include, patterns...)
+	}
+}
+
+// WithExcludedTools hides the tools of the server matching one of the patterns
+// (path.Match syntax, e.g. "delete_*")
+func WithExcludedTools(patterns ...string) HubServerOption {
+	return func(server *hubServer) {
+		server.exclude = append(server.exclude, patterns...)
+	}
+}
+
+// NewMCPHub creates an empty hub (see AddServer)
+func NewMCPHub(options ...MCPHubOption) *MCPHub {
+	hub := &MCPHub{separator: DefaultNamespaceSeparator}
+	for _, option := range options {
+		option(hub)
+	}
+	return hub
+}
+
+// AddServer adds the tools of a client to the hub. The name of the server is the namespace of its
+// tools: 1 to 64 letters, digits, underscores or dashes.
+func (hub *MCPHub) AddServer(name string, client *MCPClient, options ...HubServerOption) error {
+	if name == "" || client == nil {
+		return errors.New("a hub server needs a name and a client")
+	}
+	if !serverNameExpression.MatchString(name) {
+		return fmt.Errorf("invalid server name %q: use 1 to 64 letters, digits, underscores or dashes", name)
+	}
+	server := &hubServer{name: name, client: client}
+	for _, option := range options {
+		option(server)
+	}
+	for _, pattern := range append(slices.Clone(server.include), server.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool filter %q: %w", pattern, err)
+		}
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	for _, existing := range hub.servers {
+		if existing.name == name {
+			return fmt.Errorf("the hub already has a server named %s", name)
+		}
+	}
+	hub.servers = append(hub.servers, server)
+	return nil
+}
+
+// RemoveServer removes a server from the hub (its client is not closed)
+func (hub *MCPHub) RemoveServer(name string) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	hub.servers = slices.DeleteFunc(hub.servers, func(server *hubServer) bool {
+		return server.name == name
+	})
+}
+
+// GetServer returns the client of a server of the hub
+func (hub *MCPHub) GetServer(name string) (*MCPClient, bool) {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	for _, server := range hub.servers {
+		if server.name == name {
+			return server.client, true
+		}
+	}
+	return nil, false
+}
+
+// GetServerNames returns the names of the servers of the hub, in the order they were added
+func (hub *MCPHub) GetServerNames() []string {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	names := make([]string, 0, len(hub.servers))
+	for _, server := range hub.servers {
+		names = append(names, server.name)
+	}
+	return names
+}
+
+// GetTools returns the tools of every server, with their exposed (namespaced) names
+func (hub *MCPHub) GetTools() []mcp.Tool {
+	names, routes := hub.routes()
+	tools := make([]mcp.Tool, 0, len(names))
+	for _, name := range names {
+		tool := routes[name].tool
+		tool.Name = name
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// OpenAITools converts the tools of every server to OpenAI-compatible format
+func (hub *MCPHub) OpenAITools() []openai.ChatCompletionToolUnionParam {
+	return ConvertMCPToolsToOpenAITools(hub.GetTools())
+}
+
+// Route returns the server and the original name of an exposed tool
+func (hub *MCPHub) Route(toolName string) (serverName string, originalName string, found bool) {
+	_, routes := hub.routes()
+	route, found := routes[toolName]
+	if !found {
+		return "", "", false
+	}
+	return route.server.name, route.tool.Name, true
+}
+
+// CallTool calls an exposed tool on its server and returns its complete result
+// (the Tool of the result is the exposed name)
+func (hub *MCPHub) CallTool(toolName string, input map[string]any) (*ToolResult, error) {
+	return hub.CallToolWithContext(context.Background(), toolName, input)
+}
+
+// CallToolWithContext is CallTool with the context of the call (see MCPClient.CallToolWithContext)
+func (hub *MCPHub) CallToolWithContext(ctx context.Context, toolName string, input map[string]any) (*ToolResult, error) {
+	_, routes := hub.routes()
+	route, found := routes[toolName]
+	if !found {
+		return nil, fmt.Errorf("no server of the hub exposes tool %s", toolName)
+	}
+	result, err := route.server.client.CallToolWithContext(ctx, route.tool.Name, input)
+	if err != nil {
+		return nil, err
+	}
+	result.Tool = toolName
+	return result, nil
+}
+
+// ExecToolWithMap calls an exposed tool on its server
+func (hub *MCPHub) ExecToolWithMap(toolName string, input map[string]any) (string, error) {
+	_, routes := hub.routes()
+	route, found := routes[toolName]
+	if !found {
+		return "", fmt.Errorf("no server of the hub exposes tool %s", toolName)
+	}
+	return route.server.client.ExecToolWithMap(route.tool.Name, input)
+}
+
+// ExecToolWithString calls an exposed tool on its server with JSON arguments
+func (hub *MCPHub) ExecToolWithString(toolName string, input string) (string, error) {
+	args, err := conversion.JsonStringToMap(input)
+	if err != nil {
+		return "", fmt.Errorf("error converting input string to map: %w", err)
+	}
+	return hub.ExecToolWithMap(toolName, args)
+}
+
+// ExecuteFn returns the execute callback of a tools agent routing its calls to the servers
+// (see tools.WithExecuteFn)
+func (hub *MCPHub) ExecuteFn() func(functionName string, arguments string) (string, error) {
+	return hub.ExecToolWithString
+}
+
+// Close closes the clients of every server
+func (hub *MCPHub) Close() error {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	var errs []error
+	for _, server := range hub.servers {
+		if err := server.client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing server %s: %w", server.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routes returns the exposed tool names, in the order of the servers, and their owners.
+// They are computed on each call: the tools of the clients can change. The names already
+// assigned to the tools are kept.
+func (hub *MCPHub) routes() ([]string, map[string]hubRoute) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if hub.assigned == nil {
+		hub.assigned = make(map[hubTool]string)
+	}
+
+	var candidates []hubRoute
+	owners := make(map[string]int)
+	for _, server := range hub.servers {
+		for _, tool := range server.client.GetTools() {
+			if server.exposes(tool.Name) {
+				candidates = append(candidates, hubRoute{server: server, tool: tool})
+				owners[tool.Name]++
+			}
+		}
+	}
+
+	routes := make(map[string]hubRoute, len(candidates))
+	current := make(map[hubTool]bool, len(candidates))
+	for _, candidate := range candidates {
+		key := hubTool{server: candidate.server, name: candidate.tool.Name}
+		current[key] = true
+		if name, found := hub.assigned[key]; found {
+			if _, taken := routes[name]; taken {
+				delete(hub.assigned, key)
+				continue
+			}
+			routes[name] = candidate
+		}
+	}
+	// The names of the removed tools are released
+	for key := range hub.assigned {
+		if !current[key] {
+			delete(hub.assigned, key)
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		key := hubTool{server: candidate.server, name: candidate.tool.Name}
+		name, found := hub.assigned[key]
+		if !found {
+			name = candidate.tool.Name
+			_, taken := routes[name]
+			if hub.namespacing == NamespaceAlways || owners[name] > 1 || taken {
+				name = candidate.server.name + hub.separator + name
+			}
+			if _, duplicate := routes[name]; duplicate {
+				continue
+			}
+			routes[name] = candidate
+			hub.assigned[key] = name
+		}
+		names = append(names, name)
+	}
+	return names, routes
+}
+
+// exposes reports whether a tool passes the filters of the server
+func (server *hubServer) exposes(toolName string) bool {
+	matches := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			matched, _ := path.Match(pattern, toolName)
+			return matched
+		})
+	}
+	if len(server.include) > 0 && !matches(server.include) {
+		return false
+	}
+	return !matches(server.exclude)
+}
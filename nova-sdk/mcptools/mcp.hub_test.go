@@ -0,0 +1,102 @@
+package mcptools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newHubClient(t *testing.T) *MCPClient {
+	t.Helper()
+	httpServer := (&testServer{}).startStreamableHTTP(t)
+	mcpClient, err := NewMCPClient(context.Background(), WithStreamableHTTPTransport(httpServer.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return mcpClient
+}
+
+func toolNames(hub *MCPHub) []string {
+	var names []string
+	for _, tool := range hub.GetTools() {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestMCPHub_NamespacesAndRoutes(t *testing.T) {
+	hub := NewMCPHub()
+	defer hub.Close()
+	if err := hub.AddServer("alpha", newHubClient(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	beta := newHubClient(t)
+	defer beta.Close()
+	if err := hub.AddServer("beta", beta, WithIncludedTools("hel*")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	duplicate := newHubClient(t)
+	defer duplicate.Close()
+	if err := hub.AddServer("alpha", duplicate); err == nil {
+		t.Error("the server names should be unique")
+	}
+
+	expected := []string{"alpha__hello", "slow", "beta__hello"}
+	if names := toolNames(hub); !slices.Equal(names, expected) {
+		t.Errorf("only the colliding tools should be namespaced, expected %v, got %v", expected, names)
+	}
+	if len(hub.OpenAITools()) != 3 {
+		t.Errorf("expected 3 OpenAI tools, got %d", len(hub.OpenAITools()))
+	}
+
+	server, original, found := hub.Route("beta__hello")
+	if !found || server != "beta" || original != "hello" {
+		t.Errorf("unexpected route %s %s %v", server, original, found)
+	}
+	result, err := hub.ExecuteFn()("beta__hello", `{"name": "Bob"}`)
+	if err != nil || result != "Hello Bob" {
+		t.Errorf("unexpected result %q, error %v", result, err)
+	}
+	if _, err := hub.ExecToolWithString("hello", `{}`); err == nil {
+		t.Error("an ambiguous tool name should not be routed")
+	}
+
+	// The exposed names don't change: the model may already have called them
+	hub.RemoveServer("beta")
+	if names := toolNames(hub); !slices.Equal(names, []string{"alpha__hello", "slow"}) {
+		t.Errorf("the exposed names should be kept, got %v", names)
+	}
+	if err := hub.AddServer("gamma", newHubClient(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := toolNames(hub); !slices.Equal(names, []string{"alpha__hello", "slow", "gamma__hello", "gamma__slow"}) {
+		t.Errorf("only the tools added later should be namespaced, got %v", names)
+	}
+}
+
+func TestMCPHub_ServerNames(t *testing.T) {
+	hub := NewMCPHub()
+	defer hub.Close()
+	for _, name := range []string{"my server", "files.v2", "über", strings.Repeat("a", 65)} {
+		if err := hub.AddServer(name, &MCPClient{}); err == nil {
+			t.Errorf("%q should be rejected", name)
+		}
+	}
+}
+
+func TestMCPHub_NamespaceAlwaysAndExclusions(t *testing.T) {
+	hub := NewMCPHub(WithNamespacing(NamespaceAlways), WithNamespaceSeparator("-"))
+	defer hub.Close()
+	if err := hub.AddServer("files", newHubClient(t), WithExcludedTools("slow")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := toolNames(hub); !slices.Equal(names, []string{"files-hello"}) {
+		t.Errorf("unexpected tools %v", names)
+	}
+	rejected := newHubClient(t)
+	defer rejected.Close()
+	if err := hub.AddServer("bad", rejected, WithIncludedTools("[")); err == nil {
+		t.Error("an invalid pattern should be rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package mcptools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+// SystemInstructionsSetter updates the system instructions of an agent (implemented by chat.Agent)
+type SystemInstructionsSetter interface {
+	SetSystemInstructions(instructions string)
+}
+
+// ListPrompts returns the prompt templates exposed by the server
+func (c *MCPClient) ListPrompts() ([]mcp.Prompt, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	result, err := c.current().ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing prompts: %w", err)
+	}
+	return result.Prompts, nil
+}
+
+// GetPrompt renders a prompt template of the server with its arguments
+func (c *MCPClient) GetPrompt(name string, arguments map[string]string) (*mcp.GetPromptResult, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	result, err := c.current().GetPrompt(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error getting prompt %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// GetPromptMessages renders a prompt template as chat messages. The text and the text of
+// the embedded resources are kept, the images and audio contents are skipped.
+//
+// Usage:
+//
+//	promptMessages, err := mcpClient.GetPromptMessages("code_review", map[string]string{"language": "go"})
+//	result, err := chatAgent.GenerateCompletion(promptMessages)
+func (c *MCPClient) GetPromptMessages(name string, arguments map[string]string) ([]messages.Message, error) {
+	result, err := c.GetPrompt(name, arguments)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertMCPPromptMessages(result.Messages), nil
+}
+
+// GetPromptText renders a prompt template as text (the contents of its messages, joined with blank lines)
+func (c *MCPClient) GetPromptText(name string, arguments map[string]string) (string, error) {
+	promptMessages, err := c.GetPromptMessages(name, arguments)
+	if err != nil {
+		return "", err
+	}
+	texts := make([]string, 0, len(promptMessages))
+	for _, message := range promptMessages {
+		texts = append(texts, message.Content)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ApplyPromptAsSystemInstructions renders a prompt template and uses it as the
+// system instructions of an agent (e.g. a chat.Agent)
+func (c *MCPClient) ApplyPromptAsSystemInstructions(agent SystemInstructionsSetter, name string, arguments map[string]string) error {
+	instructions, err := c.GetPromptText(name, arguments)
+	if err != nil {
+		return err
+	}
+	agent.SetSystemInstructions(instructions)
+	return nil
+}
+
+// ConvertMCPPromptMessages converts MCP prompt messages to chat messages
+// (the messages without text are skipped)
+func ConvertMCPPromptMessages(promptMessages []mcp.PromptMessage) []messages.Message {
+	converted := make([]messages.Message, 0, len(promptMessages))
+	for _, promptMessage := range promptMessages {
+		var text string
+		switch content := promptMessage.Content.(type) {
+		case mcp.TextContent:
+			text = content.Text
+		case mcp.EmbeddedResource:
+			if resource, ok := content.Resource.(mcp.TextResourceContents); ok {
+				text = resource.Text
+			}
+		}
+		if text == "" {
+			continue
+		}
+		role := roles.User
+		if promptMessage.Role == mcp.RoleAssistant {
+			role = roles.Assistant
+		}
+		converted = append(converted, messages.Message{Role: role, Content: text})
+	}
+	return converted
+}
@@ -0,0 +1,41 @@
+package mcptools
+
+import (
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+func TestMCPClient_Prompts(t *testing.T) {
+	mcpClient, _ := newTestClient(t)
+
+	prompts, err := mcpClient.ListPrompts()
+	if err != nil || len(prompts) != 1 || prompts[0].Name != "code_review" {
+		t.Fatalf("unexpected prompts %+v, error %v", prompts, err)
+	}
+
+	promptMessages, err := mcpClient.GetPromptMessages("code_review", map[string]string{"language": "Go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []messages.Message{
+		{Role: roles.User, Content: "You review Go code."},
+		{Role: roles.Assistant, Content: "Send me the code."},
+	}
+	if len(promptMessages) != len(expected) || promptMessages[0] != expected[0] || promptMessages[1] != expected[1] {
+		t.Errorf("expected %+v, got %+v", expected, promptMessages)
+	}
+
+	chat := &chatRecorder{}
+	if err := mcpClient.ApplyPromptAsSystemInstructions(chat, "code_review", map[string]string{"language": "Go"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chat.instructions != "You review Go code.\n\nSend me the code." {
+		t.Errorf("unexpected instructions %q", chat.instructions)
+	}
+
+	if _, err := mcpClient.GetPrompt("unknown", nil); err == nil {
+		t.Error("getting an unknown prompt should fail")
+	}
+}
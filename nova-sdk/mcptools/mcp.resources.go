@@ -0,0 +1,232 @@
+package mcptools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/agents/rag/chunks"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+// ResourceUpdateHandler is called when a subscribed resource changes
+// (uri can be a sub-resource of the subscribed one)
+type ResourceUpdateHandler func(uri string)
+
+// EmbeddingStore saves the embeddings of contents (implemented by rag.Agent)
+type EmbeddingStore interface {
+	SaveEmbedding(content string) error
+}
+
+// MetadataEmbeddingStore is an EmbeddingStore that saves the embeddings with metadata
+// (implemented by rag.Agent)
+type MetadataEmbeddingStore interface {
+	EmbeddingStore
+	SaveEmbeddingWithMetadata(content string, metadata map[string]any) error
+}
+
+// MessageAdder adds a message to a conversation (implemented by chat.Agent)
+type MessageAdder interface {
+	AddMessage(role roles.Role, content string)
+}
+
+// ListResources returns the resources exposed by the server
+func (c *MCPClient) ListResources() ([]mcp.Resource, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	result, err := c.current().ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing resources: %w", err)
+	}
+	return result.Resources, nil
+}
+
+// ListResourceTemplates returns the resource templates (parameterized URIs) exposed by the server
+func (c *MCPClient) ListResourceTemplates() ([]mcp.ResourceTemplate, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	result, err := c.current().ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing resource templates: %w", err)
+	}
+	return result.ResourceTemplates, nil
+}
+
+// ReadResource returns the contents of a resource (mcp.TextResourceContents or mcp.BlobResourceContents)
+func (c *MCPClient) ReadResource(uri string) ([]mcp.ResourceContents, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := c.current().ReadResource(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error reading resource %s: %w", uri, err)
+	}
+	return result.Contents, nil
+}
+
+// ReadResourceText returns the text contents of a resource, joined with blank lines
+// (binary contents are skipped). It fails when the resource has no text content.
+func (c *MCPClient) ReadResourceText(uri string) (string, error) {
+	contents, err := c.ReadResource(uri)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, content := range contents {
+		if text, ok := content.(mcp.TextResourceContents); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return "", fmt.Errorf("no text content in resource %s", uri)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// SubscribeResource asks the server to notify the changes of a resource: handler is called
+// with the URI of the updated resource (the server must support the resource subscriptions)
+func (c *MCPClient) SubscribeResource(uri string, handler ResourceUpdateHandler) error {
+	c.mutex.Lock()
+	if c.resourceHandlers == nil {
+		c.resourceHandlers = make(map[string]ResourceUpdateHandler)
+	}
+	c.resourceHandlers[uri] = handler
+	c.mutex.Unlock()
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	request := mcp.SubscribeRequest{}
+	request.Params.URI = uri
+	if err := c.current().Subscribe(ctx, request); err != nil {
+		c.mutex.Lock()
+		delete(c.resourceHandlers, uri)
+		c.mutex.Unlock()
+		return fmt.Errorf("error subscribing to resource %s: %w", uri, err)
+	}
+	return nil
+}
+
+// UnsubscribeResource stops the notifications of a resource
+func (c *MCPClient) UnsubscribeResource(uri string) error {
+	c.mutex.Lock()
+	delete(c.resourceHandlers, uri)
+	c.mutex.Unlock()
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	request := mcp.UnsubscribeRequest{}
+	request.Params.URI = uri
+	if err := c.current().Unsubscribe(ctx, request); err != nil {
+		return fmt.Errorf("error unsubscribing from resource %s: %w", uri, err)
+	}
+	return nil
+}
+
+// handleNotification dispatches the notifications sent by the server
+func (c *MCPClient) handleNotification(notification mcp.JSONRPCNotification) {
+	switch notification.Method {
+	case mcp.MethodNotificationResourceUpdated:
+		c.handleResourceUpdated(notification)
+	case mcp.MethodNotificationToolsListChanged:
+		// Don't block the transport reading the notifications: the refresh is a request
+		go func() {
+			_ = c.RefreshTools()
+		}()
+	}
+}
+
+// handleResourceUpdated calls the handlers of an updated resource
+func (c *MCPClient) handleResourceUpdated(notification mcp.JSONRPCNotification) {
+	raw, err := json.Marshal(notification.Params.AdditionalFields)
+	if err != nil {
+		return
+	}
+	var params mcp.ResourceUpdatedNotificationParams
+	if err := json.Unmarshal(raw, &params); err != nil || params.URI == "" {
+		return
+	}
+
+	c.mutex.Lock()
+	var handlers []ResourceUpdateHandler
+	for uri, handler := range c.resourceHandlers {
+		if params.URI == uri || strings.HasPrefix(params.URI, strings.TrimSuffix(uri, "/")+"/") {
+			handlers = append(handlers, handler)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(params.URI)
+	}
+}
+
+// SaveResourcesEmbeddings reads text resources and saves their embeddings into the store
+// (e.g. a rag.Agent). Each resource is split into chunks of chunkSize characters with overlap
+// (chunkSize 0: one embedding per resource).
+// A MetadataEmbeddingStore saves each chunk with its source (the resource URI) and chunk_index.
+func (c *MCPClient) SaveResourcesEmbeddings(store EmbeddingStore, chunkSize int, overlap int, uris ...string) error {
+	for _, uri := range uris {
+		text, err := c.ReadResourceText(uri)
+		if err != nil {
+			return err
+		}
+		pieces := []string{text}
+		if chunkSize > 0 {
+			pieces = chunks.ChunkText(text, chunkSize, overlap)
+		}
+		for index, piece := range pieces {
+			if strings.TrimSpace(piece) == "" {
+				continue
+			}
+			if err := saveResourceEmbedding(store, piece, uri, index); err != nil {
+				return fmt.Errorf("error saving the embedding of resource %s: %w", uri, err)
+			}
+		}
+	}
+	return nil
+}
+
+// saveResourceEmbedding saves the embedding of a chunk of a resource, with its metadata when the store supports it
+func saveResourceEmbedding(store EmbeddingStore, piece string, uri string, index int) error {
+	if metadataStore, ok := store.(MetadataEmbeddingStore); ok {
+		return metadataStore.SaveEmbeddingWithMetadata(piece, map[string]any{"source": uri, "chunk_index": index})
+	}
+	return store.SaveEmbedding(piece)
+}
+
+// ResourcesContext reads text resources and formats them as a context block:
+//
+//	<resource uri="file:///notes.md">
+//	...
+//	</resource>
+func (c *MCPClient) ResourcesContext(uris ...string) (string, error) {
+	if len(uris) == 0 {
+		return "", errors.New("no resource to read")
+	}
+	var builder strings.Builder
+	for index, uri := range uris {
+		text, err := c.ReadResourceText(uri)
+		if err != nil {
+			return "", err
+		}
+		if index > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "<resource uri=%q>\n%s\n</resource>\n", uri, text)
+	}
+	return builder.String(), nil
+}
+
+// InjectResources adds text resources to the conversation of an agent (e.g. a chat.Agent)
+// as a system message (see ResourcesContext)
+func (c *MCPClient) InjectResources(agent MessageAdder, uris ...string) error {
+	resourcesContext, err := c.ResourcesContext(uris...)
+	if err != nil {
+		return err
+	}
+	agent.AddMessage(roles.System, resourcesContext)
+	return nil
+}
@@ -0,0 +1,132 @@
+package mcptools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+type embeddingStoreRecorder struct {
+	contents []string
+}
+
+func (s *embeddingStoreRecorder) SaveEmbedding(content string) error {
+	s.contents = append(s.contents, content)
+	return nil
+}
+
+type metadataStoreRecorder struct {
+	embeddingStoreRecorder
+	metadata []map[string]any
+}
+
+func (s *metadataStoreRecorder) SaveEmbeddingWithMetadata(content string, metadata map[string]any) error {
+	s.metadata = append(s.metadata, metadata)
+	return s.SaveEmbedding(content)
+}
+
+type chatRecorder struct {
+	roles        []roles.Role
+	contents     []string
+	instructions string
+}
+
+func (c *chatRecorder) AddMessage(role roles.Role, content string) {
+	c.roles = append(c.roles, role)
+	c.contents = append(c.contents, content)
+}
+
+func (c *chatRecorder) SetSystemInstructions(instructions string) {
+	c.instructions = instructions
+}
+
+func newTestClient(t *testing.T) (*MCPClient, *testServer) {
+	t.Helper()
+	recorder := &testServer{}
+	httpServer := recorder.startSSE(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	mcpClient, err := NewMCPClient(ctx, WithSSETransport(httpServer.URL+"/sse"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = mcpClient.Close() })
+	return mcpClient, recorder
+}
+
+func TestMCPClient_Resources(t *testing.T) {
+	mcpClient, _ := newTestClient(t)
+
+	resources, err := mcpClient.ListResources()
+	if err != nil || len(resources) != 1 || resources[0].URI != "file:///docs/notes.md" {
+		t.Fatalf("unexpected resources %+v, error %v", resources, err)
+	}
+	contents, err := mcpClient.ReadResource("file:///docs/notes.md")
+	if err != nil || len(contents) != 2 {
+		t.Fatalf("unexpected contents %+v, error %v", contents, err)
+	}
+	text, err := mcpClient.ReadResourceText("file:///docs/notes.md")
+	if err != nil || text != "# Notes\nBob likes Go." {
+		t.Errorf("only the text contents should be kept, got %q, error %v", text, err)
+	}
+	if _, err := mcpClient.ReadResourceText("file:///unknown"); err == nil {
+		t.Error("reading an unknown resource should fail")
+	}
+
+	store := &embeddingStoreRecorder{}
+	if err := mcpClient.SaveResourcesEmbeddings(store, 10, 0, "file:///docs/notes.md"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.contents) != 3 || strings.Join(store.contents, "") != text {
+		t.Errorf("the resource should be saved in chunks, got %q", store.contents)
+	}
+
+	metadataStore := &metadataStoreRecorder{}
+	if err := mcpClient.SaveResourcesEmbeddings(metadataStore, 10, 0, "file:///docs/notes.md"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metadataStore.metadata) != 3 || metadataStore.metadata[2]["source"] != "file:///docs/notes.md" || metadataStore.metadata[2]["chunk_index"] != 2 {
+		t.Errorf("the chunks should be saved with their source and index, got %v", metadataStore.metadata)
+	}
+
+	chat := &chatRecorder{}
+	if err := mcpClient.InjectResources(chat, "file:///docs/notes.md"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "<resource uri=\"file:///docs/notes.md\">\n# Notes\nBob likes Go.\n</resource>\n"
+	if len(chat.contents) != 1 || chat.roles[0] != roles.System || chat.contents[0] != expected {
+		t.Errorf("the resource should be injected as a system message, got %q", chat.contents)
+	}
+}
+
+func TestMCPClient_ResourceUpdates(t *testing.T) {
+	mcpClient, recorder := newTestClient(t)
+
+	updated := make(chan string, 1)
+	// The test server doesn't implement the subscriptions: register the handler only
+	mcpClient.resourceHandlers = map[string]ResourceUpdateHandler{
+		"file:///docs": func(uri string) { updated <- uri },
+	}
+	recorder.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": "file:///other/a.md"})
+	recorder.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": "file:///docs/notes.md"})
+
+	select {
+	case uri := <-updated:
+		if uri != "file:///docs/notes.md" {
+			t.Errorf("unexpected update of %s", uri)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the handler should be notified of the sub-resource update")
+	}
+
+	if err := mcpClient.SubscribeResource("file:///docs/notes.md", func(string) {}); err == nil {
+		t.Error("subscribing should fail when the server doesn't support it")
+	}
+	if _, found := mcpClient.resourceHandlers["file:///docs/notes.md"]; found {
+		t.Error("a failed subscription should not keep its handler")
+	}
+}
@@ -0,0 +1,183 @@
+package mcptools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolResult is the complete result of an MCP tool call: every content part
+// (text, images, audio, resources), the structured content and the error flag
+type ToolResult struct {
+	Tool    string
+	Content []mcp.Content
+	// StructuredContent is the JSON object returned by the tools with an output schema
+	StructuredContent any
+	// IsError is set when the tool failed: the content describes the error
+	IsError bool
+}
+
+// CallTool calls a tool and returns its complete result.
+// A tool failure is not an error: check IsError (the error is for connection and protocol failures).
+func (c *MCPClient) CallTool(functionName string, input map[string]any) (*ToolResult, error) {
+	return c.CallToolWithContext(c.ctx, functionName, input)
+}
+
+// CallToolWithContext is CallTool with the context of the call (e.g. the context of a tool
+// handler): its cancellation cancels the request, as the closing of the client does
+func (c *MCPClient) CallToolWithContext(ctx context.Context, functionName string, input map[string]any) (result *ToolResult, err error) {
+	if c.audit.Enabled() {
+		start := time.Now()
+		defer func() {
+			content, auditErr := "", err
+			if result != nil {
+				content, auditErr = result.ModelContent(), result.Err()
+			}
+			c.auditExec(functionName, input, content, auditErr, time.Since(start))
+		}()
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = functionName
+	request.Params.Arguments = input
+
+	// NOTE: Call the tool using the MCP client (reconnecting once when the connection is lost)
+	toolResponse, err := c.callTool(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return NewToolResult(functionName, toolResponse), nil
+}
+
+// NewToolResult wraps the raw result of a tool call
+func NewToolResult(functionName string, toolResponse *mcp.CallToolResult) *ToolResult {
+	result := &ToolResult{Tool: functionName}
+	if toolResponse != nil {
+		result.Content = toolResponse.Content
+		result.StructuredContent = toolResponse.StructuredContent
+		result.IsError = toolResponse.IsError
+	}
+	return result
+}
+
+// Empty reports whether the tool returned neither content nor structured content
+func (r *ToolResult) Empty() bool {
+	return len(r.Content) == 0 && r.StructuredContent == nil
+}
+
+// Text returns the text parts of the result and the text of its embedded resources, joined with newlines
+func (r *ToolResult) Text() string {
+	var texts []string
+	for _, content := range r.Content {
+		switch content := content.(type) {
+		case mcp.TextContent:
+			texts = append(texts, content.Text)
+		case mcp.EmbeddedResource:
+			if resource, ok := content.Resource.(mcp.TextResourceContents); ok {
+				texts = append(texts, resource.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Images returns the images of the result
+func (r *ToolResult) Images() []mcp.ImageContent {
+	var images []mcp.ImageContent
+	for _, content := range r.Content {
+		if image, ok := content.(mcp.ImageContent); ok {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// Resources returns the embedded resources of the result (text or binary)
+func (r *ToolResult) Resources() []mcp.ResourceContents {
+	var resources []mcp.ResourceContents
+	for _, content := range r.Content {
+		if embedded, ok := content.(mcp.EmbeddedResource); ok {
+			resources = append(resources, embedded.Resource)
+		}
+	}
+	return resources
+}
+
+// StructuredJSON returns the structured content encoded to JSON ("" when there is none)
+func (r *ToolResult) StructuredJSON() string {
+	if r.StructuredContent == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(r.StructuredContent)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// ModelContent returns the result as the text sent to a model: the text parts (or the
+// structured content when there is no text), followed by a placeholder for each part
+// a text model cannot read:
+//
+//	[image: image/png]
+//	[resource: file:///report.pdf]
+func (r *ToolResult) ModelContent() string {
+	var parts []string
+	if text := r.Text(); text != "" {
+		parts = append(parts, text)
+	} else if structured := r.StructuredJSON(); structured != "" {
+		parts = append(parts, structured)
+	}
+	for _, content := range r.Content {
+		switch content := content.(type) {
+		case mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image: %s]", content.MIMEType))
+		case mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio: %s]", content.MIMEType))
+		case mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource: %s]", content.URI))
+		case mcp.EmbeddedResource:
+			if blob, ok := content.Resource.(mcp.BlobResourceContents); ok {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", blob.URI))
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Decode decodes the structured content into target, or the text when there is no
+// structured content (the text must then be JSON)
+func (r *ToolResult) Decode(target any) error {
+	encoded := r.StructuredJSON()
+	if encoded == "" {
+		encoded = r.Text()
+	}
+	if encoded == "" {
+		return fmt.Errorf("no content to decode in the result of tool %s", r.Tool)
+	}
+	if err := json.Unmarshal([]byte(encoded), target); err != nil {
+		return fmt.Errorf("error decoding the result of tool %s: %w", r.Tool, err)
+	}
+	return nil
+}
+
+// Err returns the ToolCallError of a failed (ErrToolFailed) or empty (ErrNoContent) result, or nil
+func (r *ToolResult) Err() error {
+	switch {
+	case r.IsError:
+		message := r.ModelContent()
+		if message == "" {
+			message = "the tool reported an error"
+		}
+		return &ToolCallError{Tool: r.Tool, Kind: ErrToolFailed, Err: errors.New(message)}
+	case r.Empty():
+		return &ToolCallError{Tool: r.Tool, Kind: ErrNoContent}
+	default:
+		return nil
+	}
+}
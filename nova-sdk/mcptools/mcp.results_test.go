@@ -0,0 +1,62 @@
+package mcptools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type weatherReport struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestMCPClient_CallToolRichResults(t *testing.T) {
+	mcpClient, recorder := newTestClient(t)
+	recorder.mcpServer.AddTool(mcp.NewTool("screenshot"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewTextContent("Home page"),
+				mcp.NewImageContent("aGVsbG8=", "image/png"),
+				mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///page.html", Text: "<h1>Home</h1>"}),
+			}}, nil
+		})
+	recorder.mcpServer.AddTool(mcp.NewTool("weather"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{StructuredContent: weatherReport{City: "Lyon", Temperature: 21.5}}, nil
+		})
+	recorder.mcpServer.AddTool(mcp.NewTool("deploy"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("permission denied"), nil
+		})
+
+	result, err := mcpClient.CallTool("screenshot", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError || len(result.Content) != 3 || len(result.Images()) != 1 || len(result.Resources()) != 1 {
+		t.Errorf("every content part should be kept, got %+v", result)
+	}
+	if result.ModelContent() != "Home page\n<h1>Home</h1>\n[image: image/png]" {
+		t.Errorf("unexpected model content %q", result.ModelContent())
+	}
+
+	report, err := Exec[map[string]any, weatherReport](mcpClient, "weather", map[string]any{})
+	if err != nil || report.City != "Lyon" || report.Temperature != 21.5 {
+		t.Errorf("the structured content should be decoded, got %+v, error %v", report, err)
+	}
+	text, err := mcpClient.ExecToolWithMap("weather", nil)
+	if err != nil || text != `{"city":"Lyon","temperature":21.5}` {
+		t.Errorf("the structured content should be sent as JSON, got %q, error %v", text, err)
+	}
+
+	result, err = mcpClient.CallTool("deploy", nil)
+	if err != nil || !result.IsError || result.Text() != "permission denied" {
+		t.Errorf("the error flag should be kept, got %+v, error %v", result, err)
+	}
+	if _, err := mcpClient.ExecToolWithMap("deploy", nil); !errors.Is(err, ErrToolFailed) {
+		t.Errorf("expected a tool failure, got %v", err)
+	}
+}
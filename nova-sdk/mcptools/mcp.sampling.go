@@ -0,0 +1,243 @@
+package mcptools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+)
+
+// Actions of an elicitation response
+const (
+	// ElicitationAccept: the user provided the requested content
+	ElicitationAccept = mcp.ElicitationResponseActionAccept
+	// ElicitationDecline: the user refused to provide the content
+	ElicitationDecline = mcp.ElicitationResponseActionDecline
+	// ElicitationCancel: the user dismissed the request without choosing
+	ElicitationCancel = mcp.ElicitationResponseActionCancel
+)
+
+// ErrSamplingRefused is returned to the server when the confirmation refuses a sampling request
+var ErrSamplingRefused = errors.New("sampling request refused by the user")
+
+// SamplingRequest is a completion requested by the server (sampling/createMessage)
+type SamplingRequest struct {
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// Messages of the request (the images and audio contents are skipped)
+	Messages []messages.Message `json:"messages"`
+	// MaxTokens bounds the completion (below the max tokens of the agent);
+	// ModelHints are the preferences of the server (the agent keeps its model)
+	MaxTokens  int      `json:"max_tokens,omitempty"`
+	ModelHints []string `json:"model_hints,omitempty"`
+}
+
+// SamplingConfirmation approves a sampling request before it is sent to the model
+// (false: the server gets ErrSamplingRefused)
+type SamplingConfirmation func(ctx context.Context, request SamplingRequest) (bool, error)
+
+// ElicitationRequest is a request of the server for user input (elicitation/create)
+type ElicitationRequest struct {
+	Message string
+	// Schema is the JSON Schema of the expected content: an object with primitive properties
+	// (string, number, integer, boolean, with an optional enum)
+	Schema map[string]any
+}
+
+// ElicitationResponse is the answer of the user to an elicitation request
+type ElicitationResponse struct {
+	// Action is ElicitationAccept, ElicitationDecline or ElicitationCancel
+	Action mcp.ElicitationResponseAction
+	// Content follows the schema of the request (only sent with ElicitationAccept)
+	Content map[string]any
+}
+
+// ElicitationCallback asks the user for the input requested by the server
+// (e.g. prompt.MCPElicitation in a terminal)
+type ElicitationCallback func(ctx context.Context, request ElicitationRequest) (ElicitationResponse, error)
+
+// WithSampling answers the sampling requests of the server with a chat agent, after the
+// approval of confirmation (nil: the requests are approved without asking).
+// Each request starts a new conversation of the agent, with the system prompt of the request
+// (or the system instructions of the agent): use a dedicated agent. Sampling needs the stdio or streamable HTTP transport.
+//
+// Usage:
+//
+//	mcpClient, err := mcptools.NewMCPClient(ctx,
+//		mcptools.WithStreamableHTTPTransport("http://localhost:9011/mcp"),
+//		mcptools.WithSampling(samplingAgent, prompt.MCPSamplingConfirmation),
+//	)
+func WithSampling(agent *chat.Agent, confirmation SamplingConfirmation) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.clientOptions = append(config.clientOptions,
+			client.WithSamplingHandler(&samplingHandler{agent: agent, confirmation: confirmation}))
+	}
+}
+
+// WithElicitation routes the elicitation requests of the server to callback.
+// Elicitation needs the stdio or streamable HTTP transport.
+func WithElicitation(callback ElicitationCallback) MCPClientOption {
+	return func(config *mcpClientConfig) {
+		config.clientOptions = append(config.clientOptions,
+			client.WithElicitationHandler(elicitationHandler(callback)))
+	}
+}
+
+// samplingHandler implements client.SamplingHandler with a chat agent
+type samplingHandler struct {
+	// The agent handles one completion at a time
+	mutex        sync.Mutex
+	agent        *chat.Agent
+	confirmation SamplingConfirmation
+}
+
+// CreateMessage implements client.SamplingHandler
+func (handler *samplingHandler) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	samplingRequest := NewSamplingRequest(request)
+	if len(samplingRequest.Messages) == 0 {
+		return nil, errors.New("the sampling request has no text message")
+	}
+	if handler.confirmation != nil {
+		approved, err := handler.confirmation(ctx, samplingRequest)
+		if err != nil {
+			return nil, err
+		}
+		if !approved {
+			return nil, ErrSamplingRefused
+		}
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	defer handler.prepare(samplingRequest)()
+	result, err := handler.agent.GenerateCompletion(samplingRequest.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("error generating the sampling completion: %w", err)
+	}
+
+	stopReason := result.FinishReason
+	switch result.FinishReason {
+	case "stop":
+		stopReason = "endTurn"
+	case "length":
+		stopReason = "maxTokens"
+	}
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(result.Response)},
+		Model:           handler.agent.GetModelID(),
+		StopReason:      stopReason,
+	}, nil
+}
+
+// prepare starts a new conversation of the agent with the system prompt and the max tokens
+// of the request, and returns the function restoring the settings of the agent
+func (handler *samplingHandler) prepare(request SamplingRequest) (restore func()) {
+	modelConfig := handler.agent.GetModelConfig()
+	config := handler.agent.GetConfig()
+	instructions := config.SystemInstructions
+	restore = func() {
+		// SetModelConfig also clears the conversation, SetConfig restores the instructions
+		// even when they are empty (without sending an empty system message)
+		handler.agent.SetModelConfig(modelConfig)
+		handler.agent.SetConfig(config)
+		if instructions != "" {
+			handler.agent.SetSystemInstructions(instructions)
+		}
+	}
+
+	requestConfig := modelConfig
+	if request.MaxTokens > 0 && (modelConfig.MaxTokens == nil || int64(request.MaxTokens) < *modelConfig.MaxTokens) {
+		requestConfig = modelConfig.WithMaxTokens(int64(request.MaxTokens))
+	}
+	handler.agent.SetModelConfig(requestConfig)
+	if request.SystemPrompt != "" {
+		handler.agent.SetSystemInstructions(request.SystemPrompt)
+	} else if instructions != "" {
+		handler.agent.SetSystemInstructions(instructions)
+	}
+	return restore
+}
+
+// NewSamplingRequest converts a sampling request of the server
+func NewSamplingRequest(request mcp.CreateMessageRequest) SamplingRequest {
+	samplingRequest := SamplingRequest{
+		SystemPrompt: request.SystemPrompt,
+		MaxTokens:    request.MaxTokens,
+	}
+	if request.ModelPreferences != nil {
+		for _, hint := range request.ModelPreferences.Hints {
+			samplingRequest.ModelHints = append(samplingRequest.ModelHints, hint.Name)
+		}
+	}
+	for _, message := range request.Messages {
+		text := samplingText(message.Content)
+		if text == "" {
+			continue
+		}
+		role := roles.User
+		if message.Role == mcp.RoleAssistant {
+			role = roles.Assistant
+		}
+		samplingRequest.Messages = append(samplingRequest.Messages, messages.Message{Role: role, Content: text})
+	}
+	return samplingRequest
+}
+
+// samplingText returns the text of a sampling message content
+// (decoded as a map when it comes from the transport)
+func samplingText(content any) string {
+	switch content := content.(type) {
+	case mcp.TextContent:
+		return content.Text
+	case *mcp.TextContent:
+		return content.Text
+	case map[string]any:
+		if content["type"] == "text" {
+			text, _ := content["text"].(string)
+			return text
+		}
+	}
+	return ""
+}
+
+// elicitationHandler implements client.ElicitationHandler with a callback
+type elicitationHandler ElicitationCallback
+
+// Elicit implements client.ElicitationHandler
+func (callback elicitationHandler) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	elicitationRequest, err := NewElicitationRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := callback(ctx, elicitationRequest)
+	if err != nil {
+		return nil, err
+	}
+	result := &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: response.Action}}
+	if response.Action == ElicitationAccept {
+		result.Content = response.Content
+	}
+	return result, nil
+}
+
+// NewElicitationRequest converts an elicitation request of the server
+func NewElicitationRequest(request mcp.ElicitationRequest) (ElicitationRequest, error) {
+	elicitationRequest := ElicitationRequest{Message: request.Params.Message}
+	if request.Params.RequestedSchema == nil {
+		return elicitationRequest, nil
+	}
+	encoded, err := json.Marshal(request.Params.RequestedSchema)
+	if err != nil {
+		return elicitationRequest, fmt.Errorf("invalid elicitation schema: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &elicitationRequest.Schema); err != nil {
+		return elicitationRequest, fmt.Errorf("invalid elicitation schema: %w", err)
+	}
+	return elicitationRequest, nil
+}
@@ -0,0 +1,217 @@
+package mcptools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+// startServerRequestsServer starts an MCP server whose tools send sampling and elicitation requests
+func startServerRequestsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mcpServer := server.NewMCPServer("server-requests", "1.0.0", server.WithElicitation())
+	mcpServer.EnableSampling()
+	summarize := func(systemPrompt string) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			samplingRequest := mcp.CreateMessageRequest{}
+			samplingRequest.SystemPrompt = systemPrompt
+			samplingRequest.MaxTokens = 50
+			samplingRequest.Messages = []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent("Summarize Go")}}
+			result, err := mcpServer.RequestSampling(ctx, samplingRequest)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("%s (%s)", result.Content.(mcp.TextContent).Text, result.Model)), nil
+		}
+	}
+	mcpServer.AddTool(mcp.NewTool("summarize"), summarize("Be brief"))
+	mcpServer.AddTool(mcp.NewTool("summarize_without_prompt"), summarize(""))
+	mcpServer.AddTool(mcp.NewTool("ask_name"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			elicitationRequest := mcp.ElicitationRequest{}
+			elicitationRequest.Params.Message = "What is your name?"
+			elicitationRequest.Params.RequestedSchema = map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			}
+			result, err := mcpServer.RequestElicitation(ctx, elicitationRequest)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("%s %v", result.Action, result.Content)), nil
+		})
+	httpServer := httptest.NewServer(server.NewStreamableHTTPServer(mcpServer))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func TestMCPClient_Sampling(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("Go is simple")))
+	defer engine.Close()
+	httpServer := startServerRequestsServer(t)
+
+	samplingAgent, err := chat.NewAgent(context.Background(),
+		agents.Config{Name: "sampling", EngineURL: engine.URL, SystemInstructions: "You help the MCP servers"},
+		models.Config{Name: "test-model"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var confirmed []SamplingRequest
+	approve := true
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithSampling(samplingAgent, func(ctx context.Context, request SamplingRequest) (bool, error) {
+			confirmed = append(confirmed, request)
+			return approve, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	result, err := mcpClient.ExecToolWithMap("summarize", nil)
+	if err != nil || result != "Go is simple (test-model)" {
+		t.Fatalf("unexpected result %q, error %v", result, err)
+	}
+	if len(confirmed) != 1 || confirmed[0].SystemPrompt != "Be brief" || confirmed[0].MaxTokens != 50 ||
+		len(confirmed[0].Messages) != 1 || confirmed[0].Messages[0].Content != "Summarize Go" {
+		t.Errorf("unexpected confirmed request %+v", confirmed)
+	}
+	request, _ := engine.LastChatRequest()
+	if request.LastUserMessage() != "Summarize Go" {
+		t.Errorf("the request messages should be sent to the model, got %+v", request.Messages)
+	}
+
+	approve = false
+	if _, err := mcpClient.ExecToolWithMap("summarize", nil); !errors.Is(err, ErrToolFailed) {
+		t.Errorf("a refused sampling request should fail the tool, got %v", err)
+	}
+}
+
+func TestMCPClient_SamplingRequestsAreIndependent(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("Go is simple")))
+	defer engine.Close()
+	httpServer := startServerRequestsServer(t)
+
+	samplingAgent, err := chat.NewAgent(context.Background(),
+		agents.Config{Name: "sampling", EngineURL: engine.URL, SystemInstructions: "You help the MCP servers", KeepConversationHistory: true},
+		models.Config{Name: "test-model", MaxTokens: models.Int(1000)},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithSampling(samplingAgent, nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	for range 2 {
+		if _, err := mcpClient.ExecToolWithMap("summarize", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, request := range engine.ChatRequests() {
+		if len(request.Messages) != 2 || request.Messages[0].Content != "Be brief" || request.Messages[1].Content != "Summarize Go" {
+			t.Errorf("each request should only send its system prompt and messages, got %+v", request.Messages)
+		}
+		if request.MaxTokens == nil || *request.MaxTokens != 50 {
+			t.Errorf("the max tokens of the request should be applied, got %v", request.MaxTokens)
+		}
+	}
+
+	messages := samplingAgent.GetMessages()
+	if len(messages) != 1 || messages[0].Content != "You help the MCP servers" || *samplingAgent.GetModelConfig().MaxTokens != 1000 {
+		t.Errorf("the settings of the agent should be restored, got %+v", messages)
+	}
+}
+
+func TestMCPClient_SamplingKeepsTheAgentWithoutInstructions(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithDefaultResponse(novatest.Text("Go is simple")))
+	defer engine.Close()
+	httpServer := startServerRequestsServer(t)
+
+	samplingAgent, err := chat.NewAgent(context.Background(),
+		agents.Config{Name: "sampling", EngineURL: engine.URL},
+		models.Config{Name: "test-model"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithSampling(samplingAgent, nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	for _, tool := range []string{"summarize", "summarize_without_prompt"} {
+		if _, err := mcpClient.ExecToolWithMap(tool, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	request, _ := engine.LastChatRequest()
+	if len(request.Messages) != 1 || request.Messages[0].Role != "user" {
+		t.Errorf("the system prompt of the previous request should not be sent, got %+v", request.Messages)
+	}
+	if instructions := samplingAgent.GetConfig().SystemInstructions; instructions != "" || len(samplingAgent.GetMessages()) != 0 {
+		t.Errorf("the agent should stay without instructions, got %q and %+v", instructions, samplingAgent.GetMessages())
+	}
+}
+
+func TestMCPClient_Elicitation(t *testing.T) {
+	httpServer := startServerRequestsServer(t)
+
+	var received ElicitationRequest
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithElicitation(func(ctx context.Context, request ElicitationRequest) (ElicitationResponse, error) {
+			received = request
+			return ElicitationResponse{Action: ElicitationAccept, Content: map[string]any{"name": "Bob"}}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	result, err := mcpClient.ExecToolWithMap("ask_name", nil)
+	if err != nil || result != "accept map[name:Bob]" {
+		t.Fatalf("unexpected result %q, error %v", result, err)
+	}
+	if received.Message != "What is your name?" || received.Schema["type"] != "object" {
+		t.Errorf("unexpected request %+v", received)
+	}
+}
+
+func TestMCPClient_ServerRequestsNeedABidirectionalTransport(t *testing.T) {
+	recorder := &testServer{}
+	httpServer := recorder.startSSE(t)
+
+	_, err := NewMCPClient(context.Background(),
+		WithSSETransport(httpServer.URL+"/sse"),
+		WithElicitation(func(ctx context.Context, request ElicitationRequest) (ElicitationResponse, error) {
+			return ElicitationResponse{Action: ElicitationCancel}, nil
+		}),
+	)
+	if err == nil {
+		t.Error("the SSE transport should be refused")
+	}
+}
@@ -0,0 +1,221 @@
+package mcptools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testServer is an MCP server recording the headers and the client information it receives
+type testServer struct {
+	mutex      sync.Mutex
+	headers    []http.Header
+	clientInfo mcp.Implementation
+	mcpServer  *server.MCPServer
+}
+
+func (s *testServer) newMCPServer() *server.MCPServer {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.clientInfo = message.Params.ClientInfo
+	})
+	mcpServer := server.NewMCPServer("test-server", "1.0.0",
+		server.WithHooks(hooks),
+		server.WithResourceCapabilities(false, true),
+		server.WithPromptCapabilities(true),
+		server.WithToolCapabilities(true),
+	)
+	mcpServer.AddResource(mcp.NewResource("file:///docs/notes.md", "notes", mcp.WithMIMEType("text/markdown")),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/markdown", Text: "# Notes\nBob likes Go."},
+				mcp.BlobResourceContents{URI: request.Params.URI, MIMEType: "image/png", Blob: "aGVsbG8="},
+			}, nil
+		})
+	mcpServer.AddPrompt(mcp.NewPrompt("code_review", mcp.WithArgument("language", mcp.RequiredArgument())),
+		func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult("Code review", []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("You review "+request.Params.Arguments["language"]+" code.")),
+				mcp.NewPromptMessage(mcp.RoleAssistant, mcp.NewTextContent("Send me the code.")),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewImageContent("aGVsbG8=", "image/png")),
+			}), nil
+		})
+	mcpServer.AddTool(mcp.NewTool("hello", mcp.WithString("name", mcp.Required())),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("Hello " + request.GetString("name", "")), nil
+		})
+	mcpServer.AddTool(mcp.NewTool("slow"),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+			}
+			return mcp.NewToolResultText("too late"), nil
+		})
+	return mcpServer
+}
+
+func (s *testServer) record(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mutex.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *testServer) startSSE(t *testing.T) *httptest.Server {
+	t.Helper()
+	var sseServer *server.SSEServer
+	httpServer := httptest.NewServer(s.record(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sseServer.ServeHTTP(w, r)
+	})))
+	s.mcpServer = s.newMCPServer()
+	sseServer = server.NewSSEServer(s.mcpServer, server.WithBaseURL(httpServer.URL))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func (s *testServer) startStreamableHTTP(t *testing.T) *httptest.Server {
+	t.Helper()
+	s.mcpServer = s.newMCPServer()
+	httpServer := httptest.NewServer(s.record(server.NewStreamableHTTPServer(s.mcpServer)))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func TestNewMCPClient_SSEWithAuth(t *testing.T) {
+	recorder := &testServer{}
+	httpServer := recorder.startSSE(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mcpClient, err := NewMCPClient(ctx,
+		WithSSETransport(httpServer.URL+"/sse"),
+		WithHeader("X-Team", "nova"),
+		WithBearerToken("secret"),
+		WithClientInfo("my-app", "1.2.3"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	if len(mcpClient.GetTools()) != 2 {
+		t.Errorf("expected the tools of the server, got %+v", mcpClient.GetTools())
+	}
+	result, err := mcpClient.ExecToolWithString("hello", `{"name": "Bob"}`)
+	if err != nil || result != "Hello Bob" {
+		t.Errorf("unexpected result %q, error %v", result, err)
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.clientInfo.Name != "my-app" || recorder.clientInfo.Version != "1.2.3" {
+		t.Errorf("unexpected client info %+v", recorder.clientInfo)
+	}
+	for _, headers := range recorder.headers {
+		if headers.Get("Authorization") != "Bearer secret" || headers.Get("X-Team") != "nova" {
+			t.Errorf("every request should carry the headers, got %v", headers)
+		}
+	}
+}
+
+func TestNewMCPClient_StreamableHTTPTokenProvider(t *testing.T) {
+	recorder := &testServer{}
+	httpServer := recorder.startStreamableHTTP(t)
+
+	// The listening stream calls the provider concurrently with the requests
+	var tokens atomic.Int32
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithTokenProvider(func(ctx context.Context) (string, error) {
+			tokens.Add(1)
+			return "token", nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+	if tokens.Load() == 0 || recorder.clientInfo.Name != DefaultClientName {
+		t.Errorf("the token provider should be called (%d calls), client info %+v", tokens.Load(), recorder.clientInfo)
+	}
+
+	_, err = NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithTokenProvider(func(ctx context.Context) (string, error) {
+			return "", errors.New("refresh token expired")
+		}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "refresh token expired") {
+		t.Errorf("the token provider error should fail the connection, got %v", err)
+	}
+}
+
+func TestNewMCPClient_RequestTimeout(t *testing.T) {
+	httpServer := (&testServer{}).startStreamableHTTP(t)
+
+	mcpClient, err := NewMCPClient(context.Background(),
+		WithStreamableHTTPTransport(httpServer.URL),
+		WithRequestTimeout(200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+
+	start := time.Now()
+	if _, err := mcpClient.ExecToolWithString("slow", `{}`); err == nil {
+		t.Error("the call should time out")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("the call should be bounded by the request timeout, took %v", time.Since(start))
+	}
+}
+
+func TestMCPClient_CallToolWithContext(t *testing.T) {
+	httpServer := (&testServer{}).startStreamableHTTP(t)
+
+	mcpClient, err := NewMCPClient(context.Background(), WithStreamableHTTPTransport(httpServer.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer mcpClient.Close()
+	hub := NewMCPHub()
+	if err := hub.AddServer("local", mcpClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, caller := range map[string]func(context.Context) (*ToolResult, error){
+		"client": func(ctx context.Context) (*ToolResult, error) { return mcpClient.CallToolWithContext(ctx, "slow", nil) },
+		"hub":    func(ctx context.Context) (*ToolResult, error) { return hub.CallToolWithContext(ctx, "slow", nil) },
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		if _, err := caller(ctx); err == nil {
+			t.Errorf("%s: the call should be canceled", name)
+		}
+		cancel()
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("%s: the call should stop with its context, took %v", name, time.Since(start))
+		}
+	}
+}
+
+func TestNewMCPClient_RequiresATransport(t *testing.T) {
+	if _, err := NewMCPClient(context.Background(), WithBearerToken("secret")); err == nil {
+		t.Error("a transport should be required")
+	}
+}
@@ -6,15 +6,34 @@ import (
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/snipwise/nova/nova-sdk/cassette"
 )
 
+// newModelsClient creates an OpenAI client for the model runner endpoint, with the
+// NOVA_CASSETTE recorder when it is configured (like agents.NewOpenAIClient)
+func newModelsClient(modelRunnerEndpoint string) (openai.Client, error) {
+	httpClient, err := cassette.HTTPClientFromEnv()
+	if err != nil {
+		return openai.Client{}, err
+	}
+	requestOptions := []option.RequestOption{
+		option.WithBaseURL(modelRunnerEndpoint),
+		option.WithAPIKey(""),
+	}
+	if httpClient != nil {
+		requestOptions = append(requestOptions, option.WithHTTPClient(httpClient))
+	}
+	return openai.NewClient(requestOptions...), nil
+}
+
 func GetModelsList(ctx context.Context, modelRunnerEndpoint string) ([]string, error) {
 
 	// Initialize OpenAI client
-	openaiClient := openai.NewClient(
-		option.WithBaseURL(modelRunnerEndpoint),
-		option.WithAPIKey(""),
-	)
+	openaiClient, err := newModelsClient(modelRunnerEndpoint)
+	if err != nil {
+		log.Printf("Error setting up the HTTP client: %v", err)
+		return []string{}, err
+	}
 	modelsResponse, err := openaiClient.Models.List(ctx)
 	if err != nil {
 		log.Printf("Error fetching models: %v", err)
@@ -29,11 +48,12 @@ func GetModelsList(ctx context.Context, modelRunnerEndpoint string) ([]string, e
 }
 
 func IsModelAvailable(ctx context.Context, modelRunnerEndpoint, modelID string) bool {
-	openaiClient := openai.NewClient(
-		option.WithBaseURL(modelRunnerEndpoint),
-		option.WithAPIKey(""),
-	)
-	_, err := openaiClient.Models.Get(ctx, modelID)
+	openaiClient, err := newModelsClient(modelRunnerEndpoint)
+	if err != nil {
+		log.Printf("Error setting up the HTTP client: %v", err)
+		return false
+	}
+	_, err = openaiClient.Models.Get(ctx, modelID)
 	if err != nil {
 		log.Printf("Model %s not available: %v", modelID, err)
 		return false
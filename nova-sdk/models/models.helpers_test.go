@@ -0,0 +1,35 @@
+package models
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/cassette"
+)
+
+func TestGetModelsList_UsesTheEnvCassette(t *testing.T) {
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"test-model","object":"model"}]}`))
+	}))
+	defer engine.Close()
+
+	path := filepath.Join(t.TempDir(), "models.cassette.json")
+	t.Setenv(cassette.EnvCassette, path)
+	t.Setenv(cassette.EnvCassetteMode, string(cassette.ModeRecord))
+
+	list, err := GetModelsList(context.Background(), engine.URL)
+	if err != nil || len(list) != 1 || list[0] != "test-model" {
+		t.Fatalf("unexpected models %v, error %v", list, err)
+	}
+	if !IsModelAvailable(context.Background(), engine.URL, "test-model") {
+		t.Error("test-model should be available")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the requests should be recorded in the cassette: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package novatest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// RecordedRequest is a request received by the fake engine
+type RecordedRequest struct {
+	Method    string
+	Path      string
+	Body      []byte
+	Chat      *ChatRequest      // set for /chat/completions
+	Embedding *EmbeddingRequest // set for /embeddings
+}
+
+// ChatRequest is the decoded body of a chat completion request
+type ChatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Tools         []Tool         `json:"tools"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *StreamOptions `json:"stream_options"`
+	Temperature   *float64       `json:"temperature"`
+	Seed          *int64         `json:"seed"`
+	MaxTokens     *int           `json:"max_tokens"`
+}
+
+// StreamOptions is the stream_options field of a chat completion request
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// Message is a chat message; multi-part contents are flattened into Content
+type Message struct {
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall
+	ToolCallID string
+}
+
+// UnmarshalJSON decodes string and multi-part message contents
+func (message *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCallID string          `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	message.Role = raw.Role
+	message.ToolCallID = raw.ToolCallID
+	message.Content = flattenContent(raw.Content)
+	message.ToolCalls = nil
+	for _, call := range raw.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return nil
+}
+
+func flattenContent(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		builder.WriteString(part.Text)
+	}
+	return builder.String()
+}
+
+// Tool is a function tool declared in a chat completion request
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// UnmarshalJSON decodes the {"type":"function","function":{...}} form
+func (tool *Tool) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Function struct {
+			Name        string         `json:"name"`
+			Description string         `json:"description"`
+			Parameters  map[string]any `json:"parameters"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	tool.Name = raw.Function.Name
+	tool.Description = raw.Function.Description
+	tool.Parameters = raw.Function.Parameters
+	return nil
+}
+
+// LastUserMessage returns the content of the last user message
+func (request ChatRequest) LastUserMessage() string {
+	for index := len(request.Messages) - 1; index >= 0; index-- {
+		if request.Messages[index].Role == "user" {
+			return request.Messages[index].Content
+		}
+	}
+	return ""
+}
+
+// SystemMessage returns the content of the first system message
+func (request ChatRequest) SystemMessage() string {
+	for _, message := range request.Messages {
+		if message.Role == "system" || message.Role == "developer" {
+			return message.Content
+		}
+	}
+	return ""
+}
+
+// ToolNames returns the names of the declared tools
+func (request ChatRequest) ToolNames() []string {
+	names := make([]string, 0, len(request.Tools))
+	for _, tool := range request.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+// EmbeddingRequest is the decoded body of an embeddings request
+type EmbeddingRequest struct {
+	Model string
+	Input []string
+}
+
+// UnmarshalJSON decodes string and array inputs
+func (request *EmbeddingRequest) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	request.Model = raw.Model
+
+	var single string
+	if err := json.Unmarshal(raw.Input, &single); err == nil {
+		request.Input = []string{single}
+		return nil
+	}
+	return json.Unmarshal(raw.Input, &request.Input)
+}
@@ -0,0 +1,217 @@
+package novatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func (server *Server) handleChatCompletion(w http.ResponseWriter, request ChatRequest, response Response, callIndex int) {
+	if response.Status != 0 && response.Status != http.StatusOK {
+		writeError(w, response.Status, response.Error)
+		return
+	}
+
+	model := request.Model
+	if model == "" {
+		model = DefaultChatModel
+	}
+	id := "chatcmpl-novatest-" + strconv.Itoa(callIndex+1)
+	usage := response.usage(request)
+
+	if request.Stream {
+		streamChatCompletion(w, request, response, id, model, usage)
+		return
+	}
+
+	message := map[string]any{"role": "assistant", "content": response.Content}
+	if response.Content == "" && len(response.ToolCalls) > 0 {
+		message["content"] = nil
+	}
+	if response.ReasoningContent != "" {
+		message["reasoning_content"] = response.ReasoningContent
+	}
+	if len(response.ToolCalls) > 0 {
+		toolCalls := make([]map[string]any, 0, len(response.ToolCalls))
+		for _, call := range response.ToolCalls {
+			toolCalls = append(toolCalls, toolCallJSON(call))
+		}
+		message["tool_calls"] = toolCalls
+	}
+
+	writeJSON(w, map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": response.finishReason(),
+		}},
+		"usage": usageJSON(usage),
+	})
+}
+
+// streamChatCompletion sends the response as SSE chunks: role, reasoning (word by word),
+// content (word by word), tool calls, finish reason, then usage when stream_options.include_usage is set
+func streamChatCompletion(w http.ResponseWriter, request ChatRequest, response Response, id string, model string, usage Usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	created := time.Now().Unix()
+	send := func(delta map[string]any, finishReason any) {
+		writeSSE(w, flusher, map[string]any{
+			"id": id, "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		})
+	}
+
+	send(map[string]any{"role": "assistant"}, nil)
+	for _, word := range splitWords(response.ReasoningContent) {
+		send(map[string]any{"reasoning_content": word}, nil)
+	}
+	for _, word := range splitWords(response.Content) {
+		send(map[string]any{"content": word}, nil)
+	}
+	for index, call := range response.ToolCalls {
+		toolCall := toolCallJSON(call)
+		toolCall["index"] = index
+		send(map[string]any{"tool_calls": []map[string]any{toolCall}}, nil)
+	}
+	send(map[string]any{}, response.finishReason())
+
+	if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+		writeSSE(w, flusher, map[string]any{
+			"id": id, "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []map[string]any{},
+			"usage":   usageJSON(usage),
+		})
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, data map[string]any) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func toolCallJSON(call ToolCall) map[string]any {
+	return map[string]any{
+		"id":   call.ID,
+		"type": "function",
+		"function": map[string]any{
+			"name":      call.Name,
+			"arguments": call.Arguments,
+		},
+	}
+}
+
+// splitWords splits a text into chunks keeping the separators, so the chunks rebuild the text
+func splitWords(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.SplitAfter(text, " ")
+}
+
+func (response Response) usage(request ChatRequest) Usage {
+	if response.Usage != nil {
+		return *response.Usage
+	}
+	promptChars := 0
+	for _, message := range request.Messages {
+		promptChars += len(message.Content)
+	}
+	completionChars := len(response.Content) + len(response.ReasoningContent)
+	for _, call := range response.ToolCalls {
+		completionChars += len(call.Name) + len(call.Arguments)
+	}
+	return Usage{PromptTokens: estimateTokens(promptChars), CompletionTokens: estimateTokens(completionChars)}
+}
+
+func estimateTokens(chars int) int {
+	return (chars + 3) / 4
+}
+
+func usageJSON(usage Usage) map[string]any {
+	return map[string]any{
+		"prompt_tokens":     usage.PromptTokens,
+		"completion_tokens": usage.CompletionTokens,
+		"total_tokens":      usage.PromptTokens + usage.CompletionTokens,
+	}
+}
+
+// ── embeddings ──────────────────────────────────────────────────
+
+func (server *Server) handleEmbeddings(w http.ResponseWriter, request EmbeddingRequest) {
+	model := request.Model
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+
+	data := make([]map[string]any, 0, len(request.Input))
+	promptTokens := 0
+	for index, input := range request.Input {
+		data = append(data, map[string]any{
+			"object":    "embedding",
+			"index":     index,
+			"embedding": Embed(input, server.embeddingDimension),
+		})
+		promptTokens += estimateTokens(len(input))
+	}
+
+	writeJSON(w, map[string]any{
+		"object": "list",
+		"model":  model,
+		"data":   data,
+		"usage":  map[string]any{"prompt_tokens": promptTokens, "total_tokens": promptTokens},
+	})
+}
+
+// Embed returns the deterministic vector the fake engine produces for a text: a normalized
+// bag of hashed lowercase words, so texts sharing words have a high cosine similarity
+func Embed(text string, dimension int) []float64 {
+	if dimension <= 0 {
+		dimension = DefaultEmbeddingDimension
+	}
+	vector := make([]float64, dimension)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%uint32(dimension)] += 1
+	}
+
+	norm := 0.0
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm == 0 {
+		// Empty text: unit vector on the first axis, so similarities stay defined
+		vector[0] = 1
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for index := range vector {
+		vector[index] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,175 @@
+package novatest
+
+import (
+	"strings"
+)
+
+// ToolCall is a tool call returned by the fake engine
+type ToolCall struct {
+	ID        string // generated when empty
+	Name      string
+	Arguments string // JSON string, "{}" when empty
+}
+
+// Usage is the token usage returned by the fake engine.
+// When a Response has no Usage, it is estimated from the request and the answer (~4 chars per token).
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Response scripts the answer of the fake engine to a chat completion request
+type Response struct {
+	Content          string
+	ReasoningContent string
+	ToolCalls        []ToolCall
+	// FinishReason defaults to "tool_calls" when ToolCalls is set, "stop" otherwise
+	FinishReason string
+	Usage        *Usage
+
+	// Status and Error simulate an engine failure (e.g. 500, "engine overloaded")
+	Status int
+	Error  string
+}
+
+// Text returns a Response with the given content
+func Text(content string) Response {
+	return Response{Content: content}
+}
+
+// ToolCalls returns a Response requesting the given tool calls
+func ToolCalls(calls ...ToolCall) Response {
+	return Response{ToolCalls: calls}
+}
+
+// Call builds a ToolCall
+func Call(name string, arguments string) ToolCall {
+	return ToolCall{Name: name, Arguments: arguments}
+}
+
+// Failure returns a Response making the engine answer with an HTTP error
+func Failure(status int, message string) Response {
+	return Response{Status: status, Error: message}
+}
+
+func (response Response) finishReason() string {
+	switch {
+	case response.FinishReason != "":
+		return response.FinishReason
+	case len(response.ToolCalls) > 0:
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
+
+// Matcher selects the chat requests a Rule applies to.
+// callIndex is the 0-based index of the request among all the chat requests received by the server.
+type Matcher func(request ChatRequest, callIndex int) bool
+
+// LastUserMessageContains matches when the last user message contains text (case-insensitive)
+func LastUserMessageContains(text string) Matcher {
+	return func(request ChatRequest, _ int) bool {
+		return strings.Contains(strings.ToLower(request.LastUserMessage()), strings.ToLower(text))
+	}
+}
+
+// LastUserMessageIs matches when the last user message is exactly text
+func LastUserMessageIs(text string) Matcher {
+	return func(request ChatRequest, _ int) bool {
+		return request.LastUserMessage() == text
+	}
+}
+
+// HasTools matches requests declaring at least one tool
+func HasTools() Matcher {
+	return func(request ChatRequest, _ int) bool {
+		return len(request.Tools) > 0
+	}
+}
+
+// HasTool matches requests declaring the named tool
+func HasTool(name string) Matcher {
+	return func(request ChatRequest, _ int) bool {
+		for _, tool := range request.Tools {
+			if tool.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// LastMessageIsToolResult matches requests sending back tool results (last message role is "tool")
+func LastMessageIsToolResult() Matcher {
+	return func(request ChatRequest, _ int) bool {
+		return len(request.Messages) > 0 && request.Messages[len(request.Messages)-1].Role == "tool"
+	}
+}
+
+// CallIndex matches the n-th chat request received by the server (0-based)
+func CallIndex(n int) Matcher {
+	return func(_ ChatRequest, callIndex int) bool {
+		return callIndex == n
+	}
+}
+
+// Streaming matches streaming (stream == true) or non-streaming requests
+func Streaming(stream bool) Matcher {
+	return func(request ChatRequest, _ int) bool {
+		return request.Stream == stream
+	}
+}
+
+// Rule scripts the answer to the chat requests matching all its matchers
+type Rule struct {
+	server    *Server
+	matchers  []Matcher
+	responses []Response
+	times     int // 0: unlimited
+	hits      int
+}
+
+// Reply sets the response of the rule. Several responses are served in turn
+// (the last one is repeated when the rule keeps matching).
+func (rule *Rule) Reply(responses ...Response) *Rule {
+	rule.server.mutex.Lock()
+	defer rule.server.mutex.Unlock()
+	rule.responses = responses
+	return rule
+}
+
+// Times limits the number of requests the rule answers
+func (rule *Rule) Times(n int) *Rule {
+	rule.server.mutex.Lock()
+	defer rule.server.mutex.Unlock()
+	rule.times = n
+	return rule
+}
+
+// Hits returns the number of requests answered by the rule
+func (rule *Rule) Hits() int {
+	rule.server.mutex.Lock()
+	defer rule.server.mutex.Unlock()
+	return rule.hits
+}
+
+// matches must be called with the server mutex held
+func (rule *Rule) matches(request ChatRequest, callIndex int) bool {
+	if len(rule.responses) == 0 || (rule.times > 0 && rule.hits >= rule.times) {
+		return false
+	}
+	for _, matcher := range rule.matchers {
+		if !matcher(request, callIndex) {
+			return false
+		}
+	}
+	return true
+}
+
+// next must be called with the server mutex held
+func (rule *Rule) next() Response {
+	response := rule.responses[min(rule.hits, len(rule.responses)-1)]
+	rule.hits++
+	return response
+}
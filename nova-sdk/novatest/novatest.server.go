@@ -0,0 +1,245 @@
+// Package novatest provides a fake OpenAI-compatible engine for testing nova agents
+// without a model runner.
+//
+// Example:
+//
+//	engine := novatest.NewServer()
+//	defer engine.Close()
+//
+//	engine.When(novatest.LastUserMessageContains("weather")).
+//	    Reply(novatest.ToolCalls(novatest.Call("get_weather", `{"city":"Paris"}`)))
+//	engine.When(novatest.LastMessageIsToolResult()).Reply(novatest.Text("It is sunny in Paris"))
+//
+//	agent, _ := chat.NewAgent(ctx,
+//	    agents.Config{EngineURL: engine.URL},
+//	    models.Config{Name: novatest.DefaultChatModel},
+//	)
+package novatest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultChatModel is listed by /models unless WithModels is used
+	DefaultChatModel = "test-model"
+	// DefaultEmbeddingModel is listed by /models unless WithModels is used
+	DefaultEmbeddingModel = "test-embedding-model"
+	// DefaultEmbeddingDimension is the size of the vectors returned by /embeddings
+	DefaultEmbeddingDimension = 64
+)
+
+// Server is a fake OpenAI-compatible engine running on an httptest.Server.
+// Routes are served with or without the /v1 prefix, so URL and URL+"/v1" both work as EngineURL.
+type Server struct {
+	*httptest.Server
+
+	mutex              sync.Mutex
+	models             []string
+	embeddingDimension int
+	defaultResponse    Response
+	rules              []*Rule
+	requests           []RecordedRequest
+	chatCalls          int
+	toolCallCounter    int
+}
+
+// Option is a functional option for configuring a Server
+type Option func(*Server)
+
+// WithModels sets the model IDs listed by /models
+func WithModels(ids ...string) Option {
+	return func(server *Server) {
+		server.models = ids
+	}
+}
+
+// WithEmbeddingDimension sets the size of the vectors returned by /embeddings
+func WithEmbeddingDimension(dimension int) Option {
+	return func(server *Server) {
+		server.embeddingDimension = dimension
+	}
+}
+
+// WithDefaultResponse sets the answer used when no rule matches (default: Text("OK"))
+func WithDefaultResponse(response Response) Option {
+	return func(server *Server) {
+		server.defaultResponse = response
+	}
+}
+
+// NewServer starts a fake engine
+func NewServer(options ...Option) *Server {
+	server := &Server{
+		models:             []string{DefaultChatModel, DefaultEmbeddingModel},
+		embeddingDimension: DefaultEmbeddingDimension,
+		defaultResponse:    Text("OK"),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	return server
+}
+
+// When adds a rule answering the chat requests matching all the matchers.
+// Rules are evaluated in the order they were added; the first matching rule answers.
+func (server *Server) When(matchers ...Matcher) *Rule {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	rule := &Rule{server: server, matchers: matchers}
+	server.rules = append(server.rules, rule)
+	return rule
+}
+
+// Reply adds a rule answering every chat request (shortcut for When().Reply(...))
+func (server *Server) Reply(responses ...Response) *Rule {
+	return server.When().Reply(responses...)
+}
+
+// Requests returns all the requests received by the server
+func (server *Server) Requests() []RecordedRequest {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]RecordedRequest(nil), server.requests...)
+}
+
+// ChatRequests returns the chat completion requests received by the server
+func (server *Server) ChatRequests() []ChatRequest {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	var chatRequests []ChatRequest
+	for _, request := range server.requests {
+		if request.Chat != nil {
+			chatRequests = append(chatRequests, *request.Chat)
+		}
+	}
+	return chatRequests
+}
+
+// LastChatRequest returns the last chat completion request, or false when none was received
+func (server *Server) LastChatRequest() (ChatRequest, bool) {
+	chatRequests := server.ChatRequests()
+	if len(chatRequests) == 0 {
+		return ChatRequest{}, false
+	}
+	return chatRequests[len(chatRequests)-1], true
+}
+
+// Reset forgets the received requests, the rules and the call counters
+func (server *Server) Reset() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.requests = nil
+	server.rules = nil
+	server.chatCalls = 0
+	server.toolCallCounter = 0
+}
+
+func (server *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	recorded := RecordedRequest{Method: r.Method, Path: r.URL.Path, Body: body}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/models"):
+		server.record(recorded)
+		server.handleModels(w)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/chat/completions"):
+		var chatRequest ChatRequest
+		if err := json.Unmarshal(body, &chatRequest); err != nil {
+			server.record(recorded)
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		recorded.Chat = &chatRequest
+		response, callIndex := server.resolve(recorded)
+		server.handleChatCompletion(w, chatRequest, response, callIndex)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/embeddings"):
+		var embeddingRequest EmbeddingRequest
+		if err := json.Unmarshal(body, &embeddingRequest); err != nil {
+			server.record(recorded)
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		recorded.Embedding = &embeddingRequest
+		server.record(recorded)
+		server.handleEmbeddings(w, embeddingRequest)
+
+	default:
+		server.record(recorded)
+		writeError(w, http.StatusNotFound, "unknown route "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (server *Server) record(request RecordedRequest) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.requests = append(server.requests, request)
+}
+
+// resolve records a chat request and picks its response
+func (server *Server) resolve(request RecordedRequest) (Response, int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = append(server.requests, request)
+	callIndex := server.chatCalls
+	server.chatCalls++
+
+	for _, rule := range server.rules {
+		if rule.matches(*request.Chat, callIndex) {
+			return server.withToolCallIDs(rule.next()), callIndex
+		}
+	}
+	return server.withToolCallIDs(server.defaultResponse), callIndex
+}
+
+// withToolCallIDs gives an ID to the tool calls without one (must be called with the mutex held)
+func (server *Server) withToolCallIDs(response Response) Response {
+	if len(response.ToolCalls) == 0 {
+		return response
+	}
+	calls := make([]ToolCall, len(response.ToolCalls))
+	for index, call := range response.ToolCalls {
+		if call.ID == "" {
+			server.toolCallCounter++
+			call.ID = "call_" + strconv.Itoa(server.toolCallCounter)
+		}
+		if call.Arguments == "" {
+			call.Arguments = "{}"
+		}
+		calls[index] = call
+	}
+	response.ToolCalls = calls
+	return response
+}
+
+func (server *Server) handleModels(w http.ResponseWriter) {
+	data := make([]map[string]any, 0, len(server.models))
+	for _, id := range server.models {
+		data = append(data, map[string]any{"id": id, "object": "model", "created": 0, "owned_by": "novatest"})
+	}
+	writeJSON(w, map[string]any{"object": "list", "data": data})
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": "novatest_error"},
+	})
+}
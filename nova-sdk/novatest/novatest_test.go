@@ -0,0 +1,285 @@
+package novatest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+
+	"github.com/snipwise/nova/nova-sdk/agents"
+	"github.com/snipwise/nova/nova-sdk/agents/chat"
+	"github.com/snipwise/nova/nova-sdk/agents/rag"
+	"github.com/snipwise/nova/nova-sdk/agents/structured"
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/messages"
+	"github.com/snipwise/nova/nova-sdk/messages/roles"
+	"github.com/snipwise/nova/nova-sdk/models"
+	"github.com/snipwise/nova/nova-sdk/novatest"
+)
+
+func userMessage(content string) []messages.Message {
+	return []messages.Message{{Role: roles.User, Content: content}}
+}
+
+// ── chat agent ────────────────────────────────────────────────────────────────
+
+func TestChatAgent_ScriptedCompletionAndStream(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+
+	engine.When(novatest.LastUserMessageContains("hello")).Reply(novatest.Text("Hello from the fake engine"))
+
+	agent, err := chat.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL, SystemInstructions: "Be nice"},
+		models.Config{Name: novatest.DefaultChatModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.GenerateCompletion(userMessage("Hello there"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Response != "Hello from the fake engine" || result.FinishReason != "stop" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	var streamed strings.Builder
+	result, err = agent.GenerateStreamCompletion(userMessage("hello again"), func(chunk string, _ string) error {
+		streamed.WriteString(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamed.String() != "Hello from the fake engine" || result.Response != streamed.String() {
+		t.Errorf("unexpected stream %q / %q", streamed.String(), result.Response)
+	}
+
+	// Unmatched requests get the default response
+	result, _ = agent.GenerateCompletion(userMessage("something else"))
+	if result.Response != "OK" {
+		t.Errorf("want default response, got %q", result.Response)
+	}
+
+	chatRequests := engine.ChatRequests()
+	if len(chatRequests) != 3 {
+		t.Fatalf("want 3 recorded chat requests, got %d", len(chatRequests))
+	}
+	if chatRequests[0].SystemMessage() != "Be nice" || !chatRequests[1].Stream {
+		t.Errorf("unexpected recorded requests %+v", chatRequests)
+	}
+}
+
+func TestChatAgent_ReasoningContent(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+	engine.Reply(novatest.Response{Content: "42", ReasoningContent: "Let me think about it"})
+
+	agent, err := chat.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultChatModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := agent.GenerateCompletionWithReasoning(userMessage("question"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Response != "42" || result.Reasoning != "Let me think about it" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	var reasoning strings.Builder
+	result, err = agent.GenerateStreamCompletionWithReasoning(userMessage("question"),
+		func(chunk string, _ string) error { reasoning.WriteString(chunk); return nil },
+		func(string, string) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reasoning.String() != "Let me think about it" || result.Response != "42" {
+		t.Errorf("unexpected streamed reasoning %q / %q", reasoning.String(), result.Response)
+	}
+}
+
+// ── tools agent ───────────────────────────────────────────────────────────────
+
+func TestToolsAgent_ToolCallLoop(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+
+	engine.When(novatest.LastMessageIsToolResult()).Reply(novatest.Text("It is sunny in Paris"))
+	engine.When(novatest.HasTool("get_weather")).Reply(novatest.ToolCalls(novatest.Call("get_weather", `{"city":"Paris"}`)))
+
+	agent, err := tools.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultChatModel},
+		tools.WithTools([]*tools.Tool{
+			tools.NewTool("get_weather").AddParameter("city", "string", "The city", true),
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var executed []string
+	result, err := agent.DetectToolCallsLoop(userMessage("Weather in Paris?"), func(name string, arguments string) (string, error) {
+		executed = append(executed, name+arguments)
+		return `{"weather":"sunny"}`, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executed) != 1 || executed[0] != `get_weather{"city":"Paris"}` {
+		t.Errorf("unexpected executions %v", executed)
+	}
+	if result.LastAssistantMessage != "It is sunny in Paris" {
+		t.Errorf("unexpected final message %q", result.LastAssistantMessage)
+	}
+
+	last, _ := engine.LastChatRequest()
+	if last.Messages[len(last.Messages)-1].ToolCallID != "call_1" {
+		t.Errorf("tool result should reference the generated call ID, got %+v", last.Messages)
+	}
+}
+
+// ── structured agent ──────────────────────────────────────────────────────────
+
+type country struct {
+	Name    string `json:"name"`
+	Capital string `json:"capital"`
+}
+
+func TestStructuredAgent(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+	engine.Reply(novatest.Text(`{"name":"France","capital":"Paris"}`))
+
+	agent, err := structured.NewAgent[country](context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultChatModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	response, _, err := agent.GenerateStructuredData(userMessage("Tell me about France"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Capital != "Paris" {
+		t.Errorf("unexpected structured response %+v", response)
+	}
+}
+
+// ── rag agent ─────────────────────────────────────────────────────────────────
+
+func TestRagAgent_SimilarityFromFakeEmbeddings(t *testing.T) {
+	engine := novatest.NewServer(novatest.WithEmbeddingDimension(128))
+	defer engine.Close()
+
+	agent, err := rag.NewAgent(context.Background(),
+		agents.Config{EngineURL: engine.URL},
+		models.Config{Name: novatest.DefaultEmbeddingModel},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, document := range []string{"Squirrels run in the forest", "Birds fly in the sky", "Frogs swim in the pond"} {
+		if err := agent.SaveEmbedding(document); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	results, err := agent.SearchTopN("Where do birds fly?", 0.1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Prompt != "Birds fly in the sky" {
+		t.Errorf("unexpected search results %+v", results)
+	}
+	if agent.GetEmbeddingDimension() != 128 {
+		t.Errorf("want dimension 128, got %d", agent.GetEmbeddingDimension())
+	}
+}
+
+// ── rules and raw client ──────────────────────────────────────────────────────
+
+func TestRules_CallIndexTimesAndFailure(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+
+	engine.When(novatest.CallIndex(0)).Reply(novatest.Failure(400, "bad request"))
+	first := engine.When(novatest.LastUserMessageIs("ping")).Reply(novatest.Text("pong 1"), novatest.Text("pong 2")).Times(2)
+
+	client := openai.NewClient(option.WithBaseURL(engine.URL+"/v1"), option.WithAPIKey("test"))
+	params := openai.ChatCompletionNewParams{
+		Model:    novatest.DefaultChatModel,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("ping")},
+	}
+
+	if _, err := client.Chat.Completions.New(context.Background(), params); err == nil {
+		t.Error("first call should fail")
+	}
+	var answers []string
+	for range 3 {
+		completion, err := client.Chat.Completions.New(context.Background(), params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		answers = append(answers, completion.Choices[0].Message.Content)
+	}
+	if strings.Join(answers, ",") != "pong 1,pong 2,OK" {
+		t.Errorf("unexpected answers %v", answers)
+	}
+	if first.Hits() != 2 {
+		t.Errorf("want 2 hits, got %d", first.Hits())
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	engine := novatest.NewServer()
+	defer engine.Close()
+	engine.Reply(novatest.Response{Content: "four words right here", Usage: &novatest.Usage{PromptTokens: 7, CompletionTokens: 4}})
+
+	client := openai.NewClient(option.WithBaseURL(engine.URL), option.WithAPIKey("test"))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:         novatest.DefaultChatModel,
+		Messages:      []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	})
+	var totalTokens int64
+	for stream.Next() {
+		if chunk := stream.Current(); chunk.Usage.TotalTokens > 0 {
+			totalTokens = chunk.Usage.TotalTokens
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if totalTokens != 11 {
+		t.Errorf("want 11 total tokens, got %d", totalTokens)
+	}
+}
+
+func TestEmbed_SimilarTextsAreCloser(t *testing.T) {
+	cosine := func(a, b []float64) float64 {
+		sum := 0.0
+		for i := range a {
+			sum += a[i] * b[i]
+		}
+		return sum
+	}
+	cat := novatest.Embed("the cat sleeps on the sofa", 64)
+	sameTopic := novatest.Embed("a cat sleeps", 64)
+	otherTopic := novatest.Embed("stock markets fell today", 64)
+	if cosine(cat, sameTopic) <= cosine(cat, otherTopic) {
+		t.Error("texts sharing words should be more similar")
+	}
+}
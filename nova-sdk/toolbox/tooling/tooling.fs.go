@@ -0,0 +1,259 @@
+package tooling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+	"github.com/snipwise/nova/nova-sdk/toolbox/files"
+)
+
+// DefaultMaxResults is the default maximum number of entries returned by list_files and search_files
+const DefaultMaxResults = 200
+
+// MaxSearchedFileSize is the size in bytes of the beginning of a file searched by search_files
+const MaxSearchedFileSize = 1 << 20
+
+// ErrOutsideRoot is returned when a path leaves the root directory of the filesystem tools
+var ErrOutsideRoot = errors.New("path is outside the root directory")
+
+// FilesystemConfig holds the settings of the filesystem tools
+type FilesystemConfig struct {
+	Root       string // Directory the tools are confined to (paths are relative to it)
+	MaxOutput  int    // Maximum size of a file read (default: DefaultMaxOutput)
+	MaxResults int    // Maximum number of listed or found entries (default: DefaultMaxResults)
+}
+
+func (config FilesystemConfig) maxResults() int {
+	if config.MaxResults <= 0 {
+		return DefaultMaxResults
+	}
+	return config.MaxResults
+}
+
+// resolve returns the absolute path of a path relative to the root, rejecting the paths
+// leaving the root (with .. or through a symbolic link)
+func (config FilesystemConfig) resolve(path string) (string, error) {
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return "", err
+	}
+	if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolvedRoot
+	}
+
+	target := filepath.Join(root, filepath.Clean("/"+path))
+
+	// The deepest existing ancestor must stay in the root once the links are resolved
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, path)
+	}
+	return target, nil
+}
+
+// relative returns a path relative to the root, for the results sent to the model
+func (config FilesystemConfig) relative(path string) string {
+	root, _ := config.resolve("")
+	if relativePath, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(relativePath)
+	}
+	return path
+}
+
+// ReadFileInput holds the arguments of read_file
+type ReadFileInput struct {
+	Path string `json:"path" description:"Path of the file, relative to the root directory"`
+}
+
+// ReadFileTool returns the read_file tool, reading a text file
+func ReadFileTool(config FilesystemConfig) *tools.RegisteredTool {
+	return tools.Register("read_file", "Read the content of a text file",
+		func(ctx context.Context, input ReadFileInput) (string, error) {
+			path, err := config.resolve(input.Path)
+			if err != nil {
+				return "", err
+			}
+			content, truncated, err := readTextFile(path, config.MaxOutput)
+			if err != nil {
+				return "", err
+			}
+			if truncated {
+				return content + "\n[content truncated]", nil
+			}
+			return content, nil
+		})
+}
+
+// readTextFile reads at most maxLength bytes of a file (see truncate) and reports whether
+// the file is longer: a large file is never loaded entirely
+func readTextFile(path string, maxLength int) (string, bool, error) {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxOutput
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	// One more byte tells whether the file was truncated
+	content, err := io.ReadAll(io.LimitReader(file, int64(maxLength)+1))
+	if err != nil {
+		return "", false, err
+	}
+	text, truncated := truncate(string(content), maxLength)
+	return text, truncated, nil
+}
+
+// ListFilesInput holds the arguments of list_files
+type ListFilesInput struct {
+	Directory string `json:"directory,omitempty" description:"Directory to list, relative to the root directory (default: the root)"`
+}
+
+// ListFilesTool returns the list_files tool, listing the entries of a directory
+// (directories end with a slash)
+func ListFilesTool(config FilesystemConfig) *tools.RegisteredTool {
+	return tools.Register("list_files", "List the files and directories of a directory",
+		func(ctx context.Context, input ListFilesInput) ([]string, error) {
+			directory, err := config.resolve(input.Directory)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := os.ReadDir(directory)
+			if err != nil {
+				return nil, err
+			}
+			names := []string{}
+			for _, entry := range entries {
+				if len(names) >= config.maxResults() {
+					break
+				}
+				name := config.relative(filepath.Join(directory, entry.Name()))
+				if entry.IsDir() {
+					name += "/"
+				}
+				names = append(names, name)
+			}
+			return names, nil
+		})
+}
+
+// SearchFilesInput holds the arguments of search_files
+type SearchFilesInput struct {
+	Pattern   string `json:"pattern" description:"Regular expression searched in the lines of the files"`
+	Extension string `json:"extension,omitempty" description:"Extension of the searched files, e.g. .go (default: all files)"`
+	Directory string `json:"directory,omitempty" description:"Directory to search, relative to the root directory (default: the root)"`
+}
+
+// SearchMatch is a line matching the pattern of search_files
+type SearchMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// SearchFilesTool returns the search_files tool, searching a regular expression in the files
+// of a directory and its subdirectories (in the first MaxSearchedFileSize bytes of each file)
+func SearchFilesTool(config FilesystemConfig) *tools.RegisteredTool {
+	return tools.Register("search_files", "Search a regular expression in the lines of the files of a directory",
+		func(ctx context.Context, input SearchFilesInput) ([]SearchMatch, error) {
+			expression, err := regexp.Compile(input.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %w", err)
+			}
+			directory, err := config.resolve(input.Directory)
+			if err != nil {
+				return nil, err
+			}
+			extension := input.Extension
+			if extension == "" {
+				extension = ".*"
+			}
+			paths, err := files.FindFiles(directory, extension)
+			if err != nil {
+				return nil, err
+			}
+
+			matches := []SearchMatch{}
+			for _, path := range paths {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				// Links leaving the root are not followed
+				if _, err := config.resolve(config.relative(path)); err != nil {
+					continue
+				}
+				content, _, err := readTextFile(path, MaxSearchedFileSize)
+				if err != nil {
+					continue
+				}
+				for index, line := range strings.Split(content, "\n") {
+					if !expression.MatchString(line) {
+						continue
+					}
+					text, _ := truncate(line, 200)
+					matches = append(matches, SearchMatch{Path: config.relative(path), Line: index + 1, Text: text})
+					if len(matches) >= config.maxResults() {
+						return matches, nil
+					}
+				}
+			}
+			return matches, nil
+		})
+}
+
+// WriteFileInput holds the arguments of write_file
+type WriteFileInput struct {
+	Path    string `json:"path" description:"Path of the file, relative to the root directory"`
+	Content string `json:"content" description:"New content of the file"`
+}
+
+// WriteFileTool returns the write_file tool, creating or replacing a text file
+// (the missing directories are created). It is flagged as side-effecting.
+func WriteFileTool(config FilesystemConfig) *tools.RegisteredTool {
+	return sideEffecting(tools.Register("write_file", "Create or replace a text file",
+		func(ctx context.Context, input WriteFileInput) (string, error) {
+			path, err := config.resolve(input.Path)
+			if err != nil {
+				return "", err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return "", err
+			}
+			if err := files.WriteTextFile(path, input.Content); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d bytes written to %s", len(input.Content), config.relative(path)), nil
+		}))
+}
+
+// FilesystemTools returns read_file, list_files, search_files and write_file
+func FilesystemTools(config FilesystemConfig) []*tools.RegisteredTool {
+	return []*tools.RegisteredTool{
+		ReadFileTool(config),
+		ListFilesTool(config),
+		SearchFilesTool(config),
+		WriteFileTool(config),
+	}
+}
@@ -0,0 +1,78 @@
+// Package tooling provides ready-made tools built on tools.Register: filesystem access
+// confined to a root directory, shell commands with an allowlist, HTTP requests with a
+// host allowlist, time and timezone conversion, and an arithmetic evaluator.
+//
+// Each tool is created on its own and can be registered individually:
+//
+//	agent, err := tools.NewAgent(ctx, agentConfig, modelConfig,
+//		tools.WithRegisteredTools(tooling.CalculatorTool(), tooling.CurrentTimeTool()),
+//	)
+//
+// The mutating tools (write_file, run_command, http_post) are flagged as side-effecting
+// (Tool.SideEffects); ConfirmationPolicy asks for their confirmation and approves the others.
+package tooling
+
+import (
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// DefaultMaxOutput is the default maximum size in bytes of a tool result
+// (file content, command output, HTTP body)
+const DefaultMaxOutput = 64 * 1024
+
+// ConfirmationPolicy returns a policy approving the read-only tools and asking for the
+// confirmation of the mutating ones
+func ConfirmationPolicy(registeredTools ...*tools.RegisteredTool) *tools.ConfirmationPolicy {
+	policy := tools.NewConfirmationPolicy()
+	// A rule without tools matches every tool: empty lists are skipped
+	if names := ReadOnlyTools(registeredTools...); len(names) > 0 {
+		policy.AddRules(tools.AllowTools(names...))
+	}
+	if names := MutatingTools(registeredTools...); len(names) > 0 {
+		policy.AddRules(tools.AskTools(names...))
+	}
+	return policy
+}
+
+// MutatingTools returns the names of the tools flagged as side-effecting
+func MutatingTools(registeredTools ...*tools.RegisteredTool) []string {
+	var names []string
+	for _, registeredTool := range registeredTools {
+		if registeredTool.Tool.SideEffects {
+			names = append(names, registeredTool.Tool.GetName())
+		}
+	}
+	return names
+}
+
+// ReadOnlyTools returns the names of the tools not flagged as side-effecting
+func ReadOnlyTools(registeredTools ...*tools.RegisteredTool) []string {
+	var names []string
+	for _, registeredTool := range registeredTools {
+		if !registeredTool.Tool.SideEffects {
+			names = append(names, registeredTool.Tool.GetName())
+		}
+	}
+	return names
+}
+
+// truncate cuts text to maxLength bytes (without cutting a UTF-8 sequence) and reports
+// whether it was truncated
+func truncate(text string, maxLength int) (string, bool) {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxOutput
+	}
+	if len(text) <= maxLength {
+		return text, false
+	}
+	for maxLength > 0 && text[maxLength]&0xC0 == 0x80 {
+		maxLength--
+	}
+	return text[:maxLength], true
+}
+
+// sideEffecting flags a tool as mutating
+func sideEffecting(registeredTool *tools.RegisteredTool) *tools.RegisteredTool {
+	registeredTool.Tool.SetSideEffects(true)
+	return registeredTool
+}
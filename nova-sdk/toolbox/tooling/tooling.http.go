@@ -0,0 +1,163 @@
+package tooling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// DefaultHTTPTimeout is the default timeout of the HTTP tools
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTPConfig holds the settings of the HTTP tools
+type HTTPConfig struct {
+	// Hosts the tools can reach ("api.example.com", or "*.example.com" for the subdomains); required
+	AllowedHosts []string
+	Client       *http.Client      // HTTP client, copied with the redirection allowlist (default: http.Client)
+	Timeout      time.Duration     // Request timeout (default: the Client timeout, else DefaultHTTPTimeout)
+	MaxOutput    int               // Maximum size of the returned body (default: DefaultMaxOutput)
+	Headers      map[string]string // Headers added to every request (e.g. an API key)
+}
+
+// allows reports whether the host of a URL is in the allowlist
+func (config HTTPConfig) allows(target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	for _, allowed := range config.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, found := strings.CutPrefix(allowed, "*."); found {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// client returns a copy of the configured client (or a new one) with the timeout and
+// the redirection allowlist: a custom client can't bypass them
+func (config HTTPConfig) client() *http.Client {
+	client := &http.Client{}
+	if config.Client != nil {
+		*client = *config.Client
+	}
+	switch {
+	case config.Timeout > 0:
+		client.Timeout = config.Timeout
+	case client.Timeout <= 0:
+		client.Timeout = DefaultHTTPTimeout
+	}
+
+	// Redirections are followed only to allowed hosts
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if !config.allows(request.URL) {
+			return fmt.Errorf("redirection to a host not allowed: %s", request.URL.Hostname())
+		}
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirections")
+		}
+		return nil
+	}
+	return client
+}
+
+// HTTPResult is the result of the HTTP tools
+type HTTPResult struct {
+	Status    int    `json:"status"`
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// do sends a request to an allowed host
+func (config HTTPConfig) do(ctx context.Context, method string, rawURL string, contentType string, body io.Reader) (HTTPResult, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return HTTPResult{}, fmt.Errorf("invalid URL: %s", rawURL)
+	}
+	if !config.allows(target) {
+		return HTTPResult{}, fmt.Errorf("host not allowed: %s", target.Hostname())
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return HTTPResult{}, err
+	}
+	for name, value := range config.Headers {
+		request.Header.Set(name, value)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := config.client().Do(request)
+	if err != nil {
+		return HTTPResult{}, err
+	}
+	defer response.Body.Close()
+
+	maxOutput := config.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = DefaultMaxOutput
+	}
+	// One more byte tells whether the body was truncated
+	content, err := io.ReadAll(io.LimitReader(response.Body, int64(maxOutput)+1))
+	if err != nil {
+		return HTTPResult{}, err
+	}
+	result := HTTPResult{Status: response.StatusCode}
+	result.Body, result.Truncated = truncate(string(content), maxOutput)
+	return result, nil
+}
+
+// HTTPGetInput holds the arguments of http_get
+type HTTPGetInput struct {
+	URL string `json:"url" description:"URL to fetch (http or https)"`
+}
+
+// HTTPGetTool returns the http_get tool, fetching a URL of an allowed host
+func HTTPGetTool(config HTTPConfig) *tools.RegisteredTool {
+	description := fmt.Sprintf("Fetch a URL with a GET request (allowed hosts: %v)", config.AllowedHosts)
+	return tools.Register("http_get", description,
+		func(ctx context.Context, input HTTPGetInput) (HTTPResult, error) {
+			return config.do(ctx, http.MethodGet, input.URL, "", nil)
+		})
+}
+
+// HTTPPostInput holds the arguments of http_post
+type HTTPPostInput struct {
+	URL         string `json:"url" description:"URL of the request (http or https)"`
+	Body        string `json:"body" description:"Body of the request"`
+	ContentType string `json:"content_type,omitempty" description:"Content type of the body (default: application/json)"`
+}
+
+// HTTPPostTool returns the http_post tool, sending a POST request to an allowed host.
+// It is flagged as side-effecting.
+func HTTPPostTool(config HTTPConfig) *tools.RegisteredTool {
+	description := fmt.Sprintf("Send a POST request (allowed hosts: %v)", config.AllowedHosts)
+	return sideEffecting(tools.Register("http_post", description,
+		func(ctx context.Context, input HTTPPostInput) (HTTPResult, error) {
+			contentType := input.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			return config.do(ctx, http.MethodPost, input.URL, contentType, strings.NewReader(input.Body))
+		}))
+}
+
+// HTTPTools returns http_get and http_post
+func HTTPTools(config HTTPConfig) []*tools.RegisteredTool {
+	return []*tools.RegisteredTool{HTTPGetTool(config), HTTPPostTool(config)}
+}
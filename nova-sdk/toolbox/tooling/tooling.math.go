@@ -0,0 +1,284 @@
+package tooling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// MaxExpressionLength is the maximum length of an expression evaluated by calculate
+const MaxExpressionLength = 1024
+
+// maxExpressionDepth limits the nesting of the parentheses and the unary operators
+const maxExpressionDepth = 64
+
+var mathConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var mathFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"exp":   math.Exp,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+}
+
+// CalculateInput holds the arguments of calculate
+type CalculateInput struct {
+	Expression string `json:"expression" description:"Arithmetic expression, e.g. (2 + 3) * sqrt(16) ^ 2 % 7. Operators: + - * / % ^, functions: sqrt abs floor ceil round ln log exp sin cos tan, constants: pi e"`
+}
+
+// CalculateResult is the result of calculate
+type CalculateResult struct {
+	Expression string  `json:"expression"`
+	Result     float64 `json:"result"`
+}
+
+// CalculatorTool returns the calculate tool, evaluating an arithmetic expression.
+// The expression is parsed by a small evaluator: nothing is executed.
+func CalculatorTool() *tools.RegisteredTool {
+	return tools.Register("calculate", "Evaluate an arithmetic expression",
+		func(ctx context.Context, input CalculateInput) (CalculateResult, error) {
+			result, err := Evaluate(input.Expression)
+			if err != nil {
+				return CalculateResult{}, err
+			}
+			return CalculateResult{Expression: input.Expression, Result: result}, nil
+		})
+}
+
+// Evaluate computes an arithmetic expression (see CalculateInput for the grammar)
+func Evaluate(expression string) (float64, error) {
+	if len(expression) > MaxExpressionLength {
+		return 0, fmt.Errorf("expression longer than %d characters", MaxExpressionLength)
+	}
+	parser := &expressionParser{input: expression}
+	value, err := parser.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+	parser.skipSpaces()
+	if parser.position < len(parser.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", parser.input[parser.position], parser.position)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("the result is not a finite number")
+	}
+	return value, nil
+}
+
+// expressionParser is a recursive descent parser:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/" | "%") unary }
+//	unary      = ("-" | "+") unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | constant | function "(" expression ")" | "(" expression ")"
+type expressionParser struct {
+	input    string
+	position int
+	depth    int
+}
+
+func (parser *expressionParser) skipSpaces() {
+	for parser.position < len(parser.input) && unicode.IsSpace(rune(parser.input[parser.position])) {
+		parser.position++
+	}
+}
+
+// next skips the spaces and returns the next character (0 at the end)
+func (parser *expressionParser) next() byte {
+	parser.skipSpaces()
+	if parser.position >= len(parser.input) {
+		return 0
+	}
+	return parser.input[parser.position]
+}
+
+func (parser *expressionParser) enter() error {
+	parser.depth++
+	if parser.depth > maxExpressionDepth {
+		return fmt.Errorf("expression nested too deeply")
+	}
+	return nil
+}
+
+func (parser *expressionParser) parseExpression() (float64, error) {
+	value, err := parser.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch parser.next() {
+		case '+':
+			parser.position++
+			right, err := parser.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			value += right
+		case '-':
+			parser.position++
+			right, err := parser.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			value -= right
+		default:
+			return value, nil
+		}
+	}
+}
+
+func (parser *expressionParser) parseTerm() (float64, error) {
+	value, err := parser.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator := parser.next()
+		if operator != '*' && operator != '/' && operator != '%' {
+			return value, nil
+		}
+		parser.position++
+		right, err := parser.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case '*':
+			value *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("modulo by zero")
+			}
+			value = math.Mod(value, right)
+		}
+	}
+}
+
+func (parser *expressionParser) parseUnary() (float64, error) {
+	switch parser.next() {
+	case '-', '+':
+		sign := parser.input[parser.position]
+		parser.position++
+		if err := parser.enter(); err != nil {
+			return 0, err
+		}
+		value, err := parser.parseUnary()
+		parser.depth--
+		if sign == '-' {
+			value = -value
+		}
+		return value, err
+	}
+	return parser.parsePower()
+}
+
+func (parser *expressionParser) parsePower() (float64, error) {
+	base, err := parser.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+	if parser.next() != '^' {
+		return base, nil
+	}
+	parser.position++
+	// Right-associative: 2^3^2 is 2^(3^2)
+	if err := parser.enter(); err != nil {
+		return 0, err
+	}
+	exponent, err := parser.parseUnary()
+	parser.depth--
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (parser *expressionParser) parsePrimary() (float64, error) {
+	character := parser.next()
+	switch {
+	case character == '(':
+		parser.position++
+		return parser.parseParenthesized()
+	case character == '.' || (character >= '0' && character <= '9'):
+		return parser.parseNumber()
+	case unicode.IsLetter(rune(character)):
+		start := parser.position
+		for parser.position < len(parser.input) && unicode.IsLetter(rune(parser.input[parser.position])) {
+			parser.position++
+		}
+		name := strings.ToLower(parser.input[start:parser.position])
+		if function, found := mathFunctions[name]; found {
+			if parser.next() != '(' {
+				return 0, fmt.Errorf("expected ( after %s", name)
+			}
+			parser.position++
+			argument, err := parser.parseParenthesized()
+			if err != nil {
+				return 0, err
+			}
+			return function(argument), nil
+		}
+		if constant, found := mathConstants[name]; found {
+			return constant, nil
+		}
+		return 0, fmt.Errorf("unknown identifier %q", name)
+	case character == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", character, parser.position)
+	}
+}
+
+// parseParenthesized parses an expression followed by ")"
+func (parser *expressionParser) parseParenthesized() (float64, error) {
+	if err := parser.enter(); err != nil {
+		return 0, err
+	}
+	value, err := parser.parseExpression()
+	parser.depth--
+	if err != nil {
+		return 0, err
+	}
+	if parser.next() != ')' {
+		return 0, fmt.Errorf("missing ) at position %d", parser.position)
+	}
+	parser.position++
+	return value, nil
+}
+
+func (parser *expressionParser) parseNumber() (float64, error) {
+	start := parser.position
+	for parser.position < len(parser.input) {
+		character := parser.input[parser.position]
+		if (character < '0' || character > '9') && character != '.' {
+			break
+		}
+		parser.position++
+	}
+	value, err := strconv.ParseFloat(parser.input[start:parser.position], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", parser.input[start:parser.position])
+	}
+	return value, nil
+}
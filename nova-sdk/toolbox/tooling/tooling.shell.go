@@ -0,0 +1,124 @@
+package tooling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// DefaultShellTimeout is the default execution timeout of run_command
+const DefaultShellTimeout = 30 * time.Second
+
+// DefaultDeniedArguments are the arguments rejected by run_command when ShellConfig.DeniedArguments
+// is nil: they make an allowlisted command run other programs or write files
+var DefaultDeniedArguments = map[string][]string{
+	"find": {"-exec", "-execdir", "-ok", "-okdir", "-delete", "-fprint", "-fprint0", "-fprintf", "-fls"},
+	"git":  {"-c", "--config-env", "--exec-path", "--upload-pack", "--receive-pack", "-o", "--output", "--output-directory"},
+}
+
+// ShellConfig holds the settings of the run_command tool
+type ShellConfig struct {
+	// Allowlist of the executable commands (names such as "ls" or "git"); required.
+	// Only the command name is checked: an allowed command receives any argument but the
+	// denied ones, so allow only commands whose arguments can't run other programs.
+	Allowlist []string
+	// Arguments rejected per command, as "-exec" or "--option" (also matching "--option=value"),
+	// or a short option as "-o" (also matching its attached value "-oFILE")
+	// (default: DefaultDeniedArguments; an empty map denies nothing)
+	DeniedArguments map[string][]string
+
+	Directory string        // Working directory of the commands (default: the current directory)
+	Timeout   time.Duration // Execution timeout (default: DefaultShellTimeout)
+	MaxOutput int           // Maximum size of the returned output (default: DefaultMaxOutput)
+	Env       []string      // Environment of the commands (default: the environment of the process)
+}
+
+// deniedArgument returns the first denied argument of a command, or ""
+func (config ShellConfig) deniedArgument(command string, arguments []string) string {
+	deniedArguments := config.DeniedArguments
+	if deniedArguments == nil {
+		deniedArguments = DefaultDeniedArguments
+	}
+	for _, argument := range arguments {
+		for _, denied := range deniedArguments[command] {
+			if argument == denied || strings.HasPrefix(argument, denied+"=") || isShortOption(denied) && strings.HasPrefix(argument, denied) {
+				return argument
+			}
+		}
+	}
+	return ""
+}
+
+// isShortOption reports whether option is a single letter option such as "-o"
+func isShortOption(option string) bool {
+	return len(option) == 2 && option[0] == '-' && option[1] != '-'
+}
+
+// RunCommandInput holds the arguments of run_command
+type RunCommandInput struct {
+	Command   string   `json:"command" description:"Name of the command to run"`
+	Arguments []string `json:"arguments,omitempty" description:"Arguments of the command"`
+}
+
+// CommandResult is the result of run_command
+type CommandResult struct {
+	ExitCode  int    `json:"exit_code"`
+	Output    string `json:"output"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// RunCommandTool returns the run_command tool, running an allowlisted command.
+// The command is executed directly, without shell: pipes, redirections and variables are
+// not interpreted. The arguments are free except the denied ones (ShellConfig.DeniedArguments):
+// don't allow commands running their arguments (sh, env, xargs...). It is flagged as side-effecting.
+func RunCommandTool(config ShellConfig) *tools.RegisteredTool {
+	description := fmt.Sprintf("Run a command (allowed commands: %v) and return its combined output", config.Allowlist)
+	return sideEffecting(tools.Register("run_command", description,
+		func(ctx context.Context, input RunCommandInput) (CommandResult, error) {
+			// Only bare command names are allowed: "./ls" or "/tmp/ls" are not "ls"
+			if filepath.Base(input.Command) != input.Command || !slices.Contains(config.Allowlist, input.Command) {
+				return CommandResult{}, fmt.Errorf("command not allowed: %s", input.Command)
+			}
+			if denied := config.deniedArgument(input.Command, input.Arguments); denied != "" {
+				return CommandResult{}, fmt.Errorf("argument not allowed for %s: %s", input.Command, denied)
+			}
+
+			timeout := config.Timeout
+			if timeout <= 0 {
+				timeout = DefaultShellTimeout
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			command := exec.CommandContext(ctx, input.Command, input.Arguments...)
+			command.Dir = config.Directory
+			command.Env = config.Env
+			var output bytes.Buffer
+			command.Stdout = &output
+			command.Stderr = &output
+
+			err := command.Run()
+			if ctx.Err() == context.DeadlineExceeded {
+				return CommandResult{}, fmt.Errorf("command %s timed out after %v", input.Command, timeout)
+			}
+
+			result := CommandResult{}
+			result.Output, result.Truncated = truncate(output.String(), config.MaxOutput)
+			var exitErr *exec.ExitError
+			switch {
+			case errors.As(err, &exitErr):
+				result.ExitCode = exitErr.ExitCode()
+			case err != nil:
+				return CommandResult{}, err
+			}
+			return result, nil
+		}))
+}
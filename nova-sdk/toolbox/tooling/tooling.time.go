@@ -0,0 +1,102 @@
+package tooling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// TimeResult is the result of the time tools
+type TimeResult struct {
+	Time     string `json:"time"` // RFC 3339
+	Timezone string `json:"timezone"`
+	Weekday  string `json:"weekday"`
+}
+
+func timeResult(value time.Time, location *time.Location) TimeResult {
+	value = value.In(location)
+	return TimeResult{Time: value.Format(time.RFC3339), Timezone: location.String(), Weekday: value.Weekday().String()}
+}
+
+// loadLocation loads an IANA timezone ("" is UTC)
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", name)
+	}
+	return location, nil
+}
+
+// CurrentTimeInput holds the arguments of current_time
+type CurrentTimeInput struct {
+	Timezone string `json:"timezone,omitempty" description:"IANA timezone, e.g. Europe/Paris (default: UTC)"`
+}
+
+// CurrentTimeTool returns the current_time tool, giving the current date and time in a timezone
+func CurrentTimeTool() *tools.RegisteredTool {
+	return tools.Register("current_time", "Get the current date and time in a timezone",
+		func(ctx context.Context, input CurrentTimeInput) (TimeResult, error) {
+			location, err := loadLocation(input.Timezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			return timeResult(time.Now(), location), nil
+		})
+}
+
+// ConvertTimeInput holds the arguments of convert_time
+type ConvertTimeInput struct {
+	Time         string `json:"time" description:"Time to convert: RFC 3339 (2024-05-01T14:30:00Z), or 2024-05-01 14:30 / 14:30 in from_timezone"`
+	FromTimezone string `json:"from_timezone,omitempty" description:"IANA timezone of the time when it has no offset (default: UTC)"`
+	ToTimezone   string `json:"to_timezone" description:"IANA timezone to convert to, e.g. Asia/Tokyo"`
+}
+
+// ConvertTimeTool returns the convert_time tool, converting a time from a timezone to another
+func ConvertTimeTool() *tools.RegisteredTool {
+	return tools.Register("convert_time", "Convert a time from a timezone to another",
+		func(ctx context.Context, input ConvertTimeInput) (TimeResult, error) {
+			from, err := loadLocation(input.FromTimezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			to, err := loadLocation(input.ToTimezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			value, err := parseTime(input.Time, from)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			return timeResult(value, to), nil
+		})
+}
+
+// parseTime parses an RFC 3339 time, or a date and time without offset in location
+// (a time of day alone is today's)
+func parseTime(text string, location *time.Location) (time.Time, error) {
+	if value, err := time.Parse(time.RFC3339, text); err == nil {
+		return value, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if value, err := time.ParseInLocation(layout, text, location); err == nil {
+			return value, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err := time.Parse(layout, text); err == nil {
+			year, month, day := time.Now().In(location).Date()
+			return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), 0, location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", text)
+}
+
+// TimeTools returns current_time and convert_time
+func TimeTools() []*tools.RegisteredTool {
+	return []*tools.RegisteredTool{CurrentTimeTool(), ConvertTimeTool()}
+}
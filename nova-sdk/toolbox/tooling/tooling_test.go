@@ -0,0 +1,266 @@
+package tooling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/nova/nova-sdk/agents/tools"
+)
+
+// call runs the handler of a registered tool and decodes its result
+func call(t *testing.T, registeredTool *tools.RegisteredTool, arguments string, result any) error {
+	t.Helper()
+	output, err := registeredTool.Handler(context.Background(), arguments)
+	if err != nil {
+		return err
+	}
+	// A string result is returned as is
+	if text, isText := result.(*string); isText {
+		*text = output
+		return nil
+	}
+	if err := json.Unmarshal([]byte(output), result); err != nil {
+		t.Fatalf("invalid result %q: %v", output, err)
+	}
+	return nil
+}
+
+func TestFilesystemTools(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	config := FilesystemConfig{Root: root}
+
+	var written string
+	if err := call(t, WriteFileTool(config), `{"path":"notes/todo.md","content":"buy milk\ncall bob"}`, &written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var content string
+	if err := call(t, ReadFileTool(config), `{"path":"notes/todo.md"}`, &content); err != nil || !strings.Contains(content, "call bob") {
+		t.Fatalf("unexpected content %q (%v)", content, err)
+	}
+
+	var matches []SearchMatch
+	if err := call(t, SearchFilesTool(config), `{"pattern":"b[o]b"}`, &matches); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != filepath.Join("notes", "todo.md") || matches[0].Line != 2 {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+
+	for _, arguments := range []string{
+		`{"path":"../` + filepath.Base(outside) + `/secret.txt"}`,
+		`{"path":"escape/secret.txt"}`,
+	} {
+		content = ""
+		if err := call(t, ReadFileTool(config), arguments, &content); strings.Contains(content, "secret") {
+			t.Errorf("%s: the file outside the root should not be read", arguments)
+		} else if err == nil {
+			t.Errorf("%s: expected an error", arguments)
+		}
+	}
+	if err := call(t, WriteFileTool(config), `{"path":"escape/new.txt","content":"x"}`, &written); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("writing through a link leaving the root should fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Error("no file should be created outside the root")
+	}
+}
+
+func TestRunCommandTool(t *testing.T) {
+	tool := RunCommandTool(ShellConfig{Allowlist: []string{"echo"}, MaxOutput: 5})
+	if !tool.Tool.SideEffects {
+		t.Error("run_command should be flagged as side-effecting")
+	}
+
+	var result CommandResult
+	if err := call(t, tool, `{"command":"echo","arguments":["hello","world"]}`, &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || result.Output != "hello" || !result.Truncated {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	for _, command := range []string{"rm", "/bin/echo", "./echo"} {
+		if err := call(t, tool, `{"command":"`+command+`"}`, &result); err == nil {
+			t.Errorf("%s should not be allowed", command)
+		}
+	}
+}
+
+func TestRunCommandTool_DeniedArguments(t *testing.T) {
+	tool := RunCommandTool(ShellConfig{Allowlist: []string{"find", "git", "echo"}})
+	var result CommandResult
+	for _, arguments := range []string{
+		`{"command":"find","arguments":[".","-exec","rm","{}",";"]}`,
+		`{"command":"git","arguments":["-c","core.sshCommand=touch /tmp/pwned","fetch"]}`,
+		`{"command":"git","arguments":["--upload-pack=touch /tmp/pwned","fetch"]}`,
+	} {
+		if err := call(t, tool, arguments, &result); err == nil || !strings.Contains(err.Error(), "argument not allowed") {
+			t.Errorf("%s should be rejected, got %v", arguments, err)
+		}
+	}
+	if err := call(t, tool, `{"command":"echo","arguments":["-exec"]}`, &result); err != nil {
+		t.Errorf("the denied arguments are per command, got %v", err)
+	}
+
+	permissive := RunCommandTool(ShellConfig{Allowlist: []string{"find"}, DeniedArguments: map[string][]string{}})
+	if err := call(t, permissive, `{"command":"find","arguments":[".","-maxdepth","0","-exec","true",";"]}`, &result); err != nil {
+		t.Errorf("an empty denylist should deny nothing, got %v", err)
+	}
+}
+
+func TestShellConfig_DefaultDeniedArguments(t *testing.T) {
+	config := ShellConfig{}
+	for command, deniedArguments := range DefaultDeniedArguments {
+		for _, denied := range deniedArguments {
+			for _, argument := range []string{denied, denied + "=out.txt"} {
+				if config.deniedArgument(command, []string{"log", argument}) != argument {
+					t.Errorf("%s %s should be denied", command, argument)
+				}
+			}
+		}
+	}
+	if config.deniedArgument("git", []string{"diff", "-oout.txt"}) == "" {
+		t.Error("a short option with an attached value should be denied")
+	}
+	if denied := config.deniedArgument("git", []string{"log", "--oneline", "-n", "3"}); denied != "" {
+		t.Errorf("the other options should be allowed, got %q", denied)
+	}
+}
+
+func TestReadFileTool_ReadsOnlyMaxOutput(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("a", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content, truncated, err := readTextFile(filepath.Join(root, "big.txt"), 10)
+	if err != nil || content != strings.Repeat("a", 10) || !truncated {
+		t.Errorf("unexpected content %q (truncated %v, %v)", content, truncated, err)
+	}
+	var result string
+	if err := call(t, ReadFileTool(FilesystemConfig{Root: root, MaxOutput: 10}), `{"path":"big.txt"}`, &result); err != nil ||
+		result != strings.Repeat("a", 10)+"\n[content truncated]" {
+		t.Errorf("unexpected result %q (%v)", result, err)
+	}
+}
+
+func TestHTTPTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPost {
+			response.WriteHeader(http.StatusCreated)
+		}
+		_, _ = response.Write([]byte(request.Method + " " + request.Header.Get("X-Api-Key")))
+	}))
+	defer server.Close()
+
+	config := HTTPConfig{AllowedHosts: []string{"127.0.0.1"}, Headers: map[string]string{"X-Api-Key": "k"}}
+	var result HTTPResult
+	if err := call(t, HTTPGetTool(config), `{"url":"`+server.URL+`"}`, &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != http.StatusOK || result.Body != "GET k" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := call(t, HTTPPostTool(config), `{"url":"`+server.URL+`","body":"{}"}`, &result); err != nil || result.Status != http.StatusCreated {
+		t.Errorf("unexpected result %+v (%v)", result, err)
+	}
+
+	denied := HTTPConfig{AllowedHosts: []string{"*.example.com"}}
+	if err := call(t, HTTPGetTool(denied), `{"url":"`+server.URL+`"}`, &result); err == nil {
+		t.Error("a host outside the allowlist should be rejected")
+	}
+	if err := call(t, HTTPGetTool(denied), `{"url":"file:///etc/passwd"}`, &result); err == nil {
+		t.Error("only http and https should be allowed")
+	}
+}
+
+func TestHTTPTools_CustomClientKeepsTheAllowlist(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		_, _ = response.Write([]byte("secret"))
+	}))
+	defer target.Close()
+	// localhost is not in the allowlist, 127.0.0.1 is
+	redirected := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	server := httptest.NewServer(http.RedirectHandler(redirected, http.StatusFound))
+	defer server.Close()
+
+	followAll := func(*http.Request, []*http.Request) error { return nil }
+	client := &http.Client{CheckRedirect: followAll}
+	config := HTTPConfig{AllowedHosts: []string{"127.0.0.1"}, Client: client}
+	var result HTTPResult
+	if err := call(t, HTTPGetTool(config), `{"url":"`+server.URL+`"}`, &result); err == nil {
+		t.Errorf("a redirection to a host outside the allowlist should be rejected, got %+v", result)
+	}
+	if config.client().Timeout != DefaultHTTPTimeout {
+		t.Errorf("the custom client should get the default timeout, got %v", config.client().Timeout)
+	}
+	if client.Timeout != 0 {
+		t.Error("the custom client should not be modified")
+	}
+}
+
+func TestConvertTimeTool(t *testing.T) {
+	var result TimeResult
+	arguments := `{"time":"2024-07-01 09:00","from_timezone":"Europe/Paris","to_timezone":"Asia/Tokyo"}`
+	if err := call(t, ConvertTimeTool(), arguments, &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Time != "2024-07-01T16:00:00+09:00" || result.Timezone != "Asia/Tokyo" || result.Weekday != "Monday" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := call(t, CurrentTimeTool(), `{"timezone":"Mars/Olympus"}`, &result); err == nil {
+		t.Error("an unknown timezone should be rejected")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := map[string]float64{
+		"1 + 2 * 3":          7,
+		"(1 + 2) * 3":        9,
+		"-2 ^ 2":             -4,
+		"2 ^ 3 ^ 2":          512,
+		"10 % 4 - -1":        3,
+		"sqrt(16) + abs(-2)": 6,
+		"round(pi * 100)":    314,
+		".5 * 4":             2,
+	}
+	for expression, expected := range tests {
+		if value, err := Evaluate(expression); err != nil || value != expected {
+			t.Errorf("%s: expected %v, got %v (%v)", expression, expected, value, err)
+		}
+	}
+
+	for _, expression := range []string{"", "1 +", "1 / 0", "(1", "os(1)", "1 2", strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100)} {
+		if _, err := Evaluate(expression); err == nil {
+			t.Errorf("%q should be rejected", expression)
+		}
+	}
+}
+
+func TestConfirmationPolicy(t *testing.T) {
+	config := FilesystemConfig{Root: t.TempDir()}
+	policy := ConfirmationPolicy(append(FilesystemTools(config), CalculatorTool())...)
+
+	for tool, expected := range map[string]tools.PolicyAction{
+		"read_file":  tools.PolicyAllow,
+		"calculate":  tools.PolicyAllow,
+		"write_file": tools.PolicyAsk,
+	} {
+		if action, _ := policy.Evaluate(tool, `{}`); action != expected {
+			t.Errorf("%s: expected %v, got %v", tool, expected, action)
+		}
+	}
+}
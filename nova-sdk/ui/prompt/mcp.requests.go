@@ -0,0 +1,150 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/snipwise/nova/nova-sdk/mcptools"
+)
+
+// MCPSamplingConfirmation asks the user to approve a completion requested by an MCP server.
+// It can be used as a mcptools.SamplingConfirmation.
+func MCPSamplingConfirmation(ctx context.Context, request mcptools.SamplingRequest) (bool, error) {
+	fmt.Printf("%s🧠 An MCP server requests a completion%s\n", ColorBrightCyan, ColorReset)
+	if request.SystemPrompt != "" {
+		fmt.Printf("%s   system: %s%s\n", ColorGray, request.SystemPrompt, ColorReset)
+	}
+	for _, message := range request.Messages {
+		fmt.Printf("%s   %s: %s%s\n", ColorGray, message.Role, message.Content, ColorReset)
+	}
+	return NewColorConfirm("Send this request to the model?").SetDefault(false).Run()
+}
+
+// MCPElicitation asks the user for the input requested by an MCP server: the user accepts,
+// declines or cancels, then fills the properties of the requested schema.
+// It can be used as a mcptools.ElicitationCallback.
+func MCPElicitation(ctx context.Context, request mcptools.ElicitationRequest) (mcptools.ElicitationResponse, error) {
+	choices := []Choice{
+		{Label: "answer", Value: "a"},
+		{Label: "decline", Value: "d"},
+		{Label: "cancel", Value: "c"},
+	}
+	selected, err := NewColorSelectKey("🙋 An MCP server asks: "+request.Message, choices).
+		SetDefault("a").
+		SetColors(
+			ColorBrightCyan,   // message color
+			ColorWhite,        // choice color
+			ColorBrightYellow, // default color
+			ColorGray,         // key color
+			ColorRed,          // error color
+		).
+		SetSymbols("❯", "●", "✗").
+		Run()
+	if err != nil {
+		return mcptools.ElicitationResponse{}, err
+	}
+	switch selected {
+	case "d":
+		return mcptools.ElicitationResponse{Action: mcptools.ElicitationDecline}, nil
+	case "c":
+		return mcptools.ElicitationResponse{Action: mcptools.ElicitationCancel}, nil
+	}
+
+	properties, _ := request.Schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	if names, ok := request.Schema["required"].([]any); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	content := make(map[string]any, len(properties))
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		property, _ := properties[name].(map[string]any)
+		value, err := askProperty(name, property, required[name])
+		if err != nil {
+			return mcptools.ElicitationResponse{}, err
+		}
+		if value != nil {
+			content[name] = value
+		}
+	}
+	return mcptools.ElicitationResponse{Action: mcptools.ElicitationAccept, Content: content}, nil
+}
+
+// askProperty asks the value of a property of an elicitation schema
+// (nil: an optional property left empty)
+func askProperty(name string, property map[string]any, isRequired bool) (any, error) {
+	label := name
+	if title, ok := property["title"].(string); ok && title != "" {
+		label = title
+	}
+	if description, ok := property["description"].(string); ok && description != "" {
+		label += " (" + description + ")"
+	}
+	propertyType, _ := property["type"].(string)
+
+	if values, ok := property["enum"].([]any); ok && len(values) > 0 {
+		choices := make([]Choice, 0, len(values))
+		for _, value := range values {
+			choices = append(choices, Choice{Label: fmt.Sprint(value), Value: fmt.Sprint(value)})
+		}
+		selected, err := NewColorSelect(label, choices).Run()
+		if err != nil {
+			return nil, err
+		}
+		return typedValue(propertyType, selected)
+	}
+	if propertyType == "boolean" {
+		defaultValue, _ := property["default"].(bool)
+		return NewColorConfirm(label).SetDefault(defaultValue).Run()
+	}
+
+	input := NewWithColor(label).SetValidator(func(value string) error {
+		if value == "" {
+			if isRequired {
+				return errors.New("a value is required")
+			}
+			return nil
+		}
+		switch propertyType {
+		case "integer":
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return errors.New("the value must be an integer")
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return errors.New("the value must be a number")
+			}
+		}
+		return nil
+	})
+	if defaultValue, found := property["default"]; found {
+		input.SetDefault(fmt.Sprint(defaultValue))
+	}
+	value, err := input.Run()
+	if err != nil || value == "" {
+		return nil, err
+	}
+	return typedValue(propertyType, value)
+}
+
+// typedValue converts an answer to the type of the property
+func typedValue(propertyType string, value string) (any, error) {
+	switch propertyType {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,27 @@
+package prompt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		propertyType string
+		// The enum values of a schema are decoded from JSON
+		value    any
+		expected any
+	}{
+		{"integer", float64(3), int64(3)},
+		{"number", 2.5, 2.5},
+		{"boolean", true, true},
+		{"string", "red", "red"},
+		{"", "red", "red"},
+	}
+	for _, test := range tests {
+		value, err := typedValue(test.propertyType, fmt.Sprint(test.value))
+		if err != nil || value != test.expected {
+			t.Errorf("%s %v: expected %#v, got %#v (error %v)", test.propertyType, test.value, test.expected, value, err)
+		}
+	}
+}
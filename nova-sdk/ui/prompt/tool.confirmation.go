@@ -1,6 +1,9 @@
 package prompt
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/snipwise/nova/nova-sdk/agents/tools"
@@ -10,9 +13,50 @@ func HumanConfirmation(text string) tools.ConfirmationResponse {
 	choices := []Choice{
 		{Label: "yes", Value: "y"},
 		{Label: "no", Value: "n"},
+		{Label: "yes for this session", Value: "s"},
+		{Label: "always", Value: "a"},
 		{Label: "quit", Value: "q"},
 	}
 
+	return confirmationResponse(runConfirmationSelect(text, choices))
+}
+
+// HumanConfirmationDecision asks for the confirmation of a tool call, with the possibility
+// to edit the arguments before running it.
+// It can be used as a tools.ConfirmationDecisionCallback.
+func HumanConfirmationDecision(functionName string, arguments string) tools.ConfirmationDecision {
+	choices := []Choice{
+		{Label: "yes", Value: "y"},
+		{Label: "no", Value: "n"},
+		{Label: "yes for this session", Value: "s"},
+		{Label: "always", Value: "a"},
+		{Label: "edit the arguments", Value: "e"},
+		{Label: "quit", Value: "q"},
+	}
+
+	selected := runConfirmationSelect(fmt.Sprintf("Execute %s with %s?", functionName, arguments), choices)
+	if selected != "e" {
+		return tools.ConfirmationDecision{Response: confirmationResponse(selected)}
+	}
+
+	editPrompt := NewWithColor("Arguments (JSON)").
+		SetDefault(arguments).
+		SetValidator(func(input string) error {
+			if !json.Valid([]byte(input)) {
+				return errors.New("the arguments must be valid JSON")
+			}
+			return nil
+		})
+
+	edited, err := editPrompt.RunWithEdit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return tools.ConfirmationDecision{Response: tools.EditedAndConfirmed, Arguments: edited}
+}
+
+func runConfirmationSelect(text string, choices []Choice) string {
 	selectPrompt := NewColorSelectKey(text, choices).
 		SetDefault("y").
 		SetColors(
@@ -28,7 +72,10 @@ func HumanConfirmation(text string) tools.ConfirmationResponse {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return selected
+}
 
+func confirmationResponse(selected string) tools.ConfirmationResponse {
 	switch selected {
 	case "q":
 		return tools.Quit
@@ -36,6 +83,10 @@ func HumanConfirmation(text string) tools.ConfirmationResponse {
 		return tools.Denied
 	case "y":
 		return tools.Confirmed
+	case "s":
+		return tools.ConfirmedForSession
+	case "a":
+		return tools.ConfirmedAlways
 	default:
 		return tools.Denied
 	}